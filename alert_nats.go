@@ -0,0 +1,35 @@
+//go:build nats
+
+package main
+
+import (
+    "context"
+
+    "github.com/nats-io/nats.go"
+)
+
+func init() {
+    newNATSPublisher = newNATSConnPublisher
+}
+
+type natsConnPublisher struct {
+    conn *nats.Conn
+}
+
+func newNATSConnPublisher(config NATSConfig) (natsPublisher, error) {
+    // The client reconnects on its own; publishes while disconnected are
+    // buffered until the connection is back
+    conn, err := nats.Connect(config.URL, nats.Name("monitor-alert"), nats.MaxReconnects(-1))
+    if err != nil {
+        return nil, err
+    }
+    return &natsConnPublisher{conn: conn}, nil
+}
+
+func (p *natsConnPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+    if err := p.conn.Publish(subject, data); err != nil {
+        return err
+    }
+    // Flush so a failure to reach the server surfaces as an error
+    return p.conn.FlushWithContext(ctx)
+}
@@ -0,0 +1,42 @@
+//go:build sns
+
+package main
+
+import (
+    "context"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    awsconfig "github.com/aws/aws-sdk-go-v2/config"
+    "github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+func init() {
+    newSNSPublisher = newAWSSNSPublisher
+}
+
+type awsSNSPublisher struct {
+    client   *sns.Client
+    topicARN string
+}
+
+func newAWSSNSPublisher(config SNSConfig) (snsPublisher, error) {
+    var opts []func(*awsconfig.LoadOptions) error
+    if config.Region != "" {
+        opts = append(opts, awsconfig.WithRegion(config.Region))
+    }
+
+    cfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+    if err != nil {
+        return nil, err
+    }
+    return &awsSNSPublisher{client: sns.NewFromConfig(cfg), topicARN: config.TopicARN}, nil
+}
+
+func (p *awsSNSPublisher) Publish(ctx context.Context, subject, message string) error {
+    _, err := p.client.Publish(ctx, &sns.PublishInput{
+        TopicArn: aws.String(p.topicARN),
+        Subject:  aws.String(subject),
+        Message:  aws.String(message),
+    })
+    return err
+}
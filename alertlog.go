@@ -0,0 +1,117 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "sync"
+    "time"
+)
+
+// defaultAlertFileMaxSize is the size at which the alert file is rotated
+const defaultAlertFileMaxSize = 10 << 20
+
+type FileConfig struct {
+    Path       string `json:"path"`
+    MaxSize    int64  `json:"max_size"`    // in bytes, rotate once the file would exceed this
+    MaxBackups int    `json:"max_backups"` // rotated files kept as path.1, path.2, ...
+}
+
+type SyslogConfig struct {
+    Network string `json:"network"` // "" for the local syslog daemon, or "udp"/"tcp"
+    Address string `json:"address"`
+    Tag     string `json:"tag"`
+}
+
+// alertRecord is the JSON line written for each alert and recovery
+type alertRecord struct {
+    Time     string `json:"time"`
+    Event    string `json:"event"` // "alert", "recovery" or "flapping"
+    Service  string `json:"service"`
+    Severity string `json:"severity"`
+    Message  string `json:"message"`
+}
+
+// alertFile appends alert records to a file, rotating it by size
+type alertFile struct {
+    config FileConfig
+    mutex  sync.Mutex
+}
+
+func (f *alertFile) write(record alertRecord) error {
+    line, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+    line = append(line, '\n')
+
+    f.mutex.Lock()
+    defer f.mutex.Unlock()
+
+    maxSize := f.config.MaxSize
+    if maxSize <= 0 {
+        maxSize = defaultAlertFileMaxSize
+    }
+    if info, err := os.Stat(f.config.Path); err == nil && info.Size()+int64(len(line)) > maxSize {
+        if err := f.rotate(); err != nil {
+            return fmt.Errorf("error rotating alert file: %v", err)
+        }
+    }
+
+    file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    _, err = file.Write(line)
+    return err
+}
+
+func (f *alertFile) rotate() error {
+    backups := f.config.MaxBackups
+    if backups <= 0 {
+        backups = 1
+    }
+
+    for i := backups - 1; i >= 1; i-- {
+        from := fmt.Sprintf("%s.%d", f.config.Path, i)
+        if _, err := os.Stat(from); err == nil {
+            if err := os.Rename(from, fmt.Sprintf("%s.%d", f.config.Path, i+1)); err != nil {
+                return err
+            }
+        }
+    }
+    return os.Rename(f.config.Path, f.config.Path+".1")
+}
+
+// recordAlert writes an alert event to the configured file, syslog and NATS
+// outputs. These are durable records rather than notifications, so they
+// receive every event regardless of severity routing.
+func (m *Monitor) recordAlert(event string, service ServiceConfig, message string) {
+    record := alertRecord{
+        Time:     m.formatTime(time.Now()),
+        Event:    event,
+        Service:  service.Name,
+        Severity: serviceSeverity(service),
+        Message:  message,
+    }
+
+    if m.alertFile != nil && m.channelEnabled(ChannelFile) {
+        if err := m.alertFile.write(record); err != nil {
+            m.logger.Printf("file:"+service.Name, "Error writing alert file: %v", err)
+        }
+    }
+
+    if m.syslog != nil && m.channelEnabled(ChannelSyslog) {
+        if err := m.syslog.write(record); err != nil {
+            m.logger.Printf("syslog:"+service.Name, "Error writing to syslog: %v", err)
+        }
+    }
+
+    if m.nats != nil && m.channelEnabled(ChannelNATS) {
+        if err := m.publishEvent(record, service); err != nil {
+            m.logger.Printf("nats:"+service.Name, "Error publishing to NATS: %v", err)
+        }
+    }
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// readAlertRecords decodes the JSON lines of an alert file
+func readAlertRecords(t *testing.T, path string) []alertRecord {
+    t.Helper()
+    file, err := os.Open(path)
+    if err != nil {
+        t.Fatalf("opening %s: %v", path, err)
+    }
+    defer file.Close()
+
+    var records []alertRecord
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        var record alertRecord
+        if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+            t.Fatalf("line %q: %v", scanner.Text(), err)
+        }
+        records = append(records, record)
+    }
+    return records
+}
+
+func TestAlertFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "alerts.jsonl")
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    service.Severity = SeverityCritical
+    m, _ := newTestMonitor(t, MonitorConfig{
+        Services: []ServiceConfig{service},
+        Alerts:   AlertConfig{File: &FileConfig{Path: path}},
+    })
+
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+
+    records := readAlertRecords(t, path)
+    if len(records) != 2 {
+        t.Fatalf("wrote %d records, want 2: %+v", len(records), records)
+    }
+    if got := records[0]; got.Event != EventAlert || got.Service != "api" || got.Severity != SeverityCritical || got.Message != "unexpected status code: 500" {
+        t.Errorf("alert record %+v", got)
+    }
+    if got := records[1]; got.Event != EventRecovery || !strings.Contains(got.Message, "RECOVERED") {
+        t.Errorf("recovery record %+v", got)
+    }
+}
+
+func TestAlertFileRotation(t *testing.T) {
+    tests := []struct {
+        name    string
+        writes  int
+        backups int
+        want    []string // files present afterwards
+        absent  []string
+    }{
+        {"under the size", 1, 2, []string{"alerts.jsonl"}, []string{"alerts.jsonl.1"}},
+        {"rotates at the size", 3, 2, []string{"alerts.jsonl", "alerts.jsonl.1"}, []string{"alerts.jsonl.2"}},
+        {"keeps max_backups", 8, 2, []string{"alerts.jsonl", "alerts.jsonl.1", "alerts.jsonl.2"}, []string{"alerts.jsonl.3"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            record := alertRecord{Event: EventAlert, Service: "api", Message: strings.Repeat("x", 100)}
+            line, _ := json.Marshal(record)
+            // Room for two lines per file
+            file := &alertFile{config: FileConfig{Path: filepath.Join(dir, "alerts.jsonl"), MaxSize: int64(2*len(line) + 2), MaxBackups: tt.backups}}
+
+            for i := 0; i < tt.writes; i++ {
+                if err := file.write(record); err != nil {
+                    t.Fatalf("write %d: %v", i, err)
+                }
+            }
+
+            for _, name := range tt.want {
+                info, err := os.Stat(filepath.Join(dir, name))
+                if err != nil {
+                    t.Errorf("%s missing: %v", name, err)
+                } else if info.Size() > file.config.MaxSize {
+                    t.Errorf("%s is %d bytes, over max_size %d", name, info.Size(), file.config.MaxSize)
+                }
+            }
+            for _, name := range tt.absent {
+                if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+                    t.Errorf("%s exists", name)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+    "errors"
+    "log"
+    "math"
+    "math/rand/v2"
+    "sync"
+    "time"
+)
+
+// defaultAlertQueueSize bounds pending alert deliveries when
+// alert_queue_size is unset
+const defaultAlertQueueSize = 100
+
+// RetryPolicy controls how a channel's failed deliveries are retried. Delays
+// double from InitialDelay up to MaxDelay, each randomised by up to Jitter
+// of itself so channels recovering from an outage aren't hit in lockstep.
+type RetryPolicy struct {
+    Attempts     int     `json:"attempts"`
+    InitialDelay float64 `json:"initial_delay"` // in seconds
+    MaxDelay     float64 `json:"max_delay"`     // in seconds
+    Jitter       float64 `json:"jitter"`        // fraction of each delay, 0 to 1
+}
+
+// defaultRetryPolicy applies to channels without a delivery_retry entry and
+// fills unset fields of those with one
+var defaultRetryPolicy = RetryPolicy{
+    Attempts:     3,
+    InitialDelay: 1,
+    MaxDelay:     30,
+    Jitter:       0.2,
+}
+
+// alertJob is one queued notification, such as a down alert to all of a
+// service's routed channels
+type alertJob struct {
+    service string
+    deliver func()
+}
+
+// channelJob is one event's delivery to a single channel
+type channelJob struct {
+    event  AlertEvent
+    sender AlertSender
+}
+
+// channelQueues holds a queue and worker per channel, so a slow or hung
+// channel delays only its own notifications
+type channelQueues struct {
+    mutex  sync.Mutex
+    queues map[string]chan channelJob
+}
+
+// dispatch queues an alert for the delivery worker so status updates, which
+// hold statusMutex, never wait on notification I/O. When the queue is full
+// the oldest pending alert is dropped to make room.
+func (m *Monitor) dispatch(service string, deliver func()) {
+    m.alertWG.Add(1)
+    job := alertJob{service: service, deliver: deliver}
+    for {
+        select {
+        case m.alertQueue <- job:
+            return
+        default:
+        }
+
+        select {
+        case dropped := <-m.alertQueue:
+            log.Printf("Warning: alert queue full, dropping oldest pending alert for %s", dropped.service)
+            m.alertWG.Done()
+        default:
+        }
+    }
+}
+
+// runAlertWorker runs queued alerts one at a time, in order. Each decides
+// what to send and hands its deliveries to the channels' workers, so it
+// never waits on a channel.
+func (m *Monitor) runAlertWorker() {
+    for job := range m.alertQueue {
+        job.deliver()
+        m.alertWG.Done()
+    }
+}
+
+// enqueueDelivery queues a delivery on the channel's own worker, starting
+// it on first use. When the queue is full the oldest pending delivery is
+// dropped and recorded as failed.
+func (m *Monitor) enqueueDelivery(channel string, job channelJob) {
+    m.channelQueues.mutex.Lock()
+    if m.channelQueues.queues == nil {
+        m.channelQueues.queues = make(map[string]chan channelJob)
+    }
+    queue, ok := m.channelQueues.queues[channel]
+    if !ok {
+        queue = make(chan channelJob, cap(m.alertQueue))
+        m.channelQueues.queues[channel] = queue
+        go m.runChannelWorker(channel, queue)
+    }
+    m.channelQueues.mutex.Unlock()
+
+    m.alertWG.Add(1)
+    for {
+        select {
+        case queue <- job:
+            return
+        default:
+        }
+
+        select {
+        case dropped := <-queue:
+            log.Printf("Warning: %s queue full, dropping oldest pending %s for %s", channel, dropped.event.Kind, dropped.event.Service.Name)
+            m.recordDelivery(dropped.event, channel, errors.New("dropped from full queue"))
+            m.alertWG.Done()
+        default:
+        }
+    }
+}
+
+func (m *Monitor) runChannelWorker(channel string, queue chan channelJob) {
+    for job := range queue {
+        m.deliverTo(channel, job)
+        m.alertWG.Done()
+    }
+}
+
+func (m *Monitor) retryPolicy(channel string) RetryPolicy {
+    policy, ok := m.config.Alerts.DeliveryRetry[channel]
+    if !ok {
+        return defaultRetryPolicy
+    }
+    if policy.Attempts <= 0 {
+        policy.Attempts = defaultRetryPolicy.Attempts
+    }
+    if policy.InitialDelay <= 0 {
+        policy.InitialDelay = defaultRetryPolicy.InitialDelay
+    }
+    if policy.MaxDelay <= 0 {
+        policy.MaxDelay = defaultRetryPolicy.MaxDelay
+    }
+    return policy
+}
+
+// backoff is the delay before retry number retry (from 1): exponential
+// growth with jitter, never exceeding MaxDelay. Jitter is applied after the
+// cap so retries that reached it are still spread out.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+    delay := math.Min(p.InitialDelay*math.Pow(2, float64(retry-1)), p.MaxDelay)
+    if p.Jitter > 0 {
+        delay += delay * p.Jitter * (2*rand.Float64() - 1)
+    }
+    if delay > p.MaxDelay {
+        delay = p.MaxDelay
+    }
+    return time.Duration(delay * float64(time.Second))
+}
+
+// retryDelivery retries a single channel send under the channel's retry
+// policy and returns the last error if every attempt failed
+func (m *Monitor) retryDelivery(channel string, send func() error) error {
+    policy := m.retryPolicy(channel)
+    var err error
+    for attempt := 0; attempt < policy.Attempts; attempt++ {
+        if attempt > 0 {
+            // A cancelled shutdown drain ends the retries
+            select {
+            case <-time.After(policy.backoff(attempt)):
+            case <-m.ctx.Done():
+                return err
+            }
+        }
+        if err = send(); err == nil || errors.Is(err, errNotApplicable) {
+            return err
+        }
+    }
+    return err
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "math"
+    "net/http"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// blockingSender holds every delivery until released or cancelled
+type blockingSender struct {
+    release chan struct{}
+}
+
+func (s blockingSender) Send(ctx context.Context, event AlertEvent) error {
+    select {
+    case <-s.release:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func TestHungChannelDoesNotDelayOthers(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    services := []ServiceConfig{testService("a", backend.URL), testService("b", backend.URL)}
+    m, sender := newTestMonitor(t, MonitorConfig{Services: services})
+    hung := blockingSender{release: make(chan struct{})}
+    m.RegisterSender("hung", hung)
+    m.config.Alerts.Routing[SeverityWarning] = []string{"hung", "test"}
+
+    for _, service := range services {
+        m.checkService(service)
+    }
+
+    deadline := time.Now().Add(5 * time.Second)
+    for len(sender.kinds()) < 2 && time.Now().Before(deadline) {
+        time.Sleep(10 * time.Millisecond)
+    }
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert, EventAlert}) {
+        t.Errorf("delivered %v while another channel hung, want both alerts", got)
+    }
+
+    close(hung.release)
+    m.alertWG.Wait()
+}
+
+func TestCheckDoesNotWaitOnDelivery(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", backend.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    hung := blockingSender{release: make(chan struct{})}
+    m.RegisterSender("hung", hung)
+    m.config.Alerts.Routing[SeverityWarning] = []string{"hung"}
+
+    done := make(chan struct{})
+    go func() {
+        m.checkService(service)
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(2 * time.Second):
+        t.Fatal("check waited on a hung delivery")
+    }
+
+    // The worker delivers once the channel answers
+    close(hung.release)
+    m.alertWG.Wait()
+    m.statusMutex.RLock()
+    sent := m.serviceStatus["api"].AlertSent
+    m.statusMutex.RUnlock()
+    if !sent {
+        t.Error("alert was not marked sent")
+    }
+}
+
+func TestAlertQueueDropsOldest(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{AlertQueueSize: 2})
+
+    // Hold the worker so later alerts queue up behind it
+    started, release := make(chan struct{}), make(chan struct{})
+    m.dispatch("busy", func() {
+        close(started)
+        <-release
+    })
+    <-started
+
+    var mutex sync.Mutex
+    var ran []string
+    for _, name := range []string{"first", "second", "third"} {
+        m.dispatch(name, func() {
+            mutex.Lock()
+            ran = append(ran, name)
+            mutex.Unlock()
+        })
+    }
+    close(release)
+    m.alertWG.Wait()
+
+    if want := []string{"second", "third"}; !equalStrings(ran, want) {
+        t.Errorf("ran %v, want %v with the oldest dropped", ran, want)
+    }
+}
+
+// flakySender fails its first failures sends
+type flakySender struct {
+    recordingSender
+    failures atomic.Int32
+}
+
+func (s *flakySender) Send(ctx context.Context, event AlertEvent) error {
+    if s.failures.Add(-1) >= 0 {
+        return errors.New("temporarily unavailable")
+    }
+    return s.recordingSender.Send(ctx, event)
+}
+
+func TestDeliveryRetried(t *testing.T) {
+    tests := []struct {
+        name     string
+        failures int32
+        attempts int
+        want     int // events delivered
+    }{
+        {"delivered first time", 0, 3, 1},
+        {"delivered on retry", 2, 3, 1},
+        {"retries exhausted", 3, 3, 0},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", backend.URL)
+            config := MonitorConfig{Services: []ServiceConfig{service}}
+            config.Alerts.DeliveryRetry = map[string]RetryPolicy{"test": {Attempts: tt.attempts, InitialDelay: 0.01, MaxDelay: 0.05}}
+            m, _ := newTestMonitor(t, config)
+            sender := &flakySender{}
+            sender.failures.Store(tt.failures)
+            m.RegisterSender("test", sender)
+
+            checkAndFlush(m, service)
+
+            if got := len(sender.kinds()); got != tt.want {
+                t.Errorf("delivered %d events, want %d", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+    policy := RetryPolicy{Attempts: 8, InitialDelay: 1, MaxDelay: 10, Jitter: 0.2}
+
+    for retry := 1; retry <= 6; retry++ {
+        base := math.Min(math.Pow(2, float64(retry-1)), policy.MaxDelay)
+        low := time.Duration(base * (1 - policy.Jitter) * float64(time.Second))
+        high := time.Duration(math.Min(base*(1+policy.Jitter), policy.MaxDelay) * float64(time.Second))
+
+        distinct := make(map[time.Duration]bool)
+        for i := 0; i < 50; i++ {
+            delay := policy.backoff(retry)
+            if delay < low || delay > high {
+                t.Fatalf("retry %d delayed %s, want between %s and %s", retry, delay, low, high)
+            }
+            distinct[delay] = true
+        }
+        if len(distinct) < 2 && high > low {
+            t.Errorf("retry %d always delayed the same, want jitter", retry)
+        }
+    }
+
+    if delay := (RetryPolicy{InitialDelay: 1, MaxDelay: 10}).backoff(3); delay != 4*time.Second {
+        t.Errorf("unjittered third retry delayed %s, want 4s", delay)
+    }
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+    config := MonitorConfig{}
+    config.Alerts.DeliveryRetry = map[string]RetryPolicy{"slack": {Attempts: 5}}
+    m, _ := newTestMonitor(t, config)
+
+    if got := m.retryPolicy("slack"); got != (RetryPolicy{Attempts: 5, InitialDelay: 1, MaxDelay: 30}) {
+        t.Errorf("slack policy %+v, want unset fields filled but no jitter", got)
+    }
+    if got := m.retryPolicy("email"); got != defaultRetryPolicy {
+        t.Errorf("email policy %+v, want the default", got)
+    }
+}
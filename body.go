@@ -0,0 +1,94 @@
+package main
+
+import (
+    "bytes"
+    "compress/flate"
+    "compress/gzip"
+    "compress/zlib"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+)
+
+// defaultMaxBodyBytes bounds how much of a response body is read when
+// max_body_bytes is unset
+const defaultMaxBodyBytes = 1 << 20
+
+// maxBodyBytes is the body read limit for a service: its own max_body_bytes,
+// then the global one, then the default
+func (m *Monitor) maxBodyBytes(service ServiceConfig) int64 {
+    if service.MaxBodyBytes > 0 {
+        return service.MaxBodyBytes
+    }
+    if m.config.MaxBodyBytes > 0 {
+        return m.config.MaxBodyBytes
+    }
+    return defaultMaxBodyBytes
+}
+
+// readBody reads a response body up to limit bytes, transparently decoding
+// gzip and deflate content. The decoded size is bounded as well so a small
+// compressed payload cannot expand without limit. A body over the limit is
+// cut off at it and reported as truncated.
+func readBody(resp *http.Response, limit int64) ([]byte, bool, error) {
+    raw, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+    if err != nil {
+        return nil, false, fmt.Errorf("error reading response body: %v", err)
+    }
+    truncated := int64(len(raw)) > limit
+    if truncated {
+        raw = raw[:limit]
+    }
+
+    var decoder io.Reader
+    switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+    case "", "identity":
+        return raw, truncated, nil
+    case "gzip", "x-gzip":
+        reader, err := gzip.NewReader(bytes.NewReader(raw))
+        if err != nil {
+            return nil, false, fmt.Errorf("error decoding gzip body: %v", err)
+        }
+        defer reader.Close()
+        decoder = reader
+    case "deflate":
+        // "deflate" should be zlib-wrapped, but some servers send raw deflate
+        reader, err := zlib.NewReader(bytes.NewReader(raw))
+        if err != nil {
+            decoder = flate.NewReader(bytes.NewReader(raw))
+        } else {
+            defer reader.Close()
+            decoder = reader
+        }
+    default:
+        return nil, false, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+    }
+
+    body, err := io.ReadAll(io.LimitReader(decoder, limit+1))
+    // A compressed stream cut off by the limit ends early; keep what decoded
+    if err != nil && !(truncated && errors.Is(err, io.ErrUnexpectedEOF)) {
+        return nil, false, fmt.Errorf("error decoding response body: %v", err)
+    }
+    if int64(len(body)) > limit {
+        body = body[:limit]
+        truncated = true
+    }
+    return body, truncated, nil
+}
+
+// recordBodyTruncated flags a service whose last body check ran against a
+// truncated body, warning (rate limited) when it happens
+func (m *Monitor) recordBodyTruncated(service ServiceConfig, truncated bool) {
+    if truncated {
+        m.logger.Printf("body:"+service.Name, "Warning: response body for %s exceeded %d bytes and was truncated",
+            service.Name, m.maxBodyBytes(service))
+    }
+
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    if serviceStatus, ok := m.serviceStatus[service.Name]; ok {
+        serviceStatus.BodyTruncated = truncated
+    }
+}
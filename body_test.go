@@ -0,0 +1,182 @@
+package main
+
+import (
+    "bytes"
+    "compress/gzip"
+    "compress/zlib"
+    "io"
+    "math/rand/v2"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    writer := gzip.NewWriter(&buf)
+    writer.Write(data)
+    writer.Close()
+    return buf.Bytes()
+}
+
+func zlibbed(t *testing.T, data []byte) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    writer := zlib.NewWriter(&buf)
+    writer.Write(data)
+    writer.Close()
+    return buf.Bytes()
+}
+
+func TestCompressedBodyCheck(t *testing.T) {
+    const body = `{"status": "healthy", "version": "1.4.2"}`
+    tests := []struct {
+        name           string
+        encoding       string
+        payload        []byte
+        acceptEncoding string // sent by the service, "" leaves it to the transport
+        want           bool
+    }{
+        {"gzip decoded by the check", "gzip", gzipped(t, []byte(body)), "gzip", true},
+        {"gzip decoded by the transport", "gzip", gzipped(t, []byte(body)), "", true},
+        {"deflate", "deflate", zlibbed(t, []byte(body)), "deflate", true},
+        {"identity", "", []byte(body), "", true},
+        {"corrupt gzip", "gzip", []byte("not gzip at all"), "gzip", false},
+        {"unsupported encoding", "br", []byte(body), "br", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                if tt.encoding != "" {
+                    w.Header().Set("Content-Encoding", tt.encoding)
+                }
+                w.Write(tt.payload)
+            }))
+            defer server.Close()
+            service := testService("api", server.URL)
+            service.ExpectedBodySubstring = `"status": "healthy"`
+            if tt.acceptEncoding != "" {
+                service.Headers = map[string]string{"Accept-Encoding": tt.acceptEncoding}
+            }
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            if outcome := m.performCheck(service, m.serviceClient(service), nil); outcome.up != tt.want {
+                t.Errorf("check up = %v (%v), want %v", outcome.up, outcome.err, tt.want)
+            }
+        })
+    }
+}
+
+func TestCompressedBodyBomb(t *testing.T) {
+    // 8 MiB of zeros compresses to well under the limit
+    bomb := gzipped(t, make([]byte, 8<<20))
+    const limit = 64 << 10
+    resp := &http.Response{
+        Header: http.Header{"Content-Encoding": {"gzip"}},
+        Body:   io.NopCloser(bytes.NewReader(bomb)),
+    }
+
+    body, truncated, err := readBody(resp, limit)
+    if err != nil {
+        t.Fatalf("readBody: %v", err)
+    }
+    if len(body) != limit || !truncated {
+        t.Errorf("decoded %d bytes, truncated %v; want %d, true", len(body), truncated, limit)
+    }
+}
+
+func TestCompressedBodyTruncatedStream(t *testing.T) {
+    // Random bytes don't compress, so the compressed stream exceeds the limit
+    data := make([]byte, 4096)
+    random := rand.New(rand.NewPCG(1, 2))
+    for i := range data {
+        data[i] = byte(random.Uint32())
+    }
+    resp := &http.Response{
+        Header: http.Header{"Content-Encoding": {"gzip"}},
+        Body:   io.NopCloser(bytes.NewReader(gzipped(t, data))),
+    }
+
+    body, truncated, err := readBody(resp, 256)
+    if err != nil {
+        t.Fatalf("readBody: %v", err)
+    }
+    if !truncated || !bytes.HasPrefix(data, body) || len(body) == 0 {
+        t.Errorf("decoded %d bytes, truncated %v; want a truncated prefix", len(body), truncated)
+    }
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+    reader io.Reader
+    read   int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+    n, err := r.reader.Read(p)
+    r.read += int64(n)
+    return n, err
+}
+
+func TestReadBodyCapped(t *testing.T) {
+    body := &countingReader{reader: bytes.NewReader(bytes.Repeat([]byte("a"), 1<<20))}
+    data, truncated, err := readBody(&http.Response{Header: http.Header{}, Body: io.NopCloser(body)}, 1024)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(data) != 1024 || !truncated {
+        t.Errorf("read %d bytes, truncated %v, want 1024 and truncated", len(data), truncated)
+    }
+    if body.read > 1025 {
+        t.Errorf("consumed %d bytes of the body, want at most the limit and one more", body.read)
+    }
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+    // The marker sits past every limit below
+    payload := append(bytes.Repeat([]byte("a"), 64<<10), "MARKER"...)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write(payload)
+    }))
+    defer server.Close()
+
+    tests := []struct {
+        name          string
+        global        int64
+        service       int64
+        substring     string
+        wantUp        bool
+        wantTruncated bool
+    }{
+        {"under the default limit", 0, 0, "MARKER", true, false},
+        {"global limit", 1024, 0, "aaaa", true, true},
+        {"content past the global limit", 1024, 0, "MARKER", false, true},
+        {"service override raises the limit", 1024, 1 << 20, "MARKER", true, false},
+        {"service override lowers the limit", 0, 1024, "MARKER", false, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", server.URL)
+            service.ExpectedBodySubstring = tt.substring
+            service.MaxBodyBytes = tt.service
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, MaxBodyBytes: tt.global})
+            logs := captureLog(t)
+
+            checkAndFlush(m, service)
+
+            m.statusMutex.RLock()
+            up, truncated := m.serviceStatus["api"].State == StateUp, m.serviceStatus["api"].BodyTruncated
+            m.statusMutex.RUnlock()
+            if up != tt.wantUp || truncated != tt.wantTruncated {
+                t.Errorf("up %v, truncated %v, want %v and %v", up, truncated, tt.wantUp, tt.wantTruncated)
+            }
+            if warned := strings.Contains(logs.String(), "was truncated"); warned != tt.wantTruncated {
+                t.Errorf("truncation warning logged %v, want %v", warned, tt.wantTruncated)
+            }
+        })
+    }
+}
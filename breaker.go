@@ -0,0 +1,70 @@
+package main
+
+import "time"
+
+// CircuitBreakerConfig backs off checks of a service that keeps failing
+type CircuitBreakerConfig struct {
+    FailureThreshold int `json:"failure_threshold"` // consecutive failures before backing off
+    MaxInterval      int `json:"max_interval"`      // in seconds, cap on the backed-off interval
+}
+
+const (
+    BreakerClosed   = "closed"
+    BreakerOpen     = "open"
+    BreakerHalfOpen = "half-open"
+)
+
+// breakerTripped reports whether the service's breaker is open
+func breakerTripped(service ServiceConfig, status *ServiceStatus) bool {
+    breaker := service.CircuitBreaker
+    return breaker != nil && breaker.FailureThreshold > 0 && status.FailureCount >= breaker.FailureThreshold
+}
+
+// effectiveInterval is the delay until a service's next check: the check
+// interval, doubled for every failure past the breaker threshold up to the
+// breaker's max interval. A successful check resets FailureCount and with
+// it the interval.
+func (m *Monitor) effectiveInterval(service ServiceConfig) time.Duration {
+    interval := time.Duration(service.CheckInterval) * time.Second
+
+    m.statusMutex.RLock()
+    status, ok := m.serviceStatus[service.Name]
+    tripped := ok && breakerTripped(service, status)
+    var failures int
+    if tripped {
+        failures = status.FailureCount
+    }
+    m.statusMutex.RUnlock()
+
+    if !tripped {
+        return interval
+    }
+
+    maxInterval := time.Duration(service.CircuitBreaker.MaxInterval) * time.Second
+    if maxInterval <= 0 {
+        maxInterval = time.Hour
+    }
+    for i := service.CircuitBreaker.FailureThreshold; i <= failures && interval < maxInterval; i++ {
+        interval *= 2
+    }
+    if interval > maxInterval {
+        interval = maxInterval
+    }
+    return interval
+}
+
+// breakerState is reported on /health. An open breaker is half-open while
+// its probe check is running. It must be called with statusMutex held.
+func (m *Monitor) breakerState(service ServiceConfig, status *ServiceStatus) string {
+    if !breakerTripped(service, status) {
+        return BreakerClosed
+    }
+
+    m.inflightMutex.Lock()
+    _, running := m.inflight[service.Name]
+    m.inflightMutex.Unlock()
+    if running {
+        return BreakerHalfOpen
+    }
+    return BreakerOpen
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestCircuitBreakerInterval(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    service.CheckInterval = 10
+    service.CircuitBreaker = &CircuitBreakerConfig{FailureThreshold: 3, MaxInterval: 60}
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    tests := []struct {
+        code         int
+        wantInterval time.Duration
+        wantBreaker  string
+    }{
+        {500, 10 * time.Second, BreakerClosed},
+        {500, 10 * time.Second, BreakerClosed},
+        {500, 20 * time.Second, BreakerOpen},
+        {500, 40 * time.Second, BreakerOpen},
+        {500, 60 * time.Second, BreakerOpen},
+        {500, 60 * time.Second, BreakerOpen},
+        {200, 10 * time.Second, BreakerClosed},
+    }
+
+    for i, tt := range tests {
+        server.code.Store(int32(tt.code))
+        checkAndFlush(m, service)
+        if got := m.effectiveInterval(service); got != tt.wantInterval {
+            t.Errorf("check %d: interval %s, want %s", i+1, got, tt.wantInterval)
+        }
+        if got := healthBreaker(t, m); got != tt.wantBreaker {
+            t.Errorf("check %d: breaker %q, want %q", i+1, got, tt.wantBreaker)
+        }
+    }
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    service.CircuitBreaker = &CircuitBreakerConfig{FailureThreshold: 1}
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    checkAndFlush(m, service)
+
+    // A probe check of the open breaker is running
+    m.inflightMutex.Lock()
+    m.inflight["api"] = make(chan struct{})
+    m.inflightMutex.Unlock()
+
+    if got := healthBreaker(t, m); got != BreakerHalfOpen {
+        t.Errorf("breaker %q while probing, want %q", got, BreakerHalfOpen)
+    }
+}
+
+// healthBreaker returns the circuit_breaker state /health reports for api
+func healthBreaker(t *testing.T, m *Monitor) string {
+    t.Helper()
+    recorder := httptest.NewRecorder()
+    m.handleHealth(recorder, httptest.NewRequest("GET", "/health", nil))
+    var health map[string]struct {
+        CircuitBreaker string `json:"circuit_breaker"`
+    }
+    if err := json.Unmarshal(recorder.Body.Bytes(), &health); err != nil {
+        t.Fatal(err)
+    }
+    return health["api"].CircuitBreaker
+}
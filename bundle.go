@@ -0,0 +1,136 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "runtime"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    // bundleLogLines is how many recent log lines are kept for support bundles
+    bundleLogLines = 500
+    // bundleIncidents is how many recent incidents a support bundle includes
+    bundleIncidents = 100
+)
+
+// logTail keeps the most recent log lines. main tees the standard logger
+// into recentLogs.
+type logTail struct {
+    mutex   sync.Mutex
+    lines   []string
+    limit   int
+    partial string
+}
+
+var recentLogs = &logTail{limit: bundleLogLines}
+
+func (t *logTail) Write(p []byte) (int, error) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+
+    text := t.partial + string(p)
+    lines := strings.Split(text, "\n")
+    t.partial = lines[len(lines)-1]
+    t.lines = append(t.lines, lines[:len(lines)-1]...)
+    if len(t.lines) > t.limit {
+        t.lines = append([]string(nil), t.lines[len(t.lines)-t.limit:]...)
+    }
+    return len(p), nil
+}
+
+func (t *logTail) snapshot() []string {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+    return append([]string{}, t.lines...)
+}
+
+// stripBundleURLs reduces URLs in the error text of a status entry to their
+// scheme and host, since check errors quote request URLs with their tokens
+func stripBundleURLs(entry map[string]interface{}) {
+    if lastError, ok := entry["last_error"].(string); ok {
+        entry["last_error"] = stripURLs(lastError)
+    }
+    if errors, ok := entry["recent_errors"].([]errorCount); ok {
+        for i := range errors {
+            errors[i].Error = stripURLs(errors[i].Error)
+        }
+    }
+}
+
+type runtimeInfo struct {
+    GoVersion      string `json:"go_version"`
+    OS             string `json:"os"`
+    Arch           string `json:"arch"`
+    NumCPU         int    `json:"num_cpu"`
+    Goroutines     int    `json:"goroutines"`
+    HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+    NumGC          uint32 `json:"num_gc"`
+}
+
+// supportBundle is everything needed to diagnose the monitor from a ticket
+type supportBundle struct {
+    Generated time.Time              `json:"generated"`
+    Version   versionInfo            `json:"version"`
+    Runtime   runtimeInfo            `json:"runtime"`
+    Config    MonitorConfig          `json:"config"` // redacted
+    Statuses  map[string]interface{} `json:"statuses"`
+    Incidents []Incident             `json:"incidents"` // most recent, oldest first
+    Logs      []string               `json:"logs"`      // URLs reduced to scheme and host
+}
+
+func (m *Monitor) supportBundle() supportBundle {
+    var mem runtime.MemStats
+    runtime.ReadMemStats(&mem)
+
+    bundle := supportBundle{
+        Generated: time.Now(),
+        Version:   m.versionInfo(),
+        Runtime: runtimeInfo{
+            GoVersion:      runtime.Version(),
+            OS:             runtime.GOOS,
+            Arch:           runtime.GOARCH,
+            NumCPU:         runtime.NumCPU(),
+            Goroutines:     runtime.NumGoroutine(),
+            HeapAllocBytes: mem.HeapAlloc,
+            NumGC:          mem.NumGC,
+        },
+        Statuses: make(map[string]interface{}),
+        Logs:     recentLogs.snapshot(),
+    }
+    for i, line := range bundle.Logs {
+        bundle.Logs[i] = stripURLs(line)
+    }
+
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    bundle.Config = redactedConfig(m.config)
+    for name, s := range m.serviceStatus {
+        entry := m.statusEntry(name, s)
+        stripBundleURLs(entry)
+        bundle.Statuses[name] = entry
+    }
+    incidents := m.incidents
+    if len(incidents) > bundleIncidents {
+        incidents = incidents[len(incidents)-bundleIncidents:]
+    }
+    bundle.Incidents = append([]Incident{}, incidents...)
+    for i := range bundle.Incidents {
+        bundle.Incidents[i].Error = stripURLs(bundle.Incidents[i].Error)
+    }
+    return bundle
+}
+
+// handleDebugBundle serves a support bundle as a JSON download
+func (m *Monitor) handleDebugBundle(w http.ResponseWriter, r *http.Request) {
+    bundle := m.supportBundle()
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="monitor-bundle-%s.json"`,
+        bundle.Generated.UTC().Format("20060102T150405Z")))
+    json.NewEncoder(w).Encode(bundle)
+}
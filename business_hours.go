@@ -0,0 +1,116 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// BusinessHours limits checking and alerting to a recurring weekly window.
+// Outside it the service is paused rather than checked.
+type BusinessHours struct {
+    Days     []string `json:"days"`     // "mon".."sun" or "monday".."sunday"; empty means every day
+    Start    string   `json:"start"`    // "HH:MM"
+    End      string   `json:"end"`      // "HH:MM", may be before start for an overnight window
+    Timezone string   `json:"timezone"` // IANA zone, defaults to the monitor's timezone
+}
+
+// parseWeekday accepts a full day name or its three-letter abbreviation, in
+// any case. Anything else, such as "tues" or "mondays", is rejected rather
+// than guessed at.
+func parseWeekday(day string) (time.Weekday, bool) {
+    day = strings.ToLower(day)
+    for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+        name := strings.ToLower(weekday.String())
+        if day == name || day == name[:3] {
+            return weekday, true
+        }
+    }
+    return 0, false
+}
+
+// parseClock returns minutes since midnight for "HH:MM"
+func parseClock(value string) (int, error) {
+    parsed, err := time.Parse("15:04", value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+    }
+    return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func validateBusinessHours(hours *BusinessHours) error {
+    if hours == nil {
+        return nil
+    }
+    for _, day := range hours.Days {
+        if _, ok := parseWeekday(day); !ok {
+            return fmt.Errorf("business_hours: unknown day %q", day)
+        }
+    }
+    if _, err := parseClock(hours.Start); err != nil {
+        return fmt.Errorf("business_hours: %v", err)
+    }
+    if _, err := parseClock(hours.End); err != nil {
+        return fmt.Errorf("business_hours: %v", err)
+    }
+    if _, err := time.LoadLocation(hours.Timezone); err != nil {
+        return fmt.Errorf("business_hours: invalid timezone %q", hours.Timezone)
+    }
+    return nil
+}
+
+// inBusinessHours reports whether a service is inside its active window at
+// now. Services without business hours are always active. An overnight
+// window belongs to the day it starts on.
+func (m *Monitor) inBusinessHours(service ServiceConfig, now time.Time) bool {
+    hours := service.BusinessHours
+    if hours == nil {
+        return true
+    }
+
+    location := m.location
+    if hours.Timezone != "" {
+        if loaded, err := time.LoadLocation(hours.Timezone); err == nil {
+            location = loaded
+        }
+    }
+    now = now.In(location)
+
+    start, _ := parseClock(hours.Start)
+    end, _ := parseClock(hours.End)
+    minute := now.Hour()*60 + now.Minute()
+
+    day := now.Weekday()
+    var inside bool
+    switch {
+    case start <= end:
+        inside = minute >= start && minute < end
+    case minute >= start:
+        inside = true
+    case minute < end:
+        // Early morning part of a window that started yesterday
+        inside = true
+        day = (day + 6) % 7
+    }
+    if !inside || len(hours.Days) == 0 {
+        return inside
+    }
+
+    for _, name := range hours.Days {
+        if weekday, _ := parseWeekday(name); weekday == day {
+            return true
+        }
+    }
+    return false
+}
+
+func (m *Monitor) setPaused(name string, paused bool) {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    if status, ok := m.serviceStatus[name]; ok {
+        if status.Paused && !paused {
+            status.ResumedAt = time.Now()
+        }
+        status.Paused = paused
+    }
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestInBusinessHours(t *testing.T) {
+    weekdays := []string{"mon", "tue", "wed", "thu", "fri"}
+    // Wednesday 2024-03-06 and Saturday 2024-03-09, in UTC unless stated
+    tests := []struct {
+        name  string
+        hours *BusinessHours
+        now   time.Time
+        want  bool
+    }{
+        {"no business hours", nil, time.Date(2024, 3, 9, 3, 0, 0, 0, time.UTC), true},
+        {"inside the window", &BusinessHours{Days: weekdays, Start: "09:00", End: "17:00"}, time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC), true},
+        {"end is exclusive", &BusinessHours{Days: weekdays, Start: "09:00", End: "17:00"}, time.Date(2024, 3, 6, 17, 0, 0, 0, time.UTC), false},
+        {"before the window", &BusinessHours{Days: weekdays, Start: "09:00", End: "17:00"}, time.Date(2024, 3, 6, 8, 59, 0, 0, time.UTC), false},
+        {"outside the days", &BusinessHours{Days: weekdays, Start: "09:00", End: "17:00"}, time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC), false},
+        {"full day names", &BusinessHours{Days: []string{"Saturday"}, Start: "09:00", End: "17:00"}, time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC), true},
+        {"every day", &BusinessHours{Start: "09:00", End: "17:00"}, time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC), true},
+        {"overnight evening", &BusinessHours{Days: []string{"fri"}, Start: "22:00", End: "06:00"}, time.Date(2024, 3, 8, 23, 0, 0, 0, time.UTC), true},
+        {"overnight morning belongs to the start day", &BusinessHours{Days: []string{"fri"}, Start: "22:00", End: "06:00"}, time.Date(2024, 3, 9, 5, 0, 0, 0, time.UTC), true},
+        {"overnight morning of another start day", &BusinessHours{Days: []string{"sat"}, Start: "22:00", End: "06:00"}, time.Date(2024, 3, 9, 5, 0, 0, 0, time.UTC), false},
+        {"window timezone", &BusinessHours{Days: weekdays, Start: "09:00", End: "17:00", Timezone: "America/New_York"}, time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC), true},
+        {"window timezone outside", &BusinessHours{Days: weekdays, Start: "09:00", End: "17:00", Timezone: "America/New_York"}, time.Date(2024, 3, 6, 23, 0, 0, 0, time.UTC), false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("batch", "https://batch.example.com/")
+            service.BusinessHours = tt.hours
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            if got := m.inBusinessHours(service, tt.now); got != tt.want {
+                t.Errorf("inBusinessHours(%s) = %v, want %v", tt.now, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseWeekday(t *testing.T) {
+    tests := []struct {
+        day  string
+        want time.Weekday
+        ok   bool
+    }{
+        {"mon", time.Monday, true},
+        {"Monday", time.Monday, true},
+        {"SUN", time.Sunday, true},
+        {"thursday", time.Thursday, true},
+        {"tues", 0, false},
+        {"mondays", 0, false},
+        {"mo", 0, false},
+        {"monxyz", 0, false},
+        {"", 0, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.day, func(t *testing.T) {
+            got, ok := parseWeekday(tt.day)
+            if got != tt.want || ok != tt.ok {
+                t.Errorf("parseWeekday(%q) = %v, %v, want %v, %v", tt.day, got, ok, tt.want, tt.ok)
+            }
+        })
+    }
+}
+
+func TestInvalidBusinessHours(t *testing.T) {
+    tests := []struct {
+        name  string
+        hours BusinessHours
+    }{
+        {"unknown day", BusinessHours{Days: []string{"monxyz"}, Start: "09:00", End: "17:00"}},
+        {"invalid start", BusinessHours{Start: "9am", End: "17:00"}},
+        {"invalid timezone", BusinessHours{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus_Mons"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("batch", "https://batch.example.com/")
+            service.BusinessHours = &tt.hours
+            if err := validateServiceConfig(service); err == nil {
+                t.Error("validateServiceConfig accepted the business hours")
+            }
+        })
+    }
+}
+
+func TestPausedOutsideBusinessHours(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("batch", server.URL)
+    // A window on a day other than today
+    tomorrow := time.Now().UTC().Add(24 * time.Hour).Weekday().String()
+    service.BusinessHours = &BusinessHours{Days: []string{tomorrow}, Start: "00:00", End: "23:59", Timezone: "UTC"}
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    m.startServiceMonitor(service)
+    defer stopMonitors(m)
+
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        m.statusMutex.RLock()
+        paused, state := m.serviceStatus["batch"].Paused, m.serviceStatus["batch"].State
+        m.statusMutex.RUnlock()
+        if paused {
+            if state != StateUnknown || len(sender.kinds()) > 0 {
+                t.Errorf("paused service in state %s alerted %v, want it unchecked", state, sender.kinds())
+            }
+            return
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("service was not paused")
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
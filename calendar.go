@@ -0,0 +1,529 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "net/http"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+    "unicode/utf8"
+)
+
+// defaultCalendarRefresh is how often the maintenance calendar is fetched
+// when refresh_interval is unset
+const defaultCalendarRefresh = 5 * time.Minute
+
+// calendarHorizon is how far ahead recurring events are expanded at each
+// fetch
+const calendarHorizon = 30 * 24 * time.Hour
+
+// MaintenanceCalendarConfig suppresses alerts during the events of an
+// iCal (RFC 5545) feed, e.g. a change-management calendar
+type MaintenanceCalendarConfig struct {
+    URL             string `json:"url"`
+    RefreshInterval int    `json:"refresh_interval"` // in seconds, default 300
+    MatchSummary    bool   `json:"match_summary"`    // Only suppress services named in the event summary as a whole word, by name or "key=value" label; otherwise an event covers every service
+}
+
+// calendarEvent is one maintenance window from the feed
+type calendarEvent struct {
+    Summary string
+    Start   time.Time
+    End     time.Time
+}
+
+type calendarState struct {
+    mutex  sync.RWMutex
+    events []calendarEvent
+}
+
+// startMaintenanceCalendar fetches the calendar now and on its interval. A
+// failed fetch keeps the previously fetched windows.
+func (m *Monitor) startMaintenanceCalendar() {
+    config := m.config.MaintenanceCalendar
+    if config == nil {
+        return
+    }
+    interval := time.Duration(config.RefreshInterval) * time.Second
+    if interval <= 0 {
+        interval = defaultCalendarRefresh
+    }
+
+    m.supervise(func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            events, rejected, err := m.fetchCalendar(config.URL)
+            for _, reason := range rejected {
+                m.logger.Printf("maintenance-calendar:"+reason, "Ignoring recurring maintenance %s", reason)
+            }
+            if err != nil {
+                m.logger.Printf("maintenance-calendar", "Error fetching maintenance calendar: %v", err)
+            } else {
+                m.calendar.mutex.Lock()
+                m.calendar.events = events
+                m.calendar.mutex.Unlock()
+            }
+            select {
+            case <-m.ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    })
+}
+
+func (m *Monitor) fetchCalendar(url string) ([]calendarEvent, []string, error) {
+    req, err := http.NewRequestWithContext(m.ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return nil, nil, err
+    }
+    resp, err := m.httpClient.Do(req)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+    }
+    return parseCalendar(resp.Body, m.location, time.Now())
+}
+
+// inMaintenance reports the summary of an active calendar event covering
+// the service
+func (m *Monitor) inMaintenance(service string) (string, bool) {
+    config := m.config.MaintenanceCalendar
+    if config == nil {
+        return "", false
+    }
+
+    var labels map[string]string
+    if config.MatchSummary {
+        m.statusMutex.RLock()
+        labels = m.findService(service).Labels
+        m.statusMutex.RUnlock()
+    }
+
+    now := time.Now()
+    m.calendar.mutex.RLock()
+    defer m.calendar.mutex.RUnlock()
+    for _, event := range m.calendar.events {
+        if now.Before(event.Start) || !now.Before(event.End) {
+            continue
+        }
+        if !config.MatchSummary || summaryNames(event.Summary, service, labels) {
+            return event.Summary, true
+        }
+    }
+    return "", false
+}
+
+// summaryNames reports whether an event summary names the service or one
+// of its labels as "key=value", as whole words so "api" does not match
+// "rapid-api-gateway"
+func summaryNames(summary, service string, labels map[string]string) bool {
+    summary = strings.ToLower(summary)
+    if containsWord(summary, strings.ToLower(service)) {
+        return true
+    }
+    for key, value := range labels {
+        if containsWord(summary, strings.ToLower(key+"="+value)) {
+            return true
+        }
+    }
+    return false
+}
+
+// containsWord reports whether word occurs in text between word boundaries.
+// Hyphens and underscores join words, as in service names.
+func containsWord(text, word string) bool {
+    if word == "" {
+        return false
+    }
+    for offset := 0; ; {
+        i := strings.Index(text[offset:], word)
+        if i < 0 {
+            return false
+        }
+        start, end := offset+i, offset+i+len(word)
+        before, _ := utf8.DecodeLastRuneInString(text[:start])
+        after, _ := utf8.DecodeRuneInString(text[end:])
+        if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+            return true
+        }
+        offset = start + 1
+    }
+}
+
+func isWordRune(r rune) bool {
+    return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_'
+}
+
+// calendarEntry is a VEVENT as read, before its recurrence is expanded
+type calendarEntry struct {
+    event        calendarEvent
+    uid          string
+    recurrenceID time.Time // set on an override of one occurrence of a series
+    rule         string    // RRULE value
+    exdates      []time.Time
+    rdate        bool
+}
+
+// parseCalendar reads the VEVENTs of an iCal feed. Floating times are in
+// loc. Daily and weekly recurrence rules are expanded, with EXDATE and
+// overridden occurrences removed, into the occurrences that have not ended
+// by now and start within calendarHorizon of it. Recurring events the
+// expansion does not support are returned in rejected instead, and
+// cancelled events are skipped.
+func parseCalendar(r io.Reader, loc *time.Location, now time.Time) (events []calendarEvent, rejected []string, err error) {
+    entries, err := parseCalendarEntries(r, loc)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    overridden := make(map[string][]time.Time)
+    for _, entry := range entries {
+        if !entry.recurrenceID.IsZero() {
+            overridden[entry.uid] = append(overridden[entry.uid], entry.recurrenceID)
+        }
+    }
+
+    until := now.Add(calendarHorizon)
+    for _, entry := range entries {
+        switch {
+        case entry.rdate:
+            rejected = append(rejected, fmt.Sprintf("event %q: RDATE is not supported", entry.event.Summary))
+        case entry.rule == "" || !entry.recurrenceID.IsZero():
+            events = append(events, entry.event)
+        default:
+            exclude := append(append([]time.Time(nil), entry.exdates...), overridden[entry.uid]...)
+            occurrences, err := expandRule(entry.event, entry.rule, exclude, now, until, loc)
+            if err != nil {
+                rejected = append(rejected, fmt.Sprintf("event %q: %v", entry.event.Summary, err))
+                continue
+            }
+            events = append(events, occurrences...)
+        }
+    }
+    return events, rejected, nil
+}
+
+func parseCalendarEntries(r io.Reader, loc *time.Location) ([]calendarEntry, error) {
+    lines, err := unfoldLines(r)
+    if err != nil {
+        return nil, err
+    }
+
+    var entries []calendarEntry
+    var entry *calendarEntry
+    var duration time.Duration
+    var allDay, cancelled bool
+    for _, line := range lines {
+        name, params, value := parseContentLine(line)
+        switch {
+        case name == "BEGIN" && value == "VEVENT":
+            entry = &calendarEntry{}
+            duration, allDay, cancelled = 0, false, false
+        case name == "END" && value == "VEVENT":
+            if entry == nil {
+                continue
+            }
+            event := &entry.event
+            if event.End.IsZero() {
+                switch {
+                case duration > 0:
+                    event.End = event.Start.Add(duration)
+                case allDay:
+                    event.End = event.Start.AddDate(0, 0, 1)
+                default:
+                    event.End = event.Start
+                }
+            }
+            if !event.Start.IsZero() && !cancelled {
+                entries = append(entries, *entry)
+            }
+            entry = nil
+        case entry == nil:
+            continue
+        case name == "SUMMARY":
+            entry.event.Summary = unescapeText(value)
+        case name == "STATUS":
+            cancelled = strings.EqualFold(value, "CANCELLED")
+        case name == "UID":
+            entry.uid = value
+        case name == "RRULE":
+            entry.rule = value
+        case name == "RDATE":
+            entry.rdate = true
+        case name == "EXDATE":
+            for _, date := range strings.Split(value, ",") {
+                t, _, err := parseCalendarTime(date, params, loc)
+                if err != nil {
+                    return nil, fmt.Errorf("EXDATE: %v", err)
+                }
+                entry.exdates = append(entry.exdates, t)
+            }
+        case name == "RECURRENCE-ID":
+            entry.recurrenceID, _, err = parseCalendarTime(value, params, loc)
+            if err != nil {
+                return nil, fmt.Errorf("RECURRENCE-ID: %v", err)
+            }
+        case name == "DTSTART":
+            entry.event.Start, allDay, err = parseCalendarTime(value, params, loc)
+            if err != nil {
+                return nil, fmt.Errorf("DTSTART: %v", err)
+            }
+        case name == "DTEND":
+            entry.event.End, _, err = parseCalendarTime(value, params, loc)
+            if err != nil {
+                return nil, fmt.Errorf("DTEND: %v", err)
+            }
+        case name == "DURATION":
+            duration, err = parseCalendarDuration(value)
+            if err != nil {
+                return nil, fmt.Errorf("DURATION: %v", err)
+            }
+        }
+    }
+    return entries, nil
+}
+
+var calendarWeekdays = map[string]time.Weekday{
+    "SU": time.Sunday,
+    "MO": time.Monday,
+    "TU": time.Tuesday,
+    "WE": time.Wednesday,
+    "TH": time.Thursday,
+    "FR": time.Friday,
+    "SA": time.Saturday,
+}
+
+// expandRule returns the occurrences of a DAILY or WEEKLY RRULE, other than
+// those starting at an excluded time, that end after from and start before
+// until. Repeats keep the wall-clock time of the first occurrence.
+func expandRule(event calendarEvent, rule string, exclude []time.Time, from, until time.Time, loc *time.Location) ([]calendarEvent, error) {
+    freq, interval, count := "", 1, 0
+    var last time.Time
+    var days []time.Weekday
+    weekStart := time.Monday
+    for _, part := range strings.Split(rule, ";") {
+        key, value, _ := strings.Cut(part, "=")
+        switch strings.ToUpper(key) {
+        case "FREQ":
+            freq = strings.ToUpper(value)
+        case "INTERVAL":
+            n, err := strconv.Atoi(value)
+            if err != nil || n <= 0 {
+                return nil, fmt.Errorf("invalid RRULE INTERVAL %q", value)
+            }
+            interval = n
+        case "COUNT":
+            n, err := strconv.Atoi(value)
+            if err != nil || n <= 0 {
+                return nil, fmt.Errorf("invalid RRULE COUNT %q", value)
+            }
+            count = n
+        case "UNTIL":
+            t, allDay, err := parseCalendarTime(value, nil, loc)
+            if err != nil {
+                return nil, fmt.Errorf("invalid RRULE UNTIL %q", value)
+            }
+            if allDay {
+                // A date includes occurrences on that day
+                t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+            }
+            last = t
+        case "BYDAY":
+            for _, code := range strings.Split(value, ",") {
+                day, ok := calendarWeekdays[strings.ToUpper(code)]
+                if !ok {
+                    return nil, fmt.Errorf("unsupported RRULE BYDAY %q", code)
+                }
+                days = append(days, day)
+            }
+        case "WKST":
+            day, ok := calendarWeekdays[strings.ToUpper(value)]
+            if !ok {
+                return nil, fmt.Errorf("invalid RRULE WKST %q", value)
+            }
+            weekStart = day
+        default:
+            return nil, fmt.Errorf("unsupported RRULE part %s", key)
+        }
+    }
+
+    // step returns the starts of the n'th period's occurrences
+    var step func(n int) []time.Time
+    switch freq {
+    case "DAILY":
+        if len(days) > 0 {
+            return nil, fmt.Errorf("unsupported RRULE BYDAY with FREQ=DAILY")
+        }
+        step = func(n int) []time.Time { return []time.Time{event.Start.AddDate(0, 0, n*interval)} }
+    case "WEEKLY":
+        if len(days) == 0 {
+            days = []time.Weekday{event.Start.Weekday()}
+        }
+        offsets := make([]int, len(days))
+        for i, day := range days {
+            offsets[i] = (int(day) - int(weekStart) + 7) % 7
+        }
+        sort.Ints(offsets)
+        firstWeek := event.Start.AddDate(0, 0, -((int(event.Start.Weekday()) - int(weekStart) + 7) % 7))
+        step = func(n int) []time.Time {
+            starts := make([]time.Time, len(offsets))
+            for i, offset := range offsets {
+                starts[i] = firstWeek.AddDate(0, 0, n*interval*7+offset)
+            }
+            return starts
+        }
+    default:
+        return nil, fmt.Errorf("unsupported RRULE FREQ %q", freq)
+    }
+
+    length := event.End.Sub(event.Start)
+    var occurrences []calendarEvent
+    seen := 0
+    for n := 0; ; n++ {
+        for _, start := range step(n) {
+            if start.Before(event.Start) {
+                continue
+            }
+            if (!last.IsZero() && start.After(last)) || (count > 0 && seen == count) || !start.Before(until) {
+                return occurrences, nil
+            }
+            seen++
+            if containsTime(exclude, start) || !start.Add(length).After(from) {
+                continue
+            }
+            occurrences = append(occurrences, calendarEvent{Summary: event.Summary, Start: start, End: start.Add(length)})
+        }
+    }
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+    for _, other := range times {
+        if other.Equal(t) {
+            return true
+        }
+    }
+    return false
+}
+
+// unfoldLines joins folded continuation lines, which start with a space
+// or tab
+func unfoldLines(r io.Reader) ([]string, error) {
+    var lines []string
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        line := strings.TrimRight(scanner.Text(), "\r")
+        if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+            lines[len(lines)-1] += line[1:]
+            continue
+        }
+        lines = append(lines, line)
+    }
+    return lines, scanner.Err()
+}
+
+// parseContentLine splits "NAME;PARAM=x:value", ignoring colons inside
+// quoted parameter values
+func parseContentLine(line string) (name string, params map[string]string, value string) {
+    quoted := false
+    split := -1
+    for i, c := range line {
+        if c == '"' {
+            quoted = !quoted
+        } else if c == ':' && !quoted {
+            split = i
+            break
+        }
+    }
+    if split < 0 {
+        return strings.ToUpper(line), nil, ""
+    }
+
+    parts := strings.Split(line[:split], ";")
+    params = make(map[string]string)
+    for _, param := range parts[1:] {
+        if key, val, ok := strings.Cut(param, "="); ok {
+            params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+        }
+    }
+    return strings.ToUpper(parts[0]), params, line[split+1:]
+}
+
+// parseCalendarTime parses a DATE or DATE-TIME value, in UTC, in its TZID
+// or floating in loc
+func parseCalendarTime(value string, params map[string]string, loc *time.Location) (t time.Time, allDay bool, err error) {
+    if tzid := params["TZID"]; tzid != "" {
+        if zone, err := time.LoadLocation(tzid); err == nil {
+            loc = zone
+        }
+    }
+
+    if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+        t, err = time.ParseInLocation("20060102", value, loc)
+        return t, true, err
+    }
+    if strings.HasSuffix(value, "Z") {
+        t, err = time.Parse("20060102T150405Z", value)
+        return t, false, err
+    }
+    t, err = time.ParseInLocation("20060102T150405", value, loc)
+    return t, false, err
+}
+
+var calendarDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseCalendarDuration parses an RFC 5545 duration such as "PT1H30M"
+func parseCalendarDuration(value string) (time.Duration, error) {
+    match := calendarDurationPattern.FindStringSubmatch(value)
+    if match == nil || value == "P" || strings.HasSuffix(value, "T") {
+        return 0, fmt.Errorf("invalid duration %q", value)
+    }
+
+    units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+    var duration time.Duration
+    for i, unit := range units {
+        if match[i+2] == "" {
+            continue
+        }
+        n, err := strconv.Atoi(match[i+2])
+        if err != nil {
+            return 0, fmt.Errorf("invalid duration %q", value)
+        }
+        duration += time.Duration(n) * unit
+    }
+    if match[1] == "-" {
+        duration = -duration
+    }
+    return duration, nil
+}
+
+var textUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeText(value string) string {
+    return textUnescaper.Replace(value)
+}
+
+func validateMaintenanceCalendar(config *MaintenanceCalendarConfig) error {
+    if config == nil {
+        return nil
+    }
+    if config.URL == "" {
+        return fmt.Errorf("maintenance_calendar: url is required")
+    }
+    if config.RefreshInterval < 0 {
+        return fmt.Errorf("maintenance_calendar: refresh_interval must not be negative")
+    }
+    return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestSummaryNames(t *testing.T) {
+    tests := []struct {
+        summary string
+        service string
+        labels  map[string]string
+        want    bool
+    }{
+        {"Upgrade api", "api", nil, true},
+        {"API, db: patching", "api", nil, true},
+        {"rapid-api-gateway restart", "api", nil, false},
+        {"apis", "api", nil, false},
+        {"Patch API Service tonight", "API Service", nil, true},
+        {"Patch team=payments hosts", "checkout", map[string]string{"team": "payments"}, true},
+        {"Patch team=payments-eu hosts", "checkout", map[string]string{"team": "payments"}, false},
+    }
+
+    for _, tt := range tests {
+        if got := summaryNames(tt.summary, tt.service, tt.labels); got != tt.want {
+            t.Errorf("summaryNames(%q, %q) = %v, want %v", tt.summary, tt.service, got, tt.want)
+        }
+    }
+}
+
+func calendarFeed(events ...string) string {
+    return "BEGIN:VCALENDAR\r\n" + strings.Join(events, "") + "END:VCALENDAR\r\n"
+}
+
+func TestParseCalendarRecurrence(t *testing.T) {
+    // Monday 2026-10-12 10:00 UTC
+    now := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+    tests := []struct {
+        name     string
+        feed     string
+        starts   []string // RFC 3339 starts of the expanded occurrences, at most the first 3
+        rejected int
+    }{
+        {
+            name:   "single event",
+            feed:   calendarFeed("BEGIN:VEVENT\r\nSUMMARY:once\r\nDTSTART:20261013T020000Z\r\nDTEND:20261013T030000Z\r\nEND:VEVENT\r\n"),
+            starts: []string{"2026-10-13T02:00:00Z"},
+        },
+        {
+            name:   "weekly occurrences from a past first event",
+            feed:   calendarFeed("BEGIN:VEVENT\r\nSUMMARY:weekly\r\nDTSTART:20260907T020000Z\r\nDURATION:PT2H\r\nRRULE:FREQ=WEEKLY\r\nEND:VEVENT\r\n"),
+            starts: []string{"2026-10-19T02:00:00Z", "2026-10-26T02:00:00Z", "2026-11-02T02:00:00Z"},
+        },
+        {
+            name:   "ongoing occurrence is kept",
+            feed:   calendarFeed("BEGIN:VEVENT\r\nSUMMARY:daily\r\nDTSTART:20261001T090000Z\r\nDURATION:PT2H\r\nRRULE:FREQ=DAILY\r\nEND:VEVENT\r\n"),
+            starts: []string{"2026-10-12T09:00:00Z", "2026-10-13T09:00:00Z", "2026-10-14T09:00:00Z"},
+        },
+        {
+            name:   "BYDAY, INTERVAL and EXDATE",
+            feed:   calendarFeed("BEGIN:VEVENT\r\nSUMMARY:biweekly\r\nDTSTART:20261013T020000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH\r\nEXDATE:20261015T020000Z\r\nEND:VEVENT\r\n"),
+            starts: []string{"2026-10-13T02:00:00Z", "2026-10-27T02:00:00Z", "2026-10-29T02:00:00Z"},
+        },
+        {
+            name: "COUNT and UNTIL end the series",
+            feed: calendarFeed(
+                "BEGIN:VEVENT\r\nSUMMARY:count\r\nDTSTART:20261010T020000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=DAILY;COUNT=4\r\nEND:VEVENT\r\n",
+                "BEGIN:VEVENT\r\nSUMMARY:until\r\nDTSTART:20261012T230000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=DAILY;UNTIL=20261013T235959Z\r\nEND:VEVENT\r\n"),
+            starts: []string{"2026-10-12T23:00:00Z", "2026-10-13T02:00:00Z", "2026-10-13T23:00:00Z"},
+        },
+        {
+            name: "overridden occurrence replaces the series instance",
+            feed: calendarFeed(
+                "BEGIN:VEVENT\r\nUID:x\r\nSUMMARY:series\r\nDTSTART:20261013T020000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=DAILY;COUNT=2\r\nEND:VEVENT\r\n",
+                "BEGIN:VEVENT\r\nUID:x\r\nRECURRENCE-ID:20261014T020000Z\r\nSUMMARY:moved\r\nDTSTART:20261014T050000Z\r\nDURATION:PT1H\r\nEND:VEVENT\r\n"),
+            starts: []string{"2026-10-13T02:00:00Z", "2026-10-14T05:00:00Z"},
+        },
+        {
+            name: "unsupported rules are rejected",
+            feed: calendarFeed(
+                "BEGIN:VEVENT\r\nSUMMARY:monthly\r\nDTSTART:20261013T020000Z\r\nRRULE:FREQ=MONTHLY\r\nEND:VEVENT\r\n",
+                "BEGIN:VEVENT\r\nSUMMARY:rdate\r\nDTSTART:20261013T020000Z\r\nRDATE:20261020T020000Z\r\nEND:VEVENT\r\n"),
+            rejected: 2,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            events, rejected, err := parseCalendar(strings.NewReader(tt.feed), time.UTC, now)
+            if err != nil {
+                t.Fatalf("parseCalendar: %v", err)
+            }
+            if len(rejected) != tt.rejected {
+                t.Errorf("rejected %v, want %d", rejected, tt.rejected)
+            }
+
+            var starts []string
+            for _, event := range events {
+                starts = append(starts, event.Start.UTC().Format(time.RFC3339))
+            }
+            sort.Strings(starts)
+            if len(starts) > 3 {
+                starts = starts[:3]
+            }
+            if !equalStrings(starts, tt.starts) {
+                t.Errorf("starts %v, want %v", starts, tt.starts)
+            }
+        })
+    }
+}
+
+func TestMaintenanceDefersAlert(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", backend.URL)
+    m, sender := newTestMonitor(t, MonitorConfig{
+        Services:            []ServiceConfig{service},
+        MaintenanceCalendar: &MaintenanceCalendarConfig{URL: "http://calendar.invalid/feed.ics"},
+    })
+    m.calendar.events = []calendarEvent{{Summary: "api upgrade", Start: time.Now().Add(-time.Minute), End: time.Now().Add(time.Hour)}}
+
+    checkAndFlush(m, service)
+    if got := sender.kinds(); len(got) != 0 {
+        t.Fatalf("delivered %v during maintenance", got)
+    }
+
+    m.calendar.mutex.Lock()
+    m.calendar.events = nil
+    m.calendar.mutex.Unlock()
+    checkAndFlush(m, service)
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Errorf("delivered %v after maintenance ended, want an alert", got)
+    }
+}
+
+func TestMaintenanceCalendarFeed(t *testing.T) {
+    now := time.Now().UTC()
+    ics := func(summary string, start, end time.Time) string {
+        return "BEGIN:VEVENT\r\nSUMMARY:" + summary + "\r\nDTSTART:" + start.Format("20060102T150405Z") +
+            "\r\nDTEND:" + end.Format("20060102T150405Z") + "\r\nEND:VEVENT\r\n"
+    }
+    feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "text/calendar")
+        io.WriteString(w, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"+
+            ics("payments database upgrade", now.Add(-time.Minute), now.Add(time.Hour))+
+            ics("search reindex", now.Add(time.Hour), now.Add(2*time.Hour))+
+            "END:VCALENDAR\r\n")
+    }))
+    defer feed.Close()
+
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    payments, search := testService("payments", backend.URL), testService("search", backend.URL)
+    m, sender := newTestMonitor(t, MonitorConfig{
+        Services:            []ServiceConfig{payments, search},
+        MaintenanceCalendar: &MaintenanceCalendarConfig{URL: feed.URL, MatchSummary: true},
+    })
+    m.startMaintenanceCalendar()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        if _, ok := m.inMaintenance("payments"); ok {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("maintenance calendar was not fetched")
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+
+    tests := []struct {
+        name    string
+        service ServiceConfig
+        want    []string // event kinds delivered after its check
+    }{
+        {"active event names the service", payments, []string{}},
+        {"event for the service not started yet", search, []string{EventAlert}},
+    }
+    for _, tt := range tests {
+        checkAndFlush(m, tt.service)
+        if got := sender.kinds(); !equalStrings(got, tt.want) {
+            t.Fatalf("%s: delivered %v, want %v", tt.name, got, tt.want)
+        }
+    }
+    sender.mutex.Lock()
+    defer sender.mutex.Unlock()
+    if got := sender.events[0].Service.Name; got != "search" {
+        t.Errorf("alert for %s, want search", got)
+    }
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "crypto/x509"
+    "fmt"
+    "net/http"
+
+    "golang.org/x/crypto/ocsp"
+)
+
+// Certificate validation modes. Problems with the chain or the stapled
+// OCSP response degrade the service in warn mode and fail it in strict
+// mode, where a missing OCSP staple is also a failure.
+const (
+    CertValidationWarn   = "warn"
+    CertValidationStrict = "strict"
+)
+
+// checkCertificate verifies that the server presented a complete chain to
+// a trusted root and, when an OCSP response is stapled, that it is valid
+// and the certificate is not revoked
+func checkCertificate(resp *http.Response, mode string) error {
+    if resp.TLS == nil {
+        return fmt.Errorf("certificate check failed: connection is not using TLS")
+    }
+    if len(resp.TLS.VerifiedChains) == 0 || len(resp.TLS.PeerCertificates) == 0 {
+        return fmt.Errorf("certificate check failed: chain was not verified")
+    }
+
+    chain := resp.TLS.VerifiedChains[0]
+    if err := checkChainPresented(chain, resp.TLS.PeerCertificates); err != nil {
+        return err
+    }
+
+    leaf := chain[0]
+    if len(chain) < 2 {
+        // A directly trusted certificate has no issuer to answer for it
+        return nil
+    }
+    if len(resp.TLS.OCSPResponse) == 0 {
+        if mode == CertValidationStrict {
+            return fmt.Errorf("certificate check failed: no OCSP response stapled")
+        }
+        return nil
+    }
+
+    status, err := ocsp.ParseResponseForCert(resp.TLS.OCSPResponse, leaf, chain[1])
+    if err != nil {
+        return fmt.Errorf("certificate check failed: invalid stapled OCSP response: %v", err)
+    }
+    switch status.Status {
+    case ocsp.Revoked:
+        return fmt.Errorf("certificate check failed: certificate revoked at %s", status.RevokedAt.UTC().Format("2006-01-02 15:04:05"))
+    case ocsp.Unknown:
+        return fmt.Errorf("certificate check failed: OCSP responder does not know the certificate")
+    }
+
+    return nil
+}
+
+// checkChainPresented fails when verification needed an intermediate the
+// server didn't send. Clients without that intermediate cached, unlike
+// this host, would reject the connection.
+func checkChainPresented(chain, presented []*x509.Certificate) error {
+    // The last certificate is the trusted root, which servers may omit
+    for _, cert := range chain[:len(chain)-1] {
+        found := false
+        for _, peer := range presented {
+            if cert.Equal(peer) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return fmt.Errorf("certificate check failed: incomplete chain, %q was not presented", cert.Subject.CommonName)
+        }
+    }
+    return nil
+}
+
+// certificateWarning returns the certificate problem that degrades a
+// service in warn mode
+func certificateWarning(service ServiceConfig, resp *http.Response) error {
+    if service.CertValidation != CertValidationWarn {
+        return nil
+    }
+    return checkCertificate(resp, service.CertValidation)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "math/big"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "golang.org/x/crypto/ocsp"
+)
+
+// testPKI is a root, an intermediate and a leaf for 127.0.0.1
+type testPKI struct {
+    root, intermediate, leaf *x509.Certificate
+    intermediateKey, leafKey crypto.Signer
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+    t.Helper()
+    issue := func(template, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+        key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if parent == nil {
+            parent, parentKey = template, key
+        }
+        der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+        if err != nil {
+            t.Fatal(err)
+        }
+        cert, err := x509.ParseCertificate(der)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return cert, key
+    }
+    validity := func(serial int64, name string) x509.Certificate {
+        return x509.Certificate{
+            SerialNumber: big.NewInt(serial),
+            Subject:      pkix.Name{CommonName: name},
+            NotBefore:    time.Now().Add(-time.Hour),
+            NotAfter:     time.Now().Add(24 * time.Hour),
+        }
+    }
+
+    ca := validity(1, "Test Root")
+    ca.IsCA, ca.BasicConstraintsValid, ca.KeyUsage = true, true, x509.KeyUsageCertSign
+    root, rootKey := issue(&ca, nil, nil)
+
+    intermediateTemplate := validity(2, "Test Intermediate")
+    intermediateTemplate.IsCA, intermediateTemplate.BasicConstraintsValid = true, true
+    intermediateTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+    intermediate, intermediateKey := issue(&intermediateTemplate, root, rootKey)
+
+    leafTemplate := validity(3, "127.0.0.1")
+    leafTemplate.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+    leafTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+    leaf, leafKey := issue(&leafTemplate, intermediate, intermediateKey)
+
+    return &testPKI{root: root, intermediate: intermediate, leaf: leaf, intermediateKey: intermediateKey, leafKey: leafKey}
+}
+
+// staple is an OCSP response for the leaf signed by its issuer
+func (p *testPKI) staple(t *testing.T, status int) []byte {
+    t.Helper()
+    response, err := ocsp.CreateResponse(p.intermediate, p.intermediate, ocsp.Response{
+        Status:       status,
+        SerialNumber: p.leaf.SerialNumber,
+        ThisUpdate:   time.Now().Add(-time.Minute),
+        NextUpdate:   time.Now().Add(time.Hour),
+        RevokedAt:    time.Now().Add(-time.Minute),
+    }, p.intermediateKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return response
+}
+
+func TestCertValidation(t *testing.T) {
+    pki := newTestPKI(t)
+
+    tests := []struct {
+        name         string
+        mode         string
+        leafOnly     bool // the server omits the intermediate
+        staple       int  // OCSP status stapled, -1 for none
+        wantUp       bool
+        wantDegraded bool
+        wantErr      string
+    }{
+        {"strict with a good staple", CertValidationStrict, false, ocsp.Good, true, false, ""},
+        {"strict with a revoked staple", CertValidationStrict, false, ocsp.Revoked, false, false, "certificate revoked"},
+        {"strict with an unknown staple", CertValidationStrict, false, ocsp.Unknown, false, false, "does not know the certificate"},
+        {"strict without a staple", CertValidationStrict, false, -1, false, false, "no OCSP response stapled"},
+        {"warn without a staple", CertValidationWarn, false, -1, true, false, ""},
+        {"warn with a revoked staple", CertValidationWarn, false, ocsp.Revoked, false, true, "certificate revoked"},
+        {"incomplete chain fails the handshake", CertValidationWarn, true, -1, false, false, "certificate"},
+        {"revocation ignored without cert_validation", "", false, ocsp.Revoked, true, false, ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            certificate := tls.Certificate{Certificate: [][]byte{pki.leaf.Raw, pki.intermediate.Raw}, PrivateKey: pki.leafKey}
+            if tt.leafOnly {
+                certificate.Certificate = certificate.Certificate[:1]
+            }
+            if tt.staple >= 0 {
+                certificate.OCSPStaple = pki.staple(t, tt.staple)
+            }
+            server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+            server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+            server.StartTLS()
+            defer server.Close()
+
+            service := testService("api", server.URL)
+            service.CertValidation = tt.mode
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            client := m.serviceClient(service)
+            roots := x509.NewCertPool()
+            roots.AddCert(pki.root)
+            client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+
+            outcome := m.performCheck(service, client, nil)
+            if outcome.up != tt.wantUp || outcome.degraded != tt.wantDegraded {
+                t.Errorf("up %v, degraded %v (%v), want %v and %v", outcome.up, outcome.degraded, outcome.err, tt.wantUp, tt.wantDegraded)
+            }
+            if tt.wantErr != "" && (outcome.err == nil || !strings.Contains(outcome.err.Error(), tt.wantErr)) {
+                t.Errorf("error %v, want it to mention %q", outcome.err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestCheckChainPresented(t *testing.T) {
+    pki := newTestPKI(t)
+    chain := []*x509.Certificate{pki.leaf, pki.intermediate, pki.root}
+
+    tests := []struct {
+        name      string
+        presented []*x509.Certificate
+        wantErr   bool
+    }{
+        {"full chain", []*x509.Certificate{pki.leaf, pki.intermediate}, false},
+        {"full chain with the root", []*x509.Certificate{pki.leaf, pki.intermediate, pki.root}, false},
+        {"intermediate missing", []*x509.Certificate{pki.leaf}, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := checkChainPresented(chain, tt.presented)
+            if (err != nil) != tt.wantErr {
+                t.Errorf("checkChainPresented() = %v, want error %v", err, tt.wantErr)
+            }
+            if err != nil && !strings.Contains(err.Error(), "Test Intermediate") {
+                t.Errorf("error %q, want it to name the missing intermediate", err)
+            }
+        })
+    }
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sync"
+)
+
+// knownChannels are the channel names accepted by the channel toggle API
+var knownChannels = map[string]bool{
+    ChannelSlack:      true,
+    ChannelEmail:      true,
+    ChannelPagerDuty:  true,
+    ChannelFile:       true,
+    ChannelSyslog:     true,
+    ChannelSNS:        true,
+    ChannelGoogleChat: true,
+    ChannelNATS:       true,
+}
+
+// channelState holds channels muted at runtime via the API
+type channelState struct {
+    mutex    sync.RWMutex
+    disabled map[string]bool
+}
+
+func (m *Monitor) channelEnabled(channel string) bool {
+    m.channels.mutex.RLock()
+    defer m.channels.mutex.RUnlock()
+    return !m.channels.disabled[channel]
+}
+
+// disabledChannels returns the routed channels for a service that are muted
+func (m *Monitor) disabledChannels(service ServiceConfig) []string {
+    disabled := []string{}
+    for _, channel := range m.alertChannels(service) {
+        if !m.channelEnabled(channel) {
+            disabled = append(disabled, channel)
+        }
+    }
+    return disabled
+}
+
+func (m *Monitor) setChannelEnabled(enabled bool) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        channel := r.PathValue("name")
+        if !knownChannels[channel] {
+            http.Error(w, fmt.Sprintf("unknown channel %q", channel), http.StatusNotFound)
+            return
+        }
+
+        m.channels.mutex.Lock()
+        if enabled {
+            delete(m.channels.disabled, channel)
+        } else {
+            m.channels.disabled[channel] = true
+        }
+        m.channels.mutex.Unlock()
+
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "channel": channel,
+            "enabled": enabled,
+        })
+    }
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+    "net/http"
+    "testing"
+)
+
+func TestChannelToggle(t *testing.T) {
+    tests := []struct {
+        name    string
+        toggles []string // API paths posted before the service fails
+        slack   int
+        email   int
+    }{
+        {"both enabled", nil, 1, 1},
+        {"slack disabled", []string{"/channels/slack/disable"}, 0, 1},
+        {"slack disabled then enabled", []string{"/channels/slack/disable", "/channels/slack/enable"}, 1, 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", backend.URL)
+            m, err := NewMonitorFromConfig(MonitorConfig{
+                Services: []ServiceConfig{service},
+                APIToken: testAPIToken,
+                Alerts:   AlertConfig{Routing: map[string][]string{SeverityWarning: {ChannelSlack, ChannelEmail}}},
+            })
+            if err != nil {
+                t.Fatalf("NewMonitorFromConfig: %v", err)
+            }
+            t.Cleanup(m.cancel)
+            slack, email := &recordingSender{}, &recordingSender{}
+            m.RegisterSender(ChannelSlack, slack)
+            m.RegisterSender(ChannelEmail, email)
+
+            for _, path := range tt.toggles {
+                if resp := apiRequest(m, http.MethodPost, path, "", true); resp.Code != http.StatusOK {
+                    t.Fatalf("POST %s = %d", path, resp.Code)
+                }
+            }
+            checkAndFlush(m, service)
+
+            if got := len(slack.kinds()); got != tt.slack {
+                t.Errorf("slack received %d alerts, want %d", got, tt.slack)
+            }
+            if got := len(email.kinds()); got != tt.email {
+                t.Errorf("email received %d alerts, want %d", got, tt.email)
+            }
+        })
+    }
+}
+
+func TestChannelToggleRequests(t *testing.T) {
+    tests := []struct {
+        name  string
+        path  string
+        token bool
+        want  int
+    }{
+        {"known channel", "/channels/email/disable", true, http.StatusOK},
+        {"unknown channel", "/channels/carrier-pigeon/disable", true, http.StatusNotFound},
+        {"no token", "/channels/slack/disable", false, http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{APIToken: testAPIToken})
+            if resp := apiRequest(m, http.MethodPost, tt.path, "", tt.token); resp.Code != tt.want {
+                t.Errorf("POST %s = %d, want %d", tt.path, resp.Code, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,46 @@
+//go:build amqp
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const CheckTypeAMQP = "amqp"
+
+func init() {
+    checkers[CheckTypeAMQP] = checkAMQP
+}
+
+// checkAMQP connects to the broker, opens a channel and, when AMQPQueue is
+// set, passively declares the queue to confirm it exists
+func checkAMQP(ctx context.Context, service ServiceConfig) error {
+    timeout := 30 * time.Second
+    if deadline, ok := ctx.Deadline(); ok {
+        timeout = time.Until(deadline)
+    }
+
+    conn, err := amqp.DialConfig(service.URL, amqp.Config{Dial: amqp.DefaultDial(timeout)})
+    if err != nil {
+        return fmt.Errorf("error connecting to broker: %v", err)
+    }
+    defer conn.Close()
+
+    channel, err := conn.Channel()
+    if err != nil {
+        return fmt.Errorf("error opening channel: %v", err)
+    }
+    defer channel.Close()
+
+    if service.AMQPQueue != "" {
+        if _, err := channel.QueueDeclarePassive(service.AMQPQueue, false, false, false, false, nil); err != nil {
+            return fmt.Errorf("queue %s not available: %v", service.AMQPQueue, err)
+        }
+    }
+
+    return nil
+}
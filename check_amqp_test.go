@@ -0,0 +1,77 @@
+//go:build amqp
+
+package main
+
+import (
+    "context"
+    "net"
+    "os"
+    "strings"
+    "testing"
+    "time"
+)
+
+// TestCheckAMQPBroker runs against a real broker, e.g. a RabbitMQ container,
+// named by AMQP_TEST_URL
+func TestCheckAMQPBroker(t *testing.T) {
+    url := os.Getenv("AMQP_TEST_URL")
+    if url == "" {
+        t.Skip("AMQP_TEST_URL not set")
+    }
+
+    tests := []struct {
+        name    string
+        queue   string
+        wantErr bool
+    }{
+        {"connects", "", false},
+        {"missing queue", "monitor-alert-test-missing", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := ServiceConfig{Name: "queue", Type: CheckTypeAMQP, URL: url, AMQPQueue: tt.queue}
+            ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+            defer cancel()
+            if err := checkAMQP(ctx, service); (err != nil) != tt.wantErr {
+                t.Errorf("checkAMQP() = %v, want error %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestCheckAMQPFailure(t *testing.T) {
+    refused := listen(t, "127.0.0.1:0")
+    hanging, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { hanging.Close() })
+
+    tests := []struct {
+        name string
+        addr string
+    }{
+        {"connection dropped", refused}, // listen closes each connection
+        {"broker never answers", hanging.Addr().String()},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := ServiceConfig{Name: "queue", Type: CheckTypeAMQP, URL: "amqp://guest:guest@" + tt.addr + "/", Timeout: 1, RetryAttempts: 2, CheckInterval: 30}
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            start := time.Now()
+            outcome := m.performCheck(service, nil, nil)
+            if outcome.up {
+                t.Fatal("check passed")
+            }
+            if !strings.Contains(outcome.err.Error(), "error connecting to broker") {
+                t.Errorf("error %q, want a connection failure", outcome.err)
+            }
+            if elapsed := time.Since(start); elapsed > 10*time.Second {
+                t.Errorf("check took %s, timeout not applied", elapsed)
+            }
+        })
+    }
+}
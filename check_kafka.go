@@ -0,0 +1,72 @@
+//go:build kafka
+
+package main
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/segmentio/kafka-go"
+)
+
+const CheckTypeKafka = "kafka"
+
+func init() {
+    checkers[CheckTypeKafka] = checkKafka
+    urlOptional[CheckTypeKafka] = true
+    typeValidators[CheckTypeKafka] = validateKafka
+}
+
+func validateKafka(service ServiceConfig) error {
+    if len(service.KafkaBrokers) == 0 {
+        return fmt.Errorf("service %s: kafka checks need at least one of kafka_brokers", service.Name)
+    }
+    return nil
+}
+
+// checkKafka connects to the first reachable broker and fetches cluster
+// metadata. When KafkaTopic is set, the topic must exist and every
+// partition must have a leader.
+func checkKafka(ctx context.Context, service ServiceConfig) error {
+    if len(service.KafkaBrokers) == 0 {
+        return fmt.Errorf("no kafka_brokers configured")
+    }
+
+    var dialer kafka.Dialer
+    var conn *kafka.Conn
+    var err error
+    for _, broker := range service.KafkaBrokers {
+        if conn, err = dialer.DialContext(ctx, "tcp", broker); err == nil {
+            break
+        }
+    }
+    if err != nil {
+        return fmt.Errorf("error connecting to brokers: %v", err)
+    }
+    defer conn.Close()
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    if service.KafkaTopic == "" {
+        if _, err := conn.Brokers(); err != nil {
+            return fmt.Errorf("error fetching metadata: %v", err)
+        }
+        return nil
+    }
+
+    partitions, err := conn.ReadPartitions(service.KafkaTopic)
+    if err != nil {
+        return fmt.Errorf("topic %s not available: %v", service.KafkaTopic, err)
+    }
+    if len(partitions) == 0 {
+        return fmt.Errorf("topic %s not found", service.KafkaTopic)
+    }
+    for _, partition := range partitions {
+        if partition.Leader.Host == "" {
+            return fmt.Errorf("topic %s partition %d has no leader", service.KafkaTopic, partition.ID)
+        }
+    }
+
+    return nil
+}
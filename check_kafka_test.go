@@ -0,0 +1,165 @@
+//go:build kafka
+
+package main
+
+import (
+    "context"
+    "net"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/segmentio/kafka-go/protocol"
+    "github.com/segmentio/kafka-go/protocol/apiversions"
+    "github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+// newStubKafka serves ApiVersions and Metadata v1 as a single broker, node
+// 1. topics maps each topic to its partitions' leader IDs, -1 for none.
+func newStubKafka(t *testing.T, topics map[string][]int32) string {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { listener.Close() })
+    host, portText, _ := net.SplitHostPort(listener.Addr().String())
+    port, _ := net.LookupPort("tcp", portText)
+
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go serveStubKafka(conn, host, int32(port), topics)
+        }
+    }()
+    return listener.Addr().String()
+}
+
+func serveStubKafka(conn net.Conn, host string, port int32, topics map[string][]int32) {
+    defer conn.Close()
+    for {
+        version, correlationID, _, msg, err := protocol.ReadRequest(conn)
+        if err != nil {
+            return
+        }
+        var response protocol.Message
+        switch req := msg.(type) {
+        case *apiversions.Request:
+            response = &apiversions.Response{ApiKeys: []apiversions.ApiKeyResponse{
+                {ApiKey: int16(protocol.ApiVersions), MinVersion: 0, MaxVersion: 0},
+                {ApiKey: int16(protocol.Metadata), MinVersion: 1, MaxVersion: 1},
+            }}
+        case *metadata.Request:
+            res := &metadata.Response{
+                Brokers:      []metadata.ResponseBroker{{NodeID: 1, Host: host, Port: port}},
+                ControllerID: 1,
+            }
+            names := req.TopicNames
+            if names == nil {
+                for name := range topics {
+                    names = append(names, name)
+                }
+            }
+            for _, name := range names {
+                leaders, ok := topics[name]
+                if !ok {
+                    res.Topics = append(res.Topics, metadata.ResponseTopic{ErrorCode: 3, Name: name}) // UNKNOWN_TOPIC_OR_PARTITION
+                    continue
+                }
+                topic := metadata.ResponseTopic{Name: name}
+                for i, leader := range leaders {
+                    topic.Partitions = append(topic.Partitions, metadata.ResponsePartition{PartitionIndex: int32(i), LeaderID: leader})
+                }
+                res.Topics = append(res.Topics, topic)
+            }
+            response = res
+        default:
+            return
+        }
+        if err := protocol.WriteResponse(conn, version, correlationID, response); err != nil {
+            return
+        }
+    }
+}
+
+// closedAddr is an address nothing listens on
+func closedAddr(t *testing.T) string {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := listener.Addr().String()
+    listener.Close()
+    return addr
+}
+
+func TestCheckKafka(t *testing.T) {
+    broker := newStubKafka(t, map[string][]int32{"orders": {1, 1}, "leaderless": {1, -1}})
+    down := closedAddr(t)
+
+    tests := []struct {
+        name    string
+        brokers []string
+        topic   string
+        wantErr string
+    }{
+        {"cluster metadata", []string{broker}, "", ""},
+        {"topic with leaders", []string{broker}, "orders", ""},
+        {"first broker down", []string{down, broker}, "orders", ""},
+        {"topic missing", []string{broker}, "payments", "topic payments not available"},
+        {"partition without a leader", []string{broker}, "leaderless", "partition 1 has no leader"},
+        {"brokers down", []string{down}, "", "error connecting to brokers"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := ServiceConfig{Name: "events", Type: CheckTypeKafka, KafkaBrokers: tt.brokers, KafkaTopic: tt.topic}
+            ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+            defer cancel()
+
+            err := checkKafka(ctx, service)
+            if tt.wantErr == "" {
+                if err != nil {
+                    t.Errorf("checkKafka() = %v, want nil", err)
+                }
+            } else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("checkKafka() = %v, want an error containing %q", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestValidateKafkaService(t *testing.T) {
+    tests := []struct {
+        name    string
+        service ServiceConfig
+        wantErr bool
+    }{
+        {
+            name:    "brokers without a url",
+            service: ServiceConfig{Name: "events", Type: CheckTypeKafka, KafkaBrokers: []string{"kafka:9092"}, CheckInterval: 30, RetryAttempts: 1},
+        },
+        {
+            name:    "no brokers",
+            service: ServiceConfig{Name: "events", Type: CheckTypeKafka, CheckInterval: 30, RetryAttempts: 1},
+            wantErr: true,
+        },
+        {
+            name:    "an invalid url is still rejected",
+            service: ServiceConfig{Name: "events", Type: CheckTypeKafka, URL: "kafka", KafkaBrokers: []string{"kafka:9092"}, CheckInterval: 30, RetryAttempts: 1},
+            wantErr: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := validateServiceConfig(tt.service); (err != nil) != tt.wantErr {
+                t.Errorf("validateServiceConfig() = %v, want error %v", err, tt.wantErr)
+            }
+        })
+    }
+}
@@ -0,0 +1,151 @@
+//go:build sql
+
+package main
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "io"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// fakeSQL is a database/sql driver standing in for a database. The DSN
+// names the failure to simulate: "refuse", "query" or "rows".
+type fakeSQL struct {
+    mutex   sync.Mutex
+    queries []string
+}
+
+var fakeSQLDriver = &fakeSQL{}
+
+func init() {
+    sql.Register("fakesql", fakeSQLDriver)
+}
+
+func (d *fakeSQL) Open(dsn string) (driver.Conn, error) {
+    if dsn == "refuse" {
+        return nil, errors.New("connection refused")
+    }
+    return &fakeSQLConn{driver: d, dsn: dsn}, nil
+}
+
+func (d *fakeSQL) ran() []string {
+    d.mutex.Lock()
+    defer d.mutex.Unlock()
+    queries := d.queries
+    d.queries = nil
+    return queries
+}
+
+type fakeSQLConn struct {
+    driver *fakeSQL
+    dsn    string
+}
+
+func (c *fakeSQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    c.driver.mutex.Lock()
+    c.driver.queries = append(c.driver.queries, query)
+    c.driver.mutex.Unlock()
+    if c.dsn == "query" {
+        return nil, errors.New(`relation "health" does not exist`)
+    }
+    return &fakeSQLRows{remaining: 1, failOnRow: c.dsn == "rows"}, nil
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+    return nil, errors.New("not supported")
+}
+func (c *fakeSQLConn) Close() error              { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeSQLRows struct {
+    remaining int
+    failOnRow bool
+}
+
+func (r *fakeSQLRows) Columns() []string { return []string{"?column?"} }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+    if r.failOnRow {
+        return errors.New("canceling statement due to statement timeout")
+    }
+    if r.remaining == 0 {
+        return io.EOF
+    }
+    r.remaining--
+    dest[0] = int64(1)
+    return nil
+}
+
+func TestPingSQL(t *testing.T) {
+    tests := []struct {
+        name    string
+        dsn     string
+        query   string
+        wantRun bool
+        wantErr string
+    }{
+        {"ping succeeds", "ok", "SELECT 1", true, ""},
+        {"custom query", "ok", "SELECT count(*) FROM health", true, ""},
+        {"connection refused", "refuse", "SELECT 1", false, "connection refused"},
+        {"query fails", "query", "SELECT * FROM health", true, "ping query failed"},
+        {"row error", "rows", "SELECT 1", true, "statement timeout"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := pingSQL(context.Background(), "fakesql", tt.dsn, tt.query)
+            if tt.wantErr == "" && err != nil {
+                t.Errorf("pingSQL() = %v, want success", err)
+            }
+            if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Errorf("pingSQL() = %v, want an error mentioning %q", err, tt.wantErr)
+            }
+            ran := fakeSQLDriver.ran()
+            if tt.wantRun != (len(ran) == 1 && ran[0] == tt.query) {
+                t.Errorf("ran %q, want %q run %v", ran, tt.query, tt.wantRun)
+            }
+        })
+    }
+}
+
+func TestSQLDataSource(t *testing.T) {
+    tests := []struct {
+        name       string
+        url        string
+        driver     string
+        wantDriver string
+        wantDSN    string
+        wantErr    bool
+    }{
+        {"postgres url", "postgres://monitor:pw@db:5432/app", "", "pgx", "postgres://monitor:pw@db:5432/app", false},
+        {"mysql url", "mysql://monitor:pw@db:3306/app", "", "mysql", "monitor:pw@tcp(db:3306)/app", false},
+        {"driver override", "postgresql://monitor@db/app", "postgres", "pgx", "postgresql://monitor@db/app", false},
+        {"unsupported driver", "sqlite:///tmp/app.db", "", "", "", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            driver, dsn, err := sqlDataSource(ServiceConfig{Name: "db", URL: tt.url, SQLDriver: tt.driver})
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("sqlDataSource() error = %v, want error %v", err, tt.wantErr)
+            }
+            if driver != tt.wantDriver || (tt.wantDSN != "" && !strings.HasPrefix(dsn, tt.wantDSN)) {
+                t.Errorf("sqlDataSource() = %q, %q, want %q, %q", driver, dsn, tt.wantDriver, tt.wantDSN)
+            }
+        })
+    }
+}
+
+func TestCheckSQLUnreachable(t *testing.T) {
+    service := ServiceConfig{Name: "db", Type: CheckTypeSQL, URL: "postgres://monitor@" + listen(t, "127.0.0.1:0") + "/app", Timeout: 2, RetryAttempts: 2, CheckInterval: 30}
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    if outcome := m.performCheck(service, nil, nil); outcome.up {
+        t.Error("check of a database dropping connections passed")
+    }
+}
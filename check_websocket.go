@@ -0,0 +1,76 @@
+//go:build websocket
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+const CheckTypeWebSocket = "websocket"
+
+func init() {
+    checkers[CheckTypeWebSocket] = checkWebSocket
+}
+
+// checkWebSocket performs the upgrade handshake against a ws:// or wss://
+// URL and, when websocket_ping is set, waits for a pong to a ping
+func checkWebSocket(ctx context.Context, service ServiceConfig) error {
+    header := http.Header{}
+    for key, value := range service.Headers {
+        header.Add(key, value)
+    }
+
+    conn, resp, err := websocket.DefaultDialer.DialContext(ctx, service.URL, header)
+    if err != nil {
+        if resp != nil {
+            return fmt.Errorf("websocket handshake failed with status %d: %v", resp.StatusCode, err)
+        }
+        return fmt.Errorf("websocket handshake failed: %v", err)
+    }
+    defer conn.Close()
+
+    if !service.WebSocketPing {
+        return nil
+    }
+
+    deadline, ok := ctx.Deadline()
+    if !ok {
+        deadline = time.Now().Add(30 * time.Second)
+    }
+
+    pong := make(chan struct{}, 1)
+    conn.SetPongHandler(func(string) error {
+        select {
+        case pong <- struct{}{}:
+        default:
+        }
+        return nil
+    })
+    if err := conn.WriteControl(websocket.PingMessage, []byte("monitor-alert"), deadline); err != nil {
+        return fmt.Errorf("error sending ping: %v", err)
+    }
+
+    // Control frames are only processed while reading
+    conn.SetReadDeadline(deadline)
+    readErr := make(chan error, 1)
+    go func() {
+        for {
+            if _, _, err := conn.ReadMessage(); err != nil {
+                readErr <- err
+                return
+            }
+        }
+    }()
+
+    select {
+    case <-pong:
+        return nil
+    case err := <-readErr:
+        return fmt.Errorf("no pong received: %v", err)
+    }
+}
@@ -0,0 +1,109 @@
+//go:build websocket
+
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+// newWebSocketServer echoes messages on /echo, upgrades but never answers
+// pings on /silent and refuses upgrades elsewhere
+func newWebSocketServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    var upgrader websocket.Upgrader
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Authorization") != "Bearer ws-token" {
+            http.Error(w, "unauthorized", http.StatusUnauthorized)
+            return
+        }
+        if r.URL.Path != "/echo" && r.URL.Path != "/silent" {
+            w.Write([]byte("not a websocket"))
+            return
+        }
+        conn, err := upgrader.Upgrade(w, r, nil)
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        if r.URL.Path == "/silent" {
+            conn.SetPingHandler(func(string) error { return nil })
+        }
+        for {
+            kind, message, err := conn.ReadMessage()
+            if err != nil {
+                return
+            }
+            conn.WriteMessage(kind, message)
+        }
+    }))
+    t.Cleanup(server.Close)
+    return server
+}
+
+func TestCheckWebSocket(t *testing.T) {
+    server := newWebSocketServer(t)
+    wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+    tests := []struct {
+        name    string
+        path    string
+        token   string
+        ping    bool
+        wantErr string // "" for a passing check
+    }{
+        {"handshake", "/echo", "ws-token", false, ""},
+        {"ping answered", "/echo", "ws-token", true, ""},
+        {"not a websocket endpoint", "/health", "ws-token", false, "handshake failed with status 200"},
+        {"handshake rejected", "/echo", "wrong", false, "handshake failed with status 401"},
+        {"ping never answered", "/silent", "ws-token", true, "no pong received"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := ServiceConfig{
+                Name:          "stream",
+                Type:          CheckTypeWebSocket,
+                URL:           wsURL + tt.path,
+                Headers:       map[string]string{"Authorization": "Bearer " + tt.token},
+                WebSocketPing: tt.ping,
+            }
+            ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+            defer cancel()
+
+            err := checkWebSocket(ctx, service)
+            if tt.wantErr == "" && err != nil {
+                t.Errorf("checkWebSocket() = %v, want success", err)
+            }
+            if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Errorf("checkWebSocket() = %v, want an error containing %q", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestWebSocketServiceCheck(t *testing.T) {
+    server := newWebSocketServer(t)
+    service := ServiceConfig{
+        Name:          "stream",
+        Type:          CheckTypeWebSocket,
+        URL:           "ws" + strings.TrimPrefix(server.URL, "http") + "/echo",
+        Headers:       map[string]string{"Authorization": "Bearer ws-token"},
+        WebSocketPing: true,
+        CheckInterval: 30,
+        RetryAttempts: 1,
+        Timeout:       2,
+    }
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    checkAndFlush(m, service)
+    if got := m.serviceStatus["stream"].State; got != StateUp || len(sender.kinds()) > 0 {
+        t.Errorf("state %s with alerts %v, want up and quiet", got, sender.kinds())
+    }
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "time"
+)
+
+const CheckTypeHTTP = "http"
+
+// serviceChecker performs a single attempt of a non-HTTP check type
+type serviceChecker func(ctx context.Context, service ServiceConfig) error
+
+// checkers holds the non-HTTP check types compiled into this binary. Types
+// with heavy client dependencies register themselves from files behind a
+// build tag of the same name, e.g. -tags amqp.
+var checkers = map[string]serviceChecker{}
+
+// urlOptional holds check types addressed by their own settings rather than
+// the service url, such as transaction steps or kafka's broker list
+var urlOptional = map[string]bool{
+    CheckTypeTransaction: true,
+}
+
+// typeValidators check a registered type's own settings at load
+var typeValidators = map[string]func(service ServiceConfig) error{}
+
+func validateCheckType(service ServiceConfig) error {
+    if service.Type == "" || service.Type == CheckTypeHTTP {
+        return nil
+    }
+    if service.Type == CheckTypeTransaction {
+        return validateTransaction(service)
+    }
+    if _, ok := checkers[service.Type]; !ok {
+        return fmt.Errorf("service %s: check type %q is not available in this build (build with -tags %s)",
+            service.Name, service.Type, service.Type)
+    }
+    if validate, ok := typeValidators[service.Type]; ok {
+        return validate(service)
+    }
+    return nil
+}
+
+// performTypedCheck runs a registered checker with the service's timeout and
+// retry settings
+func (m *Monitor) performTypedCheck(ctx context.Context, service ServiceConfig, checker serviceChecker) checkOutcome {
+    startTime := time.Now()
+
+    var err error
+    var latency time.Duration
+    for attempt := 0; attempt < service.RetryAttempts; attempt++ {
+        attemptCtx := ctx
+        cancel := func() {}
+        if service.Timeout > 0 {
+            attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(service.Timeout)*time.Second)
+        }
+        attemptStart := time.Now()
+        err = checker(attemptCtx, service)
+        latency = time.Since(attemptStart)
+        cancel()
+        if err == nil {
+            return checkOutcome{up: true, duration: time.Since(startTime), latency: latency}
+        }
+        if !m.waitRetry(service, attempt) {
+            break
+        }
+    }
+
+    return checkOutcome{err: err, duration: time.Since(startTime), latency: latency}
+}
+
+// waitRetry sleeps for the retry delay before the attempt after attempt. It
+// returns false without sleeping after the last attempt, and early when
+// Shutdown cancels the monitor.
+func (m *Monitor) waitRetry(service ServiceConfig, attempt int) bool {
+    if attempt+1 >= service.RetryAttempts {
+        return false
+    }
+    select {
+    case <-time.After(time.Duration(service.RetryDelay) * time.Second):
+        return true
+    case <-m.ctx.Done():
+        return false
+    }
+}
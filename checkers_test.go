@@ -0,0 +1,51 @@
+package main
+
+import (
+    "context"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// registerChecker adds a check type for the rest of the test
+func registerChecker(t *testing.T, name string, checker serviceChecker) {
+    t.Helper()
+    checkers[name] = checker
+    t.Cleanup(func() { delete(checkers, name) })
+}
+
+func TestCheckPanicRecovered(t *testing.T) {
+    logs := captureLog(t)
+    var calls atomic.Int32
+    registerChecker(t, "panicky", func(ctx context.Context, service ServiceConfig) error {
+        if calls.Add(1) == 1 {
+            panic("nil map write")
+        }
+        return nil
+    })
+
+    service := ServiceConfig{Name: "queue", Type: "panicky", URL: "amqp://queue.internal:5672/", CheckInterval: 1, RetryAttempts: 1}
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    m.startServiceMonitor(service)
+    t.Cleanup(func() { stopMonitors(m) })
+
+    deadline := time.Now().Add(5 * time.Second)
+    for calls.Load() < 2 && time.Now().Before(deadline) {
+        time.Sleep(10 * time.Millisecond)
+    }
+    if calls.Load() < 2 {
+        t.Fatal("the service was not checked again after its check panicked")
+    }
+    if !strings.Contains(logs.String(), "Panic while checking queue: nil map write") {
+        t.Errorf("panic was not logged:\n%s", logs)
+    }
+
+    m.stopServiceMonitor("queue")
+    m.checkWG.Wait()
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+    if history := m.serviceStatus["queue"].History.recent(); len(history) == 0 || !strings.Contains(history[0].Error, "check panicked") {
+        t.Errorf("the panic was not recorded as a failed check: %+v", history)
+    }
+}
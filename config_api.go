@@ -0,0 +1,172 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+)
+
+// redacted replaces secret values in /config output
+const redacted = "***"
+
+// shownHeaders are request headers whose values /config may show; every
+// other header value is redacted, as custom auth headers are common
+var shownHeaders = map[string]bool{
+    "accept":          true,
+    "accept-encoding": true,
+    "accept-language": true,
+    "cache-control":   true,
+    "content-type":    true,
+    "user-agent":      true,
+}
+
+func redact(value *string) {
+    if *value != "" {
+        *value = redacted
+    }
+}
+
+// redactURL hides a URL's userinfo and query values, which carry
+// credentials such as a database password or an API key. A value that
+// does not parse as a URL is redacted whole.
+func redactURL(value *string) {
+    if *value == "" {
+        return
+    }
+    parsed, err := url.Parse(*value)
+    if err != nil {
+        *value = redacted
+        return
+    }
+    hadUser := parsed.User != nil
+    parsed.User = nil
+    if parsed.RawQuery != "" {
+        var keys []string
+        for key := range parsed.Query() {
+            keys = append(keys, key)
+        }
+        sort.Strings(keys)
+        for i, key := range keys {
+            keys[i] = url.QueryEscape(key) + "=" + redacted
+        }
+        parsed.RawQuery = strings.Join(keys, "&")
+    }
+    *value = parsed.String()
+    if hadUser {
+        *value = strings.Replace(*value, "//", "//"+redacted+"@", 1)
+    }
+}
+
+// redactHeaders returns a copy of headers with all but shownHeaders
+// redacted
+func redactHeaders(headers map[string]string) map[string]string {
+    if len(headers) == 0 {
+        return headers
+    }
+    result := make(map[string]string, len(headers))
+    for name, value := range headers {
+        if !shownHeaders[strings.ToLower(name)] {
+            value = redacted
+        }
+        result[name] = value
+    }
+    return result
+}
+
+// redactedConfig returns a copy of the config with secrets replaced. Nested
+// values are copied before being changed so the live config is untouched.
+func redactedConfig(config MonitorConfig) MonitorConfig {
+    redact(&config.APIToken)
+    redact(&config.ResultWebhook)
+    redact(&config.ResultWebhookSecret)
+    redact(&config.Alerts.Slack.WebhookURL)
+    redact(&config.Alerts.Slack.SigningSecret)
+    redact(&config.Alerts.GoogleChat.WebhookURL)
+    redact(&config.Alerts.Email.Password)
+    redact(&config.Alerts.PagerDuty.ServiceKey)
+    redact(&config.Alerts.PagerDuty.APIKey)
+    if len(config.Alerts.TimeRouting) > 0 {
+        routes := append([]TimeRoute(nil), config.Alerts.TimeRouting...)
+        for i := range routes {
+            redact(&routes[i].SlackWebhookURL)
+            redact(&routes[i].PagerDutyRoutingKey)
+        }
+        config.Alerts.TimeRouting = routes
+    }
+    if config.Alerts.NATS != nil {
+        nats := *config.Alerts.NATS
+        redactURL(&nats.URL)
+        config.Alerts.NATS = &nats
+    }
+    if config.Resolver != nil {
+        resolver := *config.Resolver
+        // DoH endpoints often name the account in their path
+        redact(&resolver.DoHURL)
+        config.Resolver = &resolver
+    }
+    if config.OTel != nil {
+        otel := *config.OTel
+        redactURL(&otel.Endpoint)
+        config.OTel = &otel
+    }
+    if config.MaintenanceCalendar != nil {
+        calendar := *config.MaintenanceCalendar
+        // Private iCal links are tokenised in their path
+        redact(&calendar.URL)
+        config.MaintenanceCalendar = &calendar
+    }
+
+    services := make([]ServiceConfig, len(config.Services))
+    for i, service := range config.Services {
+        redactURL(&service.URL)
+        redactURL(&service.UpstreamHealthURL)
+        redactURL(&service.ExpectedFinalURL)
+        redactURL(&service.RunbookURL)
+        redactURL(&service.DashboardURL)
+        redact(&service.PagerDutyRoutingKey)
+        service.Headers = redactHeaders(service.Headers)
+        if len(service.Endpoints) > 0 {
+            endpoints := append([]string(nil), service.Endpoints...)
+            for j := range endpoints {
+                redactURL(&endpoints[j])
+            }
+            service.Endpoints = endpoints
+        }
+        if len(service.Steps) > 0 {
+            steps := append([]TransactionStep(nil), service.Steps...)
+            for j := range steps {
+                redactURL(&steps[j].URL)
+                redact(&steps[j].Body)
+                steps[j].Headers = redactHeaders(steps[j].Headers)
+            }
+            service.Steps = steps
+        }
+        if service.OAuth2 != nil {
+            oauth := *service.OAuth2
+            redactURL(&oauth.TokenURL)
+            redact(&oauth.ClientSecret)
+            service.OAuth2 = &oauth
+        }
+        if service.Socks5Proxy != nil {
+            proxy := *service.Socks5Proxy
+            redact(&proxy.Password)
+            service.Socks5Proxy = &proxy
+        }
+        services[i] = service
+    }
+    config.Services = services
+    return config
+}
+
+// handleConfig returns the effective configuration, including services
+// changed through the API or a reload, with secrets redacted
+func (m *Monitor) handleConfig(w http.ResponseWriter, r *http.Request) {
+    m.statusMutex.RLock()
+    config := redactedConfig(m.config)
+    m.statusMutex.RUnlock()
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(config)
+}
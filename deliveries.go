@@ -0,0 +1,150 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "regexp"
+    "sort"
+    "sync"
+    "time"
+)
+
+// maxDeliveryRecords is how many recent delivery outcomes are kept
+const maxDeliveryRecords = 200
+
+// deliveryRecord is the outcome of delivering one alert event to one
+// channel, after its retries
+type deliveryRecord struct {
+    Time       time.Time `json:"time"`
+    Service    string    `json:"service"`
+    Kind       string    `json:"kind"`
+    Channel    string    `json:"channel"`
+    Success    bool      `json:"success"`
+    StatusCode int       `json:"status_code,omitempty"` // the channel's HTTP response, when it sent one
+    Error      string    `json:"error,omitempty"`
+}
+
+type deliveryLog struct {
+    mutex   sync.Mutex
+    records []deliveryRecord  // oldest first
+    sent    map[string]uint64 // channel -> successful deliveries
+    failed  map[string]uint64 // channel -> failed deliveries
+}
+
+// statusCodeError is a channel's rejection of a delivery, keeping the
+// response code for delivery records
+type statusCodeError struct {
+    name string
+    code int
+}
+
+func (e *statusCodeError) Error() string {
+    return fmt.Sprintf("%s returned status %d", e.name, e.code)
+}
+
+// urlPattern finds URLs in error text, such as the request URL a *url.Error
+// quotes
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"]+`)
+
+// stripURLs cuts URLs in error text down to their scheme and host, as
+// webhook paths and bot URLs carry tokens
+func stripURLs(text string) string {
+    return urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+        parsed, err := url.Parse(match)
+        if err != nil || parsed.Host == "" {
+            return "[url]"
+        }
+        return parsed.Scheme + "://" + parsed.Host
+    })
+}
+
+// recordDelivery notes a delivery outcome; err is nil on success
+func (m *Monitor) recordDelivery(event AlertEvent, channel string, err error) {
+    record := deliveryRecord{
+        Time:    time.Now(),
+        Service: event.Service.Name,
+        Kind:    event.Kind,
+        Channel: channel,
+        Success: err == nil,
+    }
+    if err != nil {
+        record.Error = stripURLs(err.Error())
+        var codeErr *statusCodeError
+        if errors.As(err, &codeErr) {
+            record.StatusCode = codeErr.code
+        }
+    }
+
+    deliveries := &m.deliveries
+    deliveries.mutex.Lock()
+    defer deliveries.mutex.Unlock()
+    if deliveries.sent == nil {
+        deliveries.sent = make(map[string]uint64)
+        deliveries.failed = make(map[string]uint64)
+    }
+    if err != nil {
+        deliveries.failed[channel]++
+    } else {
+        deliveries.sent[channel]++
+    }
+    deliveries.records = append(deliveries.records, record)
+    if len(deliveries.records) > maxDeliveryRecords {
+        deliveries.records = deliveries.records[len(deliveries.records)-maxDeliveryRecords:]
+    }
+}
+
+// handleDeliveries lists recent delivery outcomes, newest first. ?failed=true
+// keeps only failures and ?channel= a single channel.
+func (m *Monitor) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+    channel := r.URL.Query().Get("channel")
+    failedOnly := r.URL.Query().Get("failed") == "true"
+
+    m.deliveries.mutex.Lock()
+    defer m.deliveries.mutex.Unlock()
+
+    result := make([]deliveryRecord, 0, len(m.deliveries.records))
+    for i := len(m.deliveries.records) - 1; i >= 0; i-- {
+        record := m.deliveries.records[i]
+        if channel != "" && record.Channel != channel {
+            continue
+        }
+        if failedOnly && record.Success {
+            continue
+        }
+        result = append(result, record)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(result)
+}
+
+// writeDeliveryMetrics writes the per-channel delivery counters
+func (m *Monitor) writeDeliveryMetrics(w http.ResponseWriter) {
+    m.deliveries.mutex.Lock()
+    defer m.deliveries.mutex.Unlock()
+
+    channels := make([]string, 0, len(m.deliveries.sent)+len(m.deliveries.failed))
+    seen := make(map[string]bool)
+    for _, counts := range []map[string]uint64{m.deliveries.sent, m.deliveries.failed} {
+        for channel := range counts {
+            if !seen[channel] {
+                seen[channel] = true
+                channels = append(channels, channel)
+            }
+        }
+    }
+    if len(channels) == 0 {
+        return
+    }
+    sort.Strings(channels)
+
+    fmt.Fprintln(w, "# HELP monitor_alert_deliveries_total Alert deliveries by channel and result, after retries.")
+    fmt.Fprintln(w, "# TYPE monitor_alert_deliveries_total counter")
+    for _, channel := range channels {
+        fmt.Fprintf(w, "monitor_alert_deliveries_total{channel=%q,result=\"success\"} %d\n", channel, m.deliveries.sent[channel])
+        fmt.Fprintf(w, "monitor_alert_deliveries_total{channel=%q,result=\"failure\"} %d\n", channel, m.deliveries.failed[channel])
+    }
+}
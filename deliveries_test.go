@@ -0,0 +1,127 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestDeliveryRecords(t *testing.T) {
+    tests := []struct {
+        name        string
+        code        int // Slack's response, 0 for a webhook that refuses connections
+        wantSuccess bool
+        wantCode    int
+        wantError   string // substring of the recorded error
+    }{
+        {"delivered", http.StatusOK, true, 0, ""},
+        {"slack rejects the alert", http.StatusInternalServerError, false, http.StatusInternalServerError, "slack returned status 500"},
+        {"webhook unreachable", 0, false, 0, "connection refused"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                w.WriteHeader(tt.code)
+            }))
+            defer slack.Close()
+            webhookURL := slack.URL + "/services/T000/B000/secret-token"
+            if tt.code == 0 {
+                slack.Close()
+            }
+
+            backend := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", backend.URL)
+            m, err := NewMonitorFromConfig(MonitorConfig{
+                Services: []ServiceConfig{service},
+                APIToken: testAPIToken,
+                Alerts: AlertConfig{
+                    Slack:         SlackConfig{WebhookURL: webhookURL},
+                    Routing:       map[string][]string{SeverityWarning: {ChannelSlack}},
+                    DeliveryRetry: map[string]RetryPolicy{ChannelSlack: {Attempts: 1}},
+                },
+            })
+            if err != nil {
+                t.Fatalf("NewMonitorFromConfig: %v", err)
+            }
+            defer m.cancel()
+
+            checkAndFlush(m, service)
+
+            recorder := apiRequest(m, http.MethodGet, "/alerts/deliveries", "", true)
+            if recorder.Code != http.StatusOK {
+                t.Fatalf("GET /alerts/deliveries returned %d", recorder.Code)
+            }
+            var records []deliveryRecord
+            if err := json.Unmarshal(recorder.Body.Bytes(), &records); err != nil {
+                t.Fatalf("decoding deliveries: %v", err)
+            }
+            if len(records) != 1 {
+                t.Fatalf("records %+v, want one", records)
+            }
+            record := records[0]
+            if record.Service != "api" || record.Kind != EventAlert || record.Channel != ChannelSlack ||
+                record.Success != tt.wantSuccess || record.StatusCode != tt.wantCode || record.Time.IsZero() {
+                t.Errorf("record %+v, want a slack alert for api with success %v and status %d", record, tt.wantSuccess, tt.wantCode)
+            }
+            if !strings.Contains(record.Error, tt.wantError) || (tt.wantError == "") != (record.Error == "") {
+                t.Errorf("error %q, want %q", record.Error, tt.wantError)
+            }
+            if strings.Contains(record.Error, "secret-token") {
+                t.Errorf("error %q leaks the webhook path", record.Error)
+            }
+
+            failed := 0
+            if !tt.wantSuccess {
+                failed = 1
+            }
+            metrics := httptest.NewRecorder()
+            m.handleMetrics(metrics, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+            for _, line := range []string{
+                fmt.Sprintf(`monitor_alert_deliveries_total{channel="slack",result="success"} %d`, 1-failed),
+                fmt.Sprintf(`monitor_alert_deliveries_total{channel="slack",result="failure"} %d`, failed),
+            } {
+                if !strings.Contains(metrics.Body.String(), line+"\n") {
+                    t.Errorf("metrics missing %q:\n%s", line, metrics.Body.String())
+                }
+            }
+        })
+    }
+}
+
+func TestDeliveriesFilter(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{APIToken: testAPIToken})
+    alert := AlertEvent{Kind: EventAlert, Service: ServiceConfig{Name: "api"}}
+    m.recordDelivery(alert, ChannelSlack, nil)
+    m.recordDelivery(alert, ChannelSlack, &statusCodeError{name: "slack", code: http.StatusInternalServerError})
+    m.recordDelivery(alert, ChannelPagerDuty, nil)
+
+    tests := []struct {
+        query string
+        want  []string // channel and success of each record, newest first
+    }{
+        {"", []string{"pagerduty true", "slack false", "slack true"}},
+        {"?failed=true", []string{"slack false"}},
+        {"?channel=slack", []string{"slack false", "slack true"}},
+        {"?channel=pagerduty&failed=true", []string{}},
+    }
+    for _, tt := range tests {
+        recorder := apiRequest(m, http.MethodGet, "/alerts/deliveries"+tt.query, "", true)
+        var records []deliveryRecord
+        json.Unmarshal(recorder.Body.Bytes(), &records)
+        got := make([]string, len(records))
+        for i, record := range records {
+            got[i] = fmt.Sprintf("%s %v", record.Channel, record.Success)
+        }
+        if !equalStrings(got, tt.want) {
+            t.Errorf("%q listed %v, want %v", tt.query, got, tt.want)
+        }
+    }
+
+    if recorder := apiRequest(m, http.MethodGet, "/alerts/deliveries", "", false); recorder.Code != http.StatusUnauthorized {
+        t.Errorf("unauthenticated GET /alerts/deliveries returned %d, want %d", recorder.Code, http.StatusUnauthorized)
+    }
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "net"
+    "net/url"
+    "slices"
+    "sort"
+    "time"
+)
+
+// dnsLookupTimeout bounds each re-resolution of a watched service's host
+const dnsLookupTimeout = 5 * time.Second
+
+// watchesDNS reports whether the service's resolved addresses are tracked
+func watchesDNS(service ServiceConfig) bool {
+    return service.DNSCacheTTL > 0 || service.AlertOnDNSChange
+}
+
+// lookupHost resolves a host with the configured resolver, sorted so
+// answers can be compared between lookups
+func (m *Monitor) lookupHost(ctx context.Context, host string) ([]string, error) {
+    resolver := m.resolver
+    if resolver == nil {
+        resolver = net.DefaultResolver
+    }
+    addrs, err := resolver.LookupHost(ctx, host)
+    if err != nil {
+        return nil, err
+    }
+    sort.Strings(addrs)
+    return addrs, nil
+}
+
+// trackDNS re-resolves the service's host once its cached answer is older
+// than dns_cache_ttl and records the addresses checks connect to until then.
+// A changed set, e.g. after a DNS-based failover, is logged and optionally
+// alerted on at info severity.
+func (m *Monitor) trackDNS(service ServiceConfig) {
+    if !watchesDNS(service) || service.ConnectAddr != "" {
+        return
+    }
+    parsed, err := url.Parse(service.URL)
+    if err != nil || parsed.Hostname() == "" || net.ParseIP(parsed.Hostname()) != nil {
+        return
+    }
+    host := parsed.Hostname()
+
+    ttl := time.Duration(service.DNSCacheTTL) * time.Second
+    m.statusMutex.RLock()
+    status, ok := m.serviceStatus[service.Name]
+    fresh := ok && time.Since(status.DNSResolvedAt) < ttl
+    m.statusMutex.RUnlock()
+    if !ok || fresh {
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(m.ctx, dnsLookupTimeout)
+    defer cancel()
+    addrs, err := m.lookupHost(ctx, host)
+    if err != nil {
+        // The check itself reports hosts that stop resolving
+        m.logger.Printf("dns:"+service.Name, "Error resolving %s for %s: %v", host, service.Name, err)
+        return
+    }
+
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    status, ok = m.serviceStatus[service.Name]
+    if !ok {
+        return
+    }
+    previous := status.ResolvedIPs
+    status.ResolvedIPs = addrs
+    status.DNSResolvedAt = time.Now()
+    if len(previous) == 0 || slices.Equal(previous, addrs) {
+        return
+    }
+
+    status.DNSChangedAt = status.DNSResolvedAt
+    log.Printf("DNS for %s (%s) changed from %v to %v", service.Name, host, previous, addrs)
+    if service.AlertOnDNSChange {
+        msg := fmt.Sprintf("DNS for %s changed from %v to %v", host, previous, addrs)
+        alertConfig := infoAlertConfig(service)
+        m.dispatch(service.Name, func() { m.sendAlerts(alertConfig, msg) })
+    }
+}
+
+// cachedAddrs returns the service's tracked addresses while they are within
+// dns_cache_ttl, nil once they have lapsed
+func (m *Monitor) cachedAddrs(service ServiceConfig) []string {
+    ttl := time.Duration(service.DNSCacheTTL) * time.Second
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+    status, ok := m.serviceStatus[service.Name]
+    if !ok || time.Since(status.DNSResolvedAt) >= ttl {
+        return nil
+    }
+    return append([]string(nil), status.ResolvedIPs...)
+}
+
+// dialOrder filters and orders cached addresses for the service's
+// address_family
+func dialOrder(family string, addrs []string) []string {
+    var ipv4, ipv6 []string
+    for _, addr := range addrs {
+        if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+            ipv6 = append(ipv6, addr)
+        } else {
+            ipv4 = append(ipv4, addr)
+        }
+    }
+    switch family {
+    case AddressFamilyIPv4:
+        return ipv4
+    case AddressFamilyIPv6:
+        return ipv6
+    case AddressFamilyPreferIPv6:
+        return append(ipv6, ipv4...)
+    }
+    return addrs
+}
+
+// cachedDNSDial connects to the service's host at the addresses trackDNS
+// resolved while they are within dns_cache_ttl, trying each in turn, so
+// checks reach the addresses that are tracked and alerted on. Other hosts,
+// such as redirect targets, and lapsed answers are dialed as usual.
+func (m *Monitor) cachedDNSDial(service ServiceConfig, dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    target := dialTarget(service.URL)
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        addrs := dialOrder(service.AddressFamily, m.cachedAddrs(service))
+        if addr != target || len(addrs) == 0 {
+            return dial(ctx, network, addr)
+        }
+        _, port, _ := net.SplitHostPort(addr)
+        var err error
+        for _, ip := range addrs {
+            var conn net.Conn
+            if conn, err = dial(ctx, network, net.JoinHostPort(ip, port)); err == nil || ctx.Err() != nil {
+                return conn, err
+            }
+        }
+        return nil, err
+    }
+}
+
+// infoAlertConfig routes a notice about a service at info severity
+func infoAlertConfig(service ServiceConfig) ServiceConfig {
+    service.Severity = SeverityInfo
+    service.PagerDutySeverity = ""
+    return service
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+    "net"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+// newDNSWatchMonitor resolves through a stub answering api.monitor.test
+// with 127.0.0.1
+func newDNSWatchMonitor(t *testing.T, service ServiceConfig) (*Monitor, *recordingSender, *stubDNS) {
+    t.Helper()
+    dns := newStubDNS(t)
+    dns.set("api.monitor.test", "127.0.0.1")
+    m, sender := newTestMonitor(t, MonitorConfig{
+        Services: []ServiceConfig{service},
+        Resolver: &ResolverConfig{Server: dns.addr},
+    })
+    return m, sender, dns
+}
+
+// expireDNS ages the service's cached answer past any dns_cache_ttl
+func expireDNS(m *Monitor, name string) {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    m.serviceStatus[name].DNSResolvedAt = time.Time{}
+}
+
+func TestDNSChange(t *testing.T) {
+    tests := []struct {
+        name          string
+        alertOnChange bool
+        ttl           int
+        wantKinds     []string
+    }{
+        {"alerted", true, 0, []string{EventAlert}},
+        {"recorded without an alert", false, 60, []string{}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", "http://api.monitor.test/health")
+            service.AlertOnDNSChange, service.DNSCacheTTL = tt.alertOnChange, tt.ttl
+            m, sender, dns := newDNSWatchMonitor(t, service)
+            resolved := func() ([]string, time.Time) {
+                m.statusMutex.RLock()
+                defer m.statusMutex.RUnlock()
+                status := m.serviceStatus["api"]
+                return status.ResolvedIPs, status.DNSChangedAt
+            }
+
+            m.trackDNS(service)
+            expireDNS(m, "api")
+            m.trackDNS(service)
+            m.alertWG.Wait()
+            if ips, changed := resolved(); !equalStrings(ips, []string{"127.0.0.1"}) || !changed.IsZero() {
+                t.Fatalf("resolved %v, changed at %v; want 127.0.0.1 unchanged", ips, changed)
+            }
+            if kinds := sender.kinds(); len(kinds) != 0 {
+                t.Fatalf("first lookups delivered %v", kinds)
+            }
+
+            // Failover adds a second address
+            dns.set("api.monitor.test", "127.0.0.2", "127.0.0.1")
+            expireDNS(m, "api")
+            m.trackDNS(service)
+            m.alertWG.Wait()
+
+            if ips, changed := resolved(); !equalStrings(ips, []string{"127.0.0.1", "127.0.0.2"}) || changed.IsZero() {
+                t.Errorf("resolved %v, changed at %v; want the new sorted addresses and a change time", ips, changed)
+            }
+            if kinds := sender.kinds(); !equalStrings(kinds, tt.wantKinds) {
+                t.Fatalf("delivered %v, want %v", kinds, tt.wantKinds)
+            }
+            if tt.alertOnChange {
+                event := sender.events[0]
+                if event.Severity != SeverityInfo || !strings.Contains(event.Message, "changed from [127.0.0.1] to [127.0.0.1 127.0.0.2]") {
+                    t.Errorf("alert %s %q, want an info notice of the change", event.Severity, event.Message)
+                }
+            }
+
+            m.statusMutex.RLock()
+            entry := m.statusEntry("api", m.serviceStatus["api"])
+            m.statusMutex.RUnlock()
+            if _, ok := entry["resolved_ips"]; !ok {
+                t.Errorf("status entry %v lacks resolved_ips", entry)
+            }
+            if _, ok := entry["dns_changed_at"]; !ok {
+                t.Errorf("status entry %v lacks dns_changed_at", entry)
+            }
+        })
+    }
+}
+
+func TestDNSCacheTTL(t *testing.T) {
+    service := testService("api", "http://api.monitor.test/health")
+    service.DNSCacheTTL = 60
+    m, _, dns := newDNSWatchMonitor(t, service)
+
+    m.trackDNS(service)
+    queries := dns.queries.Load()
+    if queries == 0 {
+        t.Fatal("first check did not resolve the host")
+    }
+    m.trackDNS(service)
+    if got := dns.queries.Load(); got != queries {
+        t.Errorf("cached answer re-resolved: %d queries, want %d", got, queries)
+    }
+    expireDNS(m, "api")
+    m.trackDNS(service)
+    if got := dns.queries.Load(); got <= queries {
+        t.Errorf("expired answer not re-resolved: %d queries", got)
+    }
+}
+
+func TestDNSCacheDial(t *testing.T) {
+    backend := newStatusServer(t, http.StatusOK)
+    _, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+    service := testService("api", "http://api.monitor.test:"+port+"/health")
+    service.DNSCacheTTL, service.DisableKeepAlive = 60, true
+    m, _, dns := newDNSWatchMonitor(t, service)
+    state := func() ServiceState {
+        m.statusMutex.RLock()
+        defer m.statusMutex.RUnlock()
+        return m.serviceStatus["api"].State
+    }
+
+    checkAndFlush(m, service)
+    queries := dns.queries.Load()
+    if state() != StateUp {
+        t.Fatalf("state %s, want up through the resolved address", state())
+    }
+
+    // Nothing listens on the new address, but the cached answer is still
+    // within its TTL, so checks keep connecting to the old one without a lookup
+    dns.set("api.monitor.test", "127.0.0.2")
+    checkAndFlush(m, service)
+    if state() != StateUp {
+        t.Errorf("state %s within the TTL, want up through the cached address", state())
+    }
+    if got := dns.queries.Load(); got != queries {
+        t.Errorf("%d DNS queries within the TTL, want %d", got, queries)
+    }
+
+    expireDNS(m, "api")
+    checkAndFlush(m, service)
+    if state() != StateDown {
+        t.Errorf("state %s after the TTL, want down at the new address", state())
+    }
+}
+
+func TestDialOrder(t *testing.T) {
+    addrs := []string{"192.0.2.1", "2001:db8::1", "192.0.2.2"}
+    tests := []struct {
+        family string
+        want   []string
+    }{
+        {"", addrs},
+        {AddressFamilyIPv4, []string{"192.0.2.1", "192.0.2.2"}},
+        {AddressFamilyIPv6, []string{"2001:db8::1"}},
+        {AddressFamilyPreferIPv6, []string{"2001:db8::1", "192.0.2.1", "192.0.2.2"}},
+    }
+    for _, tt := range tests {
+        if got := dialOrder(tt.family, addrs); !equalStrings(got, tt.want) {
+            t.Errorf("dialOrder(%q) = %v, want %v", tt.family, got, tt.want)
+        }
+    }
+}
+
+func TestDNSWatchSkipped(t *testing.T) {
+    tests := []struct {
+        name    string
+        service func(ServiceConfig) ServiceConfig
+    }{
+        {"not watched", func(s ServiceConfig) ServiceConfig { return s }},
+        {"connect_addr set", func(s ServiceConfig) ServiceConfig {
+            s.AlertOnDNSChange, s.ConnectAddr = true, "127.0.0.1:80"
+            return s
+        }},
+        {"IP literal", func(s ServiceConfig) ServiceConfig {
+            s.AlertOnDNSChange, s.URL = true, "http://127.0.0.1/health"
+            return s
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := tt.service(testService("api", "http://api.monitor.test/health"))
+            m, _, dns := newDNSWatchMonitor(t, service)
+
+            m.trackDNS(service)
+            if got := dns.queries.Load(); got != 0 {
+                t.Errorf("%d DNS queries, want none", got)
+            }
+        })
+    }
+}
+
+func TestInvalidDNSCacheTTL(t *testing.T) {
+    service := testService("api", "http://api.monitor.test/health")
+    service.DNSCacheTTL = -1
+    if err := validateServiceConfig(service); err == nil {
+        t.Error("validateServiceConfig accepted a negative dns_cache_ttl")
+    }
+}
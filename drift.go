@@ -0,0 +1,44 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "time"
+)
+
+// latencyDrift returns the service's pXX latency over the recent history
+// and how many percent it is above baseline_latency_ms
+func latencyDrift(service ServiceConfig, history *checkHistory) (time.Duration, float64, bool) {
+    if service.BaselineLatencyMs <= 0 {
+        return 0, 0, false
+    }
+    latency, ok := history.latencyPercentile(sloPercentile(service))
+    if !ok {
+        return 0, 0, false
+    }
+    baseline := time.Duration(service.BaselineLatencyMs) * time.Millisecond
+    return latency, 100 * float64(latency-baseline) / float64(baseline), true
+}
+
+// checkLatencyDrift sends a degradation alert when the latency drifts more
+// than drift_percent above the baseline, and clears once it is back within
+// it. It must be called with statusMutex held.
+func (m *Monitor) checkLatencyDrift(service ServiceConfig, status *ServiceStatus) {
+    latency, drift, ok := latencyDrift(service, status.History)
+    drifting := ok && drift > service.DriftPercent
+    if drifting == status.LatencyDrift {
+        return
+    }
+    status.LatencyDrift = drifting
+
+    baseline := time.Duration(service.BaselineLatencyMs) * time.Millisecond
+    if !drifting {
+        log.Printf("Latency of %s is back within %g%% of its %s baseline", service.Name, service.DriftPercent, baseline)
+        return
+    }
+
+    msg := fmt.Sprintf("p%g latency %s is %.0f%% above the %s baseline (allowed %g%%)",
+        sloPercentile(service), latency, drift, baseline, service.DriftPercent)
+    alertConfig := degradedAlertConfig(service)
+    m.dispatch(service.Name, func() { m.sendAlerts(alertConfig, msg) })
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLatencyDriftAlert(t *testing.T) {
+    service := testService("api", "https://api.example.com/")
+    service.BaselineLatencyMs, service.DriftPercent = 100, 25
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    // feed records checks at the given latency, evaluating the drift after each
+    feed := func(latency time.Duration, checks int) {
+        m.statusMutex.Lock()
+        status := m.serviceStatus["api"]
+        for i := 0; i < checks; i++ {
+            record := CheckRecord{Time: time.Now(), Up: true, Latency: latency}
+            status.History.add(record)
+            m.checkLatencyDrift(service, status)
+        }
+        m.statusMutex.Unlock()
+        m.alertWG.Wait()
+    }
+
+    steps := []struct {
+        name    string
+        latency time.Duration
+        checks  int
+        want    int // drift alerts sent so far
+    }{
+        {"at the baseline", 100 * time.Millisecond, 20, 0},
+        {"within the allowed drift", 120 * time.Millisecond, 20, 0},
+        {"drifted past the allowed percentage", 200 * time.Millisecond, 20, 1},
+        {"still drifting alerts once", 210 * time.Millisecond, 20, 1},
+        {"back within the baseline", 100 * time.Millisecond, 200, 1},
+        {"drifting again", 300 * time.Millisecond, 200, 2},
+    }
+    for _, step := range steps {
+        feed(step.latency, step.checks)
+        if got := len(sender.kinds()); got != step.want {
+            t.Fatalf("%s: %d drift alerts, want %d", step.name, got, step.want)
+        }
+    }
+
+    sender.mutex.Lock()
+    defer sender.mutex.Unlock()
+    event := sender.events[0]
+    if event.Severity != SeverityWarning || !strings.Contains(event.Message, "p95 latency 200ms is 100% above the 100ms baseline (allowed 25%)") {
+        t.Errorf("drift alert at %s: %q", event.Severity, event.Message)
+    }
+}
+
+func TestLatencyDrift(t *testing.T) {
+    tests := []struct {
+        name      string
+        baseline  int
+        latencies []time.Duration
+        wantDrift float64
+        wantOK    bool
+    }{
+        {"no baseline", 0, []time.Duration{time.Second}, 0, false},
+        {"no history", 100, nil, 0, false},
+        {"p95 above the baseline", 100, []time.Duration{150 * time.Millisecond}, 50, true},
+        {"below the baseline", 200, []time.Duration{100 * time.Millisecond}, -50, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{testService("api", "https://api.example.com/")}})
+            history := m.serviceStatus["api"].History
+            for _, latency := range tt.latencies {
+                history.add(CheckRecord{Time: time.Now(), Up: true, Latency: latency})
+            }
+            _, drift, ok := latencyDrift(ServiceConfig{BaselineLatencyMs: tt.baseline}, history)
+            if ok != tt.wantOK || drift != tt.wantDrift {
+                t.Errorf("latencyDrift() = %v, %v; want %v, %v", drift, ok, tt.wantDrift, tt.wantOK)
+            }
+        })
+    }
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+    "sort"
+    "time"
+)
+
+const (
+    // maxDistinctErrors bounds the errors tracked per service; the least
+    // recently seen is dropped to make room
+    maxDistinctErrors = 10
+    // errorFrequencyWindow is how long an error counts after it was last seen
+    errorFrequencyWindow = time.Hour
+)
+
+type errorCount struct {
+    Error    string    `json:"error"`
+    Count    int       `json:"count"`
+    Share    float64   `json:"share"` // fraction of the recent failures
+    LastSeen time.Time `json:"last_seen"`
+}
+
+// errorFrequency counts the distinct errors a service failed with recently
+type errorFrequency struct {
+    entries []errorCount
+}
+
+func (f *errorFrequency) add(errMsg string, now time.Time) {
+    f.expire(now)
+    for i := range f.entries {
+        if f.entries[i].Error == errMsg {
+            f.entries[i].Count++
+            f.entries[i].LastSeen = now
+            return
+        }
+    }
+
+    if len(f.entries) >= maxDistinctErrors {
+        oldest := 0
+        for i, entry := range f.entries {
+            if entry.LastSeen.Before(f.entries[oldest].LastSeen) {
+                oldest = i
+            }
+        }
+        f.entries = append(f.entries[:oldest], f.entries[oldest+1:]...)
+    }
+    f.entries = append(f.entries, errorCount{Error: errMsg, Count: 1, LastSeen: now})
+}
+
+func (f *errorFrequency) expire(now time.Time) {
+    kept := f.entries[:0]
+    for _, entry := range f.entries {
+        if now.Sub(entry.LastSeen) < errorFrequencyWindow {
+            kept = append(kept, entry)
+        }
+    }
+    f.entries = kept
+}
+
+// breakdown returns the recent errors, most frequent first, with each
+// error's share of the total
+func (f errorFrequency) breakdown(now time.Time) []errorCount {
+    var total int
+    result := make([]errorCount, 0, len(f.entries))
+    for _, entry := range f.entries {
+        if now.Sub(entry.LastSeen) < errorFrequencyWindow {
+            result = append(result, entry)
+            total += entry.Count
+        }
+    }
+    for i := range result {
+        result[i].Share = float64(result[i].Count) / float64(total)
+    }
+    sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+    return result
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestErrorFrequency(t *testing.T) {
+    now := time.Now()
+
+    type seen struct {
+        err string
+        at  time.Duration // before now
+    }
+    tests := []struct {
+        name   string
+        errors []seen
+        want   map[string]int // error -> count
+        first  string         // most frequent
+    }{
+        {
+            name:   "mixed errors counted",
+            errors: []seen{{"timeout", 4 * time.Minute}, {"503", 3 * time.Minute}, {"timeout", 2 * time.Minute}, {"timeout", time.Minute}},
+            want:   map[string]int{"timeout": 3, "503": 1},
+            first:  "timeout",
+        },
+        {
+            name:   "errors not seen within the window dropped",
+            errors: []seen{{"refused", 2 * time.Hour}, {"503", time.Minute}},
+            want:   map[string]int{"503": 1},
+            first:  "503",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var frequency errorFrequency
+            for _, e := range tt.errors {
+                frequency.add(e.err, now.Add(-e.at))
+            }
+            got := frequency.breakdown(now)
+            if len(got) != len(tt.want) || got[0].Error != tt.first {
+                t.Fatalf("breakdown %+v, want %v with %s first", got, tt.want, tt.first)
+            }
+            var total int
+            for _, count := range tt.want {
+                total += count
+            }
+            for _, entry := range got {
+                if entry.Count != tt.want[entry.Error] || entry.Share != float64(entry.Count)/float64(total) {
+                    t.Errorf("%s counted %d with share %v, want %d of %d", entry.Error, entry.Count, entry.Share, tt.want[entry.Error], total)
+                }
+            }
+        })
+    }
+}
+
+func TestErrorFrequencyBounded(t *testing.T) {
+    now := time.Now()
+    var frequency errorFrequency
+    for i := 0; i <= maxDistinctErrors; i++ {
+        frequency.add(fmt.Sprintf("error %d", i), now.Add(time.Duration(i)*time.Second))
+    }
+
+    got := frequency.breakdown(now.Add(time.Minute))
+    if len(got) != maxDistinctErrors {
+        t.Fatalf("%d distinct errors tracked, want %d", len(got), maxDistinctErrors)
+    }
+    for _, entry := range got {
+        if entry.Error == "error 0" {
+            t.Error("least recently seen error was kept")
+        }
+    }
+}
+
+func TestRecentErrorsInStatus(t *testing.T) {
+    server := newStatusServer(t, http.StatusServiceUnavailable)
+    service := testService("api", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    for _, code := range []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusBadGateway} {
+        server.code.Store(int32(code))
+        checkAndFlush(m, service)
+    }
+
+    var health map[string]struct {
+        RecentErrors []errorCount `json:"recent_errors"`
+    }
+    if err := json.Unmarshal(apiRequest(m, http.MethodGet, "/health", "", false).Body.Bytes(), &health); err != nil {
+        t.Fatal(err)
+    }
+    got := health["api"].RecentErrors
+    if len(got) != 2 {
+        t.Fatalf("recent_errors %+v, want two distinct errors", got)
+    }
+    for _, entry := range got {
+        if entry.Count != 2 || entry.Share != 0.5 {
+            t.Errorf("%q counted %d with share %v, want 2 and 0.5", entry.Error, entry.Count, entry.Share)
+        }
+    }
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "sort"
+    "strings"
+    "time"
+)
+
+// fleetServiceName identifies fleet-level alerts to channels and PagerDuty.
+// No service or group may use it while fleet_health_alert is configured.
+const fleetServiceName = "fleet"
+
+// fleetSweepInterval is how often fleet health is re-evaluated between
+// check results, so the window can elapse while checks are quiet
+const fleetSweepInterval = 10 * time.Second
+
+// FleetHealthAlert raises a single alert when a large share of all services
+// is down at once, which usually points at shared infrastructure
+type FleetHealthAlert struct {
+    Threshold float64  `json:"threshold"` // percentage of checked services down, e.g. 30
+    Window    int      `json:"window"`    // in seconds the threshold must be held before alerting
+    Channels  []string `json:"channels"`
+}
+
+type fleetState struct {
+    overSince time.Time // when the down share first reached the threshold
+    alertedAt time.Time // zero unless a fleet alert is open
+}
+
+func validateFleetHealthAlert(alert *FleetHealthAlert) error {
+    if alert == nil {
+        return nil
+    }
+    if alert.Threshold <= 0 || alert.Threshold > 100 {
+        return fmt.Errorf("fleet_health_alert: threshold must be between 0 and 100")
+    }
+    if alert.Window < 0 {
+        return fmt.Errorf("fleet_health_alert: window must not be negative")
+    }
+    if len(alert.Channels) == 0 {
+        return fmt.Errorf("fleet_health_alert: at least one channel is required")
+    }
+    for _, channel := range alert.Channels {
+        if !knownChannels[channel] {
+            return fmt.Errorf("fleet_health_alert: unknown channel %q", channel)
+        }
+    }
+    return nil
+}
+
+// validateFleetServiceName keeps services and groups from taking the name
+// fleet alerts are raised under
+func validateFleetServiceName(alert *FleetHealthAlert, service ServiceConfig) error {
+    if alert == nil {
+        return nil
+    }
+    if service.Name == fleetServiceName {
+        return fmt.Errorf("service name %q is reserved for fleet_health_alert", fleetServiceName)
+    }
+    if service.GroupKey == fleetServiceName {
+        return fmt.Errorf("service %s: group_key %q is reserved for fleet_health_alert", service.Name, fleetServiceName)
+    }
+    return nil
+}
+
+// startFleetHealth re-evaluates fleet health on a timer
+func (m *Monitor) startFleetHealth() {
+    if m.config.FleetHealthAlert == nil {
+        return
+    }
+    m.supervise(func() {
+        ticker := time.NewTicker(fleetSweepInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-m.ctx.Done():
+                return
+            case now := <-ticker.C:
+                m.statusMutex.Lock()
+                m.evaluateFleetHealth(now)
+                m.statusMutex.Unlock()
+            }
+        }
+    })
+}
+
+// evaluateFleetHealth alerts once the share of down services has stayed at
+// the threshold for the window, and recovers once it drops below. Services
+// not yet checked, paused outside business hours or whose alerts are held
+// by the startup grace period or their warmup are not counted. It must be
+// called with statusMutex held.
+func (m *Monitor) evaluateFleetHealth(now time.Time) {
+    config := m.config.FleetHealthAlert
+    if config == nil {
+        return
+    }
+
+    var total int
+    var down []string
+    for name, status := range m.serviceStatus {
+        if status.State == StateUnknown || status.Paused || m.alertsHeld(m.findService(name), status) {
+            continue
+        }
+        total++
+        if status.State == StateDown {
+            down = append(down, name)
+        }
+    }
+    if total == 0 {
+        return
+    }
+    percent := 100 * float64(len(down)) / float64(total)
+
+    if percent < config.Threshold {
+        m.fleet.overSince = time.Time{}
+        if !m.fleet.alertedAt.IsZero() {
+            downtime := now.Sub(m.fleet.alertedAt)
+            m.fleet.alertedAt = time.Time{}
+            msg := fmt.Sprintf("✅ Fleet health has RECOVERED\n%d of %d services down\nDowntime: %s\nTime: %s",
+                len(down), total, downtime.Round(time.Second), m.formatTime(now))
+            m.dispatch(fleetServiceName, func() { m.sendFleetAlert(EventRecovery, msg, downtime) })
+        }
+        return
+    }
+
+    if m.fleet.overSince.IsZero() {
+        m.fleet.overSince = now
+    }
+    if !m.fleet.alertedAt.IsZero() || now.Sub(m.fleet.overSince) < time.Duration(config.Window)*time.Second {
+        return
+    }
+
+    m.fleet.alertedAt = now
+    sort.Strings(down)
+    msg := fmt.Sprintf("%.0f%% of services are down (%d of %d): %s",
+        percent, len(down), total, strings.Join(down, ", "))
+    m.dispatch(fleetServiceName, func() { m.sendFleetAlert(EventAlert, msg, 0) })
+}
+
+func (m *Monitor) sendFleetAlert(kind, message string, downtime time.Duration) {
+    if m.isSilenced(fleetServiceName) {
+        log.Printf("Fleet %s suppressed by active silence", kind)
+        return
+    }
+
+    service := ServiceConfig{Name: fleetServiceName, Severity: SeverityCritical}
+    m.recordAlert(kind, service, message)
+    event := m.newAlertEvent(kind, service, message)
+    event.Downtime = downtime
+    m.deliver(event, m.config.FleetHealthAlert.Channels)
+}
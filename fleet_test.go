@@ -0,0 +1,125 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestFleetHealthAlert(t *testing.T) {
+    tests := []struct {
+        name  string
+        grace int // startup_grace_period in seconds
+        want  []string
+    }{
+        {name: "pages once the window elapses", want: []string{EventAlert}},
+        {name: "held during the startup grace period", grace: 3600, want: []string{}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusInternalServerError)
+            services := []ServiceConfig{testService("a", backend.URL), testService("b", backend.URL)}
+            m, _ := newTestMonitor(t, MonitorConfig{
+                Services:           services,
+                StartupGracePeriod: tt.grace,
+                FleetHealthAlert:   &FleetHealthAlert{Threshold: 50, Window: 60, Channels: []string{ChannelSlack}},
+            })
+            fleetSender := &recordingSender{}
+            m.RegisterSender(ChannelSlack, fleetSender)
+
+            for _, service := range services {
+                checkAndFlush(m, service)
+            }
+            // The window elapses with no further check results
+            m.statusMutex.Lock()
+            m.evaluateFleetHealth(time.Now().Add(2 * time.Minute))
+            m.statusMutex.Unlock()
+            m.alertWG.Wait()
+
+            if got := fleetSender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestFleetServiceNameReserved(t *testing.T) {
+    fleet := &FleetHealthAlert{Threshold: 50, Channels: []string{ChannelSlack}}
+    tests := []struct {
+        service ServiceConfig
+        alert   *FleetHealthAlert
+        wantErr bool
+    }{
+        {ServiceConfig{Name: "fleet"}, fleet, true},
+        {ServiceConfig{Name: "api", GroupKey: "fleet"}, fleet, true},
+        {ServiceConfig{Name: "fleet"}, nil, false},
+        {ServiceConfig{Name: "api"}, fleet, false},
+    }
+
+    for _, tt := range tests {
+        if err := validateFleetServiceName(tt.alert, tt.service); (err != nil) != tt.wantErr {
+            t.Errorf("validateFleetServiceName(%+v) = %v, want error %v", tt.service, err, tt.wantErr)
+        }
+    }
+}
+
+func TestFleetHealthThreshold(t *testing.T) {
+    var backends []*statusServer
+    var services []ServiceConfig
+    for i := 0; i < 10; i++ {
+        backend := newStatusServer(t, http.StatusOK)
+        backends = append(backends, backend)
+        services = append(services, testService(fmt.Sprintf("svc%d", i), backend.URL))
+    }
+    m, _ := newTestMonitor(t, MonitorConfig{
+        Services:         services,
+        FleetHealthAlert: &FleetHealthAlert{Threshold: 30, Channels: []string{ChannelSlack}},
+    })
+    fleetSender := &recordingSender{}
+    m.RegisterSender(ChannelSlack, fleetSender)
+    for _, service := range services {
+        checkAndFlush(m, service)
+    }
+
+    // setDown sets the first n services down and checks every service
+    setDown := func(n int) {
+        for i, backend := range backends {
+            code := http.StatusOK
+            if i < n {
+                code = http.StatusInternalServerError
+            }
+            backend.code.Store(int32(code))
+        }
+        for _, service := range services {
+            checkAndFlush(m, service)
+        }
+    }
+
+    steps := []struct {
+        name string
+        down int
+        want []string // fleet events delivered so far
+    }{
+        {"below the threshold", 2, []string{}},
+        {"at the threshold", 3, []string{EventAlert}},
+        {"further services down", 5, []string{EventAlert}},
+        {"still over the threshold", 3, []string{EventAlert}},
+        {"fleet recovered", 2, []string{EventAlert, EventRecovery}},
+    }
+    for _, step := range steps {
+        setDown(step.down)
+        if got := fleetSender.kinds(); !equalStrings(got, step.want) {
+            t.Fatalf("%s: delivered %v, want %v", step.name, got, step.want)
+        }
+    }
+
+    fleetSender.mutex.Lock()
+    defer fleetSender.mutex.Unlock()
+    alert := fleetSender.events[0]
+    if alert.Service.Name != fleetServiceName || !strings.HasPrefix(alert.Message, "30% of services are down (3 of 10): svc0, svc1, svc2") {
+        t.Errorf("fleet alert for %s: %q", alert.Service.Name, alert.Message)
+    }
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// forceCheckCooldown is how long a force-checked service must wait before
+// it can be force-checked again
+const forceCheckCooldown = 30 * time.Second
+
+// forceCheckState records when each service was last force-checked
+type forceCheckState struct {
+    mutex sync.Mutex
+    last  map[string]time.Time
+}
+
+// claimForceCheck records a forced check of the services, or returns how
+// long until the most recently forced of them may be forced again
+func (m *Monitor) claimForceCheck(services []ServiceConfig) time.Duration {
+    m.forced.mutex.Lock()
+    defer m.forced.mutex.Unlock()
+
+    now := time.Now()
+    var wait time.Duration
+    for _, service := range services {
+        if remaining := forceCheckCooldown - now.Sub(m.forced.last[service.Name]); remaining > wait {
+            wait = remaining
+        }
+    }
+    if wait > 0 {
+        return wait
+    }
+    for _, service := range services {
+        m.forced.last[service.Name] = now
+    }
+    return 0
+}
+
+// handleForceCheck runs an immediate check of the requested services (all
+// of them when none are named) and returns their fresh statuses. A service
+// can be forced once per forceCheckCooldown.
+func (m *Monitor) handleForceCheck(w http.ResponseWriter, r *http.Request) {
+    var request struct {
+        Services []string `json:"services"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+        http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+        return
+    }
+
+    m.statusMutex.RLock()
+    var services []ServiceConfig
+    if len(request.Services) == 0 {
+        services = append(services, m.config.Services...)
+    } else {
+        for _, name := range request.Services {
+            index := m.serviceIndex(name)
+            if index < 0 {
+                m.statusMutex.RUnlock()
+                http.Error(w, fmt.Sprintf("service %q not found", name), http.StatusNotFound)
+                return
+            }
+            services = append(services, m.config.Services[index])
+        }
+    }
+    m.statusMutex.RUnlock()
+
+    if wait := m.claimForceCheck(services); wait > 0 {
+        w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+        http.Error(w, fmt.Sprintf("service was force-checked less than %s ago", forceCheckCooldown), http.StatusTooManyRequests)
+        return
+    }
+
+    var wg sync.WaitGroup
+    for _, service := range services {
+        wg.Add(1)
+        go func(s ServiceConfig) {
+            defer wg.Done()
+            m.runCheck(s)
+        }(service)
+    }
+    wg.Wait()
+
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    status := make(map[string]interface{})
+    for _, service := range services {
+        if s, ok := m.serviceStatus[service.Name]; ok {
+            status[service.Name] = m.statusEntry(service.Name, s)
+        }
+    }
+
+    json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+)
+
+func TestForceCheck(t *testing.T) {
+    // The monitor's own schedule is not running, so only the forced check
+    // can have found the service up
+    server := newStatusServer(t, http.StatusOK)
+    service := testService("api", server.URL)
+    idle := testService("web", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service, idle}, APIToken: testAPIToken})
+
+    resp := apiRequest(m, http.MethodPost, "/check", `{"services": ["api"]}`, true)
+    if resp.Code != http.StatusOK {
+        t.Fatalf("POST /check = %d: %s", resp.Code, resp.Body)
+    }
+    var statuses map[string]struct {
+        State      ServiceState `json:"state"`
+        StatusCode int          `json:"status_code"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+        t.Fatalf("decoding statuses: %v", err)
+    }
+    if got := statuses["api"]; got.State != StateUp || got.StatusCode != http.StatusOK {
+        t.Errorf("api returned as %+v, want up with 200", got)
+    }
+    if _, ok := statuses["web"]; ok {
+        t.Error("a service that was not requested was returned")
+    }
+}
+
+func TestForceCheckRequests(t *testing.T) {
+    tests := []struct {
+        name       string
+        first      string // body of an earlier force check, "" for none
+        body       string
+        token      bool
+        want       int
+        retryAfter bool
+    }{
+        {"every service", "", "", true, http.StatusOK, false},
+        {"unknown service", "", `{"services": ["db"]}`, true, http.StatusNotFound, false},
+        {"bad json", "", `{"services":`, true, http.StatusBadRequest, false},
+        {"no token", "", `{"services": ["api"]}`, false, http.StatusUnauthorized, false},
+        {"repeat within the cooldown", `{"services": ["api"]}`, `{"services": ["api"]}`, true, http.StatusTooManyRequests, true},
+        {"all after one was forced", `{"services": ["api"]}`, "", true, http.StatusTooManyRequests, true},
+        {"another service within the cooldown", `{"services": ["api"]}`, `{"services": ["web"]}`, true, http.StatusOK, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusOK)
+            services := []ServiceConfig{testService("api", server.URL), testService("web", server.URL)}
+            m, _ := newTestMonitor(t, MonitorConfig{Services: services, APIToken: testAPIToken})
+
+            if tt.first != "" {
+                if resp := apiRequest(m, http.MethodPost, "/check", tt.first, true); resp.Code != http.StatusOK {
+                    t.Fatalf("first POST /check = %d", resp.Code)
+                }
+            }
+            resp := apiRequest(m, http.MethodPost, "/check", tt.body, tt.token)
+            if resp.Code != tt.want {
+                t.Errorf("POST /check = %d, want %d: %s", resp.Code, tt.want, resp.Body)
+            }
+            if got := resp.Header().Get("Retry-After") != ""; got != tt.retryAfter {
+                t.Errorf("Retry-After set = %v, want %v", got, tt.retryAfter)
+            }
+        })
+    }
+}
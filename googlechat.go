@@ -0,0 +1,74 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+)
+
+const ChannelGoogleChat = "googlechat"
+
+// GoogleChatConfig posts alerts to a Google Chat space's incoming webhook
+type GoogleChatConfig struct {
+    WebhookURL     string `json:"webhook_url"`
+    WebhookURLFile string `json:"webhook_url_file"` // read into webhook_url, e.g. a mounted secret
+}
+
+// googleChatThreadKey groups a service's alerts and recovery in one thread
+func googleChatThreadKey(service ServiceConfig) string {
+    return "monitor-alert/" + service.Name
+}
+
+type googleChatSender struct{ m *Monitor }
+
+func (s googleChatSender) Send(ctx context.Context, event AlertEvent) error {
+    text := event.Message
+    if event.Kind == EventAlert {
+        text = fmt.Sprintf("🚨 *ALERT*: Service %s is DOWN!\nError: %s\nTime: %s",
+            event.Service.Name, event.Message, s.m.formatTime(event.Time))
+        for _, link := range serviceLinks(event.Service) {
+            text += fmt.Sprintf("\n<%s|%s>", link.url, link.label)
+        }
+    }
+    return s.m.postGoogleChatMessage(ctx, googleChatThreadKey(event.Service), text)
+}
+
+// postGoogleChatMessage posts text into the thread with threadKey, starting
+// the thread if it does not exist yet
+func (m *Monitor) postGoogleChatMessage(ctx context.Context, threadKey, text string) error {
+    webhook, err := url.Parse(m.config.Alerts.GoogleChat.WebhookURL)
+    if err != nil {
+        return fmt.Errorf("invalid google chat webhook URL: %v", err)
+    }
+    query := webhook.Query()
+    query.Set("messageReplyOption", "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD")
+    webhook.RawQuery = query.Encode()
+
+    payload, err := json.Marshal(map[string]interface{}{
+        "text":   text,
+        "thread": map[string]string{"threadKey": threadKey},
+    })
+    if err != nil {
+        return err
+    }
+
+    req, err := http.NewRequestWithContext(ctx, "POST", webhook.String(), bytes.NewBuffer(payload))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+    resp, err := m.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        return &statusCodeError{name: "google chat", code: resp.StatusCode}
+    }
+    return nil
+}
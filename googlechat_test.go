@@ -0,0 +1,90 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// googleChatMessage is a message posted to the webhook and its query
+type googleChatMessage struct {
+    Text   string `json:"text"`
+    Thread struct {
+        ThreadKey string `json:"threadKey"`
+    } `json:"thread"`
+    query url.Values
+}
+
+func TestGoogleChatAlerts(t *testing.T) {
+    var mutex sync.Mutex
+    var messages []googleChatMessage
+    webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var message googleChatMessage
+        if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        message.query = r.URL.Query()
+        mutex.Lock()
+        messages = append(messages, message)
+        mutex.Unlock()
+    }))
+    defer webhook.Close()
+
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    api, web := testService("api", backend.URL), testService("web", backend.URL)
+    api.RunbookURL = "https://runbooks.example.com/api"
+    m, err := NewMonitorFromConfig(MonitorConfig{
+        Services: []ServiceConfig{api, web},
+        Alerts: AlertConfig{
+            GoogleChat: GoogleChatConfig{WebhookURL: webhook.URL + "/v1/spaces/AAA/messages?key=k&token=t"},
+            Routing:    map[string][]string{SeverityWarning: {ChannelGoogleChat}},
+        },
+    })
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    defer m.cancel()
+
+    checkAndFlush(m, api)
+    checkAndFlush(m, web)
+    backend.code.Store(http.StatusOK)
+    checkAndFlush(m, api)
+
+    mutex.Lock()
+    defer mutex.Unlock()
+    if len(messages) != 3 {
+        t.Fatalf("%d messages posted, want api down, web down and api recovered", len(messages))
+    }
+    tests := []struct {
+        name       string
+        message    googleChatMessage
+        wantThread string
+        wantText   string
+    }{
+        {"down alert", messages[0], "monitor-alert/api", "🚨 *ALERT*: Service api is DOWN!\nError: "},
+        {"other service's alert", messages[1], "monitor-alert/web", "🚨 *ALERT*: Service web is DOWN!"},
+        {"recovery in the alert's thread", messages[2], "monitor-alert/api", "✅ Service api has RECOVERED"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if tt.message.Thread.ThreadKey != tt.wantThread {
+                t.Errorf("thread key %q, want %q", tt.message.Thread.ThreadKey, tt.wantThread)
+            }
+            if !strings.HasPrefix(tt.message.Text, tt.wantText) {
+                t.Errorf("text %q, want it to start %q", tt.message.Text, tt.wantText)
+            }
+            query := tt.message.query
+            if query.Get("messageReplyOption") != "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD" || query.Get("key") != "k" || query.Get("token") != "t" {
+                t.Errorf("query %v, want the webhook's key and token with messageReplyOption", query)
+            }
+        })
+    }
+    if !strings.Contains(messages[0].Text, "<https://runbooks.example.com/api|Runbook>") {
+        t.Errorf("alert text %q, want the runbook link", messages[0].Text)
+    }
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "sort"
+    "strings"
+    "time"
+)
+
+// alertGroup is the open incident of services sharing a group_key. It is
+// guarded by the monitor's status mutex.
+type alertGroup struct {
+    config   ServiceConfig     // alert config of the member that raised the incident to target, renamed to the key
+    down     map[string]string // members still down -> their error
+    affected []string          // every member that failed during the incident
+    since    time.Time
+    target   ServiceState      // state the down members call for a page at
+    paged    ServiceState      // state the incident was last paged at, empty until a page goes out
+    sending  bool              // a page is queued or being delivered
+}
+
+// groupAlert records a grouped service's confirmed failure at the state it
+// is alerting for. The first failing member pages for the members down by
+// the time the page is sent; later members join the incident silently
+// unless they raise it from degraded to down, which pages again. It must be
+// called with statusMutex held.
+func (m *Monitor) groupAlert(alertConfig ServiceConfig, state ServiceState, errMsg string) {
+    key := alertConfig.GroupKey
+    group, open := m.groups[key]
+    if !open {
+        group = &alertGroup{down: make(map[string]string), since: time.Now()}
+        m.groups[key] = group
+    }
+    if _, ok := group.down[alertConfig.Name]; !ok {
+        group.affected = append(group.affected, alertConfig.Name)
+    }
+    group.down[alertConfig.Name] = errMsg
+
+    if group.target == "" || (group.target == StateDegraded && state == StateDown) {
+        config := alertConfig
+        config.Name = key
+        // Members' upstreams are checked one by one when the page is sent
+        config.UpstreamHealthURL = ""
+        group.config = config
+        group.target = state
+    }
+    if group.sending || group.paged == group.target {
+        log.Printf("%s joined the open incident of group %s", alertConfig.Name, key)
+        return
+    }
+    group.sending = true
+    m.dispatch(key, func() { m.sendGroupAlert(key, group) })
+}
+
+// sendGroupAlert pages for the group's down members, leaving out those whose
+// own alerts are suppressed. When nothing is paged the members fall back to
+// the incident's previous state, so their next failed check tries again.
+func (m *Monitor) sendGroupAlert(key string, group *alertGroup) {
+    m.statusMutex.RLock()
+    config, state := group.config, group.target
+    members := make(map[string]ServiceConfig, len(group.down))
+    down := make(map[string]string, len(group.down))
+    for name, errMsg := range group.down {
+        members[name] = m.findService(name)
+        down[name] = errMsg
+    }
+    m.statusMutex.RUnlock()
+
+    for name, member := range members {
+        if reason, ok := m.alertsSuppressed(name); ok {
+            m.logger.Printf("suppressed:"+name, "Alert for %s in group %s suppressed by active %s", name, key, reason)
+            delete(down, name)
+        } else if err := m.upstreamDown(member); err != nil {
+            m.logger.Printf("suppressed:"+name, "Alert for %s in group %s suppressed, upstream %s is down: %v", name, key, member.UpstreamHealthURL, err)
+            delete(down, name)
+        }
+    }
+    delivered := len(down) > 0 && m.sendAlerts(config, groupMessage(down))
+
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    group.sending = false
+    if delivered {
+        group.paged = state
+    } else {
+        group.target = group.paged
+    }
+    if m.groups[key] != group {
+        // Resolved while the page was queued
+        return
+    }
+
+    for name := range members {
+        status, ok := m.serviceStatus[name]
+        if !ok {
+            continue
+        }
+        _, paged := down[name]
+        status.AlertSuppressed = !paged
+        if !delivered && status.AlertState == state {
+            status.AlertSent = group.paged != ""
+            status.AlertState = group.paged
+        }
+    }
+    if group.target != group.paged {
+        // A member escalated the incident while this page was being sent
+        group.sending = true
+        m.dispatch(key, func() { m.sendGroupAlert(key, group) })
+    }
+}
+
+// groupRecovered resolves the group's incident once its last down member
+// recovers. It must be called with statusMutex held.
+func (m *Monitor) groupRecovered(service ServiceConfig) {
+    group, ok := m.groups[service.GroupKey]
+    if !ok {
+        return
+    }
+    delete(group.down, service.Name)
+    if len(group.down) > 0 {
+        return
+    }
+
+    delete(m.groups, service.GroupKey)
+    downtime := time.Since(group.since)
+    members := strings.Join(group.affected, ", ")
+    m.dispatch(service.GroupKey, func() {
+        // Queued behind any page still being sent, so it knows if one went out
+        m.statusMutex.RLock()
+        paged := group.paged != ""
+        m.statusMutex.RUnlock()
+        if paged {
+            m.sendGroupRecovery(group.config, members, downtime)
+        }
+    })
+}
+
+// sendGroupRecovery is sendRecoveryAlert for a group, naming the affected
+// members while keeping the group's key for PagerDuty to resolve
+func (m *Monitor) sendGroupRecovery(config ServiceConfig, members string, downtime time.Duration) {
+    if reason, ok := m.alertsSuppressed(config.Name); ok {
+        log.Printf("Recovery notification for group %s suppressed by active %s", config.Name, reason)
+        return
+    }
+
+    recoveryMsg := fmt.Sprintf("✅ Service group %s has RECOVERED\nMembers: %s\nDowntime: %s\nTime: %s",
+        config.Name, members, downtime.Round(time.Second), m.formatTime(time.Now()))
+    m.recordAlert(EventRecovery, config, recoveryMsg)
+
+    event := m.newAlertEvent(EventRecovery, config, recoveryMsg)
+    event.Downtime = downtime
+    m.deliver(event, m.alertChannels(config))
+}
+
+// groupMessage lists the down members and their errors
+func groupMessage(down map[string]string) string {
+    names := make([]string, 0, len(down))
+    for name := range down {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    members := make([]string, len(names))
+    for i, name := range names {
+        members[i] = fmt.Sprintf("%s: %s", name, down[name])
+    }
+    return fmt.Sprintf("%d member(s) down - %s", len(names), strings.Join(members, "; "))
+}
+
+// groupKeyConflict validates group keys as they would be with service
+// added, or replacing the running service of its name
+func (m *Monitor) groupKeyConflict(service ServiceConfig) error {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    services := []ServiceConfig{service}
+    for _, other := range m.config.Services {
+        if other.Name != service.Name {
+            services = append(services, other)
+        }
+    }
+    return validateGroupKeys(services)
+}
+
+// validateGroupKeys keeps group keys apart from service names, as both name
+// incidents
+func validateGroupKeys(services []ServiceConfig) error {
+    names := make(map[string]bool)
+    for _, service := range services {
+        names[service.Name] = true
+    }
+    for _, service := range services {
+        if service.GroupKey != "" && names[service.GroupKey] {
+            return fmt.Errorf("service %s: group_key %q is also a service name", service.Name, service.GroupKey)
+        }
+    }
+    return nil
+}
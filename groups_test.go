@@ -0,0 +1,129 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestGroupAlerts(t *testing.T) {
+    tests := []struct {
+        name    string
+        snoozed string           // member snoozed for the whole test
+        steps   []map[string]int // member -> response, per round of checks
+        want    []string
+    }{
+        {
+            name:  "members share one page and one recovery",
+            steps: []map[string]int{{"a": 500, "b": 500}, {"a": 200, "b": 500}, {"a": 200, "b": 200}},
+            want:  []string{EventAlert, EventRecovery},
+        },
+        {
+            name:    "a snoozed member does not page the group",
+            snoozed: "a",
+            steps:   []map[string]int{{"a": 500, "b": 200}, {"a": 500, "b": 200}, {"a": 200, "b": 200}},
+            want:    []string{},
+        },
+        {
+            name:    "an unsnoozed member still pages",
+            snoozed: "a",
+            steps:   []map[string]int{{"a": 500, "b": 200}, {"a": 500, "b": 500}, {"a": 200, "b": 200}},
+            want:    []string{EventAlert, EventRecovery},
+        },
+        {
+            name:  "degraded to down pages again",
+            steps: []map[string]int{{"a": 503, "b": 200}, {"a": 503, "b": 500}, {"a": 200, "b": 200}},
+            want:  []string{EventAlert, EventAlert, EventRecovery},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backends := map[string]*statusServer{}
+            var services []ServiceConfig
+            for _, name := range []string{"a", "b"} {
+                backends[name] = newStatusServer(t, http.StatusOK)
+                service := testService(name, backends[name].URL)
+                service.GroupKey = "shards"
+                service.StatusCodeStates = map[int]string{503: CodeDegraded}
+                services = append(services, service)
+            }
+            m, sender := newTestMonitor(t, MonitorConfig{Services: services})
+            if tt.snoozed != "" {
+                m.setSnooze(tt.snoozed, time.Now().Add(time.Hour))
+            }
+
+            for _, codes := range tt.steps {
+                for _, service := range services {
+                    backends[service.Name].code.Store(int32(codes[service.Name]))
+                    checkAndFlush(m, service)
+                }
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+            for _, event := range sender.events {
+                if event.Kind == EventAlert && tt.snoozed != "" && strings.Contains(event.Message, tt.snoozed+":") {
+                    t.Errorf("page %q names snoozed member %s", event.Message, tt.snoozed)
+                }
+            }
+        })
+    }
+}
+
+func TestGroupKeyConflictOnAPI(t *testing.T) {
+    backend := newStatusServer(t, http.StatusOK)
+    member := testService("a", backend.URL)
+    member.GroupKey = "shards"
+    m, _ := newTestMonitor(t, MonitorConfig{APIToken: "secret", Services: []ServiceConfig{member}})
+
+    body := `{"name": "shards", "url": "` + backend.URL + `", "check_interval": 60, "retry_attempts": 1}`
+    req := httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(body))
+    req.Header.Set("Authorization", "Bearer secret")
+    recorder := httptest.NewRecorder()
+    m.requireAuth(m.handleAddService)(recorder, req)
+
+    if recorder.Code != http.StatusBadRequest {
+        t.Errorf("adding a service named after a group key returned %d, want %d", recorder.Code, http.StatusBadRequest)
+    }
+}
+
+func TestGroupPagerDutyIncident(t *testing.T) {
+    backends := map[string]*statusServer{}
+    var services []ServiceConfig
+    for _, name := range []string{"shard-1", "shard-2"} {
+        backends[name] = newStatusServer(t, http.StatusInternalServerError)
+        service := testService(name, backends[name].URL)
+        service.GroupKey = "orders-db"
+        services = append(services, service)
+    }
+    m, events := newPagerDutyMonitor(t, services...)
+
+    // The second member joins the first one's incident, then both recover
+    for _, service := range services {
+        checkAndFlush(m, service)
+    }
+    for _, service := range services {
+        backends[service.Name].code.Store(http.StatusOK)
+        checkAndFlush(m, service)
+    }
+
+    got := events()
+    if len(got) != 2 || got[0].EventAction != "trigger" || got[1].EventAction != "resolve" {
+        t.Fatalf("events %+v, want one trigger then one resolve", got)
+    }
+    for _, event := range got {
+        if event.DedupKey != "monitor-alert/orders-db" {
+            t.Errorf("%s dedup key %q, want monitor-alert/orders-db", event.EventAction, event.DedupKey)
+        }
+    }
+    summary := got[0].Payload.Summary
+    for _, want := range []string{"orders-db is DOWN", "shard-1: unexpected status code: 500"} {
+        if !strings.Contains(summary, want) {
+            t.Errorf("trigger summary %q missing %q", summary, want)
+        }
+    }
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "time"
+)
+
+// defaultHistorySize is the number of recent checks kept per service
+const defaultHistorySize = 100
+
+// CheckRecord is the outcome of a single check
+type CheckRecord struct {
+    Time    time.Time     `json:"time"`
+    Up      bool          `json:"up"`
+    Latency time.Duration `json:"latency"`
+    Error   string        `json:"error,omitempty"`
+}
+
+// checkHistory is a fixed-size ring of recent check results. It is guarded
+// by the monitor's status mutex.
+type checkHistory struct {
+    records []CheckRecord
+    next    int
+    full    bool
+}
+
+func newCheckHistory(size int) *checkHistory {
+    if size <= 0 {
+        size = defaultHistorySize
+    }
+    return &checkHistory{records: make([]CheckRecord, size)}
+}
+
+func (h *checkHistory) add(record CheckRecord) {
+    h.records[h.next] = record
+    h.next = (h.next + 1) % len(h.records)
+    if h.next == 0 {
+        h.full = true
+    }
+}
+
+// recent returns the stored records, oldest first
+func (h *checkHistory) recent() []CheckRecord {
+    if !h.full {
+        return append([]CheckRecord(nil), h.records[:h.next]...)
+    }
+    return append(append([]CheckRecord(nil), h.records[h.next:]...), h.records[:h.next]...)
+}
+
+// maxSummaryErrors is how many recent errors a HistorySummary carries
+const maxSummaryErrors = 3
+
+// HistorySummary condenses the recent check history for alerts, e.g.
+// "failed 3 of last 10 checks"
+type HistorySummary struct {
+    Checks       int      `json:"checks"`
+    Failures     int      `json:"failures"`
+    FailureRatio float64  `json:"failure_ratio"`           // 0 to 1
+    RecentErrors []string `json:"recent_errors,omitempty"` // distinct errors, newest first
+}
+
+func (h *checkHistory) summary() HistorySummary {
+    records := h.recent()
+    summary := HistorySummary{Checks: len(records)}
+    seen := make(map[string]bool)
+    for i := len(records) - 1; i >= 0; i-- {
+        record := records[i]
+        if record.Up {
+            continue
+        }
+        summary.Failures++
+        if record.Error != "" && !seen[record.Error] && len(summary.RecentErrors) < maxSummaryErrors {
+            seen[record.Error] = true
+            summary.RecentErrors = append(summary.RecentErrors, record.Error)
+        }
+    }
+    if summary.Checks > 0 {
+        summary.FailureRatio = float64(summary.Failures) / float64(summary.Checks)
+    }
+    return summary
+}
+
+// String renders the summary as a line for alert messages
+func (s HistorySummary) String() string {
+    return fmt.Sprintf("Recent checks: failed %d of last %d", s.Failures, s.Checks)
+}
+
+// historySummary summarises the service's recent checks
+func (m *Monitor) historySummary(service string) HistorySummary {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+    if status, ok := m.serviceStatus[service]; ok && status.History != nil {
+        return status.History.summary()
+    }
+    return HistorySummary{}
+}
+
+// latencyPercentile returns the nearest-rank percentile of recorded latencies
+func (h *checkHistory) latencyPercentile(percentile float64) (time.Duration, bool) {
+    records := h.recent()
+    if len(records) == 0 {
+        return 0, false
+    }
+
+    latencies := make([]time.Duration, len(records))
+    for i, record := range records {
+        latencies[i] = record.Latency
+    }
+    sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+    rank := int(math.Ceil(percentile / 100 * float64(len(latencies))))
+    if rank < 1 {
+        rank = 1
+    }
+    if rank > len(latencies) {
+        rank = len(latencies)
+    }
+    return latencies[rank-1], true
+}
+
+// sloPercentile is the latency percentile the SLO and baseline drift are
+// evaluated at
+func sloPercentile(service ServiceConfig) float64 {
+    if service.SLOPercentile <= 0 {
+        return 95
+    }
+    return service.SLOPercentile
+}
+
+// sloStatus reports the service's current pXX latency and whether it is
+// within the configured SLO
+func sloStatus(service ServiceConfig, history *checkHistory) (time.Duration, bool, bool) {
+    if service.SLOResponseTimeMs <= 0 {
+        return 0, false, false
+    }
+
+    latency, ok := history.latencyPercentile(sloPercentile(service))
+    if !ok {
+        return 0, false, false
+    }
+    return latency, latency <= time.Duration(service.SLOResponseTimeMs)*time.Millisecond, true
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// historyOf records a check per latency, in milliseconds
+func historyOf(size int, latencies ...int) *checkHistory {
+    history := newCheckHistory(size)
+    for _, ms := range latencies {
+        history.add(CheckRecord{Time: time.Now(), Up: true, Latency: time.Duration(ms) * time.Millisecond})
+    }
+    return history
+}
+
+// rangeMs is 1..n
+func rangeMs(n int) []int {
+    values := make([]int, n)
+    for i := range values {
+        values[i] = i + 1
+    }
+    return values
+}
+
+func TestLatencyPercentile(t *testing.T) {
+    history := historyOf(100, rangeMs(100)...)
+    tests := []struct {
+        percentile float64
+        want       time.Duration
+    }{
+        {50, 50 * time.Millisecond},
+        {95, 95 * time.Millisecond},
+        {99, 99 * time.Millisecond},
+        {100, 100 * time.Millisecond},
+        {0.1, 1 * time.Millisecond},
+    }
+
+    for _, tt := range tests {
+        if got, ok := history.latencyPercentile(tt.percentile); !ok || got != tt.want {
+            t.Errorf("p%g = %s, want %s", tt.percentile, got, tt.want)
+        }
+    }
+    if _, ok := newCheckHistory(10).latencyPercentile(95); ok {
+        t.Error("an empty history reported a percentile")
+    }
+}
+
+func TestSLOCompliance(t *testing.T) {
+    tests := []struct {
+        name       string
+        sloMs      int
+        percentile float64
+        latencies  []int
+        within     bool
+        evaluated  bool
+    }{
+        {"p95 within the SLO", 96, 0, rangeMs(100), true, true},
+        {"p95 at the SLO", 95, 0, rangeMs(100), true, true},
+        {"p95 over the SLO", 90, 0, rangeMs(100), false, true},
+        {"p50 within the SLO", 60, 50, rangeMs(100), true, true},
+        {"a slow tail breaches p99", 100, 99, append(rangeMs(98), 500, 900), false, true},
+        {"no SLO configured", 0, 0, rangeMs(100), false, false},
+        {"no checks yet", 100, 0, nil, false, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := ServiceConfig{SLOResponseTimeMs: tt.sloMs, SLOPercentile: tt.percentile}
+            _, within, evaluated := sloStatus(service, historyOf(100, tt.latencies...))
+            if within != tt.within || evaluated != tt.evaluated {
+                t.Errorf("within %v, evaluated %v; want %v, %v", within, evaluated, tt.within, tt.evaluated)
+            }
+        })
+    }
+}
+
+func TestHistoryKeepsNewest(t *testing.T) {
+    history := historyOf(10, rangeMs(25)...)
+    records := history.recent()
+    if len(records) != 10 || records[0].Latency != 16*time.Millisecond || records[9].Latency != 25*time.Millisecond {
+        t.Errorf("kept %d records from %s to %s, want the 10 newest", len(records), records[0].Latency, records[len(records)-1].Latency)
+    }
+}
+
+func TestHistorySummary(t *testing.T) {
+    tests := []struct {
+        name       string
+        size       int
+        errors     []string // one check each, "" for up
+        wantChecks int
+        wantFailed int
+        wantRatio  float64
+        wantErrors []string
+    }{
+        {"no checks", 5, nil, 0, 0, 0, nil},
+        {"all up", 5, []string{"", "", ""}, 3, 0, 0, nil},
+        {"errors newest first and distinct", 10, []string{"timeout", "", "refused", "timeout"}, 4, 3, 0.75, []string{"timeout", "refused"}},
+        {"capped errors", 10, []string{"a", "b", "c", "d"}, 4, 4, 1, []string{"d", "c", "b"}},
+        {"only the buffered checks", 4, []string{"old", "old", "", "", "", "new"}, 4, 1, 0.25, []string{"new"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            history := newCheckHistory(tt.size)
+            for _, errMsg := range tt.errors {
+                history.add(CheckRecord{Time: time.Now(), Up: errMsg == "", Error: errMsg})
+            }
+
+            got := history.summary()
+            if got.Checks != tt.wantChecks || got.Failures != tt.wantFailed || got.FailureRatio != tt.wantRatio {
+                t.Errorf("summary %+v, want %d of %d failed (%g)", got, tt.wantFailed, tt.wantChecks, tt.wantRatio)
+            }
+            if !equalStrings(got.RecentErrors, tt.wantErrors) {
+                t.Errorf("recent errors %q, want %q", got.RecentErrors, tt.wantErrors)
+            }
+        })
+    }
+}
+
+func TestSlackAlertHistory(t *testing.T) {
+    var mutex sync.Mutex
+    var texts []string
+    slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var payload struct{ Text string }
+        json.NewDecoder(r.Body).Decode(&payload)
+        mutex.Lock()
+        texts = append(texts, payload.Text)
+        mutex.Unlock()
+    }))
+    defer slack.Close()
+
+    server := newStatusServer(t, http.StatusOK)
+    service := testService("api", server.URL)
+    m, err := NewMonitorFromConfig(MonitorConfig{
+        Services: []ServiceConfig{service},
+        Alerts: AlertConfig{
+            Slack:   SlackConfig{WebhookURL: slack.URL},
+            Routing: map[string][]string{SeverityWarning: {ChannelSlack}},
+        },
+    })
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    defer m.cancel()
+
+    checkAndFlush(m, service)
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusInternalServerError)
+    checkAndFlush(m, service)
+
+    mutex.Lock()
+    defer mutex.Unlock()
+    if len(texts) != 1 || !strings.Contains(texts[0], "\nRecent checks: failed 1 of last 3") {
+        t.Errorf("slack messages %q, want one alert naming 1 of last 3 failed", texts)
+    }
+}
@@ -0,0 +1,33 @@
+package main
+
+// OnCheckResult registers hook to be called with a snapshot of a service's
+// status after each check result is recorded. Hooks run in registration
+// order on the checking goroutine, without any monitor lock held, so a
+// slow hook delays that service's next check but nothing else.
+func (m *Monitor) OnCheckResult(hook func(ServiceStatus)) {
+    m.hookMutex.Lock()
+    defer m.hookMutex.Unlock()
+    m.checkHooks = append(m.checkHooks, hook)
+}
+
+func (m *Monitor) runCheckHooks(serviceName string) {
+    m.hookMutex.RLock()
+    hooks := m.checkHooks
+    m.hookMutex.RUnlock()
+    if len(hooks) == 0 {
+        return
+    }
+
+    m.statusMutex.RLock()
+    serviceStatus, ok := m.serviceStatus[serviceName]
+    if !ok {
+        m.statusMutex.RUnlock()
+        return
+    }
+    snapshot := serviceStatus.clone()
+    m.statusMutex.RUnlock()
+
+    for _, hook := range hooks {
+        hook(snapshot)
+    }
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+    "net/http"
+    "testing"
+)
+
+func TestOnCheckResult(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    var first, second []ServiceStatus
+    var order []string
+    m.OnCheckResult(func(status ServiceStatus) {
+        first = append(first, status)
+        order = append(order, "first")
+    })
+    m.OnCheckResult(func(status ServiceStatus) {
+        // No monitor lock is held, so hooks may read the monitor
+        if snapshot := m.Snapshot(); len(snapshot) != 1 {
+            t.Errorf("snapshot from hook %+v, want one service", snapshot)
+        }
+        second = append(second, status)
+        order = append(order, "second")
+    })
+
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+
+    if !equalStrings(order, []string{"first", "second", "first", "second"}) {
+        t.Fatalf("hooks called %v, want each in registration order after every check", order)
+    }
+    tests := []struct {
+        name       string
+        wantStatus bool
+        wantState  ServiceState
+        wantCode   int
+    }{
+        {"failed check", false, StateDown, http.StatusInternalServerError},
+        {"recovered check", true, StateUp, http.StatusOK},
+    }
+    for i, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := first[i]
+            if got.Name != "api" || got.Status != tt.wantStatus || got.State != tt.wantState || got.LastStatusCode != tt.wantCode {
+                t.Errorf("hook got %s status %v, state %s, code %d, want %v, %s, %d",
+                    got.Name, got.Status, got.State, got.LastStatusCode, tt.wantStatus, tt.wantState, tt.wantCode)
+            }
+            if got.LastCheck.IsZero() {
+                t.Error("hook got a zero LastCheck")
+            }
+            if second[i].State != got.State {
+                t.Errorf("second hook got state %s, want %s", second[i].State, got.State)
+            }
+        })
+    }
+
+    // The hook's snapshot is its own
+    if first[0].History == m.serviceStatus["api"].History {
+        t.Error("hook snapshot shares the monitor's history")
+    }
+}
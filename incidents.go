@@ -0,0 +1,65 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// defaultIncidentHistory bounds the number of completed incidents kept
+const defaultIncidentHistory = 1000
+
+// Incident is a completed down->up cycle of a service
+type Incident struct {
+    Service  string        `json:"service"`
+    Start    time.Time     `json:"start"`
+    End      time.Time     `json:"end"`
+    Duration time.Duration `json:"duration"`
+    Error    string        `json:"error"`
+}
+
+// recordIncident must be called with statusMutex held
+func (m *Monitor) recordIncident(incident Incident) {
+    limit := m.config.IncidentHistory
+    if limit <= 0 {
+        limit = defaultIncidentHistory
+    }
+
+    m.incidents = append(m.incidents, incident)
+    if len(m.incidents) > limit {
+        m.incidents = append([]Incident(nil), m.incidents[len(m.incidents)-limit:]...)
+    }
+}
+
+func (m *Monitor) handleIncidents(w http.ResponseWriter, r *http.Request) {
+    service := r.URL.Query().Get("service")
+
+    var since time.Time
+    if value := r.URL.Query().Get("since"); value != "" {
+        parsed, err := time.Parse(time.RFC3339, value)
+        if err != nil {
+            http.Error(w, fmt.Sprintf("invalid since %q: %v", value, err), http.StatusBadRequest)
+            return
+        }
+        since = parsed
+    }
+
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    // Newest first
+    result := make([]Incident, 0, len(m.incidents))
+    for i := len(m.incidents) - 1; i >= 0; i-- {
+        incident := m.incidents[i]
+        if service != "" && incident.Service != service {
+            continue
+        }
+        if incident.End.Before(since) {
+            continue
+        }
+        result = append(result, incident)
+    }
+
+    json.NewEncoder(w).Encode(result)
+}
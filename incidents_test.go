@@ -0,0 +1,84 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "testing"
+    "time"
+)
+
+func TestIncidentHistory(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    api, web := testService("api", server.URL), testService("web", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{api, web}})
+
+    outage := func(service ServiceConfig, length time.Duration) {
+        server.code.Store(http.StatusInternalServerError)
+        checkAndFlush(m, service)
+        time.Sleep(length)
+        server.code.Store(http.StatusOK)
+        checkAndFlush(m, service)
+    }
+    outage(api, 20*time.Millisecond)
+    outage(web, time.Millisecond)
+    between := time.Now()
+    outage(api, 40*time.Millisecond)
+
+    tests := []struct {
+        name  string
+        query url.Values
+        want  []string // services of the incidents returned, newest first
+        min   []time.Duration
+    }{
+        {"all", nil, []string{"api", "web", "api"}, []time.Duration{40 * time.Millisecond, 0, 20 * time.Millisecond}},
+        {"by service", url.Values{"service": {"api"}}, []string{"api", "api"}, []time.Duration{40 * time.Millisecond, 20 * time.Millisecond}},
+        {"since", url.Values{"since": {between.Format(time.RFC3339Nano)}}, []string{"api"}, []time.Duration{40 * time.Millisecond}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resp := apiRequest(m, http.MethodGet, "/incidents?"+tt.query.Encode(), "", false)
+            var incidents []Incident
+            if err := json.NewDecoder(resp.Body).Decode(&incidents); err != nil {
+                t.Fatalf("decoding incidents: %v", err)
+            }
+
+            services := make([]string, len(incidents))
+            for i, incident := range incidents {
+                services[i] = incident.Service
+                // The wall clock times lose the monotonic reading Duration used
+                skew := incident.Duration - incident.End.Sub(incident.Start)
+                if skew < -time.Millisecond || skew > time.Millisecond || incident.Duration < tt.min[i] {
+                    t.Errorf("incident %d lasted %s from %s to %s, want at least %s", i, incident.Duration, incident.Start, incident.End, tt.min[i])
+                }
+                if i > 0 && incident.End.After(incidents[i-1].End) {
+                    t.Errorf("incident %d ended after the one before it", i)
+                }
+                if incident.Error != "unexpected status code: 500" {
+                    t.Errorf("incident %d error = %q", i, incident.Error)
+                }
+            }
+            if !equalStrings(services, tt.want) {
+                t.Errorf("incidents of %v, want %v", services, tt.want)
+            }
+        })
+    }
+}
+
+func TestIncidentHistoryLimit(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{IncidentHistory: 2})
+    for _, name := range []string{"a", "b", "c"} {
+        m.recordIncident(Incident{Service: name})
+    }
+    if len(m.incidents) != 2 || m.incidents[0].Service != "b" || m.incidents[1].Service != "c" {
+        t.Errorf("kept %+v, want the two newest", m.incidents)
+    }
+}
+
+func TestIncidentsBadSince(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{})
+    if resp := apiRequest(m, http.MethodGet, "/incidents?since=yesterday", "", false); resp.Code != http.StatusBadRequest {
+        t.Errorf("GET /incidents?since=yesterday = %d, want 400", resp.Code)
+    }
+}
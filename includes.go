@@ -0,0 +1,63 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+)
+
+// includedConfig is the format of a file listed in includes. Only its
+// services are merged into the main config.
+type includedConfig struct {
+    Services []ServiceConfig `json:"services"`
+}
+
+// mergeIncludes appends the services of each included file to config.
+// Include paths may be globs and are relative to the main config's
+// directory. A service name defined twice is an error naming both files.
+func mergeIncludes(config *MonitorConfig, configPath string) error {
+    if len(config.Includes) == 0 {
+        return nil
+    }
+
+    definedIn := make(map[string]string, len(config.Services))
+    for _, service := range config.Services {
+        definedIn[service.Name] = configPath
+    }
+
+    dir := filepath.Dir(configPath)
+    for _, include := range config.Includes {
+        pattern := include
+        if !filepath.IsAbs(pattern) {
+            pattern = filepath.Join(dir, pattern)
+        }
+        paths, err := filepath.Glob(pattern)
+        if err != nil {
+            return fmt.Errorf("invalid include %q: %v", include, err)
+        }
+        if len(paths) == 0 {
+            return fmt.Errorf("include %q matched no files", include)
+        }
+
+        for _, path := range paths {
+            data, err := os.ReadFile(path)
+            if err != nil {
+                return fmt.Errorf("error reading include: %v", err)
+            }
+            var included includedConfig
+            if err := json.Unmarshal(data, &included); err != nil {
+                return fmt.Errorf("error parsing include %s: %v", path, err)
+            }
+
+            for _, service := range included.Services {
+                if other, ok := definedIn[service.Name]; ok {
+                    return fmt.Errorf("service %q in %s is already defined in %s", service.Name, path, other)
+                }
+                definedIn[service.Name] = path
+                config.Services = append(config.Services, service)
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+)
+
+// writeJSON writes value as JSON to path, creating its directory
+func writeJSON(t *testing.T, path string, value interface{}) {
+    t.Helper()
+    data, err := json.Marshal(value)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, data, 0600); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestConfigIncludes(t *testing.T) {
+    gateway := testService("gateway", "https://gateway.example.com/")
+    payments := includedConfig{Services: []ServiceConfig{testService("payments", "https://pay.example.com/"), testService("refunds", "https://pay.example.com/refunds")}}
+    search := includedConfig{Services: []ServiceConfig{testService("search", "https://search.example.com/")}}
+    duplicate := includedConfig{Services: []ServiceConfig{testService("payments", "https://other.example.com/")}}
+
+    tests := []struct {
+        name     string
+        includes []string
+        files    map[string]includedConfig // path under the config dir -> contents
+        want     []string                  // merged service names
+        wantErr  string
+    }{
+        {
+            name:     "two team files",
+            includes: []string{"teams/payments.json", "teams/search.json"},
+            files:    map[string]includedConfig{"teams/payments.json": payments, "teams/search.json": search},
+            want:     []string{"gateway", "payments", "refunds", "search"},
+        },
+        {
+            name:     "glob",
+            includes: []string{"teams/*.json"},
+            files:    map[string]includedConfig{"teams/payments.json": payments, "teams/search.json": search},
+            want:     []string{"gateway", "payments", "refunds", "search"},
+        },
+        {
+            name:     "duplicate across files",
+            includes: []string{"teams/*.json"},
+            files:    map[string]includedConfig{"teams/payments.json": payments, "teams/z-other.json": duplicate},
+            wantErr:  `service "payments" in`,
+        },
+        {
+            name:     "duplicate of the main config",
+            includes: []string{"gateway.json"},
+            files:    map[string]includedConfig{"gateway.json": {Services: []ServiceConfig{gateway}}},
+            wantErr:  "config.json",
+        },
+        {
+            name:     "include matching nothing",
+            includes: []string{"teams/missing.json"},
+            wantErr:  "matched no files",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            for path, contents := range tt.files {
+                writeJSON(t, filepath.Join(dir, path), contents)
+            }
+            configPath := filepath.Join(dir, "config.json")
+            writeJSON(t, configPath, MonitorConfig{Services: []ServiceConfig{gateway}, Includes: tt.includes})
+
+            // Includes resolve against the config's directory, not the working one
+            t.Chdir(t.TempDir())
+            config, err := readConfig(configPath)
+            if tt.wantErr != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                    t.Fatalf("readConfig() = %v, want an error containing %q", err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("readConfig() = %v", err)
+            }
+            var names []string
+            for _, service := range config.Services {
+                names = append(names, service.Name)
+            }
+            sort.Strings(names)
+            if !equalStrings(names, tt.want) {
+                t.Errorf("services %v, want %v", names, tt.want)
+            }
+        })
+    }
+}
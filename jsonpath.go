@@ -0,0 +1,95 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+func checkJSONAssertions(body []byte, checks []JSONCheck) error {
+    var doc interface{}
+    if err := json.Unmarshal(body, &doc); err != nil {
+        return fmt.Errorf("error parsing JSON response: %v", err)
+    }
+
+    for _, check := range checks {
+        value, err := lookupJSONPath(doc, check.Path)
+        if err != nil {
+            return fmt.Errorf("json check %s failed: %v", check.Path, err)
+        }
+
+        actual := jsonValueString(value)
+        if actual != check.ExpectedValue {
+            return fmt.Errorf("json check %s failed: expected %q, got %q", check.Path, check.ExpectedValue, actual)
+        }
+    }
+
+    return nil
+}
+
+// validateJSONPath rejects selectors lookupJSONPath can never resolve, such
+// as "a..b" or "items[]"
+func validateJSONPath(path string) error {
+    normalized := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+    if normalized == "" {
+        return nil
+    }
+    if strings.Count(normalized, "[") != strings.Count(normalized, "]") {
+        return fmt.Errorf("invalid json path %q: unbalanced brackets", path)
+    }
+    normalized = strings.ReplaceAll(normalized, "[", ".")
+    normalized = strings.ReplaceAll(normalized, "]", "")
+    for _, key := range strings.Split(normalized, ".") {
+        if key == "" {
+            return fmt.Errorf("invalid json path %q: empty segment", path)
+        }
+    }
+    return nil
+}
+
+// lookupJSONPath resolves a simple JSONPath-like selector such as
+// "$.checks[0].status" or "checks.0.status" against a decoded document
+func lookupJSONPath(doc interface{}, path string) (interface{}, error) {
+    path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+    path = strings.ReplaceAll(path, "[", ".")
+    path = strings.ReplaceAll(path, "]", "")
+
+    current := doc
+    if path == "" {
+        return current, nil
+    }
+
+    for _, key := range strings.Split(path, ".") {
+        switch node := current.(type) {
+        case map[string]interface{}:
+            value, ok := node[key]
+            if !ok {
+                return nil, fmt.Errorf("path not found at %q", key)
+            }
+            current = value
+        case []interface{}:
+            index, err := strconv.Atoi(key)
+            if err != nil || index < 0 || index >= len(node) {
+                return nil, fmt.Errorf("invalid array index %q", key)
+            }
+            current = node[index]
+        default:
+            return nil, fmt.Errorf("path not found at %q", key)
+        }
+    }
+
+    return current, nil
+}
+
+func jsonValueString(value interface{}) string {
+    if str, ok := value.(string); ok {
+        return str
+    }
+
+    encoded, err := json.Marshal(value)
+    if err != nil {
+        return fmt.Sprintf("%v", value)
+    }
+    return string(encoded)
+}
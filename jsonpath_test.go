@@ -0,0 +1,58 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestJSONChecks(t *testing.T) {
+    const body = `{"status": "ok", "checks": [{"name": "db", "status": "up", "replicas": 3}], "ready": true}`
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(body))
+    }))
+    defer server.Close()
+
+    tests := []struct {
+        name    string
+        checks  []JSONCheck
+        wantErr string // substring of the check error, empty for a pass
+    }{
+        {"passing top-level field", []JSONCheck{{Path: "status", ExpectedValue: "ok"}}, ""},
+        {"passing nested field", []JSONCheck{{Path: "$.checks[0].status", ExpectedValue: "up"}}, ""},
+        {"passing dotted index", []JSONCheck{{Path: "checks.0.replicas", ExpectedValue: "3"}}, ""},
+        {"passing boolean", []JSONCheck{{Path: "$.ready", ExpectedValue: "true"}}, ""},
+        {"failing value", []JSONCheck{{Path: "$.checks[0].status", ExpectedValue: "down"}}, `expected "down", got "up"`},
+        {"missing path", []JSONCheck{{Path: "$.cache.status", ExpectedValue: "up"}}, "path not found"},
+        {"index out of range", []JSONCheck{{Path: "$.checks[1].status", ExpectedValue: "up"}}, "invalid array index"},
+        {"second check fails", []JSONCheck{{Path: "status", ExpectedValue: "ok"}, {Path: "ready", ExpectedValue: "false"}}, "json check ready failed"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", server.URL)
+            service.JSONChecks = tt.checks
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            outcome := m.performCheck(service, m.serviceClient(service), nil)
+            if tt.wantErr == "" {
+                if !outcome.up {
+                    t.Errorf("check failed: %v", outcome.err)
+                }
+                return
+            }
+            if outcome.up || !strings.Contains(outcome.err.Error(), tt.wantErr) {
+                t.Errorf("check error = %v, want one containing %q", outcome.err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestJSONChecksInvalidBody(t *testing.T) {
+    err := checkJSONAssertions([]byte("<html>ok</html>"), []JSONCheck{{Path: "status", ExpectedValue: "ok"}})
+    if err == nil || !strings.Contains(err.Error(), "error parsing JSON response") {
+        t.Errorf("checkJSONAssertions() = %v, want a parse error", err)
+    }
+}
@@ -0,0 +1,20 @@
+package main
+
+// serviceLink is a responder link attached to a service's alerts
+type serviceLink struct {
+    key   string // field name in status output
+    label string
+    url   string
+}
+
+// serviceLinks returns the configured runbook and dashboard links
+func serviceLinks(service ServiceConfig) []serviceLink {
+    var links []serviceLink
+    if service.RunbookURL != "" {
+        links = append(links, serviceLink{"runbook_url", "Runbook", service.RunbookURL})
+    }
+    if service.DashboardURL != "" {
+        links = append(links, serviceLink{"dashboard_url", "Dashboard", service.DashboardURL})
+    }
+    return links
+}
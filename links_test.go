@@ -0,0 +1,106 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+const (
+    testRunbookURL   = "https://wiki.example.com/runbooks/api"
+    testDashboardURL = "https://grafana.example.com/d/api"
+)
+
+func linkedService(url string) ServiceConfig {
+    service := testService("api", url)
+    service.RunbookURL = testRunbookURL
+    service.DashboardURL = testDashboardURL
+    return service
+}
+
+func TestSlackPayloadLinks(t *testing.T) {
+    payloads := make(chan string, 1)
+    webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        payloads <- string(body)
+    }))
+    defer webhook.Close()
+
+    config := MonitorConfig{}
+    config.Alerts.Slack.WebhookURL = webhook.URL
+    m, _ := newTestMonitor(t, config)
+
+    if err := m.sendSlackAlert(context.Background(), m.config.Alerts.Slack.WebhookURL, linkedService("https://api.example.com/"), "connection refused", HistorySummary{}); err != nil {
+        t.Fatalf("sendSlackAlert: %v", err)
+    }
+
+    var payload struct {
+        Blocks []struct {
+            Elements []struct {
+                Text struct {
+                    Text string `json:"text"`
+                } `json:"text"`
+                URL string `json:"url"`
+            } `json:"elements"`
+        } `json:"blocks"`
+    }
+    if err := json.Unmarshal([]byte(<-payloads), &payload); err != nil {
+        t.Fatal(err)
+    }
+    buttons := make(map[string]string)
+    for _, block := range payload.Blocks {
+        for _, element := range block.Elements {
+            buttons[element.Text.Text] = element.URL
+        }
+    }
+    if buttons["Runbook"] != testRunbookURL || buttons["Dashboard"] != testDashboardURL {
+        t.Errorf("buttons %v, want runbook and dashboard links", buttons)
+    }
+}
+
+func TestPagerDutyPayloadLinks(t *testing.T) {
+    tests := []struct {
+        name    string
+        service ServiceConfig
+        want    []map[string]string
+    }{
+        {"runbook and dashboard", linkedService("https://api.example.com/"), []map[string]string{
+            {"href": testRunbookURL, "text": "Runbook"},
+            {"href": testDashboardURL, "text": "Dashboard"},
+        }},
+        {"no links", testService("api", "https://api.example.com/"), nil},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{Alerts: AlertConfig{PagerDuty: PagerDutyConfig{ServiceKey: "routing-key"}}})
+            event := m.pagerDutyTriggerEvent(tt.service, "connection refused")
+
+            links, _ := event["links"].([]map[string]string)
+            if len(links) != len(tt.want) {
+                t.Fatalf("links %v, want %v", event["links"], tt.want)
+            }
+            for i := range links {
+                if links[i]["href"] != tt.want[i]["href"] || links[i]["text"] != tt.want[i]["text"] {
+                    t.Errorf("link %d = %v, want %v", i, links[i], tt.want[i])
+                }
+            }
+        })
+    }
+}
+
+func TestStatusLinks(t *testing.T) {
+    service := linkedService("https://api.example.com/")
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    body := apiRequest(m, http.MethodGet, "/health", "", false).Body.String()
+    for _, want := range []string{`"runbook_url":"` + testRunbookURL + `"`, `"dashboard_url":"` + testDashboardURL + `"`} {
+        if !strings.Contains(body, want) {
+            t.Errorf("/health missing %s: %s", want, body)
+        }
+    }
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+    "log"
+    "net/http"
+    "strings"
+)
+
+// ListenerConfig is one API listener. Several can be configured to keep,
+// say, a public liveness port apart from the authenticated internal API.
+type ListenerConfig struct {
+    Address     string   `json:"address"`      // e.g. ":8080" or "127.0.0.1:9090"
+    RequireAuth bool     `json:"require_auth"` // require the api_token on every route
+    Routes      []string `json:"routes"`       // paths served, e.g. "/livez" or "/services"; empty serves all
+}
+
+// defaultListener preserves the single API port used before listeners
+// were configurable
+var defaultListener = ListenerConfig{Address: ":8080"}
+
+// apiRoute is a ServeMux pattern and its handler
+type apiRoute struct {
+    pattern string
+    handler http.HandlerFunc
+}
+
+// path is the pattern without its method
+func (r apiRoute) path() string {
+    if i := strings.IndexByte(r.pattern, ' '); i >= 0 {
+        return r.pattern[i+1:]
+    }
+    return r.pattern
+}
+
+// enabled reports whether the listener serves a route. A listed path also
+// enables the paths below it, so "/services" covers "/services/{name}".
+func (l ListenerConfig) enabled(route apiRoute) bool {
+    if len(l.Routes) == 0 {
+        return true
+    }
+    path := route.path()
+    for _, allowed := range l.Routes {
+        if path == allowed || strings.HasPrefix(path, strings.TrimSuffix(allowed, "/")+"/") {
+            return true
+        }
+    }
+    return false
+}
+
+func (m *Monitor) listenerMux(listener ListenerConfig) *http.ServeMux {
+    mux := http.NewServeMux()
+    for _, route := range m.apiRoutes() {
+        if !listener.enabled(route) {
+            continue
+        }
+        handler := route.handler
+        if listener.RequireAuth {
+            handler = m.requireAuth(handler)
+        }
+        mux.HandleFunc(route.pattern, handler)
+    }
+    return mux
+}
+
+// handleLivez reports that the process is serving requests
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+    w.Write([]byte("ok\n"))
+}
+
+// startAPIServer serves the configured listeners and exits if any fails
+func (m *Monitor) startAPIServer() {
+    listeners := m.config.Listeners
+    if len(listeners) == 0 {
+        listeners = []ListenerConfig{defaultListener}
+    }
+
+    errs := make(chan error, len(listeners))
+    for _, listener := range listeners {
+        server := &http.Server{Addr: listener.Address, Handler: m.listenerMux(listener)}
+        log.Printf("API listening on %s", listener.Address)
+        go func() { errs <- server.ListenAndServe() }()
+    }
+    log.Fatal(<-errs)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestListeners(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{
+        Services: []ServiceConfig{testService("api", "https://api.example.com/")},
+        APIToken: testAPIToken,
+    })
+    internal := httptest.NewServer(m.listenerMux(ListenerConfig{Address: "127.0.0.1:9090", RequireAuth: true}))
+    defer internal.Close()
+    public := httptest.NewServer(m.listenerMux(ListenerConfig{Address: ":8080", Routes: []string{"/livez", "/services"}}))
+    defer public.Close()
+
+    tests := []struct {
+        name   string
+        server *httptest.Server
+        method string
+        path   string
+        token  bool
+        want   int
+    }{
+        {"config on the internal listener", internal, http.MethodGet, "/config", true, http.StatusOK},
+        {"config needs the token internally", internal, http.MethodGet, "/config", false, http.StatusUnauthorized},
+        {"internal listener requires auth on every route", internal, http.MethodGet, "/health", false, http.StatusUnauthorized},
+        {"config not served publicly", public, http.MethodGet, "/config", true, http.StatusNotFound},
+        {"health not served publicly", public, http.MethodGet, "/health", false, http.StatusNotFound},
+        {"livez served publicly", public, http.MethodGet, "/livez", false, http.StatusOK},
+        {"listed path covers subpaths", public, http.MethodDelete, "/services/missing", true, http.StatusNotFound},
+        {"route auth kept publicly", public, http.MethodDelete, "/services/api", false, http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req, _ := http.NewRequest(tt.method, tt.server.URL+tt.path, nil)
+            if tt.token {
+                req.Header.Set("Authorization", "Bearer "+testAPIToken)
+            }
+            resp, err := http.DefaultClient.Do(req)
+            if err != nil {
+                t.Fatal(err)
+            }
+            resp.Body.Close()
+            if resp.StatusCode != tt.want {
+                t.Errorf("%s %s returned %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+            }
+        })
+    }
+}
+
+func TestInvalidListener(t *testing.T) {
+    if _, err := NewMonitorFromConfig(MonitorConfig{Listeners: []ListenerConfig{{Routes: []string{"/livez"}}}}); err == nil {
+        t.Error("NewMonitorFromConfig accepted a listener without an address")
+    }
+}
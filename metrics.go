@@ -0,0 +1,132 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// defaultLatencyBuckets are the histogram upper bounds in seconds
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// latencyHistogram is a fixed-bucket histogram of check durations. It is
+// guarded by the monitor's status mutex.
+type latencyHistogram struct {
+    bounds []float64
+    counts []uint64 // per bucket, non-cumulative; the last entry is +Inf
+    sum    float64
+    count  uint64
+}
+
+func newLatencyHistogram(bounds []float64) *latencyHistogram {
+    if len(bounds) == 0 {
+        bounds = defaultLatencyBuckets
+    }
+    sorted := append([]float64(nil), bounds...)
+    sort.Float64s(sorted)
+
+    return &latencyHistogram{
+        bounds: sorted,
+        counts: make([]uint64, len(sorted)+1),
+    }
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+    seconds := d.Seconds()
+    index := sort.SearchFloat64s(h.bounds, seconds)
+    h.counts[index]++
+    h.sum += seconds
+    h.count++
+}
+
+// slotMetrics records how long checks wait for a max_concurrent_checks
+// slot, which shows whether the monitor is keeping up
+type slotMetrics struct {
+    mutex   sync.Mutex
+    delayed uint64        // checks that found every slot taken
+    maxWait time.Duration // longest wait for a slot
+}
+
+// acquireCheckSlot takes a slot from the concurrency semaphore, recording
+// the wait if none was free
+func (m *Monitor) acquireCheckSlot() {
+    select {
+    case m.checkSlots <- struct{}{}:
+        return
+    default:
+    }
+
+    start := time.Now()
+    m.checkSlots <- struct{}{}
+    wait := time.Since(start)
+
+    m.slots.mutex.Lock()
+    defer m.slots.mutex.Unlock()
+    m.slots.delayed++
+    if wait > m.slots.maxWait {
+        m.slots.maxWait = wait
+    }
+}
+
+func (m *Monitor) handleMetrics(w http.ResponseWriter, r *http.Request) {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    names := make([]string, 0, len(m.serviceStatus))
+    for name := range m.serviceStatus {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    fmt.Fprintln(w, "# HELP monitor_check_duration_seconds Duration of service checks.")
+    fmt.Fprintln(w, "# TYPE monitor_check_duration_seconds histogram")
+    for _, name := range names {
+        h := m.serviceStatus[name].Latency
+        if h == nil {
+            continue
+        }
+
+        service := strconv.Quote(name)
+        var cumulative uint64
+        for i, bound := range h.bounds {
+            cumulative += h.counts[i]
+            fmt.Fprintf(w, "monitor_check_duration_seconds_bucket{service=%s,le=\"%s\"} %d\n",
+                service, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+        }
+        cumulative += h.counts[len(h.bounds)]
+        fmt.Fprintf(w, "monitor_check_duration_seconds_bucket{service=%s,le=\"+Inf\"} %d\n", service, cumulative)
+        fmt.Fprintf(w, "monitor_check_duration_seconds_sum{service=%s} %g\n", service, h.sum)
+        fmt.Fprintf(w, "monitor_check_duration_seconds_count{service=%s} %d\n", service, h.count)
+    }
+
+    if m.checkSlots != nil {
+        m.slots.mutex.Lock()
+        delayed, maxWait := m.slots.delayed, m.slots.maxWait
+        m.slots.mutex.Unlock()
+
+        fmt.Fprintln(w, "# HELP monitor_checks_in_flight Checks currently holding a concurrency slot.")
+        fmt.Fprintln(w, "# TYPE monitor_checks_in_flight gauge")
+        fmt.Fprintf(w, "monitor_checks_in_flight %d\n", len(m.checkSlots))
+        fmt.Fprintln(w, "# HELP monitor_check_slots Concurrency slots, from max_concurrent_checks.")
+        fmt.Fprintln(w, "# TYPE monitor_check_slots gauge")
+        fmt.Fprintf(w, "monitor_check_slots %d\n", cap(m.checkSlots))
+        fmt.Fprintln(w, "# HELP monitor_checks_delayed_total Checks that waited for a concurrency slot.")
+        fmt.Fprintln(w, "# TYPE monitor_checks_delayed_total counter")
+        fmt.Fprintf(w, "monitor_checks_delayed_total %d\n", delayed)
+        fmt.Fprintln(w, "# HELP monitor_check_slot_wait_max_seconds Longest wait for a concurrency slot.")
+        fmt.Fprintln(w, "# TYPE monitor_check_slot_wait_max_seconds gauge")
+        fmt.Fprintf(w, "monitor_check_slot_wait_max_seconds %g\n", maxWait.Seconds())
+    }
+
+    m.writeDeliveryMetrics(w)
+
+    if score, ok := m.availabilityScore(); ok {
+        fmt.Fprintln(w, "# HELP monitor_availability_score Weighted fraction of services that are up.")
+        fmt.Fprintln(w, "# TYPE monitor_availability_score gauge")
+        fmt.Fprintf(w, "monitor_availability_score %g\n", score)
+    }
+}
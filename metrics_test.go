@@ -0,0 +1,147 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+    h := newLatencyHistogram([]float64{1, 0.1, 5, 0.5})
+    for _, d := range []time.Duration{
+        50 * time.Millisecond,
+        100 * time.Millisecond, // a bound is inclusive
+        300 * time.Millisecond,
+        2 * time.Second,
+        20 * time.Second,
+    } {
+        h.observe(d)
+    }
+
+    want := []uint64{2, 1, 0, 1, 1}
+    for i, count := range h.counts {
+        if count != want[i] {
+            t.Errorf("bucket %d = %d, want %d (counts %v)", i, count, want[i], h.counts)
+        }
+    }
+    if h.count != 5 || h.sum < 22.44 || h.sum > 22.46 {
+        t.Errorf("count %d, sum %g; want 5, 22.45", h.count, h.sum)
+    }
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{
+        Services:       []ServiceConfig{testService("api", "https://api.example.com/")},
+        LatencyBuckets: []float64{0.1, 1},
+    })
+    m.statusMutex.Lock()
+    for _, d := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond, 3 * time.Second} {
+        m.serviceStatus["api"].Latency.observe(d)
+    }
+    m.statusMutex.Unlock()
+
+    recorder := httptest.NewRecorder()
+    m.handleMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+    body := recorder.Body.String()
+
+    for _, line := range []string{
+        `monitor_check_duration_seconds_bucket{service="api",le="0.1"} 1`,
+        `monitor_check_duration_seconds_bucket{service="api",le="1"} 2`,
+        `monitor_check_duration_seconds_bucket{service="api",le="+Inf"} 3`,
+        `monitor_check_duration_seconds_count{service="api"} 3`,
+    } {
+        if !strings.Contains(body, line+"\n") {
+            t.Errorf("metrics missing %q:\n%s", line, body)
+        }
+    }
+}
+
+func TestCheckSlotMetrics(t *testing.T) {
+    tests := []struct {
+        name         string
+        limit        int // max_concurrent_checks
+        checks       int // run at once
+        wantInFlight int // while the checks hold their slots
+        wantDelayed  int
+    }{
+        {"tight limit delays the rest", 1, 3, 1, 2},
+        {"enough slots", 3, 3, 3, 0},
+        {"unlimited", 0, 2, 0, 0},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            started := make(chan struct{}, tt.checks)
+            release := make(chan struct{})
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                started <- struct{}{}
+                <-release
+            }))
+            defer server.Close()
+
+            var services []ServiceConfig
+            for i := 0; i < tt.checks; i++ {
+                services = append(services, testService(fmt.Sprintf("svc%d", i), server.URL))
+            }
+            m, _ := newTestMonitor(t, MonitorConfig{Services: services, MaxConcurrentChecks: tt.limit})
+            metrics := func() string {
+                recorder := httptest.NewRecorder()
+                m.handleMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+                return recorder.Body.String()
+            }
+
+            var wg sync.WaitGroup
+            for _, service := range services {
+                wg.Add(1)
+                go func() {
+                    defer wg.Done()
+                    m.runCheck(service)
+                }()
+            }
+            running := tt.checks
+            if tt.limit > 0 {
+                running = min(tt.limit, tt.checks)
+            }
+            for i := 0; i < running; i++ {
+                <-started
+            }
+
+            if tt.limit == 0 {
+                if body := metrics(); strings.Contains(body, "monitor_checks_in_flight") {
+                    t.Errorf("slot metrics exported without max_concurrent_checks:\n%s", body)
+                }
+                close(release)
+                wg.Wait()
+                return
+            }
+            // Leave the delayed checks waiting long enough to record a wait
+            time.Sleep(50 * time.Millisecond)
+            if line := fmt.Sprintf("monitor_checks_in_flight %d\n", tt.wantInFlight); !strings.Contains(metrics(), line) {
+                t.Errorf("metrics missing %q while checks run:\n%s", line, metrics())
+            }
+            close(release)
+            wg.Wait()
+
+            body := metrics()
+            for _, line := range []string{
+                "monitor_checks_in_flight 0",
+                fmt.Sprintf("monitor_check_slots %d", tt.limit),
+                fmt.Sprintf("monitor_checks_delayed_total %d", tt.wantDelayed),
+            } {
+                if !strings.Contains(body, line+"\n") {
+                    t.Errorf("metrics missing %q:\n%s", line, body)
+                }
+            }
+            m.slots.mutex.Lock()
+            maxWait := m.slots.maxWait
+            m.slots.mutex.Unlock()
+            if (maxWait >= 50*time.Millisecond) != (tt.wantDelayed > 0) {
+                t.Errorf("max slot wait %s with %d checks delayed", maxWait, tt.wantDelayed)
+            }
+        })
+    }
+}
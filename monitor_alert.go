@@ -2,216 +2,1232 @@ package main
 
 import (
     "bytes"
+    "context"
+    "crypto/tls"
     "encoding/json"
+    "flag"
     "fmt"
+    "io"
     "log"
+    "mime"
+    "net"
     "net/http"
+    "net/http/httptrace"
+    "net/smtp"
     "os"
+    "strings"
     "sync"
     "time"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+    "golang.org/x/oauth2"
 )
 
 type AlertConfig struct {
-    Slack     SlackConfig     `json:"slack"`
-    Email     EmailConfig     `json:"email"`
-    PagerDuty PagerDutyConfig `json:"pagerduty"`
+    Slack         SlackConfig            `json:"slack"`
+    Email         EmailConfig            `json:"email"`
+    GoogleChat    GoogleChatConfig       `json:"google_chat"`
+    PagerDuty     PagerDutyConfig        `json:"pagerduty"`
+    Routing       map[string][]string    `json:"routing"`        // severity -> channels ("slack", "email", "pagerduty")
+    File          *FileConfig            `json:"file"`           // Append alerts as JSON lines
+    Syslog        *SyslogConfig          `json:"syslog"`
+    SNS           *SNSConfig             `json:"sns"`
+    NATS          *NATSConfig            `json:"nats"`           // Publish every alert event as JSON
+    TimeRouting   []TimeRoute            `json:"time_routing"`   // Routing overrides by UTC time of day; the first active match wins
+    DeliveryRetry map[string]RetryPolicy `json:"delivery_retry"` // channel -> retry policy for failed deliveries
+    MessageLimits map[string]int         `json:"message_limits"` // channel -> max message bytes, truncated with an ellipsis
+}
+
+const (
+    SeverityInfo     = "info"
+    SeverityWarning  = "warning"
+    SeverityCritical = "critical"
+)
+
+const (
+    FailModeClosed = "closed"
+    FailModeOpen   = "open"
+)
+
+const (
+    ChannelSlack     = "slack"
+    ChannelEmail     = "email"
+    ChannelPagerDuty = "pagerduty"
+    ChannelFile      = "file"
+    ChannelSyslog    = "syslog"
+)
+
+// defaultRouting preserves the original behaviour: Slack for everything,
+// PagerDuty additionally for critical services
+var defaultRouting = map[string][]string{
+    SeverityInfo:     {ChannelSlack},
+    SeverityWarning:  {ChannelSlack},
+    SeverityCritical: {ChannelSlack, ChannelPagerDuty},
 }
 
 type SlackConfig struct {
-    WebhookURL string   `json:"webhook_url"`
-    Channels   []string `json:"channels"`
+    WebhookURL        string   `json:"webhook_url"`
+    Channels          []string `json:"channels"`
+    WebhookURLFile    string   `json:"webhook_url_file"`    // read webhook_url from this file
+    SigningSecret     string   `json:"signing_secret"`      // Slack app signing secret; enables the Acknowledge button
+    SigningSecretFile string   `json:"signing_secret_file"`
 }
 
 type EmailConfig struct {
-    SMTPServer string   `json:"smtp_server"`
-    SMTPPort   int      `json:"smtp_port"`
-    Username   string   `json:"username"`
-    Password   string   `json:"password"`
-    Recipients []string `json:"recipients"`
+    SMTPServer   string   `json:"smtp_server"`
+    SMTPPort     int      `json:"smtp_port"`
+    Username     string   `json:"username"`
+    Password     string   `json:"password"`
+    Recipients   []string `json:"recipients"`
+    PasswordFile string   `json:"password_file"`
 }
 
 type PagerDutyConfig struct {
-    ServiceKey string `json:"service_key"`
-    APIKey     string `json:"api_key"`
+    ServiceKey     string `json:"service_key"`
+    APIKey         string `json:"api_key"`
+    ServiceKeyFile string `json:"service_key_file"`
+    APIKeyFile     string `json:"api_key_file"`
 }
 
 type ServiceConfig struct {
-    Name             string            `json:"name"`
-    URL              string            `json:"url"`
-    Method           string            `json:"method"`
-    Headers          map[string]string `json:"headers"`
-    ExpectedStatus   int               `json:"expected_status"`
-    Timeout          int              `json:"timeout"`          // in seconds
-    CheckInterval    int              `json:"check_interval"`   // in seconds
-    RetryAttempts    int              `json:"retry_attempts"`
-    RetryDelay       int              `json:"retry_delay"`      // in seconds
-    CriticalService  bool             `json:"critical_service"` // If true, triggers immediate paging
+    Name                  string                `json:"name"`
+    Type                  string                `json:"type"`                    // "http" (default), "transaction" or a check type compiled in via build tags, e.g. "amqp"
+    URL                   string                `json:"url"`
+    Endpoints             []string              `json:"endpoints"`               // Further URLs probed concurrently with url, e.g. from other vantage points
+    Quorum                int                   `json:"quorum"`                  // Endpoints that must pass for the service to be up, a majority if unset
+    UpstreamHealthURL     string                `json:"upstream_health_url"`     // Alerts are suppressed while this shared upstream is down
+    Method                string                `json:"method"`
+    Headers               map[string]string     `json:"headers"`
+    ExpectedStatus        int                   `json:"expected_status"`
+    Timeout               int                   `json:"timeout"`                 // in seconds
+    CheckInterval         int                   `json:"check_interval"`          // in seconds
+    RetryAttempts         int                   `json:"retry_attempts"`
+    RetryDelay            int                   `json:"retry_delay"`             // in seconds
+    RetryableStatuses     []int                 `json:"retryable_statuses"`      // Only failures with these status codes are retried; all are if unset
+    DisableTransportRetry bool                  `json:"disable_transport_retry"` // Fail fast on connection, DNS and timeout errors
+    CriticalService       bool                  `json:"critical_service"`        // If true, triggers immediate paging
+    Severity              string                `json:"severity"`                // info, warning or critical
+    JSONChecks            []JSONCheck           `json:"json_checks"`             // Assertions against the JSON response body
+    ExpectedHeaders       map[string]string     `json:"expected_headers"`
+    HeaderMatchRegex      bool                  `json:"header_match_regex"`      // If true, ExpectedHeaders values are regular expressions
+    FlapThreshold         int                   `json:"flap_threshold"`          // State changes within FlapWindow before the service is flapping
+    FlapWindow            int                   `json:"flap_window"`             // in seconds
+    OAuth2                *OAuth2Config         `json:"oauth2"`                  // Client-credentials token attached as the Authorization header
+    FailMode              string                `json:"fail_mode"`               // "closed" (default) or "open" to ignore transport errors
+    AddressFamily         string                `json:"address_family"`          // "auto" (default), "ipv4", "ipv6" or "prefer-ipv6" to fall back to IPv4
+    MinTLSVersion         string                `json:"min_tls_version"`         // "1.2" or "1.3"
+    ExpectedProtocol      string                `json:"expected_protocol"`       // "HTTP/1.1" or "HTTP/2.0" the response must be served over
+    RequireNonEmptyBody   bool                  `json:"require_non_empty_body"`
+    MinBodyBytes          int                   `json:"min_body_bytes"`
+    ExpectedBodySubstring string                `json:"expected_body_substring"`
+    ExpectedBodyRegex     string                `json:"expected_body_regex"`
+    SLOResponseTimeMs     int                   `json:"slo_response_time_ms"`
+    SLOPercentile         float64               `json:"slo_percentile"`          // e.g. 95, evaluated over the recent check history for the SLO and baseline drift
+    BaselineLatencyMs     int                   `json:"baseline_latency_ms"`     // Expected pXX latency; alerts when it drifts above by more than drift_percent
+    DriftPercent          float64               `json:"drift_percent"`           // e.g. 25 for 25% above baseline_latency_ms
+    PagerDutySeverity     string                `json:"pagerduty_severity"`      // critical, error, warning or info; derived from Severity if unset
+    PagerDutyDetails      map[string]string     `json:"pagerduty_details"`       // Merged into the incident's custom_details
+    PagerDutyRoutingKey   string                `json:"pagerduty_routing_key"`   // Pages this team's PagerDuty service instead of alerts.pagerduty.service_key
+    Labels                map[string]string     `json:"labels"`
+    WarmupSeconds         int                   `json:"warmup_seconds"`          // Failures after the service is added don't alert until this elapses
+    ForceHTTP1            bool                  `json:"force_http1"`             // Never negotiate HTTP/2
+    DisableKeepAlive      bool                  `json:"disable_keep_alive"`      // Open a new connection for every check
+    ExpectedFinalURL      string                `json:"expected_final_url"`      // URL the redirect chain must end at
+    FinalURLMatch         string                `json:"final_url_match"`         // "exact" (default) or "prefix"
+    ExpectedContentType   string                `json:"expected_content_type"`   // e.g. "application/json; charset=utf-8"
+    ContentTypeMatch      string                `json:"content_type_match"`      // "exact" (default) or "prefix"
+    DialTimeout           int                   `json:"dial_timeout"`            // in seconds, bound on establishing the connection
+    ResponseHeaderTimeout int                   `json:"response_header_timeout"` // in seconds, bound on waiting for response headers
+    AMQPQueue             string                `json:"amqp_queue"`              // Queue that must exist for amqp checks
+    KafkaBrokers          []string              `json:"kafka_brokers"`           // Broker addresses for kafka checks, tried in order
+    KafkaTopic            string                `json:"kafka_topic"`             // Topic that must exist with leaders for kafka checks
+    Steps                 []TransactionStep     `json:"steps"`                   // Ordered requests for transaction checks
+    StatusCodeStates      map[int]string        `json:"status_code_states"`      // status code -> "healthy", "degraded" or "down"
+    Socks5Proxy           *Socks5Config         `json:"socks5_proxy"`            // Route checks through a SOCKS5 proxy
+    FailureCountAlerts    []FailureCountAlert   `json:"failure_count_alerts"`    // Escalate to more channels as consecutive failures grow
+    DisableHeadFallback   bool                  `json:"disable_head_fallback"`   // With method HEAD, treat 405 as a failure instead of retrying with GET
+    Weight                float64               `json:"weight"`                  // Share of the availability score, defaults to 1
+    Public                bool                  `json:"public"`                  // Listed in status_export; other services are left out of it
+    CircuitBreaker        *CircuitBreakerConfig `json:"circuit_breaker"`         // Back off checks of a service that stays down
+    ExpectedTrailers      map[string]string     `json:"expected_trailers"`       // Checked like expected_headers once the body is drained
+    BusinessHours         *BusinessHours        `json:"business_hours"`          // Only check and alert inside this weekly window
+    SQLDriver             string                `json:"sql_driver"`              // "postgres" or "mysql" for sql checks, defaults to the URL scheme
+    SQLQuery              string                `json:"sql_query"`               // Ping query for sql checks, defaults to SELECT 1
+    RunbookURL            string                `json:"runbook_url"`             // Linked from alerts and status output
+    DashboardURL          string                `json:"dashboard_url"`
+    MaxBodyBytes          int64                 `json:"max_body_bytes"`          // Body read limit, overrides the global max_body_bytes
+    WebSocketPing         bool                  `json:"websocket_ping"`          // For websocket checks, require a pong to a ping
+    CertValidation        string                `json:"cert_validation"`         // "warn" or "strict" to verify the chain and stapled OCSP response
+    MaxRedirects          int                   `json:"max_redirects"`           // Redirect hops followed before the check fails, default 10
+    ExpectedRedirects     *int                  `json:"expected_redirects"`      // Exact number of hops the redirect chain must take, unchecked if unset
+    Priority              string                `json:"priority"`                // P1 (most urgent) to P5, sets the PagerDuty severity
+    PriorityEscalation    []PriorityEscalation  `json:"priority_escalation"`     // Raise the priority, and re-page, as downtime grows
+    StatusBodyChecks      map[int]BodyAssertion `json:"status_body_checks"`      // status code -> body assertions replacing the service-wide ones for that code
+    GroupKey              string                `json:"group_key"`               // Services sharing a key, e.g. shards, alert and resolve as one incident
+    ConnectAddr           string                `json:"connect_addr"`            // ip:port dialed instead of the URL host, e.g. one backend behind a VIP
+    Host                  string                `json:"host"`                    // Host header and TLS SNI sent instead of the URL host
+    DNSCacheTTL           int                   `json:"dns_cache_ttl"`           // in seconds, re-resolve the host at most this often to track its addresses, which checks connect to meanwhile; 0 with alert_on_dns_change resolves every check
+    AlertOnDNSChange      bool                  `json:"alert_on_dns_change"`     // Info alert when the resolved addresses change, e.g. on DNS failover
+}
+
+// FailureCountAlert notifies extra channels once a service has failed Count
+// consecutive checks
+type FailureCountAlert struct {
+    Count    int      `json:"count"`
+    Channels []string `json:"channels"`
+}
+
+type JSONCheck struct {
+    Path          string `json:"path"`           // e.g. "db", "$.checks[0].status"
+    ExpectedValue string `json:"expected_value"`
 }
 
 type MonitorConfig struct {
-    Services []ServiceConfig `json:"services"`
-    Alerts   AlertConfig    `json:"alerts"`
+    Services                []ServiceConfig            `json:"services"`
+    Alerts                  AlertConfig                `json:"alerts"`
+    LogRateLimit            int                        `json:"log_rate_limit"`             // in seconds, minimum interval between repeated log lines
+    LatencyBuckets          []float64                  `json:"latency_buckets"`            // histogram bucket upper bounds in seconds
+    Timezone                string                     `json:"timezone"`                   // IANA zone for alert timestamps, defaults to UTC
+    APIToken                string                     `json:"api_token"`                  // Bearer token for the management API
+    StatePath               string                     `json:"state_path"`                 // Optional file for persisting incidents across restarts
+    IncidentHistory         int                        `json:"incident_history"`           // Maximum completed incidents kept
+    AlertTimeout            int                        `json:"alert_timeout"`              // in seconds, bound on each alert delivery
+    HistorySize             int                        `json:"history_size"`               // Recent checks kept per service
+    Resolver                *ResolverConfig            `json:"resolver"`                   // Custom DNS server used by checks
+    MaxConcurrentChecks     int                        `json:"max_concurrent_checks"`      // 0 means unlimited
+    StartupGracePeriod      int                        `json:"startup_grace_period"`       // in seconds, no alerts while the monitor establishes a baseline
+    OTel                    *OTelConfig                `json:"otel"`                       // Export check spans over OTLP
+    AlertDedupWindow        int                        `json:"alert_dedup_window"`         // in seconds, how long a persisted active incident suppresses re-alerting after a restart
+    ResultWebhook           string                     `json:"result_webhook"`             // Receives the summary of a --once pass
+    HistoryMaxPoints        int                        `json:"history_max_points"`         // Latency trend points kept per service across all tiers
+    APITokenFile            string                     `json:"api_token_file"`             // read api_token from this file
+    AlertQueueSize          int                        `json:"alert_queue_size"`           // Pending alerts buffered, and per channel pending deliveries, before the oldest is dropped
+    CheckCacheTTL           int                        `json:"check_cache_ttl"`            // in seconds, reuse a check result for an identical request and assertions; 0 disables
+    MaxBodyBytes            int64                      `json:"max_body_bytes"`             // Default body read limit, 1 MiB if unset
+    Listeners               []ListenerConfig           `json:"listeners"`                  // API listeners, a single :8080 serving every route if unset
+    FleetHealthAlert        *FleetHealthAlert          `json:"fleet_health_alert"`         // Alert when a share of all services is down at once
+    Includes                []string                   `json:"includes"`                   // Files, or globs, whose services are merged in; relative to this file
+    StaleFactor             float64                    `json:"stale_factor"`               // Check intervals without a result before alerting that monitoring stalled, default 3
+    StaleAlertChannels      []string                   `json:"stale_alert_channels"`       // Ops channels for monitoring-stalled alerts, the warning routing if unset
+    StatusExport            *StatusExportConfig        `json:"status_export"`              // Periodically write the aggregate status to a JSON file
+    MaintenanceCalendar     *MaintenanceCalendarConfig `json:"maintenance_calendar"`       // iCal feed whose events suppress alerts
+    ShutdownDrain           int                        `json:"shutdown_drain"`             // in seconds, wait for in-flight checks and alerts on SIGTERM, default 30
+    ResultWebhookSecret     string                     `json:"result_webhook_secret"`      // Signs result_webhook posts with an X-Signature: sha256=<hex> HMAC of the body
+    ResultWebhookSecretFile string                     `json:"result_webhook_secret_file"` // read result_webhook_secret from this file
 }
 
+// defaultAlertTimeout bounds alert deliveries when alert_timeout is unset
+const defaultAlertTimeout = 10 * time.Second
+
+// ServiceState is the last confirmed state of a service. Services start out
+// as StateUnknown until their first check completes.
+type ServiceState string
+
+const (
+    StateUnknown ServiceState = "unknown"
+    StateUp      ServiceState = "up"
+    StateDown    ServiceState = "down"
+)
+
 type ServiceStatus struct {
-    Name           string
-    Status         bool
-    LastCheck      time.Time
-    LastError      string
-    FailureCount   int
-    ResponseTime   time.Duration
-    AlertSent      bool
-    RecoveryTime   *time.Time
+    Name              string
+    Status            bool
+    State             ServiceState
+    LastCheck         time.Time
+    LastError         string
+    FailureCount      int
+    ResponseTime      time.Duration     // latency of the last check's final attempt
+    CheckDuration     time.Duration     // the last check including retries and retry delays
+    LastStatusCode    int               // HTTP status of the last response, 0 if none was received
+    AlertSent         bool
+    RecoveryTime      *time.Time
+    DownSince         *time.Time
+    IncidentError     string            // error that started the current outage
+    AddedAt           time.Time
+    Flapping          bool
+    Transitions       []time.Time       // up/down state changes within the flap window
+    Latency           *latencyHistogram
+    History           *checkHistory
+    TLSVersion        string            // negotiated on the last HTTPS response
+    TLSCipher         string
+    AlertState        ServiceState      // state the last down alert was sent for
+    AlertSuppressed   bool              // the outage's down alert was held back, e.g. by a snooze; retried on the next failed check
+    Trend             *latencyTrend
+    NextCheck         time.Time         // when the scheduler will next check the service
+    Acknowledged      bool              // the current outage was acknowledged from Slack
+    AcknowledgedBy    string
+    Paused            bool              // outside business hours, so not being checked
+    ResumedAt         time.Time         // when the service last left a business-hours pause
+    BodyTruncated     bool              // the last body check read a truncated body
+    Errors            errorFrequency    // distinct recent errors with counts
+    SnoozedUntil      time.Time         // alerts are suppressed until then, checks keep running
+    LatencyDrift      bool              // latency is more than drift_percent above the baseline
+    MonitoringStalled bool              // no check result for stale_factor intervals
+    StalledSince      time.Time         // last result before monitoring stalled
+    Endpoints         []EndpointResult  // last result per endpoint of a quorum check
+    Priority          string            // priority of the current outage, raised by priority_escalation
+    AddressFamily     string            // "ipv4" or "ipv6", that the last response came over
+    ResolvedIPs       []string          // host addresses from the last DNS lookup, when dns is tracked
+    DNSResolvedAt     time.Time
+    DNSChangedAt      time.Time         // last time the resolved addresses changed
 }
 
 type Monitor struct {
-    config         MonitorConfig
-    serviceStatus  map[string]*ServiceStatus
-    statusMutex    sync.RWMutex
-    httpClient     *http.Client
+    config        MonitorConfig                 // config.Services is guarded by statusMutex
+    serviceStatus map[string]*ServiceStatus
+    statusMutex   sync.RWMutex
+    httpClient    *http.Client                  // used for alert delivery, always has a timeout
+    logger        *rateLimitedLogger
+    tokenSources  map[string]oauth2.TokenSource
+    tokenMutex    sync.Mutex
+    clients       map[string]*http.Client
+    patterns      map[string]patternSet         // service -> compiled patterns; guarded by clientMutex
+    clientMutex   sync.Mutex
+    location      *time.Location
+    silence       silenceState
+    channels      channelState
+    forced        forceCheckState
+    checkSlots    chan struct{}                 // concurrency semaphore, nil when unlimited
+    slots         slotMetrics
+    inflight      map[string]chan struct{}      // closed when the service's running check completes
+    inflightMutex sync.Mutex
+    draining      bool                          // set by Shutdown; guarded by inflightMutex
+    checkWG       sync.WaitGroup                // running checks
+    supervisorWG  sync.WaitGroup                // background loops started by supervise
+    monitors      map[string]chan struct{}      // stop channels for running service goroutines
+    monitorMutex  sync.Mutex
+    incidents     []Incident                    // completed incidents, oldest first; guarded by statusMutex
+    alertTimeout  time.Duration
+    alertWG       sync.WaitGroup                // queued and in-flight alert deliveries
+    alertQueue    chan alertJob
+    channelQueues channelQueues                 // per-channel delivery workers
+    senders       map[string]AlertSender        // channel -> sender
+    senderMutex   sync.RWMutex
+    deliveries    deliveryLog
+    pagerDuty     pagerDutyIncidents
+    checkHooks    []func(ServiceStatus)
+    fleet         fleetState                    // guarded by statusMutex
+    groups        map[string]*alertGroup        // open incidents by group_key; guarded by statusMutex
+    hookMutex     sync.RWMutex
+    results       resultCache
+    configPath    string                        // set by NewMonitor, used by Reload
+    resolver      *net.Resolver                 // nil uses the system resolver
+    startTime     time.Time
+    alertFile     *alertFile
+    syslog        *syslogWriter
+    sns           snsPublisher
+    nats          natsPublisher
+    calendar      calendarState
+    tracer        trace.Tracer
+    ctx           context.Context               // parent of checks and alert deliveries, cancelled by Shutdown
+    cancel        context.CancelFunc
 }
 
 func NewMonitor(configPath string) (*Monitor, error) {
+    config, err := readConfig(configPath)
+    if err != nil {
+        return nil, err
+    }
+
+    monitor, err := NewMonitorFromConfig(config)
+    if err != nil {
+        return nil, err
+    }
+    monitor.configPath = configPath
+    return monitor, nil
+}
+
+func readConfig(configPath string) (MonitorConfig, error) {
+    var config MonitorConfig
+
     // Read configuration
     file, err := os.ReadFile(configPath)
     if err != nil {
-        return nil, fmt.Errorf("error reading config: %v", err)
+        return config, fmt.Errorf("error reading config: %v", err)
     }
 
-    var config MonitorConfig
     if err := json.Unmarshal(file, &config); err != nil {
-        return nil, fmt.Errorf("error parsing config: %v", err)
+        return config, fmt.Errorf("error parsing config: %v", err)
+    }
+    if err := mergeIncludes(&config, configPath); err != nil {
+        return config, err
+    }
+    return config, nil
+}
+
+// NewMonitorFromConfig builds a monitor from an in-memory configuration,
+// applying the same validation and defaults as NewMonitor
+func NewMonitorFromConfig(config MonitorConfig) (*Monitor, error) {
+    if err := loadSecrets(&config); err != nil {
+        return nil, fmt.Errorf("error reading secrets: %v", err)
+    }
+
+    if err := validateConfig(config); err != nil {
+        return nil, fmt.Errorf("invalid config: %v", err)
+    }
+
+    location, err := time.LoadLocation(config.Timezone)
+    if err != nil {
+        return nil, fmt.Errorf("invalid timezone %q: %v", config.Timezone, err)
+    }
+
+    alertTimeout := time.Duration(config.AlertTimeout) * time.Second
+    if alertTimeout <= 0 {
+        alertTimeout = defaultAlertTimeout
+    }
+
+    alertQueueSize := config.AlertQueueSize
+    if alertQueueSize <= 0 {
+        alertQueueSize = defaultAlertQueueSize
     }
 
     monitor := &Monitor{
         config:        config,
         serviceStatus: make(map[string]*ServiceStatus),
-        httpClient:    &http.Client{},
+        httpClient:    &http.Client{Timeout: alertTimeout},
+        logger:        newRateLimitedLogger(time.Duration(config.LogRateLimit) * time.Second),
+        tokenSources:  make(map[string]oauth2.TokenSource),
+        clients:       make(map[string]*http.Client),
+        patterns:      make(map[string]patternSet),
+        location:      location,
+        monitors:      make(map[string]chan struct{}),
+        alertTimeout:  alertTimeout,
+        channels:      channelState{disabled: make(map[string]bool)},
+        forced:        forceCheckState{last: make(map[string]time.Time)},
+        inflight:      make(map[string]chan struct{}),
+        resolver:      newResolver(config.Resolver, 5*time.Second),
+        startTime:     time.Now(),
+        alertQueue:    make(chan alertJob, alertQueueSize),
+        senders:       make(map[string]AlertSender),
+        results:       resultCache{entries: make(map[string]cachedResult)},
+        groups:        make(map[string]*alertGroup),
+    }
+    monitor.ctx, monitor.cancel = context.WithCancel(context.Background())
+    if config.MaxConcurrentChecks > 0 {
+        monitor.checkSlots = make(chan struct{}, config.MaxConcurrentChecks)
     }
 
+    monitor.tracer, err = newTracer(config.OTel)
+    if err != nil {
+        return nil, err
+    }
+
+    if config.Alerts.File != nil && config.Alerts.File.Path != "" {
+        monitor.alertFile = &alertFile{config: *config.Alerts.File}
+    }
+    if config.Alerts.Syslog != nil {
+        monitor.syslog, err = newSyslogWriter(config.Alerts.Syslog)
+        if err != nil {
+            return nil, fmt.Errorf("error connecting to syslog: %v", err)
+        }
+    }
+    if config.Alerts.SNS != nil {
+        monitor.sns, err = newSNSPublisher(*config.Alerts.SNS)
+        if err != nil {
+            return nil, fmt.Errorf("error configuring SNS: %v", err)
+        }
+    }
+    if config.Alerts.NATS != nil {
+        monitor.nats, err = newNATSPublisher(*config.Alerts.NATS)
+        if err != nil {
+            return nil, fmt.Errorf("error connecting to NATS: %v", err)
+        }
+    }
+    monitor.registerBuiltinSenders()
+
     // Initialize service status
     for _, service := range config.Services {
-        monitor.serviceStatus[service.Name] = &ServiceStatus{
-            Name:      service.Name,
-            Status:    true,
-            LastCheck: time.Now(),
+        monitor.serviceStatus[service.Name] = monitor.newServiceStatus(service)
+    }
+
+    if config.StatePath != "" {
+        if err := monitor.loadState(); err != nil {
+            return nil, err
         }
     }
 
+    go monitor.runAlertWorker()
+
     return monitor, nil
 }
 
+func (m *Monitor) newServiceStatus(service ServiceConfig) *ServiceStatus {
+    return &ServiceStatus{
+        Name:      service.Name,
+        State:     StateUnknown,
+        LastCheck: time.Now(),
+        AddedAt:   time.Now(),
+        Latency:   newLatencyHistogram(m.config.LatencyBuckets),
+        History:   newCheckHistory(m.config.HistorySize),
+        Trend:     newLatencyTrend(m.config.HistoryMaxPoints),
+    }
+}
+
+// checkOutcome is the result of checking a service once, including retries
+type checkOutcome struct {
+    up            bool
+    err           error
+    statusCode    int
+    duration      time.Duration        // the whole check, including retries and retry delays
+    latency       time.Duration        // the final attempt alone
+    transportErr  bool                 // the last attempt failed without receiving a response
+    degraded      bool                 // the response code is mapped to degraded, or a certificate warning
+    bodyTruncated bool                 // the body exceeded max_body_bytes and was checked truncated
+    tls           *tls.ConnectionState
+    addressFamily string               // "ipv4" or "ipv6", of the connection the response came over
+    endpoints     []EndpointResult     // per-endpoint results of a quorum check
+}
+
 func (m *Monitor) checkService(service ServiceConfig) {
-    m.statusMutex.Lock()
-    status := m.serviceStatus[service.Name]
-    m.statusMutex.Unlock()
+    m.trackDNS(service)
+    outcome := m.cachedCheck(service)
+    if m.ctx.Err() != nil {
+        // Cancelled by Shutdown, so the failure says nothing about the service
+        return
+    }
+
+    if outcome.statusCode != 0 {
+        m.recordConnectionState(service.Name, outcome.tls, outcome.addressFamily)
+        m.recordBodyTruncated(service, outcome.bodyTruncated)
+    }
+    if len(service.Endpoints) > 0 {
+        m.recordEndpoints(service.Name, outcome.endpoints)
+    }
+
+    if outcome.up {
+        m.updateServiceStatus(service.Name, StateUp, "", outcome.statusCode, outcome.latency, outcome.duration)
+        return
+    }
+
+    if outcome.degraded {
+        m.updateServiceStatus(service.Name, StateDegraded, outcome.err.Error(), outcome.statusCode, outcome.latency, outcome.duration)
+        return
+    }
+
+    if outcome.transportErr && service.FailMode == FailModeOpen {
+        m.recordTransportError(service.Name, outcome.err.Error())
+        return
+    }
+
+    m.updateServiceStatus(service.Name, StateDown, outcome.err.Error(), outcome.statusCode, outcome.latency, outcome.duration)
+}
+
+// performCheck runs the HTTP check for a service without touching its
+// status. When diag is set, diagnostics are collected for a one-off probe.
+func (m *Monitor) performCheck(service ServiceConfig, client *http.Client, diag *probeDiagnostics) (outcome checkOutcome) {
+    if len(service.Endpoints) > 0 && diag == nil {
+        return m.performQuorumCheck(service, client)
+    }
 
     startTime := time.Now()
-    client := &http.Client{
-        Timeout: time.Duration(service.Timeout) * time.Second,
+    // A probe's patterns are caller-supplied, so they are not kept
+    var patterns patternSet
+    if diag == nil {
+        patterns = m.servicePatterns(service)
+    }
+
+    ctx, span := m.tracer.Start(m.ctx, "check "+service.Name, trace.WithAttributes(
+        attribute.String("service.name", service.Name),
+        attribute.String("http.url", service.URL),
+    ))
+    defer func() { endCheckSpan(span, outcome) }()
+
+    // Transactions are built in, but need the monitor's HTTP clients
+    if service.Type == CheckTypeTransaction {
+        return m.performTypedCheck(ctx, service, m.checkTransaction)
+    }
+    if checker, ok := checkers[service.Type]; ok {
+        return m.performTypedCheck(ctx, service, checker)
     }
 
     // Create request
-    req, err := http.NewRequest(service.Method, service.URL, nil)
+    var family string
+    clientTrace := checkTrace(span)
+    clientTrace.GotConn = func(info httptrace.GotConnInfo) { family = addressFamily(info.Conn.RemoteAddr()) }
+    req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, clientTrace), service.Method, service.URL, nil)
     if err != nil {
-        m.updateServiceStatus(service.Name, false, err.Error(), time.Since(startTime))
-        return
+        return checkOutcome{err: err, duration: time.Since(startTime)}
     }
 
     // Add headers
     for key, value := range service.Headers {
         req.Header.Add(key, value)
     }
+    if service.Host != "" {
+        req.Host = service.Host
+    }
+
+    if service.OAuth2 != nil {
+        var token *oauth2.Token
+        if diag != nil {
+            // Probes must not share the token cache of a configured service
+            token, err = fetchOAuth2Token(newOAuth2TokenSource(service))
+        } else {
+            token, err = m.oauth2Token(service)
+        }
+        if err != nil {
+            return checkOutcome{err: err, duration: time.Since(startTime)}
+        }
+        token.SetAuthHeader(req)
+    }
 
     // Perform the request with retries
-    var lastErr error
     for attempt := 0; attempt < service.RetryAttempts; attempt++ {
+        attemptStart := time.Now()
         resp, err := client.Do(req)
         if err != nil {
-            lastErr = err
-            time.Sleep(time.Duration(service.RetryDelay) * time.Second)
+            // Connection, DNS and timeout errors never produced a response
+            outcome = checkOutcome{err: describeTransportError(err), transportErr: true, latency: time.Since(attemptStart)}
+            if service.DisableTransportRetry || !m.waitRetry(service, attempt) {
+                break
+            }
             continue
         }
 
-        if resp.StatusCode == service.ExpectedStatus {
-            m.updateServiceStatus(service.Name, true, "", time.Since(startTime))
-            return
+        if resp.StatusCode == http.StatusMethodNotAllowed && req.Method == http.MethodHead && !service.DisableHeadFallback {
+            // The server doesn't support HEAD; use GET for the rest of this check
+            resp.Body.Close()
+            req = req.Clone(req.Context())
+            req.Method = http.MethodGet
+            attempt--
+            continue
+        }
+
+        if diag != nil {
+            diag.observe(resp)
         }
 
-        lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+        outcome = checkOutcome{statusCode: resp.StatusCode, tls: resp.TLS, addressFamily: family}
+        outcome.bodyTruncated, err = m.validateResponse(service, resp, patterns)
         resp.Body.Close()
-        time.Sleep(time.Duration(service.RetryDelay) * time.Second)
+        outcome.latency = time.Since(attemptStart)
+        if err == nil {
+            // A degraded code is a definite answer, so it is not retried
+            if statusCodeState(service, resp.StatusCode) == CodeDegraded {
+                outcome.degraded = true
+                outcome.err = fmt.Errorf("degraded status code: %d", resp.StatusCode)
+            } else if certErr := certificateWarning(service, resp); certErr != nil {
+                outcome.degraded = true
+                outcome.err = certErr
+            } else {
+                outcome.up = true
+            }
+            outcome.duration = time.Since(startTime)
+            return outcome
+        }
+
+        outcome.err = err
+        if !retryableStatus(service, resp.StatusCode) {
+            // Failures like a 401 won't fix themselves within a check
+            break
+        }
+        if !m.waitRetry(service, attempt) {
+            break
+        }
     }
 
-    m.updateServiceStatus(service.Name, false, lastErr.Error(), time.Since(startTime))
+    outcome.duration = time.Since(startTime)
+    return outcome
 }
 
-func (m *Monitor) updateServiceStatus(serviceName string, status bool, errMsg string, responseTime time.Duration) {
+// recordTransportError notes a transport failure for a fail-open service
+// without changing its state or alerting
+func (m *Monitor) recordTransportError(serviceName, errMsg string) {
     m.statusMutex.Lock()
     defer m.statusMutex.Unlock()
 
-    serviceStatus := m.serviceStatus[serviceName]
-    prevStatus := serviceStatus.Status
+    serviceStatus, ok := m.serviceStatus[serviceName]
+    if !ok {
+        return
+    }
+    serviceStatus.LastCheck = time.Now()
+    serviceStatus.LastError = errMsg
+    serviceStatus.Errors.add(errMsg, serviceStatus.LastCheck)
+    m.logger.Printf("check:"+serviceName, "Check for %s failed open on transport error: %s", serviceName, errMsg)
+}
+
+// validateResponse applies the service's assertions to a response and
+// reports whether the body had to be truncated to be checked
+func (m *Monitor) validateResponse(service ServiceConfig, resp *http.Response, patterns patternSet) (truncated bool, err error) {
+    if statusCodeState(service, resp.StatusCode) == CodeDown {
+        return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+    }
+
+    if err := checkFinalURL(resp, service); err != nil {
+        return false, err
+    }
+
+    if err := checkRedirectCount(resp, service); err != nil {
+        return false, err
+    }
+
+    if err := checkContentType(resp, service); err != nil {
+        return false, err
+    }
+
+    if err := checkMinTLSVersion(resp, service.MinTLSVersion); err != nil {
+        return false, err
+    }
+
+    if err := checkProtocol(resp, service.ExpectedProtocol); err != nil {
+        return false, err
+    }
+
+    if service.CertValidation == CertValidationStrict {
+        if err := checkCertificate(resp, service.CertValidation); err != nil {
+            return false, err
+        }
+    }
+
+    if err := checkHeaders("header", resp.Header, service.ExpectedHeaders, service.HeaderMatchRegex, patterns); err != nil {
+        return false, err
+    }
+
+    // HEAD responses carry no body, so only status and headers are checked
+    if resp.Request.Method == http.MethodHead {
+        return false, nil
+    }
+
+    limit := m.maxBodyBytes(service)
+    service = bodyAssertionsFor(service, resp.StatusCode)
+    if readsBody(service) {
+        if truncated, err = validateBody(service, resp, limit, patterns); err != nil {
+            return truncated, err
+        }
+    }
+
+    if len(service.ExpectedTrailers) > 0 {
+        // Trailers are only populated once the body has been read to EOF
+        if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, limit)); err != nil {
+            return truncated, fmt.Errorf("error reading response body: %v", err)
+        }
+        return truncated, checkHeaders("trailer", resp.Trailer, service.ExpectedTrailers, service.HeaderMatchRegex, patterns)
+    }
+
+    return truncated, nil
+}
+
+func validateBody(service ServiceConfig, resp *http.Response, limit int64, patterns patternSet) (bool, error) {
+    // The body is read and decoded once and shared by all body assertions
+    body, truncated, err := readBody(resp, limit)
+    if err != nil {
+        return false, err
+    }
+
+    if service.RequireNonEmptyBody && len(body) == 0 {
+        return truncated, fmt.Errorf("response body is empty")
+    }
+    if service.MinBodyBytes > 0 && len(body) < service.MinBodyBytes {
+        return truncated, fmt.Errorf("response body is %d bytes, expected at least %d", len(body), service.MinBodyBytes)
+    }
+
+    if service.ExpectedBodySubstring != "" && !bytes.Contains(body, []byte(service.ExpectedBodySubstring)) {
+        return truncated, fmt.Errorf("response body does not contain %q", service.ExpectedBodySubstring)
+    }
+    if service.ExpectedBodyRegex != "" {
+        re, err := patterns.compile(service.ExpectedBodyRegex)
+        if err != nil {
+            return truncated, fmt.Errorf("invalid body pattern: %v", err)
+        }
+        if !re.Match(body) {
+            return truncated, fmt.Errorf("response body does not match %q", service.ExpectedBodyRegex)
+        }
+    }
+
+    if len(service.JSONChecks) > 0 {
+        return truncated, checkJSONAssertions(body, service.JSONChecks)
+    }
+
+    return truncated, nil
+}
+
+func readsBody(service ServiceConfig) bool {
+    return len(service.JSONChecks) > 0 || service.RequireNonEmptyBody || service.MinBodyBytes > 0 ||
+        service.ExpectedBodySubstring != "" || service.ExpectedBodyRegex != ""
+}
+
+// checkFinalURL compares the URL the redirect chain ended at
+func checkFinalURL(resp *http.Response, service ServiceConfig) error {
+    if service.ExpectedFinalURL == "" {
+        return nil
+    }
+
+    final := resp.Request.URL.String()
+    if service.FinalURLMatch == "prefix" {
+        if !strings.HasPrefix(final, service.ExpectedFinalURL) {
+            return fmt.Errorf("redirected to %s, expected a URL starting with %s", final, service.ExpectedFinalURL)
+        }
+        return nil
+    }
+
+    if final != service.ExpectedFinalURL {
+        return fmt.Errorf("redirected to %s, expected %s", final, service.ExpectedFinalURL)
+    }
+    return nil
+}
+
+// checkContentType compares the Content-Type header with the expected one.
+// Exact matches ignore case and parameter spacing, so "application/json;
+// charset=UTF-8" matches "application/json;charset=utf-8".
+func checkContentType(resp *http.Response, service ServiceConfig) error {
+    if service.ExpectedContentType == "" {
+        return nil
+    }
+
+    actual := resp.Header.Get("Content-Type")
+    if service.ContentTypeMatch == "prefix" {
+        if !strings.HasPrefix(strings.ToLower(actual), strings.ToLower(service.ExpectedContentType)) {
+            return fmt.Errorf("content type %q, expected one starting with %q", actual, service.ExpectedContentType)
+        }
+        return nil
+    }
+
+    if !strings.EqualFold(normalizeMediaType(actual), normalizeMediaType(service.ExpectedContentType)) {
+        return fmt.Errorf("content type %q, expected %q", actual, service.ExpectedContentType)
+    }
+    return nil
+}
+
+func normalizeMediaType(value string) string {
+    mediaType, params, err := mime.ParseMediaType(value)
+    if err != nil {
+        return strings.TrimSpace(value)
+    }
+    return mime.FormatMediaType(mediaType, params)
+}
+
+// checkHeaders validates response headers or trailers; kind names which in
+// errors
+func checkHeaders(kind string, header http.Header, expected map[string]string, useRegex bool, patterns patternSet) error {
+    for name, want := range expected {
+        values, ok := header[http.CanonicalHeaderKey(name)]
+        if !ok || len(values) == 0 {
+            return fmt.Errorf("missing %s %s", kind, name)
+        }
+
+        got := strings.Join(values, ", ")
+        if useRegex {
+            re, err := patterns.compile(want)
+            if err != nil {
+                return fmt.Errorf("invalid %s pattern for %s: %v", kind, name, err)
+            }
+            if !re.MatchString(got) {
+                return fmt.Errorf("%s %s value %q does not match %q", kind, name, got, want)
+            }
+            continue
+        }
+
+        if got != want {
+            return fmt.Errorf("%s %s: expected %q, got %q", kind, name, want, got)
+        }
+    }
+
+    return nil
+}
+
+// updateServiceStatus records a check result. responseTime is the latency of
+// the final attempt; checkDuration also covers retries and their delays.
+func (m *Monitor) updateServiceStatus(serviceName string, newState ServiceState, errMsg string, statusCode int, responseTime, checkDuration time.Duration) {
+    // Deferred first so the hooks run after the lock is released
+    defer m.runCheckHooks(serviceName)
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    // Deferred after the unlock, so it runs first with the new state
+    defer m.evaluateFleetHealth(time.Now())
+
+    serviceConfig := m.findService(serviceName)
+    serviceStatus, ok := m.serviceStatus[serviceName]
+    if !ok {
+        // Service was removed while its check was in flight
+        return
+    }
+    prevState := serviceStatus.State
+    status := newState == StateUp
     serviceStatus.Status = status
     serviceStatus.LastCheck = time.Now()
     serviceStatus.ResponseTime = responseTime
+    serviceStatus.CheckDuration = checkDuration
+    serviceStatus.LastStatusCode = statusCode
+    serviceStatus.Latency.observe(responseTime)
+    record := CheckRecord{
+        Time:    serviceStatus.LastCheck,
+        Up:      status,
+        Latency: responseTime,
+        Error:   errMsg,
+    }
+    serviceStatus.History.add(record)
+    serviceStatus.Trend.add(record)
+    m.checkLatencyDrift(serviceConfig, serviceStatus)
+    if errMsg != "" {
+        serviceStatus.Errors.add(errMsg, serviceStatus.LastCheck)
+    }
+
+    serviceStatus.State = newState
+    transitioned := prevState != StateUnknown && prevState != newState
+    flapping := m.updateFlapState(serviceConfig, serviceStatus, transitioned)
 
     if !status {
         serviceStatus.LastError = errMsg
         serviceStatus.FailureCount++
-        
-        if prevStatus && !serviceStatus.AlertSent {
-            // Service just went down, send alert
-            m.sendAlerts(serviceName, errMsg)
+        if serviceStatus.DownSince == nil {
+            downSince := time.Now()
+            serviceStatus.DownSince = &downSince
+            serviceStatus.IncidentError = errMsg
+        }
+        m.logger.Printf("check:"+serviceName, "Check failed for %s: %s", serviceName, errMsg)
+        m.escalatePriority(serviceConfig, serviceStatus, errMsg)
+
+        // Alert on the first confirmed-down check, including a service that
+        // was already down when the monitor started (prevState unknown).
+        // Flapping services get a single flapping alert instead. A degraded
+        // service that goes on to fail outright is alerted on again at its
+        // own severity.
+        escalated := serviceStatus.AlertSent && serviceStatus.AlertState == StateDegraded && newState == StateDown
+        if (!serviceStatus.AlertSent || escalated) && !flapping && !m.alertsHeld(serviceConfig, serviceStatus) {
+            alertConfig := serviceConfig
+            if newState == StateDegraded {
+                alertConfig = degradedAlertConfig(serviceConfig)
+            }
+            if serviceConfig.GroupKey != "" {
+                m.groupAlert(alertConfig, newState, errMsg)
+            } else {
+                prevAlertState, downSince := serviceStatus.AlertState, *serviceStatus.DownSince
+                m.dispatch(serviceName, func() {
+                    delivered := m.sendAlerts(alertConfig, errMsg)
+                    m.noteAlertOutcome(serviceName, downSince, delivered, prevAlertState)
+                })
+            }
             serviceStatus.AlertSent = true
+            serviceStatus.AlertState = newState
+        }
+
+        // FailureCount rises by one per failed check and resets on recovery,
+        // so each threshold fires once per outage. An acknowledged outage
+        // is not escalated further.
+        for _, threshold := range serviceConfig.FailureCountAlerts {
+            if threshold.Count != serviceStatus.FailureCount || flapping || serviceStatus.Acknowledged || m.alertsHeld(serviceConfig, serviceStatus) {
+                continue
+            }
+            channels := threshold.Channels
+            message := fmt.Sprintf("%s (%d consecutive failures)", errMsg, serviceStatus.FailureCount)
+            m.dispatch(serviceName, func() { m.sendAlertsTo(serviceConfig, message, channels) })
         }
-    } else if !prevStatus && status {
+        // Persisted on every failed check so last_seen stays current and a
+        // restart mid-outage resumes the incident instead of re-alerting
+        m.persistState()
+        return
+    }
+
+    if prevState == StateDown || prevState == StateDegraded {
         // Service recovered
         recoveryTime := time.Now()
         serviceStatus.RecoveryTime = &recoveryTime
         serviceStatus.FailureCount = 0
+        serviceStatus.Priority = ""
+        if serviceStatus.DownSince != nil {
+            m.recordIncident(Incident{
+                Service:  serviceName,
+                Start:    *serviceStatus.DownSince,
+                End:      recoveryTime,
+                Duration: recoveryTime.Sub(*serviceStatus.DownSince),
+                Error:    serviceStatus.IncidentError,
+            })
+        }
+        if serviceConfig.GroupKey != "" {
+            // The group resolves as one once all its members have recovered
+            m.groupRecovered(serviceConfig)
+        } else if serviceStatus.AlertSent && !flapping {
+            downtime := time.Duration(0)
+            if serviceStatus.DownSince != nil {
+                downtime = recoveryTime.Sub(*serviceStatus.DownSince)
+            }
+            m.dispatch(serviceName, func() { m.sendRecoveryAlert(serviceConfig, downtime) })
+        }
         serviceStatus.AlertSent = false
-        m.sendRecoveryAlert(serviceName)
+        serviceStatus.AlertState = ""
+        serviceStatus.AlertSuppressed = false
+        serviceStatus.Acknowledged = false
+        serviceStatus.AcknowledgedBy = ""
+        serviceStatus.DownSince = nil
+        serviceStatus.IncidentError = ""
+        m.persistState()
     }
 }
 
-func (m *Monitor) sendSlackAlert(service, message string) error {
-    payload := map[string]interface{}{
-        "text": fmt.Sprintf("🚨 *ALERT*: Service %s is DOWN!\nError: %s\nTime: %s",
-            service, message, time.Now().Format(time.RFC3339)),
+// noteAlertOutcome feeds a dispatched down alert's suppression back into
+// the outage it was sent for. A suppressed alert leaves the outage at its
+// previous alert state, so the next failed check tries again and recovery
+// is not announced for a page nobody received.
+func (m *Monitor) noteAlertOutcome(serviceName string, downSince time.Time, delivered bool, prevAlertState ServiceState) {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+
+    serviceStatus, ok := m.serviceStatus[serviceName]
+    if !ok || serviceStatus.DownSince == nil || !serviceStatus.DownSince.Equal(downSince) {
+        // Removed, or recovered while the alert was queued
+        return
+    }
+    serviceStatus.AlertSuppressed = !delivered
+    if !delivered {
+        serviceStatus.AlertSent = prevAlertState != ""
+        serviceStatus.AlertState = prevAlertState
+    }
+}
+
+// alertsHeld reports whether a service is still inside its warmup period or
+// the monitor's startup grace period. Failures are recorded but not alerted
+// on; a service still down afterwards alerts on its next failed check.
+func (m *Monitor) alertsHeld(service ServiceConfig, status *ServiceStatus) bool {
+    grace := time.Duration(m.config.StartupGracePeriod) * time.Second
+    if time.Since(m.startTime) < grace {
+        return true
+    }
+
+    warmup := time.Duration(service.WarmupSeconds) * time.Second
+    return time.Since(status.AddedAt) < warmup
+}
+
+// updateFlapState records a state transition, drops transitions that have
+// left the flap window and reports whether the service is flapping
+func (m *Monitor) updateFlapState(service ServiceConfig, status *ServiceStatus, transitioned bool) bool {
+    if service.FlapThreshold <= 0 {
+        return false
+    }
+
+    now := time.Now()
+    window := time.Duration(service.FlapWindow) * time.Second
+    if transitioned {
+        status.Transitions = append(status.Transitions, now)
+    }
+
+    cutoff := now.Add(-window)
+    expired := 0
+    for expired < len(status.Transitions) && status.Transitions[expired].Before(cutoff) {
+        expired++
+    }
+    status.Transitions = status.Transitions[expired:]
+
+    if !status.Flapping && len(status.Transitions) > service.FlapThreshold {
+        status.Flapping = true
+        transitions := len(status.Transitions)
+        if !m.alertsHeld(service, status) {
+            m.dispatch(service.Name, func() { m.sendFlappingAlert(service, transitions, window) })
+        }
+    } else if status.Flapping && len(status.Transitions) == 0 {
+        status.Flapping = false
+        log.Printf("Service %s is no longer flapping", service.Name)
+    }
+
+    return status.Flapping
+}
+
+// formatTime renders alert timestamps in the configured timezone
+func (m *Monitor) formatTime(t time.Time) string {
+    return t.In(m.location).Format(time.RFC3339)
+}
+
+func (m *Monitor) sendSlackAlert(ctx context.Context, webhookURL string, service ServiceConfig, message string, history HistorySummary) error {
+    text := fmt.Sprintf("🚨 *ALERT*: Service %s is DOWN!\nError: %s\nTime: %s",
+        service.Name, message, m.formatTime(time.Now()))
+    if history.Checks > 0 {
+        text += "\n" + history.String()
     }
 
+    payload := map[string]interface{}{"text": text}
+    // Interactive buttons need a Slack app, implied by a signing secret
+    interactive := m.config.Alerts.Slack.SigningSecret != ""
+    if interactive || len(serviceLinks(service)) > 0 {
+        payload["blocks"] = slackAlertBlocks(service, text, interactive)
+    }
+    return m.postSlackPayload(ctx, webhookURL, payload)
+}
+
+func (m *Monitor) postSlackMessage(ctx context.Context, webhookURL, text string) error {
+    return m.postSlackPayload(ctx, webhookURL, map[string]interface{}{"text": text})
+}
+
+func (m *Monitor) postSlackPayload(ctx context.Context, webhookURL string, payload map[string]interface{}) error {
     jsonPayload, err := json.Marshal(payload)
     if err != nil {
         return err
     }
 
-    resp, err := http.Post(m.config.Alerts.Slack.WebhookURL, "application/json", bytes.NewBuffer(jsonPayload))
+    req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(jsonPayload))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := m.httpClient.Do(req)
     if err != nil {
         return err
     }
     defer resp.Body.Close()
 
+    if resp.StatusCode >= 300 {
+        return &statusCodeError{name: "slack", code: resp.StatusCode}
+    }
     return nil
 }
 
-func (m *Monitor) sendPagerDutyAlert(service, message string) error {
-    incident := map[string]interface{}{
-        "service_key": m.config.Alerts.PagerDuty.ServiceKey,
-        "event_type":  "trigger",
-        "description": fmt.Sprintf("Service %s is DOWN - %s", service, message),
-        "details": map[string]interface{}{
-            "error":     message,
-            "timestamp": time.Now().Unix(),
+func (m *Monitor) sendEmailAlert(subject, body string) error {
+    emailConfig := m.config.Alerts.Email
+    addr := fmt.Sprintf("%s:%d", emailConfig.SMTPServer, emailConfig.SMTPPort)
+
+    var auth smtp.Auth
+    if emailConfig.Username != "" {
+        auth = smtp.PlainAuth("", emailConfig.Username, emailConfig.Password, emailConfig.SMTPServer)
+    }
+
+    msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+        emailConfig.Username, strings.Join(emailConfig.Recipients, ", "), subject, body)
+
+    return m.sendMail(addr, auth, emailConfig.Username, emailConfig.Recipients, []byte(msg))
+}
+
+// sendMail is smtp.SendMail with the whole exchange bounded by the alert
+// timeout, as net/smtp has no deadline of its own
+func (m *Monitor) sendMail(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+    conn, err := net.DialTimeout("tcp", addr, m.alertTimeout)
+    if err != nil {
+        return err
+    }
+    conn.SetDeadline(time.Now().Add(m.alertTimeout))
+
+    host, _, _ := net.SplitHostPort(addr)
+    client, err := smtp.NewClient(conn, host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer client.Close()
+
+    if ok, _ := client.Extension("STARTTLS"); ok {
+        if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+            return err
+        }
+    }
+    if auth != nil {
+        if err := client.Auth(auth); err != nil {
+            return err
+        }
+    }
+    if err := client.Mail(from); err != nil {
+        return err
+    }
+    for _, recipient := range to {
+        if err := client.Rcpt(recipient); err != nil {
+            return err
+        }
+    }
+
+    writer, err := client.Data()
+    if err != nil {
+        return err
+    }
+    if _, err := writer.Write(msg); err != nil {
+        return err
+    }
+    if err := writer.Close(); err != nil {
+        return err
+    }
+    return client.Quit()
+}
+
+// pagerDutySeverities maps our severities onto the PagerDuty v2 levels
+var pagerDutySeverities = map[string]string{
+    SeverityInfo:     "info",
+    SeverityWarning:  "warning",
+    SeverityCritical: "critical",
+}
+
+// pagerDutySeverity prefers an explicit pagerduty_severity, then the
+// outage's priority, then the service's severity
+func (m *Monitor) pagerDutySeverity(service ServiceConfig) string {
+    if service.PagerDutySeverity != "" {
+        return service.PagerDutySeverity
+    }
+    if priority := m.currentPriority(service); priority != "" {
+        return priorityLevels[priority]
+    }
+    return pagerDutySeverities[serviceSeverity(service)]
+}
+
+// pagerDutyRoutingKey is the key a service's incidents are sent with. The
+// same key must be used to resolve them.
+func (m *Monitor) pagerDutyRoutingKey(service ServiceConfig) string {
+    if service.PagerDutyRoutingKey != "" {
+        return service.PagerDutyRoutingKey
+    }
+    return m.config.Alerts.PagerDuty.ServiceKey
+}
+
+// pagerDutyDedupKey ties a trigger to its later resolve
+func pagerDutyDedupKey(service ServiceConfig) string {
+    return "monitor-alert/" + service.Name
+}
+
+func (m *Monitor) pagerDutyTriggerEvent(service ServiceConfig, message string) map[string]interface{} {
+    now := time.Now()
+    details := map[string]interface{}{
+        "error":     message,
+        "timestamp": now.Unix(),
+        "time":      m.formatTime(now),
+    }
+    for key, value := range service.Labels {
+        details["label."+key] = value
+    }
+    if priority := m.currentPriority(service); priority != "" {
+        details["priority"] = priority
+    }
+    for key, value := range service.PagerDutyDetails {
+        details[key] = value
+    }
+
+    event := map[string]interface{}{
+        "routing_key":  m.pagerDutyRoutingKey(service),
+        "event_action": "trigger",
+        "dedup_key":    pagerDutyDedupKey(service),
+        "payload": map[string]interface{}{
+            "summary":        truncateMessage(fmt.Sprintf("Service %s is DOWN - %s", service.Name, message), pagerDutySummaryLimit),
+            "source":         service.URL,
+            "severity":       m.pagerDutySeverity(service),
+            "timestamp":      now.Format(time.RFC3339),
+            "custom_details": details,
         },
     }
 
-    jsonPayload, err := json.Marshal(incident)
+    var links []map[string]string
+    for _, link := range serviceLinks(service) {
+        links = append(links, map[string]string{"href": link.url, "text": link.label})
+    }
+    if len(links) > 0 {
+        event["links"] = links
+    }
+    return event
+}
+
+func (m *Monitor) sendPagerDutyAlert(ctx context.Context, service ServiceConfig, message string) error {
+    return m.postPagerDutyEvent(ctx, m.pagerDutyTriggerEvent(service, message))
+}
+
+// resolvePagerDutyIncident closes the incident opened by sendPagerDutyAlert
+func (m *Monitor) resolvePagerDutyIncident(ctx context.Context, service ServiceConfig) error {
+    return m.postPagerDutyEvent(ctx, map[string]interface{}{
+        "routing_key":  m.pagerDutyRoutingKey(service),
+        "event_action": "resolve",
+        "dedup_key":    pagerDutyDedupKey(service),
+    })
+}
+
+func (m *Monitor) postPagerDutyEvent(ctx context.Context, event map[string]interface{}) error {
+    jsonPayload, err := json.Marshal(event)
     if err != nil {
         return err
     }
 
-    req, err := http.NewRequest("POST", "https://events.pagerduty.com/v2/enqueue",
+    req, err := http.NewRequestWithContext(ctx, "POST", "https://events.pagerduty.com/v2/enqueue",
         bytes.NewBuffer(jsonPayload))
     if err != nil {
         return err
@@ -226,102 +1242,328 @@ func (m *Monitor) sendPagerDutyAlert(service, message string) error {
     }
     defer resp.Body.Close()
 
+    if resp.StatusCode >= 300 {
+        return &statusCodeError{name: "pagerduty", code: resp.StatusCode}
+    }
     return nil
 }
 
-func (m *Monitor) sendRecoveryAlert(service string) {
-    status := m.serviceStatus[service]
-    duration := time.Since(*status.RecoveryTime)
+func (m *Monitor) sendRecoveryAlert(serviceConfig ServiceConfig, downtime time.Duration) {
+    service := serviceConfig.Name
+    if reason, ok := m.alertsSuppressed(service); ok {
+        log.Printf("Recovery notification for %s suppressed by active %s", service, reason)
+        return
+    }
 
     recoveryMsg := fmt.Sprintf("✅ Service %s has RECOVERED\nDowntime: %s\nTime: %s",
-        service, duration.Round(time.Second), time.Now().Format(time.RFC3339))
+        service, downtime.Round(time.Second), m.formatTime(time.Now()))
+    m.recordAlert(EventRecovery, serviceConfig, recoveryMsg)
 
-    // Send recovery notifications
-    if m.config.Alerts.Slack.WebhookURL != "" {
-        payload := map[string]interface{}{"text": recoveryMsg}
-        jsonPayload, _ := json.Marshal(payload)
-        http.Post(m.config.Alerts.Slack.WebhookURL, "application/json", bytes.NewBuffer(jsonPayload))
-    }
+    event := m.newAlertEvent(EventRecovery, serviceConfig, recoveryMsg)
+    event.Downtime = downtime
+    m.deliver(event, m.alertChannels(serviceConfig))
+}
 
-    // Resolve PagerDuty incident
-    if m.config.Alerts.PagerDuty.ServiceKey != "" {
-        incident := map[string]interface{}{
-            "service_key": m.config.Alerts.PagerDuty.ServiceKey,
-            "event_type":  "resolve",
-            "description": fmt.Sprintf("Service %s has recovered", service),
+// findService must be called with statusMutex held
+func (m *Monitor) findService(name string) ServiceConfig {
+    for _, s := range m.config.Services {
+        if s.Name == name {
+            return s
         }
-        jsonPayload, _ := json.Marshal(incident)
-        http.Post("https://events.pagerduty.com/v2/enqueue", "application/json", bytes.NewBuffer(jsonPayload))
     }
+    return ServiceConfig{Name: name}
 }
 
-func (m *Monitor) sendAlerts(service, message string) {
-    // Find service configuration
-    var serviceConfig ServiceConfig
-    for _, s := range m.config.Services {
-        if s.Name == service {
-            serviceConfig = s
-            break
-        }
+// serviceSeverity falls back to CriticalService for configs that predate
+// the explicit Severity field
+func serviceSeverity(service ServiceConfig) string {
+    if service.Severity != "" {
+        return service.Severity
+    }
+    if service.CriticalService {
+        return SeverityCritical
     }
+    return SeverityWarning
+}
 
-    // Send Slack alert
-    if m.config.Alerts.Slack.WebhookURL != "" {
-        if err := m.sendSlackAlert(service, message); err != nil {
-            log.Printf("Error sending Slack alert: %v", err)
-        }
+func (m *Monitor) alertChannels(service ServiceConfig) []string {
+    severity := serviceSeverity(service)
+    if channels, ok := m.timeRoutedChannels(severity, time.Now()); ok {
+        return channels
+    }
+    if channels, ok := m.config.Alerts.Routing[severity]; ok {
+        return channels
     }
+    return defaultRouting[severity]
+}
 
-    // Send PagerDuty alert for critical services
-    if serviceConfig.CriticalService && m.config.Alerts.PagerDuty.ServiceKey != "" {
-        if err := m.sendPagerDutyAlert(service, message); err != nil {
-            log.Printf("Error sending PagerDuty alert: %v", err)
-        }
+func (m *Monitor) sendAlerts(serviceConfig ServiceConfig, message string) bool {
+    return m.sendAlertsTo(serviceConfig, message, m.alertChannels(serviceConfig))
+}
+
+// sendAlertsTo delivers a down alert to the given channels. It reports false
+// when the alert was suppressed instead.
+func (m *Monitor) sendAlertsTo(serviceConfig ServiceConfig, message string, channels []string) bool {
+    service := serviceConfig.Name
+    if reason, ok := m.alertsSuppressed(service); ok {
+        m.logger.Printf("suppressed:"+service, "Alert for %s suppressed by active %s", service, reason)
+        return false
     }
+    // The alert is collateral damage while the shared upstream is down
+    if err := m.upstreamDown(serviceConfig); err != nil {
+        m.logger.Printf("suppressed:"+service, "Alert for %s suppressed, upstream %s is down: %v", service, serviceConfig.UpstreamHealthURL, err)
+        return false
+    }
+    m.recordAlert(EventAlert, serviceConfig, message)
+    m.deliver(m.newAlertEvent(EventAlert, serviceConfig, message), channels)
+    return true
+}
+
+// sendFlappingAlert notifies the routed chat channels once when a service
+// starts flapping. PagerDuty is skipped as flapping is not an outage.
+func (m *Monitor) sendFlappingAlert(service ServiceConfig, transitions int, window time.Duration) {
+    if reason, ok := m.alertsSuppressed(service.Name); ok {
+        log.Printf("Flapping alert for %s suppressed by active %s", service.Name, reason)
+        return
+    }
+
+    msg := fmt.Sprintf("⚠️ Service %s is FLAPPING\nState changes: %d in %s\nTime: %s",
+        service.Name, transitions, window, m.formatTime(time.Now()))
+    m.recordAlert(EventFlapping, service, msg)
+    m.deliver(m.newAlertEvent(EventFlapping, service, msg), m.alertChannels(service))
+}
+
+// supervise runs a background loop that returns once ctx is cancelled, so
+// Shutdown can wait for it
+func (m *Monitor) supervise(loop func()) {
+    m.supervisorWG.Add(1)
+    go func() {
+        defer m.supervisorWG.Done()
+        loop()
+    }()
 }
 
 func (m *Monitor) startMonitoring() {
     for _, service := range m.config.Services {
-        go func(s ServiceConfig) {
-            ticker := time.NewTicker(time.Duration(s.CheckInterval) * time.Second)
-            for {
-                m.checkService(s)
-                <-ticker.C
-            }
-        }(service)
+        m.startServiceMonitor(service)
     }
+    m.superviseMonitoring()
+    m.startFleetHealth()
+    m.startStatusExport()
+    m.startMaintenanceCalendar()
 }
 
-func (m *Monitor) startAPIServer() {
-    http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-        m.statusMutex.RLock()
-        defer m.statusMutex.RUnlock()
+func (m *Monitor) startServiceMonitor(s ServiceConfig) {
+    stop := make(chan struct{})
+    m.monitorMutex.Lock()
+    m.monitors[s.Name] = stop
+    m.monitorMutex.Unlock()
 
-        status := make(map[string]interface{})
-        for name, s := range m.serviceStatus {
-            status[name] = map[string]interface{}{
-                "status":        s.Status,
-                "last_check":    s.LastCheck,
-                "last_error":    s.LastError,
-                "failure_count": s.FailureCount,
-                "response_time": s.ResponseTime.String(),
+    go func() {
+        for {
+            // Intervals are measured from the start of each check, as with a
+            // ticker, but may stretch while the circuit breaker is open
+            start := time.Now()
+            active := m.inBusinessHours(s, start)
+            m.setPaused(s.Name, !active)
+            if active {
+                m.runCheck(s)
+            }
+            next := start.Add(m.effectiveInterval(s))
+            m.setNextCheck(s.Name, next)
+            timer := time.NewTimer(time.Until(next))
+            select {
+            case <-timer.C:
+            case <-stop:
+                timer.Stop()
+                return
             }
         }
+    }()
+}
 
-        json.NewEncoder(w).Encode(status)
-    })
+func (m *Monitor) setNextCheck(name string, next time.Time) {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    if status, ok := m.serviceStatus[name]; ok {
+        status.NextCheck = next
+    }
+}
+
+// runCheck performs a check under the concurrency semaphore. If a check for
+// the service is already running, it waits for that result instead of
+// checking the service twice. Nothing new starts once Shutdown has begun.
+func (m *Monitor) runCheck(s ServiceConfig) {
+    m.inflightMutex.Lock()
+    if m.draining {
+        m.inflightMutex.Unlock()
+        return
+    }
+    if done, ok := m.inflight[s.Name]; ok {
+        m.inflightMutex.Unlock()
+        <-done
+        return
+    }
+    done := make(chan struct{})
+    m.inflight[s.Name] = done
+    m.checkWG.Add(1)
+    m.inflightMutex.Unlock()
+
+    defer func() {
+        m.inflightMutex.Lock()
+        delete(m.inflight, s.Name)
+        m.inflightMutex.Unlock()
+        close(done)
+        m.checkWG.Done()
+    }()
+
+    if m.checkSlots != nil {
+        m.acquireCheckSlot()
+        defer func() { <-m.checkSlots }()
+    }
+
+    m.safeCheckService(s)
+}
 
-    log.Fatal(http.ListenAndServe(":8080", nil))
+// safeCheckService runs a single check, converting a panic into a recorded
+// check failure so the service's monitoring goroutine keeps running
+func (m *Monitor) safeCheckService(s ServiceConfig) {
+    defer func() {
+        if r := recover(); r != nil {
+            log.Printf("Panic while checking %s: %v", s.Name, r)
+            m.updateServiceStatus(s.Name, StateDown, fmt.Sprintf("check panicked: %v", r), 0, 0, 0)
+        }
+    }()
+
+    m.checkService(s)
+}
+
+func (m *Monitor) stopServiceMonitor(name string) {
+    m.monitorMutex.Lock()
+    defer m.monitorMutex.Unlock()
+
+    if stop, ok := m.monitors[name]; ok {
+        close(stop)
+        delete(m.monitors, name)
+    }
+}
+
+// statusEntry renders a service's status for the API and must be called
+// with statusMutex held
+func (m *Monitor) statusEntry(name string, s *ServiceStatus) map[string]interface{} {
+    entry := map[string]interface{}{
+        "status":             s.Status,
+        "state":              s.State,
+        "flapping":           s.Flapping,
+        "last_check":         s.LastCheck,
+        "next_check":         s.NextCheck,
+        "last_error":         s.LastError,
+        "failure_count":      s.FailureCount,
+        "priority":           outagePriority(m.findService(name), s),
+        "acknowledged":       s.Acknowledged,
+        "alert_suppressed":   s.AlertSuppressed,
+        "paused":             s.Paused,
+        "monitoring_stalled": s.MonitoringStalled,
+        "response_time":      s.ResponseTime.String(),
+        "latency_ms":         s.ResponseTime.Milliseconds(),
+        "check_duration_ms":  s.CheckDuration.Milliseconds(),
+        "status_code":        s.LastStatusCode,
+        "silenced":           m.isSilenced(name),
+        "tls_version":        s.TLSVersion,
+        "tls_cipher":         s.TLSCipher,
+        "address_family":     s.AddressFamily,
+        "body_truncated":     s.BodyTruncated,
+        "recent_errors":      s.Errors.breakdown(time.Now()),
+        "disabled_channels":  m.disabledChannels(m.findService(name)),
+    }
+    snoozeEntry(entry, s)
+    for _, link := range serviceLinks(m.findService(name)) {
+        entry[link.key] = link.url
+    }
+    if len(s.Endpoints) > 0 {
+        entry["endpoints"] = s.Endpoints
+    }
+    if len(s.ResolvedIPs) > 0 {
+        entry["resolved_ips"] = s.ResolvedIPs
+    }
+    if !s.DNSChangedAt.IsZero() {
+        entry["dns_changed_at"] = s.DNSChangedAt
+    }
+    if service := m.findService(name); service.CircuitBreaker != nil {
+        entry["circuit_breaker"] = m.breakerState(service, s)
+    }
+    if _, drift, ok := latencyDrift(m.findService(name), s.History); ok {
+        entry["latency_drift"] = s.LatencyDrift
+        entry["latency_drift_percent"] = drift
+    }
+    if latency, compliant, ok := sloStatus(m.findService(name), s.History); ok {
+        entry["slo_latency"] = latency.String()
+        entry["slo_compliant"] = compliant
+    }
+    return entry
+}
+
+func (m *Monitor) handleHealth(w http.ResponseWriter, r *http.Request) {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    status := make(map[string]interface{})
+    for name, s := range m.serviceStatus {
+        status[name] = m.statusEntry(name, s)
+    }
+
+    json.NewEncoder(w).Encode(status)
+}
+
+// apiRoutes lists every API endpoint; listeners serve all or a subset
+func (m *Monitor) apiRoutes() []apiRoute {
+    return []apiRoute{
+        {"/health", m.handleHealth},
+        {"/livez", handleLivez},
+        {"/metrics", m.handleMetrics},
+        {"/version", m.handleVersion},
+        {"/silence", m.requireAuth(m.handleSilence)},
+        {"/incidents", m.handleIncidents},
+        {"GET /alerts/deliveries", m.requireAuth(m.handleDeliveries)},
+        {"/history", m.handleHistory},
+        {"/summary", m.handleSummary},
+        {"POST /slack/interactions", m.handleSlackInteraction},
+        {"POST /check", m.requireAuth(m.handleForceCheck)},
+        {"POST /probe", m.requireAuth(m.handleProbe)},
+        {"GET /config", m.requireAuth(m.handleConfig)},
+        {"GET /debug/bundle", m.requireAuth(m.handleDebugBundle)},
+        {"POST /channels/{name}/disable", m.requireAuth(m.setChannelEnabled(false))},
+        {"POST /channels/{name}/enable", m.requireAuth(m.setChannelEnabled(true))},
+        {"POST /services", m.requireAuth(m.handleAddService)},
+        {"PUT /services/{name}", m.requireAuth(m.handleUpdateService)},
+        {"DELETE /services/{name}", m.requireAuth(m.handleDeleteService)},
+        {"POST /services/{name}/snooze", m.requireAuth(m.handleSnoozeService)},
+        {"DELETE /services/{name}/snooze", m.requireAuth(m.handleUnsnoozeService)},
+    }
 }
 
 func main() {
+    once := flag.Bool("once", false, "check every service once, report the result and exit non-zero on failure")
+    flag.Parse()
+    log.SetOutput(io.MultiWriter(os.Stderr, recentLogs))
+
     monitor, err := NewMonitor("monitor_config.json")
     if err != nil {
         log.Fatal(err)
     }
 
+    if *once {
+        if !monitor.runOnce() {
+            os.Exit(1)
+        }
+        return
+    }
+
     // Start monitoring routines
     monitor.startMonitoring()
+    monitor.reloadOnSignal()
+    monitor.shutdownOnSignal()
+    monitor.notifySystemd()
 
     // Start API server
     monitor.startAPIServer()
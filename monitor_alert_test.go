@@ -0,0 +1,979 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// recordingSender collects the events delivered to it
+type recordingSender struct {
+    mutex  sync.Mutex
+    events []AlertEvent
+}
+
+func (s *recordingSender) Send(ctx context.Context, event AlertEvent) error {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.events = append(s.events, event)
+    return nil
+}
+
+// kinds lists the kinds of the events received so far, oldest first
+func (s *recordingSender) kinds() []string {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    kinds := make([]string, len(s.events))
+    for i, event := range s.events {
+        kinds[i] = event.Kind
+    }
+    return kinds
+}
+
+// newTestMonitor builds a monitor whose alerts of every severity go to the
+// returned sender
+func newTestMonitor(t *testing.T, config MonitorConfig) (*Monitor, *recordingSender) {
+    t.Helper()
+    config.Alerts.Routing = map[string][]string{
+        SeverityInfo:     {"test"},
+        SeverityWarning:  {"test"},
+        SeverityCritical: {"test"},
+    }
+    m, err := NewMonitorFromConfig(config)
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    t.Cleanup(m.cancel)
+
+    sender := &recordingSender{}
+    m.RegisterSender("test", sender)
+    return m, sender
+}
+
+// testService is a minimal valid HTTP service checking url
+func testService(name, url string) ServiceConfig {
+    return ServiceConfig{
+        Name:           name,
+        URL:            url,
+        ExpectedStatus: http.StatusOK,
+        CheckInterval:  60,
+        RetryAttempts:  1,
+        Timeout:        5,
+    }
+}
+
+// statusServer answers every request with the status code it is set to
+type statusServer struct {
+    *httptest.Server
+    code atomic.Int32
+}
+
+func newStatusServer(t *testing.T, code int) *statusServer {
+    t.Helper()
+    s := &statusServer{}
+    s.code.Store(int32(code))
+    s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(int(s.code.Load()))
+    }))
+    t.Cleanup(s.Close)
+    return s
+}
+
+// checkAndFlush runs one check of the service and waits for the alerts it
+// queued to be delivered
+func checkAndFlush(m *Monitor, service ServiceConfig) {
+    m.checkService(service)
+    m.alertWG.Wait()
+}
+
+const testAPIToken = "test-token"
+
+// apiRequest serves one API request against every route, authenticated with
+// testAPIToken unless token is false
+func apiRequest(m *Monitor, method, path, body string, token bool) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(method, path, strings.NewReader(body))
+    if token {
+        req.Header.Set("Authorization", "Bearer "+testAPIToken)
+    }
+    recorder := httptest.NewRecorder()
+    m.listenerMux(ListenerConfig{}).ServeHTTP(recorder, req)
+    return recorder
+}
+
+func equalStrings(a, b []string) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
+
+func TestFirstCheckDown(t *testing.T) {
+    tests := []struct {
+        name  string
+        codes []int    // service response per check
+        want  []string // event kinds delivered
+    }{
+        {"down from the first check", []int{500}, []string{EventAlert}},
+        {"alerted once while down", []int{500, 500, 500}, []string{EventAlert}},
+        {"down from the first check then recovers", []int{500, 200}, []string{EventAlert, EventRecovery}},
+        {"up from the first check", []int{200, 200}, []string{}},
+        {"up then down", []int{200, 500}, []string{EventAlert}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusOK)
+            service := testService("api", server.URL)
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            for _, code := range tt.codes {
+                server.code.Store(int32(code))
+                checkAndFlush(m, service)
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestFirstCheckState(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    m.statusMutex.RLock()
+    initial := m.serviceStatus["api"].State
+    m.statusMutex.RUnlock()
+    if initial != StateUnknown {
+        t.Errorf("state before the first check = %s, want %s", initial, StateUnknown)
+    }
+
+    checkAndFlush(m, service)
+    m.statusMutex.RLock()
+    status := *m.serviceStatus["api"]
+    m.statusMutex.RUnlock()
+    if status.State != StateDown || !status.AlertSent || status.DownSince == nil {
+        t.Errorf("after a failed first check: state %s, AlertSent %v, DownSince %v", status.State, status.AlertSent, status.DownSince)
+    }
+}
+
+func TestSeverityRouting(t *testing.T) {
+    routing := map[string][]string{
+        SeverityInfo:     {ChannelSlack},
+        SeverityWarning:  {ChannelSlack, ChannelEmail},
+        SeverityCritical: {ChannelSlack, ChannelPagerDuty, ChannelEmail},
+    }
+    tests := []struct {
+        name     string
+        severity string
+        critical bool
+        want     map[string]int // channel -> alerts received
+    }{
+        {"info hits slack only", SeverityInfo, false, map[string]int{ChannelSlack: 1}},
+        {"warning", SeverityWarning, false, map[string]int{ChannelSlack: 1, ChannelEmail: 1}},
+        {"critical hits every channel", SeverityCritical, false, map[string]int{ChannelSlack: 1, ChannelPagerDuty: 1, ChannelEmail: 1}},
+        {"critical_service without a severity", "", true, map[string]int{ChannelSlack: 1, ChannelPagerDuty: 1, ChannelEmail: 1}},
+        {"no severity defaults to warning", "", false, map[string]int{ChannelSlack: 1, ChannelEmail: 1}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", server.URL)
+            service.Severity = tt.severity
+            service.CriticalService = tt.critical
+            m, err := NewMonitorFromConfig(MonitorConfig{Services: []ServiceConfig{service}, Alerts: AlertConfig{Routing: routing}})
+            if err != nil {
+                t.Fatalf("NewMonitorFromConfig: %v", err)
+            }
+            t.Cleanup(m.cancel)
+            senders := make(map[string]*recordingSender)
+            for _, channel := range []string{ChannelSlack, ChannelPagerDuty, ChannelEmail} {
+                senders[channel] = &recordingSender{}
+                m.RegisterSender(channel, senders[channel])
+            }
+
+            checkAndFlush(m, service)
+
+            for channel, sender := range senders {
+                if got := len(sender.kinds()); got != tt.want[channel] {
+                    t.Errorf("%s received %d alerts, want %d", channel, got, tt.want[channel])
+                }
+            }
+        })
+    }
+}
+
+func TestDefaultRouting(t *testing.T) {
+    m, err := NewMonitorFromConfig(MonitorConfig{})
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    defer m.cancel()
+
+    if got := m.alertChannels(ServiceConfig{Severity: SeverityInfo}); !equalStrings(got, []string{ChannelSlack}) {
+        t.Errorf("info channels = %v", got)
+    }
+    if got := m.alertChannels(ServiceConfig{CriticalService: true}); !equalStrings(got, []string{ChannelSlack, ChannelPagerDuty}) {
+        t.Errorf("critical channels = %v", got)
+    }
+}
+
+func TestExpectedHeaders(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("X-Version", "v2.4.1")
+        w.Header().Set("Cache-Control", "no-store")
+    }))
+    defer server.Close()
+
+    tests := []struct {
+        name     string
+        expected map[string]string
+        regex    bool
+        want     bool // check passes
+    }{
+        {"matching header", map[string]string{"X-Version": "v2.4.1"}, false, true},
+        {"header name is case-insensitive", map[string]string{"cache-control": "no-store"}, false, true},
+        {"mismatched value", map[string]string{"X-Version": "v2.4.0"}, false, false},
+        {"missing header", map[string]string{"X-Request-Id": "abc"}, false, false},
+        {"matching pattern", map[string]string{"X-Version": `^v2\.\d+\.\d+$`}, true, true},
+        {"mismatched pattern", map[string]string{"X-Version": `^v3\.`}, true, false},
+        {"missing header with a pattern", map[string]string{"X-Request-Id": ".*"}, true, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", server.URL)
+            service.ExpectedHeaders = tt.expected
+            service.HeaderMatchRegex = tt.regex
+            if err := validateServiceConfig(service); err != nil {
+                t.Fatalf("validateServiceConfig: %v", err)
+            }
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            if outcome := m.performCheck(service, m.serviceClient(service), nil); outcome.up != tt.want {
+                t.Errorf("check up = %v (%v), want %v", outcome.up, outcome.err, tt.want)
+            }
+        })
+    }
+}
+
+func TestExpectedTrailers(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Trailer", "Grpc-Status")
+        w.Write([]byte("streamed"))
+        w.Header().Set("Grpc-Status", "0")
+    }))
+    defer server.Close()
+
+    tests := []struct {
+        name     string
+        expected map[string]string
+        body     string // expected_body_substring, read before the trailers
+        regex    bool
+        want     bool // check passes
+    }{
+        {"matching trailer", map[string]string{"Grpc-Status": "0"}, "", false, true},
+        {"matching trailer after body check", map[string]string{"grpc-status": "0"}, "streamed", false, true},
+        {"mismatched trailer", map[string]string{"Grpc-Status": "14"}, "", false, false},
+        {"missing trailer", map[string]string{"Grpc-Message": "ok"}, "", false, false},
+        {"matching pattern", map[string]string{"Grpc-Status": `^0$`}, "", true, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", server.URL)
+            service.ExpectedTrailers = tt.expected
+            service.ExpectedBodySubstring = tt.body
+            service.HeaderMatchRegex = tt.regex
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            outcome := m.performCheck(service, m.serviceClient(service), nil)
+            if outcome.up != tt.want {
+                t.Errorf("check up = %v (%v), want %v", outcome.up, outcome.err, tt.want)
+            }
+            if !tt.want && (outcome.err == nil || !strings.Contains(outcome.err.Error(), "trailer")) {
+                t.Errorf("error %v, want it to name the trailer", outcome.err)
+            }
+        })
+    }
+}
+
+func TestFlappingAlert(t *testing.T) {
+    tests := []struct {
+        name      string
+        threshold int
+        codes     []int
+        want      []string
+    }{
+        {
+            name:      "oscillating past the threshold",
+            threshold: 3,
+            codes:     []int{500, 200, 500, 200, 500, 200, 500, 200},
+            want:      []string{EventAlert, EventRecovery, EventAlert, EventRecovery, EventFlapping},
+        },
+        {
+            name:      "below the threshold",
+            threshold: 5,
+            codes:     []int{500, 200, 500, 200},
+            want:      []string{EventAlert, EventRecovery, EventAlert, EventRecovery},
+        },
+        {
+            name:      "flap detection off",
+            threshold: 0,
+            codes:     []int{500, 200, 500, 200},
+            want:      []string{EventAlert, EventRecovery, EventAlert, EventRecovery},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusOK)
+            service := testService("api", server.URL)
+            service.FlapThreshold = tt.threshold
+            service.FlapWindow = 600
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            for _, code := range tt.codes {
+                server.code.Store(int32(code))
+                checkAndFlush(m, service)
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestLastStatusCode(t *testing.T) {
+    tests := []struct {
+        name  string
+        code  int
+        state ServiceState
+    }{
+        {"passing check", http.StatusOK, StateUp},
+        {"failing check", http.StatusServiceUnavailable, StateDown},
+        {"unexpected success code", http.StatusNoContent, StateDown},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, tt.code)
+            service := testService("api", server.URL)
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+            m.statusMutex.RLock()
+            status := *m.serviceStatus["api"]
+            m.statusMutex.RUnlock()
+            if status.LastStatusCode != tt.code || status.State != tt.state {
+                t.Errorf("status code %d, state %s; want %d, %s", status.LastStatusCode, status.State, tt.code, tt.state)
+            }
+        })
+    }
+}
+
+func TestLastStatusCodeWithoutResponse(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    service := testService("api", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    checkAndFlush(m, service)
+
+    server.Close()
+    checkAndFlush(m, service)
+    m.statusMutex.RLock()
+    code := m.serviceStatus["api"].LastStatusCode
+    m.statusMutex.RUnlock()
+    if code != 0 {
+        t.Errorf("status code after a connection error = %d, want 0", code)
+    }
+}
+
+func TestFailMode(t *testing.T) {
+    tests := []struct {
+        name      string
+        mode      string
+        connError bool
+        state     ServiceState
+        alerts    int
+    }{
+        {"closed on a connection error", FailModeClosed, true, StateDown, 1},
+        {"closed on a bad status", FailModeClosed, false, StateDown, 1},
+        {"open on a connection error", FailModeOpen, true, StateUnknown, 0},
+        {"open on a bad status", FailModeOpen, false, StateDown, 1},
+        {"default is closed", "", true, StateDown, 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", server.URL)
+            service.FailMode = tt.mode
+            if tt.connError {
+                server.Close()
+            }
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+            m.statusMutex.RLock()
+            status := *m.serviceStatus["api"]
+            m.statusMutex.RUnlock()
+            if status.State != tt.state {
+                t.Errorf("state = %s, want %s", status.State, tt.state)
+            }
+            if status.LastError == "" {
+                t.Error("the failure was not recorded")
+            }
+            if got := len(sender.kinds()); got != tt.alerts {
+                t.Errorf("delivered %d alerts, want %d", got, tt.alerts)
+            }
+        })
+    }
+}
+
+func TestAlertTimezone(t *testing.T) {
+    instant := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+    tests := []struct {
+        timezone string
+        want     string
+    }{
+        {"", "2026-01-15T12:00:00Z"},
+        {"UTC", "2026-01-15T12:00:00Z"},
+        {"Asia/Tokyo", "2026-01-15T21:00:00+09:00"},
+        {"America/New_York", "2026-01-15T07:00:00-05:00"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.timezone, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{Timezone: tt.timezone})
+            if got := m.formatTime(instant); got != tt.want {
+                t.Errorf("formatTime() = %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestAlertTimezoneInMessage(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, Timezone: "Asia/Tokyo"})
+
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+
+    sender.mutex.Lock()
+    defer sender.mutex.Unlock()
+    if len(sender.events) != 2 || !strings.Contains(sender.events[1].Message, "+09:00") {
+        t.Errorf("recovery message does not carry the configured zone: %+v", sender.events)
+    }
+}
+
+func TestInvalidTimezone(t *testing.T) {
+    if _, err := NewMonitorFromConfig(MonitorConfig{Timezone: "Mars/Olympus_Mons"}); err == nil {
+        t.Error("NewMonitorFromConfig accepted an unknown timezone")
+    }
+}
+
+func TestNonEmptyBody(t *testing.T) {
+    tests := []struct {
+        name     string
+        body     string
+        nonEmpty bool
+        minBytes int
+        want     bool
+    }{
+        {"empty body fails", "", true, 0, false},
+        {"normal body passes", `{"status": "ok"}`, true, 0, true},
+        {"empty body without the check", "", false, 0, true},
+        {"body below min_body_bytes", "ok", false, 10, false},
+        {"body at min_body_bytes", "0123456789", false, 10, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                w.Write([]byte(tt.body))
+            }))
+            defer server.Close()
+            service := testService("api", server.URL)
+            service.RequireNonEmptyBody = tt.nonEmpty
+            service.MinBodyBytes = tt.minBytes
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            if outcome := m.performCheck(service, m.serviceClient(service), nil); outcome.up != tt.want {
+                t.Errorf("check up = %v (%v), want %v", outcome.up, outcome.err, tt.want)
+            }
+        })
+    }
+}
+
+func TestPagerDutyPayload(t *testing.T) {
+    tests := []struct {
+        name     string
+        service  ServiceConfig
+        severity string
+        details  map[string]interface{}
+    }{
+        {
+            name:     "severity mapped from a critical service",
+            service:  ServiceConfig{Name: "api", Severity: SeverityCritical},
+            severity: "critical",
+        },
+        {
+            name:     "severity mapped from info",
+            service:  ServiceConfig{Name: "api", Severity: SeverityInfo},
+            severity: "info",
+        },
+        {
+            name:     "explicit pagerduty_severity wins",
+            service:  ServiceConfig{Name: "api", Severity: SeverityCritical, PagerDutySeverity: "error"},
+            severity: "error",
+        },
+        {
+            name: "labels and details merged into custom_details",
+            service: ServiceConfig{
+                Name:             "api",
+                Labels:           map[string]string{"team": "payments"},
+                PagerDutyDetails: map[string]string{"region": "eu-west-1", "error": "overridden"},
+            },
+            severity: "warning",
+            details:  map[string]interface{}{"label.team": "payments", "region": "eu-west-1", "error": "overridden"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{Alerts: AlertConfig{PagerDuty: PagerDutyConfig{ServiceKey: "routing-key"}}})
+            event := m.pagerDutyTriggerEvent(tt.service, "connection refused")
+
+            if event["routing_key"] != "routing-key" || event["event_action"] != "trigger" || event["dedup_key"] != "monitor-alert/api" {
+                t.Errorf("envelope %v", event)
+            }
+            payload := event["payload"].(map[string]interface{})
+            if payload["severity"] != tt.severity {
+                t.Errorf("severity = %v, want %s", payload["severity"], tt.severity)
+            }
+            details := payload["custom_details"].(map[string]interface{})
+            want := map[string]interface{}{"error": "connection refused"}
+            for key, value := range tt.details {
+                want[key] = value
+            }
+            for key, value := range want {
+                if details[key] != value {
+                    t.Errorf("custom_details[%s] = %v, want %v", key, details[key], value)
+                }
+            }
+        })
+    }
+}
+
+func TestWarmup(t *testing.T) {
+    tests := []struct {
+        name  string
+        added time.Duration // how long before the checks the service was added
+        codes []int
+        want  []string
+    }{
+        {"fails during warmup", 0, []int{500, 500}, []string{}},
+        {"fails after warmup", 2 * time.Minute, []int{500}, []string{EventAlert}},
+        {"recovers during warmup without a recovery notice", 0, []int{500, 200}, []string{}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusOK)
+            service := testService("api", server.URL)
+            service.WarmupSeconds = 60
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            m.statusMutex.Lock()
+            m.serviceStatus["api"].AddedAt = time.Now().Add(-tt.added)
+            m.statusMutex.Unlock()
+
+            for _, code := range tt.codes {
+                server.code.Store(int32(code))
+                checkAndFlush(m, service)
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestWarmupEndsDuringOutage(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    service.WarmupSeconds = 60
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    checkAndFlush(m, service)
+    m.statusMutex.Lock()
+    m.serviceStatus["api"].AddedAt = time.Now().Add(-2 * time.Minute)
+    m.statusMutex.Unlock()
+    checkAndFlush(m, service)
+
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Errorf("delivered %v, want the outage alerted once warmup ended", got)
+    }
+}
+
+func TestStartupGracePeriod(t *testing.T) {
+    tests := []struct {
+        name    string
+        started time.Duration // how long before the checks the monitor started
+        codes   []int
+        want    []string
+    }{
+        {"down inside the grace window", 0, []int{500, 500}, []string{}},
+        {"down after the grace window", 2 * time.Minute, []int{500}, []string{EventAlert}},
+        {"converged inside the grace window", 0, []int{500, 200}, []string{}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusOK)
+            service := testService("api", server.URL)
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, StartupGracePeriod: 60})
+            m.startTime = time.Now().Add(-tt.started)
+
+            for _, code := range tt.codes {
+                server.code.Store(int32(code))
+                checkAndFlush(m, service)
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestStartupGraceEndsDuringOutage(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, StartupGracePeriod: 60})
+
+    checkAndFlush(m, service)
+    m.startTime = time.Now().Add(-2 * time.Minute)
+    checkAndFlush(m, service)
+
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Errorf("delivered %v, want the persistent outage alerted after the grace window", got)
+    }
+}
+
+func TestNewMonitorFromConfig(t *testing.T) {
+    tests := []struct {
+        name    string
+        config  MonitorConfig
+        wantErr bool
+    }{
+        {"valid config", MonitorConfig{Services: []ServiceConfig{testService("api", "https://api.example.com/")}}, false},
+        {"duplicate service", MonitorConfig{Services: []ServiceConfig{testService("api", "https://a.example.com/"), testService("api", "https://b.example.com/")}}, true},
+        {"invalid service", MonitorConfig{Services: []ServiceConfig{{Name: "api", URL: "https://api.example.com/"}}}, true},
+        {"unknown timezone", MonitorConfig{Timezone: "Mars/Olympus_Mons"}, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, err := NewMonitorFromConfig(tt.config)
+            if tt.wantErr {
+                if err == nil {
+                    m.cancel()
+                    t.Fatal("NewMonitorFromConfig accepted the config")
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("NewMonitorFromConfig: %v", err)
+            }
+            defer m.cancel()
+
+            if m.configPath != "" {
+                t.Errorf("config path %q, want none", m.configPath)
+            }
+            if m.alertTimeout != defaultAlertTimeout {
+                t.Errorf("alert timeout %s, want the default %s", m.alertTimeout, defaultAlertTimeout)
+            }
+            if status, ok := m.serviceStatus["api"]; !ok || status.State != StateUnknown {
+                t.Errorf("status %+v, want api in state unknown", status)
+            }
+        })
+    }
+}
+
+func TestFailureCountAlerts(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    service.FailureCountAlerts = []FailureCountAlert{
+        {Count: 3, Channels: []string{ChannelSlack}},
+        {Count: 5, Channels: []string{ChannelEmail}},
+        {Count: 7, Channels: []string{ChannelEmail, ChannelPagerDuty}},
+    }
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    channels := map[string]*recordingSender{}
+    for _, channel := range []string{ChannelSlack, ChannelEmail, ChannelPagerDuty} {
+        channels[channel] = &recordingSender{}
+        m.RegisterSender(channel, channels[channel])
+    }
+    counts := func() [3]int {
+        var counts [3]int
+        for i, channel := range []string{ChannelSlack, ChannelEmail, ChannelPagerDuty} {
+            counts[i] = len(channels[channel].kinds())
+        }
+        return counts
+    }
+
+    tests := []struct {
+        codes []int
+        want  [3]int // alerts received so far by slack, email and pagerduty
+    }{
+        {[]int{500, 500}, [3]int{0, 0, 0}},
+        {[]int{500}, [3]int{1, 0, 0}},
+        {[]int{500, 500}, [3]int{1, 1, 0}},
+        {[]int{500, 500}, [3]int{1, 2, 1}},
+        {[]int{500, 500}, [3]int{1, 2, 1}},
+        // Recovery resets the count, so the thresholds fire again
+        {[]int{200, 500, 500, 500}, [3]int{2, 2, 1}},
+    }
+
+    failures := 0
+    for _, tt := range tests {
+        for _, code := range tt.codes {
+            server.code.Store(int32(code))
+            checkAndFlush(m, service)
+            if code == http.StatusOK {
+                failures = 0
+            } else {
+                failures++
+            }
+        }
+        if got := counts(); got != tt.want {
+            t.Errorf("at %d failures slack, email and pagerduty received %v, want %v", failures, got, tt.want)
+        }
+    }
+
+    if got := channels[ChannelSlack].events[0].Message; !strings.Contains(got, "3 consecutive failures") {
+        t.Errorf("threshold alert %q, want the failure count", got)
+    }
+}
+
+func TestHeadCheck(t *testing.T) {
+    tests := []struct {
+        name        string
+        allowHead   bool
+        wantBody    string // expected_body_substring of the service
+        disableGet  bool   // disable_head_fallback
+        wantMethods []string
+        wantUp      bool
+    }{
+        {"head skips body checks", true, "absent from any head response", false, []string{"HEAD"}, true},
+        {"get fallback on 405", false, "ok", false, []string{"HEAD", "GET"}, true},
+        {"get fallback checks the body", false, "missing", false, []string{"HEAD", "GET"}, false},
+        {"fallback disabled", false, "", true, []string{"HEAD"}, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var mutex sync.Mutex
+            var methods []string
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                mutex.Lock()
+                methods = append(methods, r.Method)
+                mutex.Unlock()
+                if r.Method == http.MethodHead && !tt.allowHead {
+                    w.WriteHeader(http.StatusMethodNotAllowed)
+                    return
+                }
+                w.Header().Set("X-Health", "ok")
+                w.Write([]byte("ok"))
+            }))
+            defer server.Close()
+
+            service := testService("api", server.URL)
+            service.Method = http.MethodHead
+            service.ExpectedBodySubstring = tt.wantBody
+            service.ExpectedHeaders = map[string]string{"X-Health": "ok"}
+            service.DisableHeadFallback = tt.disableGet
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            outcome := m.performCheck(service, m.serviceClient(service), nil)
+            if outcome.up != tt.wantUp {
+                t.Errorf("up %v (%v), want %v", outcome.up, outcome.err, tt.wantUp)
+            }
+            mutex.Lock()
+            defer mutex.Unlock()
+            if !equalStrings(methods, tt.wantMethods) {
+                t.Errorf("requests %v, want %v", methods, tt.wantMethods)
+            }
+        })
+    }
+}
+
+func TestNextCheck(t *testing.T) {
+    tests := []struct {
+        name     string
+        code     int
+        breaker  *CircuitBreakerConfig
+        interval time.Duration // expected gap from one check to the next
+    }{
+        {"check interval", http.StatusOK, nil, time.Second},
+        {"backed off by the breaker", http.StatusInternalServerError, &CircuitBreakerConfig{FailureThreshold: 1, MaxInterval: 2}, 2 * time.Second},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, tt.code)
+            service := testService("api", server.URL)
+            service.CheckInterval = 1
+            service.CircuitBreaker = tt.breaker
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            // nextCheck waits for the scheduler to publish a next check later than after
+            nextCheck := func(after time.Time) (lastCheck, next time.Time) {
+                t.Helper()
+                deadline := time.Now().Add(5 * time.Second)
+                for time.Now().Before(deadline) {
+                    var health map[string]struct {
+                        LastCheck time.Time `json:"last_check"`
+                        NextCheck time.Time `json:"next_check"`
+                    }
+                    json.Unmarshal(apiRequest(m, http.MethodGet, "/health", "", false).Body.Bytes(), &health)
+                    if status := health["api"]; status.NextCheck.After(after) {
+                        return status.LastCheck, status.NextCheck
+                    }
+                    time.Sleep(10 * time.Millisecond)
+                }
+                t.Fatal("next_check was not updated")
+                return
+            }
+
+            m.startServiceMonitor(service)
+            defer stopMonitors(m)
+
+            lastCheck, first := nextCheck(time.Time{})
+            if gap := first.Sub(lastCheck); gap <= 0 || gap > tt.interval {
+                t.Errorf("next check %s after the last, want up to %s", gap, tt.interval)
+            }
+            _, second := nextCheck(first)
+            if gap := second.Sub(first); gap < tt.interval-100*time.Millisecond || gap > tt.interval+100*time.Millisecond {
+                t.Errorf("next check moved on by %s, want %s", gap, tt.interval)
+            }
+        })
+    }
+}
+
+func TestResponseTimeExcludesRetries(t *testing.T) {
+    tests := []struct {
+        name         string
+        slowFailures int // slow failing responses before a fast success
+        wantDuration time.Duration
+    }{
+        {"first attempt succeeds", 0, 0},
+        {"fails slowly then succeeds", 1, 1300 * time.Millisecond},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var requests atomic.Int32
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                if int(requests.Add(1)) <= tt.slowFailures {
+                    time.Sleep(300 * time.Millisecond)
+                    w.WriteHeader(http.StatusServiceUnavailable)
+                }
+            }))
+            defer server.Close()
+            service := testService("api", server.URL)
+            service.RetryAttempts, service.RetryDelay = 2, 1
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+            var health map[string]struct {
+                LatencyMs       int64 `json:"latency_ms"`
+                CheckDurationMs int64 `json:"check_duration_ms"`
+            }
+            json.Unmarshal(apiRequest(m, http.MethodGet, "/health", "", false).Body.Bytes(), &health)
+            got := health["api"]
+            if got.LatencyMs >= 200 {
+                t.Errorf("latency_ms = %d, want only the fast successful request", got.LatencyMs)
+            }
+            if got.CheckDurationMs < tt.wantDuration.Milliseconds() || got.CheckDurationMs < got.LatencyMs {
+                t.Errorf("check_duration_ms = %d, want at least %d and the latency %d", got.CheckDurationMs, tt.wantDuration.Milliseconds(), got.LatencyMs)
+            }
+        })
+    }
+}
+
+func TestNoRetryDelayAfterLastAttempt(t *testing.T) {
+    backend := newStatusServer(t, http.StatusServiceUnavailable)
+    service := testService("api", backend.URL)
+    service.RetryAttempts, service.RetryDelay = 2, 1
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    start := time.Now()
+    outcome := m.performCheck(service, m.serviceClient(service), nil)
+    if elapsed := time.Since(start); elapsed < time.Second || elapsed >= 2*time.Second {
+        t.Errorf("two failed attempts took %s, want one retry delay of 1s", elapsed)
+    }
+    if outcome.up || outcome.statusCode != http.StatusServiceUnavailable {
+        t.Errorf("outcome %+v, want the 503", outcome)
+    }
+}
+
+func TestExpectedContentType(t *testing.T) {
+    tests := []struct {
+        name     string
+        header   string // Content-Type served
+        expected string
+        match    string
+        wantUp   bool
+    }{
+        {"exact match", "application/json; charset=utf-8", "application/json; charset=utf-8", "", true},
+        {"case and spacing ignored", "Application/JSON;charset=UTF-8", "application/json; charset=utf-8", "exact", true},
+        {"charset changed", "application/json; charset=iso-8859-1", "application/json; charset=utf-8", "", false},
+        {"charset missing", "application/json", "application/json; charset=utf-8", "", false},
+        {"different media type", "text/html; charset=utf-8", "application/json; charset=utf-8", "", false},
+        {"prefix match", "application/json; charset=utf-8", "application/json", "prefix", true},
+        {"prefix mismatch", "text/plain", "application/json", "prefix", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                w.Header().Set("Content-Type", tt.header)
+                w.Write([]byte(`{}`))
+            }))
+            defer server.Close()
+            service := testService("api", server.URL)
+            service.ExpectedContentType, service.ContentTypeMatch = tt.expected, tt.match
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+            m.statusMutex.RLock()
+            up, lastError := m.serviceStatus["api"].Status, m.serviceStatus["api"].LastError
+            m.statusMutex.RUnlock()
+            if up != tt.wantUp {
+                t.Errorf("up = %v (%s), want %v", up, lastError, tt.wantUp)
+            }
+            if !tt.wantUp && !strings.Contains(lastError, "content type") {
+                t.Errorf("error %q, want it to name the content type", lastError)
+            }
+        })
+    }
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+)
+
+const ChannelNATS = "nats"
+
+// defaultNATSSubject prefixes event subjects when subject is unset
+const defaultNATSSubject = "monitor.alerts"
+
+// NATSConfig publishes every alert event as JSON to a NATS subject for
+// downstream processing, alongside the routed notification channels
+type NATSConfig struct {
+    URL     string `json:"url"`     // e.g. "nats://nats:4222"
+    Subject string `json:"subject"` // events go to <subject>.<event>, e.g. monitor.alerts.recovery
+}
+
+// busEvent is the structured event published to the message bus
+type busEvent struct {
+    alertRecord
+    URL    string            `json:"url,omitempty"`
+    Labels map[string]string `json:"labels,omitempty"`
+}
+
+// natsPublisher publishes one message to a subject
+type natsPublisher interface {
+    Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// newNATSPublisher is set by alert_nats.go when built with the nats tag, so
+// the NATS client is only pulled in by builds that need it
+var newNATSPublisher func(config NATSConfig) (natsPublisher, error)
+
+func validateNATSConfig(config *NATSConfig) error {
+    if config == nil {
+        return nil
+    }
+    if config.URL == "" {
+        return fmt.Errorf("alerts.nats: url is required")
+    }
+    if newNATSPublisher == nil {
+        return fmt.Errorf("alerts.nats: NATS is not available in this build (build with -tags nats)")
+    }
+    return nil
+}
+
+func natsSubject(config NATSConfig, event string) string {
+    subject := config.Subject
+    if subject == "" {
+        subject = defaultNATSSubject
+    }
+    return subject + "." + event
+}
+
+// publishEvent sends an alert record to NATS with the service's URL and
+// labels attached
+func (m *Monitor) publishEvent(record alertRecord, service ServiceConfig) error {
+    data, err := json.Marshal(busEvent{alertRecord: record, URL: service.URL, Labels: service.Labels})
+    if err != nil {
+        return err
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), m.alertTimeout)
+    defer cancel()
+    return m.nats.Publish(ctx, natsSubject(*m.config.Alerts.NATS, record.Event), data)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// fakeNATS records what would have been published
+type fakeNATS struct {
+    mutex    sync.Mutex
+    url      string
+    subjects []string
+    events   []busEvent
+}
+
+func (f *fakeNATS) Publish(ctx context.Context, subject string, data []byte) error {
+    f.mutex.Lock()
+    defer f.mutex.Unlock()
+    var event busEvent
+    if err := json.Unmarshal(data, &event); err != nil {
+        return err
+    }
+    f.subjects = append(f.subjects, subject)
+    f.events = append(f.events, event)
+    return nil
+}
+
+// stubNATS swaps in publisher for the real NATS client for the test
+func stubNATS(t *testing.T, publisher func(config NATSConfig) (natsPublisher, error)) {
+    t.Helper()
+    previous := newNATSPublisher
+    newNATSPublisher = publisher
+    t.Cleanup(func() { newNATSPublisher = previous })
+}
+
+func TestNATSEvents(t *testing.T) {
+    tests := []struct {
+        name         string
+        subject      string
+        wantSubjects []string
+    }{
+        {"default subject", "", []string{"monitor.alerts.alert", "monitor.alerts.recovery"}},
+        {"configured subject", "ops.events", []string{"ops.events.alert", "ops.events.recovery"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            fake := &fakeNATS{}
+            stubNATS(t, func(config NATSConfig) (natsPublisher, error) {
+                fake.url = config.URL
+                return fake, nil
+            })
+
+            server := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", server.URL)
+            service.Labels = map[string]string{"team": "payments"}
+            config := MonitorConfig{Services: []ServiceConfig{service}}
+            config.Alerts.NATS = &NATSConfig{URL: "nats://nats:4222", Subject: tt.subject}
+            m, sender := newTestMonitor(t, config)
+
+            checkAndFlush(m, service)
+            server.code.Store(http.StatusOK)
+            checkAndFlush(m, service)
+
+            // Published alongside the routed channels, not instead of them
+            if got := sender.kinds(); !equalStrings(got, []string{EventAlert, EventRecovery}) {
+                t.Errorf("routed channel got %v, want the alert and recovery", got)
+            }
+            fake.mutex.Lock()
+            defer fake.mutex.Unlock()
+            if fake.url != "nats://nats:4222" {
+                t.Errorf("connected to %q", fake.url)
+            }
+            if !equalStrings(fake.subjects, tt.wantSubjects) {
+                t.Fatalf("published to %v, want %v", fake.subjects, tt.wantSubjects)
+            }
+            alert := fake.events[0]
+            if alert.Event != EventAlert || alert.Service != "api" || alert.Severity != SeverityWarning ||
+                alert.URL != server.URL || alert.Labels["team"] != "payments" || !strings.Contains(alert.Message, "500") {
+                t.Errorf("alert event %+v", alert)
+            }
+            if recovery := fake.events[1]; recovery.Event != EventRecovery || recovery.Service != "api" {
+                t.Errorf("recovery event %+v", recovery)
+            }
+        })
+    }
+}
+
+func TestNATSNotBuilt(t *testing.T) {
+    stubNATS(t, nil)
+    if err := validateNATSConfig(&NATSConfig{URL: "nats://nats:4222"}); err == nil || !strings.Contains(err.Error(), "-tags nats") {
+        t.Errorf("validateNATSConfig() = %v, want the build tag named", err)
+    }
+}
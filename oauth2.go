@@ -0,0 +1,55 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "time"
+
+    "golang.org/x/oauth2"
+    "golang.org/x/oauth2/clientcredentials"
+)
+
+type OAuth2Config struct {
+    TokenURL         string   `json:"token_url"`
+    ClientID         string   `json:"client_id"`
+    ClientSecret     string   `json:"client_secret"`
+    Scopes           []string `json:"scopes"`
+    ClientSecretFile string   `json:"client_secret_file"`
+}
+
+// newOAuth2TokenSource reuses its token until it is within oauth2's expiry
+// delta and then fetches a new one
+func newOAuth2TokenSource(service ServiceConfig) oauth2.TokenSource {
+    config := clientcredentials.Config{
+        ClientID:     service.OAuth2.ClientID,
+        ClientSecret: service.OAuth2.ClientSecret,
+        TokenURL:     service.OAuth2.TokenURL,
+        Scopes:       service.OAuth2.Scopes,
+    }
+    tokenClient := &http.Client{Timeout: time.Duration(service.Timeout) * time.Second}
+    ctx := context.WithValue(context.Background(), oauth2.HTTPClient, tokenClient)
+    return config.TokenSource(ctx)
+}
+
+// oauth2Token returns a cached token for the service, fetching a new one from
+// the token endpoint when the cached token is missing or close to expiry
+func (m *Monitor) oauth2Token(service ServiceConfig) (*oauth2.Token, error) {
+    m.tokenMutex.Lock()
+    source, ok := m.tokenSources[service.Name]
+    if !ok {
+        source = newOAuth2TokenSource(service)
+        m.tokenSources[service.Name] = source
+    }
+    m.tokenMutex.Unlock()
+
+    return fetchOAuth2Token(source)
+}
+
+func fetchOAuth2Token(source oauth2.TokenSource) (*oauth2.Token, error) {
+    token, err := source.Token()
+    if err != nil {
+        return nil, fmt.Errorf("error fetching oauth2 token: %v", err)
+    }
+    return token, nil
+}
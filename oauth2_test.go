@@ -0,0 +1,75 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+)
+
+func TestOAuth2TokenReuse(t *testing.T) {
+    tests := []struct {
+        name      string
+        expiresIn int   // lifetime of each issued token, in seconds
+        want      int32 // token requests over three checks
+    }{
+        {"token reused until near expiry", 3600, 1},
+        {"token within the expiry delta is refetched", 5, 3},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var issued atomic.Int32
+            tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                if id, secret, _ := r.BasicAuth(); id != "monitor" || secret != "s3cret" {
+                    w.WriteHeader(http.StatusUnauthorized)
+                    return
+                }
+                n := issued.Add(1)
+                w.Header().Set("Content-Type", "application/json")
+                fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d}`, n, tt.expiresIn)
+            }))
+            defer tokens.Close()
+
+            var authorized atomic.Int32
+            backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", issued.Load()) {
+                    w.WriteHeader(http.StatusUnauthorized)
+                    return
+                }
+                authorized.Add(1)
+            }))
+            defer backend.Close()
+
+            service := testService("api", backend.URL)
+            service.OAuth2 = &OAuth2Config{TokenURL: tokens.URL, ClientID: "monitor", ClientSecret: "s3cret"}
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            for i := 0; i < 3; i++ {
+                if outcome := m.performCheck(service, m.serviceClient(service), nil); !outcome.up {
+                    t.Fatalf("check %d failed: %v", i, outcome.err)
+                }
+            }
+            if got := issued.Load(); got != tt.want {
+                t.Errorf("token requests = %d, want %d", got, tt.want)
+            }
+            if got := authorized.Load(); got != 3 {
+                t.Errorf("authorized checks = %d, want 3", got)
+            }
+        })
+    }
+}
+
+func TestOAuth2TokenFailure(t *testing.T) {
+    tokens := newStatusServer(t, http.StatusUnauthorized)
+    backend := newStatusServer(t, http.StatusOK)
+    service := testService("api", backend.URL)
+    service.OAuth2 = &OAuth2Config{TokenURL: tokens.URL, ClientID: "monitor", ClientSecret: "wrong"}
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    outcome := m.performCheck(service, m.serviceClient(service), nil)
+    if outcome.up || outcome.err == nil {
+        t.Fatal("check passed without a token")
+    }
+}
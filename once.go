@@ -0,0 +1,113 @@
+package main
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// onceResult is one service's outcome in a one-shot pass
+type onceResult struct {
+    Name           string `json:"name"`
+    Up             bool   `json:"up"`
+    StatusCode     int    `json:"status_code,omitempty"`
+    ResponseTimeMs int64  `json:"response_time_ms"`
+    Error          string `json:"error,omitempty"`
+}
+
+// onceSummary is posted to result_webhook after a one-shot pass
+type onceSummary struct {
+    Passed   int          `json:"passed"`
+    Failed   int          `json:"failed"`
+    Success  bool         `json:"success"` // the exit decision: true exits 0
+    Time     string       `json:"time"`
+    Services []onceResult `json:"services"`
+}
+
+// runOnce checks every service a single time without alerting, reports the
+// summary to the result webhook and returns whether all services passed.
+// It backs the --once flag used as a post-deploy health gate.
+func (m *Monitor) runOnce() bool {
+    results := make([]onceResult, len(m.config.Services))
+
+    var wg sync.WaitGroup
+    for i, service := range m.config.Services {
+        wg.Add(1)
+        go func(i int, s ServiceConfig) {
+            defer wg.Done()
+            outcome := m.cachedCheck(s)
+            results[i] = onceResult{
+                Name:           s.Name,
+                Up:             outcome.up,
+                StatusCode:     outcome.statusCode,
+                ResponseTimeMs: outcome.latency.Milliseconds(),
+            }
+            if outcome.err != nil {
+                results[i].Error = outcome.err.Error()
+            }
+        }(i, service)
+    }
+    wg.Wait()
+
+    summary := onceSummary{Time: m.formatTime(time.Now()), Services: results}
+    for _, result := range results {
+        if result.Up {
+            summary.Passed++
+        } else {
+            summary.Failed++
+            log.Printf("Check failed for %s: %s", result.Name, result.Error)
+        }
+    }
+    summary.Success = summary.Failed == 0
+    log.Printf("One-shot check: %d passed, %d failed", summary.Passed, summary.Failed)
+
+    if m.config.ResultWebhook != "" {
+        if err := m.postResultWebhook(summary); err != nil {
+            log.Printf("Error posting result webhook: %v", err)
+        }
+    }
+
+    return summary.Success
+}
+
+func (m *Monitor) postResultWebhook(summary onceSummary) error {
+    payload, err := json.Marshal(summary)
+    if err != nil {
+        return err
+    }
+
+    req, err := http.NewRequest(http.MethodPost, m.config.ResultWebhook, bytes.NewBuffer(payload))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    if secret := m.config.ResultWebhookSecret; secret != "" {
+        req.Header.Set("X-Signature", webhookSignature(secret, payload))
+    }
+
+    resp, err := m.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        return fmt.Errorf("result webhook returned status %d", resp.StatusCode)
+    }
+    return nil
+}
+
+// webhookSignature is the GitHub-style "sha256=<hex>" HMAC of a webhook
+// body, letting the receiver authenticate the sender
+func webhookSignature(secret string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// newWebhookReceiver captures the body and signature of each post it receives
+func newWebhookReceiver(t *testing.T) (*httptest.Server, chan *http.Request, chan []byte) {
+    t.Helper()
+    requests := make(chan *http.Request, 1)
+    bodies := make(chan []byte, 1)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        requests <- r
+        bodies <- body
+    }))
+    t.Cleanup(server.Close)
+    return server, requests, bodies
+}
+
+func TestRunOnce(t *testing.T) {
+    healthy := newStatusServer(t, http.StatusOK)
+    broken := newStatusServer(t, http.StatusBadGateway)
+
+    tests := []struct {
+        name     string
+        services []ServiceConfig
+        passed   int
+        failed   int
+    }{
+        {"all healthy", []ServiceConfig{testService("web", healthy.URL), testService("api", healthy.URL)}, 2, 0},
+        {"mixed", []ServiceConfig{testService("web", healthy.URL), testService("api", broken.URL)}, 1, 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            receiver, requests, bodies := newWebhookReceiver(t)
+            m, sender := newTestMonitor(t, MonitorConfig{
+                Services:            tt.services,
+                ResultWebhook:       receiver.URL,
+                ResultWebhookSecret: "s3cret",
+            })
+
+            success := m.runOnce()
+
+            req, body := <-requests, <-bodies
+            var summary onceSummary
+            if err := json.Unmarshal(body, &summary); err != nil {
+                t.Fatal(err)
+            }
+            if summary.Passed != tt.passed || summary.Failed != tt.failed {
+                t.Errorf("summary %d passed, %d failed, want %d and %d", summary.Passed, summary.Failed, tt.passed, tt.failed)
+            }
+            if want := tt.failed == 0; summary.Success != want || success != want {
+                t.Errorf("success %v (returned %v), want %v", summary.Success, success, want)
+            }
+            if len(summary.Services) != len(tt.services) {
+                t.Fatalf("summary lists %d services, want %d", len(summary.Services), len(tt.services))
+            }
+            for i, result := range summary.Services {
+                service := tt.services[i]
+                if wantUp := service.URL == healthy.URL; result.Name != service.Name || result.Up != wantUp || (result.Error == "") != wantUp {
+                    t.Errorf("result %+v for %s", result, service.Name)
+                }
+            }
+            if got, want := req.Header.Get("X-Signature"), webhookSignature("s3cret", body); got != want {
+                t.Errorf("signature %q, want %q", got, want)
+            }
+            if got := sender.kinds(); len(got) > 0 {
+                t.Errorf("one-shot pass alerted %v", got)
+            }
+        })
+    }
+}
+
+func TestResultWebhookSignature(t *testing.T) {
+    tests := []struct {
+        name   string
+        secret string
+    }{
+        {"signed with the secret", "s3cret"},
+        {"unsigned without a secret", ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            receiver, requests, bodies := newWebhookReceiver(t)
+            healthy := newStatusServer(t, http.StatusOK)
+            m, _ := newTestMonitor(t, MonitorConfig{
+                Services:            []ServiceConfig{testService("web", healthy.URL)},
+                ResultWebhook:       receiver.URL,
+                ResultWebhookSecret: tt.secret,
+            })
+
+            m.runOnce()
+
+            req, body := <-requests, <-bodies
+            signature := req.Header.Get("X-Signature")
+            if tt.secret == "" {
+                if signature != "" {
+                    t.Errorf("unsigned post carries X-Signature %q", signature)
+                }
+                return
+            }
+            // Verified the way a GitHub-style receiver does
+            hexDigest, ok := strings.CutPrefix(signature, "sha256=")
+            if !ok {
+                t.Fatalf("X-Signature %q lacks the sha256= prefix", signature)
+            }
+            got, err := hex.DecodeString(hexDigest)
+            if err != nil {
+                t.Fatalf("X-Signature %q is not hex: %v", signature, err)
+            }
+            mac := hmac.New(sha256.New, []byte(tt.secret))
+            mac.Write(body)
+            if !hmac.Equal(got, mac.Sum(nil)) {
+                t.Errorf("X-Signature %q does not verify against the body", signature)
+            }
+            mac = hmac.New(sha256.New, []byte("wrong"))
+            mac.Write(body)
+            if hmac.Equal(got, mac.Sum(nil)) {
+                t.Error("X-Signature verifies with the wrong secret")
+            }
+        })
+    }
+}
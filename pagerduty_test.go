@@ -0,0 +1,164 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "sync"
+    "testing"
+)
+
+// redirectTransport sends every request to target, keeping its path
+type redirectTransport struct{ target *url.URL }
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    req = req.Clone(req.Context())
+    req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+    return http.DefaultTransport.RoundTrip(req)
+}
+
+// pagerDutyEvent is the part of an Events API v2 event the tests check
+type pagerDutyEvent struct {
+    RoutingKey  string `json:"routing_key"`
+    EventAction string `json:"event_action"`
+    DedupKey    string `json:"dedup_key"`
+    Payload     struct {
+        Summary       string                 `json:"summary"`
+        Severity      string                 `json:"severity"`
+        CustomDetails map[string]interface{} `json:"custom_details"`
+    } `json:"payload"`
+}
+
+// newPagerDutyMonitor routes every alert to a stub PagerDuty and returns the
+// events it receives
+func newPagerDutyMonitor(t *testing.T, services ...ServiceConfig) (*Monitor, func() []pagerDutyEvent) {
+    t.Helper()
+    var mutex sync.Mutex
+    var events []pagerDutyEvent
+    pagerDuty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var event pagerDutyEvent
+        json.NewDecoder(r.Body).Decode(&event)
+        mutex.Lock()
+        events = append(events, event)
+        mutex.Unlock()
+        w.WriteHeader(http.StatusAccepted)
+    }))
+    t.Cleanup(pagerDuty.Close)
+    target, _ := url.Parse(pagerDuty.URL)
+
+    m, err := NewMonitorFromConfig(MonitorConfig{
+        Services: services,
+        Alerts: AlertConfig{
+            PagerDuty: PagerDutyConfig{ServiceKey: "key"},
+            Routing: map[string][]string{
+                SeverityInfo:     {ChannelPagerDuty},
+                SeverityWarning:  {ChannelPagerDuty},
+                SeverityCritical: {ChannelPagerDuty},
+            },
+        },
+    })
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    t.Cleanup(m.cancel)
+    m.httpClient.Transport = redirectTransport{target}
+
+    return m, func() []pagerDutyEvent {
+        mutex.Lock()
+        defer mutex.Unlock()
+        return append([]pagerDutyEvent(nil), events...)
+    }
+}
+
+func TestPagerDutyRoutingKey(t *testing.T) {
+    tests := []struct {
+        name    string
+        key     string // service's pagerduty_routing_key
+        wantKey string
+    }{
+        {"service override", "payments-team-key", "payments-team-key"},
+        {"global key when unset", "", "global-key"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var mutex sync.Mutex
+            var events []pagerDutyEvent
+            pagerDuty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                var event pagerDutyEvent
+                json.NewDecoder(r.Body).Decode(&event)
+                mutex.Lock()
+                events = append(events, event)
+                mutex.Unlock()
+                w.WriteHeader(http.StatusAccepted)
+            }))
+            defer pagerDuty.Close()
+            target, _ := url.Parse(pagerDuty.URL)
+
+            backend := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("payments", backend.URL)
+            service.Severity = SeverityCritical
+            service.PagerDutyRoutingKey = tt.key
+            m, err := NewMonitorFromConfig(MonitorConfig{
+                Services: []ServiceConfig{service},
+                Alerts: AlertConfig{
+                    PagerDuty: PagerDutyConfig{ServiceKey: "global-key"},
+                    Routing:   map[string][]string{SeverityCritical: {ChannelPagerDuty}},
+                },
+            })
+            if err != nil {
+                t.Fatalf("NewMonitorFromConfig: %v", err)
+            }
+            defer m.cancel()
+            m.httpClient.Transport = redirectTransport{target}
+
+            checkAndFlush(m, service)
+            backend.code.Store(http.StatusOK)
+            checkAndFlush(m, service)
+
+            mutex.Lock()
+            defer mutex.Unlock()
+            if len(events) != 2 || events[0].EventAction != "trigger" || events[1].EventAction != "resolve" {
+                t.Fatalf("events %+v, want a trigger then a resolve", events)
+            }
+            for _, event := range events {
+                if event.RoutingKey != tt.wantKey || event.DedupKey != "monitor-alert/payments" {
+                    t.Errorf("%s sent with key %q and dedup %q, want %q and monitor-alert/payments",
+                        event.EventAction, event.RoutingKey, event.DedupKey, tt.wantKey)
+                }
+            }
+        })
+    }
+}
+
+func TestPagerDutySkipsUnrecorded(t *testing.T) {
+    tests := []struct {
+        name string
+        kind string
+        key  string // global service key
+    }{
+        {"flapping", EventFlapping, "key"},
+        {"no routing key", EventAlert, ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", "https://api.example.com/")
+            m, events := newPagerDutyMonitor(t, service)
+            m.config.Alerts.PagerDuty.ServiceKey = tt.key
+
+            m.deliver(m.newAlertEvent(tt.kind, service, "api is flapping"), []string{ChannelPagerDuty})
+            m.alertWG.Wait()
+
+            if got := events(); len(got) != 0 {
+                t.Errorf("PagerDuty received %+v, want nothing", got)
+            }
+            m.deliveries.mutex.Lock()
+            defer m.deliveries.mutex.Unlock()
+            if len(m.deliveries.records) != 0 || m.deliveries.sent[ChannelPagerDuty] != 0 || m.deliveries.failed[ChannelPagerDuty] != 0 {
+                t.Errorf("recorded %+v (sent %d), want no delivery", m.deliveries.records, m.deliveries.sent[ChannelPagerDuty])
+            }
+        })
+    }
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+)
+
+// patternSet holds a service's compiled body, header and trailer patterns
+// by pattern. A pattern missing from the set, or a nil set, is compiled on
+// use and not kept.
+type patternSet map[string]*regexp.Regexp
+
+func (p patternSet) compile(pattern string) (*regexp.Regexp, error) {
+    if re, ok := p[pattern]; ok {
+        return re, nil
+    }
+    return regexp.Compile(pattern)
+}
+
+// servicePatterns returns the compiled patterns of a configured service,
+// compiling them on its first check. They are dropped with the service's
+// client when it is updated or removed, so nothing outlives its config.
+func (m *Monitor) servicePatterns(service ServiceConfig) patternSet {
+    m.clientMutex.Lock()
+    defer m.clientMutex.Unlock()
+
+    if patterns, ok := m.patterns[service.Name]; ok {
+        return patterns
+    }
+    patterns := make(patternSet)
+    for _, pattern := range serviceRegexes(service) {
+        if re, err := regexp.Compile(pattern); err == nil {
+            patterns[pattern] = re
+        }
+    }
+    m.patterns[service.Name] = patterns
+    return patterns
+}
+
+// serviceRegexes lists the regular expressions among a service's assertions
+func serviceRegexes(service ServiceConfig) []string {
+    var patterns []string
+    if service.ExpectedBodyRegex != "" {
+        patterns = append(patterns, service.ExpectedBodyRegex)
+    }
+    for _, assertion := range service.StatusBodyChecks {
+        if assertion.ExpectedBodyRegex != "" {
+            patterns = append(patterns, assertion.ExpectedBodyRegex)
+        }
+    }
+    if service.HeaderMatchRegex {
+        for _, pattern := range service.ExpectedHeaders {
+            patterns = append(patterns, pattern)
+        }
+        for _, pattern := range service.ExpectedTrailers {
+            patterns = append(patterns, pattern)
+        }
+    }
+    return patterns
+}
+
+// validatePatterns compiles the service's body, header and trailer patterns
+// and checks its JSON paths, so a typo fails the load instead of every check
+func validatePatterns(service ServiceConfig) error {
+    if service.ExpectedBodyRegex != "" {
+        if _, err := regexp.Compile(service.ExpectedBodyRegex); err != nil {
+            return fmt.Errorf("service %s: invalid expected_body_regex: %v", service.Name, err)
+        }
+    }
+    for code, assertion := range service.StatusBodyChecks {
+        if assertion.ExpectedBodyRegex == "" {
+            continue
+        }
+        if _, err := regexp.Compile(assertion.ExpectedBodyRegex); err != nil {
+            return fmt.Errorf("service %s: invalid expected_body_regex for status %d: %v", service.Name, code, err)
+        }
+    }
+
+    if service.HeaderMatchRegex {
+        for name, pattern := range service.ExpectedHeaders {
+            if _, err := regexp.Compile(pattern); err != nil {
+                return fmt.Errorf("service %s: invalid pattern for header %s: %v", service.Name, name, err)
+            }
+        }
+        for name, pattern := range service.ExpectedTrailers {
+            if _, err := regexp.Compile(pattern); err != nil {
+                return fmt.Errorf("service %s: invalid pattern for trailer %s: %v", service.Name, name, err)
+            }
+        }
+    }
+
+    for _, check := range service.JSONChecks {
+        if err := validateJSONPath(check.Path); err != nil {
+            return fmt.Errorf("service %s: %v", service.Name, err)
+        }
+    }
+    return nil
+}
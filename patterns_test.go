@@ -0,0 +1,102 @@
+package main
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestValidatePatterns(t *testing.T) {
+    tests := []struct {
+        name    string
+        modify  func(*ServiceConfig)
+        wantErr bool
+    }{
+        {"valid body regex", func(s *ServiceConfig) { s.ExpectedBodyRegex = `"status":\s*"ok"` }, false},
+        {"invalid body regex", func(s *ServiceConfig) { s.ExpectedBodyRegex = `"status":(` }, true},
+        {"invalid status body regex", func(s *ServiceConfig) {
+            s.StatusBodyChecks = map[int]BodyAssertion{503: {ExpectedBodyRegex: "[maintenance"}}
+        }, true},
+        {"invalid header pattern", func(s *ServiceConfig) {
+            s.HeaderMatchRegex = true
+            s.ExpectedHeaders = map[string]string{"X-Version": "v(2"}
+        }, true},
+        {"literal header value is not a pattern", func(s *ServiceConfig) {
+            s.ExpectedHeaders = map[string]string{"X-Version": "v(2"}
+        }, false},
+        {"invalid trailer pattern", func(s *ServiceConfig) {
+            s.HeaderMatchRegex = true
+            s.ExpectedTrailers = map[string]string{"X-Checksum": "*"}
+        }, true},
+        {"valid json path", func(s *ServiceConfig) { s.JSONChecks = []JSONCheck{{Path: "$.checks[0].status"}} }, false},
+        {"unbalanced json path", func(s *ServiceConfig) { s.JSONChecks = []JSONCheck{{Path: "$.checks[0.status"}} }, true},
+        {"empty json path segment", func(s *ServiceConfig) { s.JSONChecks = []JSONCheck{{Path: "checks..status"}} }, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", "https://api.example.com/health")
+            tt.modify(&service)
+            if err := validateServiceConfig(service); (err != nil) != tt.wantErr {
+                t.Errorf("validateServiceConfig() = %v, want error %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestServicePatterns(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("X-Version", "v2")
+        io.WriteString(w, `{"status":"ok-42"}`)
+    }))
+    defer server.Close()
+    service := testService("api", server.URL)
+    service.ExpectedBodyRegex = `ok-\d+`
+    service.HeaderMatchRegex = true
+    service.ExpectedHeaders = map[string]string{"X-Version": `^v\d+$`}
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, APIToken: testAPIToken})
+    cached := func(name string) (patternSet, bool) {
+        m.clientMutex.Lock()
+        defer m.clientMutex.Unlock()
+        patterns, ok := m.patterns[name]
+        return patterns, ok
+    }
+
+    checkAndFlush(m, service)
+    first, ok := cached("api")
+    if !ok || len(first) != 2 || m.serviceStatus["api"].State != StateUp {
+        t.Fatalf("patterns %v after a passing check, want the body and header patterns", first)
+    }
+    checkAndFlush(m, service)
+    if again, _ := cached("api"); again[`ok-\d+`] != first[`ok-\d+`] {
+        t.Error("second check recompiled the body pattern")
+    }
+
+    // A probe's patterns are compiled for it alone
+    body := `{"name": "adhoc", "url": "` + server.URL + `", "expected_status": 200, "expected_body_regex": "status.+ok"}`
+    if recorder := apiRequest(m, http.MethodPost, "/probe", body, true); recorder.Code != http.StatusOK ||
+        !strings.Contains(recorder.Body.String(), `"up":true`) {
+        t.Fatalf("probe returned %d: %s", recorder.Code, recorder.Body)
+    }
+    if _, ok := cached("adhoc"); ok {
+        t.Error("probe patterns were kept")
+    }
+
+    updated := service
+    updated.ExpectedBodyRegex = `"status":"ok`
+    m.updateService(updated)
+    if _, ok := cached("api"); ok {
+        t.Error("patterns kept across an update")
+    }
+    checkAndFlush(m, updated)
+    if patterns, _ := cached("api"); patterns[`"status":"ok`] == nil || patterns[`ok-\d+`] != nil {
+        t.Errorf("patterns %v after the update, want only the new body pattern and the header", patterns)
+    }
+
+    m.removeService("api")
+    if _, ok := cached("api"); ok {
+        t.Error("patterns kept for a removed service")
+    }
+}
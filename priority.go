@@ -0,0 +1,88 @@
+package main
+
+import (
+    "fmt"
+    "time"
+)
+
+// priorityLevels are the P1 (most urgent) to P5 priorities of incident
+// tools, mapped onto the PagerDuty v2 severities
+var priorityLevels = map[string]string{
+    "P1": "critical",
+    "P2": "error",
+    "P3": "warning",
+    "P4": "info",
+    "P5": "info",
+}
+
+// PriorityEscalation raises a service's priority once it has been down for
+// After seconds
+type PriorityEscalation struct {
+    After    int    `json:"after"` // in seconds
+    Priority string `json:"priority"`
+}
+
+// servicePriority is the most urgent of the service's base priority and
+// the escalations its downtime has reached; "" if no priority is set.
+// Priorities compare as strings, "P1" sorting first.
+func servicePriority(service ServiceConfig, downtime time.Duration) string {
+    priority := service.Priority
+    for _, step := range service.PriorityEscalation {
+        if downtime < time.Duration(step.After)*time.Second {
+            continue
+        }
+        if priority == "" || step.Priority < priority {
+            priority = step.Priority
+        }
+    }
+    return priority
+}
+
+// currentPriority is the priority of the service's current outage, or its
+// base priority while it is up
+func (m *Monitor) currentPriority(service ServiceConfig) string {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+    return outagePriority(service, m.serviceStatus[service.Name])
+}
+
+func outagePriority(service ServiceConfig, status *ServiceStatus) string {
+    if status != nil && status.Priority != "" {
+        return status.Priority
+    }
+    return service.Priority
+}
+
+// escalatePriority re-pages PagerDuty when the outage has lasted long
+// enough to raise its priority. It must be called with statusMutex held.
+func (m *Monitor) escalatePriority(service ServiceConfig, status *ServiceStatus, message string) {
+    if status.DownSince == nil {
+        return
+    }
+    downtime := time.Since(*status.DownSince)
+    priority := servicePriority(service, downtime)
+    previous := status.Priority
+    status.Priority = priority
+    raised := priority != "" && (previous == "" || priority < previous)
+    if !raised || !status.AlertSent || status.Acknowledged || m.alertsHeld(service, status) {
+        return
+    }
+
+    message = fmt.Sprintf("%s (escalated to %s after %s down)", message, priority, downtime.Round(time.Second))
+    m.dispatch(service.Name, func() { m.sendAlertsTo(service, message, []string{ChannelPagerDuty}) })
+}
+
+func validatePriority(service ServiceConfig) error {
+    if service.Priority != "" && priorityLevels[service.Priority] == "" {
+        return fmt.Errorf("service %s: priority must be one of P1 to P5, got %q", service.Name, service.Priority)
+    }
+    for _, step := range service.PriorityEscalation {
+        if step.After <= 0 {
+            return fmt.Errorf("service %s: priority_escalation after must be positive", service.Name)
+        }
+        if priorityLevels[step.Priority] == "" {
+            return fmt.Errorf("service %s: priority_escalation priority must be one of P1 to P5, got %q", service.Name, step.Priority)
+        }
+    }
+    return nil
+}
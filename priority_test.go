@@ -0,0 +1,128 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestPagerDutyPriority(t *testing.T) {
+    tests := []struct {
+        name         string
+        priority     string
+        severity     string // service's severity
+        override     string // service's pagerduty_severity
+        wantSeverity string
+        wantPriority string // custom_details priority, "" for none
+    }{
+        {"P1 is critical", "P1", SeverityWarning, "", "critical", "P1"},
+        {"P2 is error", "P2", SeverityCritical, "", "error", "P2"},
+        {"P3 is warning", "P3", "", "", "warning", "P3"},
+        {"P5 is info", "P5", SeverityCritical, "", "info", "P5"},
+        {"severity without a priority", "", SeverityCritical, "", "critical", ""},
+        {"explicit pagerduty_severity wins", "P1", SeverityCritical, "info", "info", "P1"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("payments", backend.URL)
+            service.Priority, service.Severity, service.PagerDutySeverity = tt.priority, tt.severity, tt.override
+            m, events := newPagerDutyMonitor(t, service)
+
+            checkAndFlush(m, service)
+
+            got := events()
+            if len(got) != 1 || got[0].EventAction != "trigger" {
+                t.Fatalf("events %+v, want one trigger", got)
+            }
+            if got[0].Payload.Severity != tt.wantSeverity {
+                t.Errorf("severity %q, want %q", got[0].Payload.Severity, tt.wantSeverity)
+            }
+            priority, _ := got[0].Payload.CustomDetails["priority"].(string)
+            if priority != tt.wantPriority {
+                t.Errorf("priority detail %q, want %q", priority, tt.wantPriority)
+            }
+        })
+    }
+}
+
+func TestPriorityEscalation(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("payments", backend.URL)
+    service.Priority = "P3"
+    service.PriorityEscalation = []PriorityEscalation{{After: 600, Priority: "P2"}, {After: 1800, Priority: "P1"}}
+    m, events := newPagerDutyMonitor(t, service)
+
+    backdate := func(d time.Duration) {
+        m.statusMutex.Lock()
+        defer m.statusMutex.Unlock()
+        downSince := m.serviceStatus["payments"].DownSince.Add(-d)
+        m.serviceStatus["payments"].DownSince = &downSince
+    }
+
+    steps := []struct {
+        name         string
+        down         time.Duration // added to the outage before the check
+        wantSeverity string        // of the newest trigger
+        wantPriority string
+        wantEvents   int
+    }{
+        {"first page at the base priority", 0, "warning", "P3", 1},
+        {"below the first escalation", 5 * time.Minute, "warning", "P3", 1},
+        {"escalated to P2", 10 * time.Minute, "error", "P2", 2},
+        {"no re-page at the same priority", time.Minute, "error", "P2", 2},
+        {"escalated to P1", 30 * time.Minute, "critical", "P1", 3},
+    }
+    for _, step := range steps {
+        if step.down > 0 {
+            backdate(step.down)
+        }
+        checkAndFlush(m, service)
+
+        got := events()
+        if len(got) != step.wantEvents {
+            t.Fatalf("%s: %d events, want %d", step.name, len(got), step.wantEvents)
+        }
+        last := got[len(got)-1]
+        priority, _ := last.Payload.CustomDetails["priority"].(string)
+        if last.Payload.Severity != step.wantSeverity || priority != step.wantPriority {
+            t.Errorf("%s: severity %q priority %q, want %q %q",
+                step.name, last.Payload.Severity, priority, step.wantSeverity, step.wantPriority)
+        }
+        if len(got) > 1 && !strings.Contains(last.Payload.Summary, "escalated to "+step.wantPriority) {
+            t.Errorf("%s: summary %q does not name the escalation", step.name, last.Payload.Summary)
+        }
+    }
+
+    // A recovery resets the outage's priority back to the base
+    backend.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+    if got := m.currentPriority(service); got != "P3" {
+        t.Errorf("priority after recovery %q, want P3", got)
+    }
+}
+
+func TestValidatePriority(t *testing.T) {
+    tests := []struct {
+        name       string
+        priority   string
+        escalation []PriorityEscalation
+        wantErr    string
+    }{
+        {"unset", "", nil, ""},
+        {"valid with escalation", "P4", []PriorityEscalation{{After: 60, Priority: "P2"}}, ""},
+        {"unknown priority", "P0", nil, "priority must be one of P1 to P5"},
+        {"escalation after not positive", "P3", []PriorityEscalation{{After: 0, Priority: "P1"}}, "after must be positive"},
+        {"unknown escalation priority", "P3", []PriorityEscalation{{After: 60, Priority: "high"}}, "priority_escalation priority"},
+    }
+    for _, tt := range tests {
+        err := validatePriority(ServiceConfig{Name: "api", Priority: tt.priority, PriorityEscalation: tt.escalation})
+        if tt.wantErr == "" && err != nil {
+            t.Errorf("%s: unexpected error %v", tt.name, err)
+        } else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+            t.Errorf("%s: error %v, want %q", tt.name, err, tt.wantErr)
+        }
+    }
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+    "bytes"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+)
+
+// probeSnippetBytes is how much of the response body a probe returns
+const probeSnippetBytes = 1024
+
+// probeDiagnostics is the result of a one-off POST /probe check
+type probeDiagnostics struct {
+    Service           string            `json:"service"`
+    URL               string            `json:"url"`
+    Up                bool              `json:"up"`
+    Error             string            `json:"error,omitempty"`
+    StatusCode        int               `json:"status_code"`
+    LatencyMs         int64             `json:"latency_ms"`                    // the final attempt
+    CheckDurationMs   int64             `json:"check_duration_ms"`             // including retries
+    FinalURL          string            `json:"final_url,omitempty"`
+    Redirects         []string          `json:"redirects"`
+    Headers           map[string]string `json:"headers,omitempty"`
+    TLSVersion        string            `json:"tls_version,omitempty"`
+    TLSCipher         string            `json:"tls_cipher,omitempty"`
+    CertSubject       string            `json:"cert_subject,omitempty"`
+    CertExpiry        *time.Time        `json:"cert_expiry,omitempty"`
+    CertDaysRemaining *int              `json:"cert_days_remaining,omitempty"`
+    BodySnippet       string            `json:"body_snippet"`
+
+    maxBodyBytes int64
+}
+
+// observe captures diagnostics from a response. The body is buffered and
+// put back so the normal validation still sees it.
+func (d *probeDiagnostics) observe(resp *http.Response) {
+    d.StatusCode = resp.StatusCode
+    d.FinalURL = resp.Request.URL.String()
+
+    d.Headers = make(map[string]string)
+    for name := range resp.Header {
+        d.Headers[name] = resp.Header.Get(name)
+    }
+
+    d.TLSVersion, d.TLSCipher = "", ""
+    d.CertSubject, d.CertExpiry, d.CertDaysRemaining = "", nil, nil
+    if resp.TLS != nil {
+        d.TLSVersion = tlsVersionName(resp.TLS.Version)
+        d.TLSCipher = tls.CipherSuiteName(resp.TLS.CipherSuite)
+        if len(resp.TLS.PeerCertificates) > 0 {
+            cert := resp.TLS.PeerCertificates[0]
+            expiry := cert.NotAfter
+            days := int(time.Until(expiry).Hours() / 24)
+            d.CertSubject = cert.Subject.String()
+            d.CertExpiry = &expiry
+            d.CertDaysRemaining = &days
+        }
+    }
+
+    // One byte past the limit is kept so validation can still flag truncation
+    raw, _ := io.ReadAll(io.LimitReader(resp.Body, d.maxBodyBytes+1))
+    resp.Body.Close()
+    resp.Body = io.NopCloser(bytes.NewReader(raw))
+
+    decoded, _, err := readBody(&http.Response{Header: resp.Header, Body: io.NopCloser(bytes.NewReader(raw))}, d.maxBodyBytes)
+    if err != nil {
+        decoded = raw
+    }
+    if len(decoded) > probeSnippetBytes {
+        decoded = decoded[:probeSnippetBytes]
+    }
+    d.BodySnippet = string(decoded)
+}
+
+// handleProbe runs a single check of an inline service definition, or of a
+// configured service when only its name is given, without recording state
+func (m *Monitor) handleProbe(w http.ResponseWriter, r *http.Request) {
+    service, err := decodeServiceConfig(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    if service.URL == "" && service.Name != "" {
+        m.statusMutex.RLock()
+        index := m.serviceIndex(service.Name)
+        if index >= 0 {
+            service = m.config.Services[index]
+        }
+        m.statusMutex.RUnlock()
+        if index < 0 {
+            http.Error(w, fmt.Sprintf("service %q not found", service.Name), http.StatusNotFound)
+            return
+        }
+    }
+
+    // Scheduling fields are irrelevant to a probe
+    if service.Name == "" {
+        service.Name = "probe"
+    }
+    if service.RetryAttempts <= 0 {
+        service.RetryAttempts = 1
+    }
+    if service.CheckInterval <= 0 {
+        service.CheckInterval = 1
+    }
+    if service.Timeout <= 0 {
+        service.Timeout = 10
+    }
+    if err := validateServiceConfig(service); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    transport := newServiceTransport(service, m.resolver)
+    defer transport.CloseIdleConnections()
+
+    diag := &probeDiagnostics{
+        Service:      service.Name,
+        URL:          service.URL,
+        Redirects:    []string{},
+        maxBodyBytes: m.maxBodyBytes(service),
+    }
+    checkRedirect := redirectPolicy(service)
+    client := &http.Client{
+        Timeout:   time.Duration(service.Timeout) * time.Second,
+        Transport: transport,
+        CheckRedirect: func(req *http.Request, via []*http.Request) error {
+            diag.Redirects = append(diag.Redirects, req.URL.String())
+            return checkRedirect(req, via)
+        },
+    }
+
+    outcome := m.performCheck(service, client, diag)
+    diag.Up = outcome.up
+    diag.LatencyMs = outcome.latency.Milliseconds()
+    diag.CheckDurationMs = outcome.duration.Milliseconds()
+    if outcome.err != nil {
+        diag.Error = outcome.err.Error()
+    }
+
+    json.NewEncoder(w).Encode(diag)
+}
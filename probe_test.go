@@ -0,0 +1,83 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestProbe(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/old":
+            http.Redirect(w, r, "/health", http.StatusFound)
+        case "/health":
+            w.Header().Set("X-Build", "42")
+            fmt.Fprint(w, `{"status":"ok"}`)
+        default:
+            w.WriteHeader(http.StatusServiceUnavailable)
+            fmt.Fprint(w, "maintenance")
+        }
+    }))
+    defer server.Close()
+
+    tests := []struct {
+        name       string
+        path       string
+        wantUp     bool
+        wantStatus int
+        redirects  int
+        snippet    string
+    }{
+        {"healthy endpoint", "/old", true, http.StatusOK, 1, `{"status":"ok"}`},
+        {"unhealthy endpoint", "/down", false, http.StatusServiceUnavailable, 0, "maintenance"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, sender := newTestMonitor(t, MonitorConfig{APIToken: testAPIToken})
+            body := fmt.Sprintf(`{"name":"candidate","url":%q,"expected_status":200}`, server.URL+tt.path)
+
+            recorder := apiRequest(m, http.MethodPost, "/probe", body, true)
+            if recorder.Code != http.StatusOK {
+                t.Fatalf("probe returned %d: %s", recorder.Code, recorder.Body)
+            }
+            var diag probeDiagnostics
+            if err := json.Unmarshal(recorder.Body.Bytes(), &diag); err != nil {
+                t.Fatal(err)
+            }
+
+            if diag.Up != tt.wantUp || diag.StatusCode != tt.wantStatus {
+                t.Errorf("up %v with status %d, want up %v with %d", diag.Up, diag.StatusCode, tt.wantUp, tt.wantStatus)
+            }
+            if tt.wantUp == (diag.Error != "") {
+                t.Errorf("error %q for up %v", diag.Error, diag.Up)
+            }
+            if len(diag.Redirects) != tt.redirects {
+                t.Errorf("redirects %v, want %d", diag.Redirects, tt.redirects)
+            }
+            if diag.BodySnippet != tt.snippet {
+                t.Errorf("body snippet %q, want %q", diag.BodySnippet, tt.snippet)
+            }
+            if tt.wantUp && (diag.FinalURL != server.URL+"/health" || diag.Headers["X-Build"] != "42") {
+                t.Errorf("final url %q, headers %v", diag.FinalURL, diag.Headers)
+            }
+
+            m.statusMutex.RLock()
+            _, recorded := m.serviceStatus["candidate"]
+            m.statusMutex.RUnlock()
+            if recorded || len(sender.kinds()) > 0 {
+                t.Errorf("probe persisted state %v or alerted %v", recorded, sender.kinds())
+            }
+        })
+    }
+}
+
+func TestProbeInvalidConfig(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{APIToken: testAPIToken})
+    if recorder := apiRequest(m, http.MethodPost, "/probe", `{"name":"candidate","url":"http://example.com/","expected_body_regex":"("}`, true); recorder.Code != http.StatusBadRequest {
+        t.Errorf("probe of an invalid config returned %d, want 400", recorder.Code)
+    }
+}
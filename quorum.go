@@ -0,0 +1,119 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// EndpointResult is the outcome of probing one endpoint of a quorum check
+type EndpointResult struct {
+    URL        string `json:"url"`
+    Up         bool   `json:"up"`
+    StatusCode int    `json:"status_code"`
+    LatencyMs  int64  `json:"latency_ms"`
+    Error      string `json:"error,omitempty"`
+}
+
+// serviceEndpoints lists the URLs a quorum check probes: url, if set,
+// followed by endpoints
+func serviceEndpoints(service ServiceConfig) []string {
+    var urls []string
+    if service.URL != "" {
+        urls = append(urls, service.URL)
+    }
+    return append(urls, service.Endpoints...)
+}
+
+// serviceQuorum is how many endpoints must pass, a majority if unset
+func serviceQuorum(service ServiceConfig) int {
+    if service.Quorum > 0 {
+        return service.Quorum
+    }
+    return len(serviceEndpoints(service))/2 + 1
+}
+
+// performQuorumCheck probes every endpoint concurrently with the service's
+// settings. The service is up when at least quorum endpoints pass.
+func (m *Monitor) performQuorumCheck(service ServiceConfig, client *http.Client) checkOutcome {
+    startTime := time.Now()
+    urls := serviceEndpoints(service)
+    outcomes := make([]checkOutcome, len(urls))
+
+    var wg sync.WaitGroup
+    for i, url := range urls {
+        wg.Add(1)
+        go func(i int, endpoint ServiceConfig) {
+            defer wg.Done()
+            outcomes[i] = m.performCheck(endpoint, client, nil)
+        }(i, endpointService(service, url))
+    }
+    wg.Wait()
+
+    var result checkOutcome
+    var passed int
+    var failures []string
+    allTransport := true
+    for i, outcome := range outcomes {
+        endpoint := EndpointResult{
+            URL:        urls[i],
+            Up:         outcome.up,
+            StatusCode: outcome.statusCode,
+            LatencyMs:  outcome.latency.Milliseconds(),
+        }
+        if outcome.up {
+            if passed == 0 {
+                // Report the status and TLS state of a passing endpoint
+                result.statusCode = outcome.statusCode
+                result.tls = outcome.tls
+            }
+            passed++
+        } else {
+            endpoint.Error = outcome.err.Error()
+            failures = append(failures, fmt.Sprintf("%s: %s", urls[i], endpoint.Error))
+            allTransport = allTransport && outcome.transportErr
+        }
+        if passed == 0 && result.statusCode == 0 {
+            result.statusCode = outcome.statusCode
+        }
+        if outcome.latency > result.latency {
+            result.latency = outcome.latency
+        }
+        result.bodyTruncated = result.bodyTruncated || outcome.bodyTruncated
+        result.endpoints = append(result.endpoints, endpoint)
+    }
+
+    quorum := serviceQuorum(service)
+    result.up = passed >= quorum
+    if !result.up {
+        result.err = fmt.Errorf("%d of %d endpoints passed, quorum is %d: %s",
+            passed, len(urls), quorum, strings.Join(failures, "; "))
+        result.transportErr = allTransport
+    }
+    result.duration = time.Since(startTime)
+    return result
+}
+
+// endpointService is the service config used to probe one endpoint
+func endpointService(service ServiceConfig, url string) ServiceConfig {
+    service.URL = url
+    service.Endpoints = nil
+    return service
+}
+
+func (m *Monitor) recordEndpoints(serviceName string, endpoints []EndpointResult) {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+    if serviceStatus, ok := m.serviceStatus[serviceName]; ok {
+        serviceStatus.Endpoints = endpoints
+    }
+}
+
+func validateQuorum(service ServiceConfig) error {
+    if service.Quorum < 0 || service.Quorum > len(serviceEndpoints(service)) {
+        return fmt.Errorf("service %s: quorum must be between 1 and the number of endpoints", service.Name)
+    }
+    return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestQuorumCheck(t *testing.T) {
+    tests := []struct {
+        name    string
+        codes   []int // per endpoint, url first
+        quorum  int
+        wantUp  bool
+        wantErr string
+    }{
+        {"all pass", []int{200, 200, 200}, 0, true, ""},
+        {"majority passes", []int{200, 500, 200}, 0, true, ""},
+        {"majority fails", []int{500, 200, 500}, 0, false, "1 of 3 endpoints passed, quorum is 2"},
+        {"single pass meets a quorum of one", []int{500, 500, 200}, 1, true, ""},
+        {"one failure breaks a quorum of all", []int{200, 200, 503}, 3, false, "2 of 3 endpoints passed, quorum is 3"},
+        {"even split under a majority", []int{200, 200, 500, 500}, 0, false, "2 of 4 endpoints passed, quorum is 3"},
+        {"even split under a quorum of half", []int{200, 200, 500, 500}, 2, true, ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var urls []string
+            for _, code := range tt.codes {
+                urls = append(urls, newStatusServer(t, code).URL)
+            }
+            service := testService("geo", urls[0])
+            service.Endpoints, service.Quorum = urls[1:], tt.quorum
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+            m.statusMutex.RLock()
+            status := m.serviceStatus["geo"]
+            up, lastError, endpoints := status.Status, status.LastError, status.Endpoints
+            m.statusMutex.RUnlock()
+            if up != tt.wantUp {
+                t.Errorf("up = %v (%s), want %v", up, lastError, tt.wantUp)
+            }
+            if tt.wantErr != "" && !strings.HasPrefix(lastError, tt.wantErr) {
+                t.Errorf("error %q, want it to start %q", lastError, tt.wantErr)
+            }
+
+            if len(endpoints) != len(urls) {
+                t.Fatalf("%d endpoint results, want %d", len(endpoints), len(urls))
+            }
+            for i, endpoint := range endpoints {
+                wantUp := tt.codes[i] == http.StatusOK
+                if endpoint.URL != urls[i] || endpoint.Up != wantUp || endpoint.StatusCode != tt.codes[i] {
+                    t.Errorf("endpoint %d: %+v, want %s up %v with %d", i, endpoint, urls[i], wantUp, tt.codes[i])
+                }
+                if !tt.wantUp && !wantUp && !strings.Contains(lastError, urls[i]) {
+                    t.Errorf("error %q does not name failing endpoint %s", lastError, urls[i])
+                }
+            }
+        })
+    }
+}
+
+func TestQuorumProbesConcurrently(t *testing.T) {
+    slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { time.Sleep(300 * time.Millisecond) })
+    var urls []string
+    for i := 0; i < 4; i++ {
+        server := httptest.NewServer(slow)
+        t.Cleanup(server.Close)
+        urls = append(urls, server.URL)
+    }
+    service := testService("geo", urls[0])
+    service.Endpoints = urls[1:]
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    start := time.Now()
+    outcome := m.performQuorumCheck(service, m.serviceClient(service))
+    if elapsed := time.Since(start); !outcome.up || elapsed > 900*time.Millisecond {
+        t.Errorf("up %v after %s, want the endpoints probed in parallel", outcome.up, elapsed)
+    }
+}
+
+func TestValidateQuorum(t *testing.T) {
+    tests := []struct {
+        name    string
+        quorum  int
+        wantErr bool
+    }{
+        {"majority by default", 0, false},
+        {"every endpoint", 3, false},
+        {"more than the endpoints", 4, true},
+        {"negative", -1, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("geo", "https://eu.example.com/")
+            service.Endpoints = []string{"https://us.example.com/", "https://ap.example.com/"}
+            service.Quorum = tt.quorum
+            if err := validateQuorum(service); (err != nil) != tt.wantErr {
+                t.Errorf("validateQuorum() = %v, want error %v", err, tt.wantErr)
+            }
+        })
+    }
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "sync"
+    "time"
+)
+
+// defaultLogRateLimit is how often a repeated log line may be emitted
+const defaultLogRateLimit = 60 * time.Second
+
+// rateLimitedLogger logs the first occurrence of a keyed message and then at
+// most once per interval, reporting how many repeats were suppressed
+type rateLimitedLogger struct {
+    interval time.Duration
+    mutex    sync.Mutex
+    entries  map[string]*rateLimitedEntry
+}
+
+type rateLimitedEntry struct {
+    lastLogged time.Time
+    suppressed int
+}
+
+func newRateLimitedLogger(interval time.Duration) *rateLimitedLogger {
+    if interval <= 0 {
+        interval = defaultLogRateLimit
+    }
+    return &rateLimitedLogger{
+        interval: interval,
+        entries:  make(map[string]*rateLimitedEntry),
+    }
+}
+
+func (l *rateLimitedLogger) Printf(key, format string, args ...interface{}) {
+    l.mutex.Lock()
+    defer l.mutex.Unlock()
+
+    now := time.Now()
+    entry, ok := l.entries[key]
+    if !ok {
+        l.entries[key] = &rateLimitedEntry{lastLogged: now}
+        log.Printf(format, args...)
+        return
+    }
+
+    if now.Sub(entry.lastLogged) < l.interval {
+        entry.suppressed++
+        return
+    }
+
+    msg := fmt.Sprintf(format, args...)
+    if entry.suppressed > 0 {
+        msg = fmt.Sprintf("%s (suppressed %d similar messages in the last %s)", msg, entry.suppressed, now.Sub(entry.lastLogged).Round(time.Second))
+    }
+    log.Print(msg)
+
+    entry.lastLogged = now
+    entry.suppressed = 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+    "bytes"
+    "log"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// logBuffer is a buffer safe to read while goroutines are logging to it
+type logBuffer struct {
+    mutex sync.Mutex
+    buf   bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+    return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+    return b.buf.String()
+}
+
+// captureLog redirects the standard logger to the returned buffer for the
+// rest of the test
+func captureLog(t *testing.T) *logBuffer {
+    t.Helper()
+    buf := &logBuffer{}
+    output, flags := log.Writer(), log.Flags()
+    log.SetOutput(buf)
+    log.SetFlags(0)
+    t.Cleanup(func() {
+        log.SetOutput(output)
+        log.SetFlags(flags)
+    })
+    return buf
+}
+
+func logLines(buf *logBuffer) []string {
+    return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestRateLimitedLogger(t *testing.T) {
+    buf := captureLog(t)
+    logger := newRateLimitedLogger(time.Hour)
+
+    for i := 0; i < 100; i++ {
+        logger.Printf("check:api", "Check failed for api: attempt %d", i)
+    }
+    logger.Printf("check:web", "Check failed for web")
+
+    want := []string{"Check failed for api: attempt 0", "Check failed for web"}
+    if got := logLines(buf); !equalStrings(got, want) {
+        t.Errorf("logged %q, want %q", got, want)
+    }
+}
+
+func TestRateLimitedLoggerReportsSuppressed(t *testing.T) {
+    buf := captureLog(t)
+    logger := newRateLimitedLogger(50 * time.Millisecond)
+
+    for i := 0; i < 10; i++ {
+        logger.Printf("check:api", "Check failed for api")
+    }
+    time.Sleep(60 * time.Millisecond)
+    logger.Printf("check:api", "Check failed for api")
+
+    lines := logLines(buf)
+    if len(lines) != 2 {
+        t.Fatalf("logged %d lines, want 2: %q", len(lines), lines)
+    }
+    if !strings.Contains(lines[1], "suppressed 9 similar messages") {
+        t.Errorf("second line %q does not report the suppressed repeats", lines[1])
+    }
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+)
+
+// defaultMaxRedirects matches the limit net/http applies on its own
+const defaultMaxRedirects = 10
+
+func maxRedirects(service ServiceConfig) int {
+    if service.MaxRedirects <= 0 {
+        return defaultMaxRedirects
+    }
+    return service.MaxRedirects
+}
+
+// redirectPolicy fails a check whose redirect chain grows past the
+// service's hop limit, which catches redirect loops early
+func redirectPolicy(service ServiceConfig) func(*http.Request, []*http.Request) error {
+    limit := maxRedirects(service)
+    return func(req *http.Request, via []*http.Request) error {
+        if len(via) > limit {
+            return fmt.Errorf("stopped after %d redirects", limit)
+        }
+        return nil
+    }
+}
+
+// redirectCount is the number of hops followed to reach resp
+func redirectCount(resp *http.Response) int {
+    var hops int
+    for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+        hops++
+    }
+    return hops
+}
+
+// checkRedirectCount compares the hops followed with expected_redirects
+func checkRedirectCount(resp *http.Response, service ServiceConfig) error {
+    if service.ExpectedRedirects == nil {
+        return nil
+    }
+    if hops := redirectCount(resp); hops != *service.ExpectedRedirects {
+        return fmt.Errorf("followed %d redirects, expected %d", hops, *service.ExpectedRedirects)
+    }
+    return nil
+}
+
+func validateRedirects(service ServiceConfig) error {
+    if service.MaxRedirects < 0 {
+        return fmt.Errorf("service %s: max_redirects must not be negative", service.Name)
+    }
+    if expected := service.ExpectedRedirects; expected != nil {
+        if *expected < 0 {
+            return fmt.Errorf("service %s: expected_redirects must not be negative", service.Name)
+        }
+        if *expected > maxRedirects(service) {
+            return fmt.Errorf("service %s: expected_redirects %d exceeds max_redirects %d", service.Name, *expected, maxRedirects(service))
+        }
+    }
+    return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+// newRedirectServer serves /hops/N, which takes N redirects to reach /ok,
+// plus /loop, which redirects to itself, and /moved, which sends clients to
+// /login
+func newRedirectServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch {
+        case r.URL.Path == "/ok", r.URL.Path == "/login":
+        case r.URL.Path == "/loop":
+            http.Redirect(w, r, "/loop", http.StatusFound)
+        case r.URL.Path == "/moved":
+            http.Redirect(w, r, "/login?next=%2Fdashboard", http.StatusFound)
+        case strings.HasPrefix(r.URL.Path, "/hops/"):
+            n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hops/"))
+            if err != nil {
+                w.WriteHeader(http.StatusNotFound)
+                return
+            }
+            if n == 0 {
+                http.Redirect(w, r, "/ok", http.StatusFound)
+                return
+            }
+            http.Redirect(w, r, "/hops/"+strconv.Itoa(n-1), http.StatusMovedPermanently)
+        default:
+            w.WriteHeader(http.StatusNotFound)
+        }
+    }))
+    t.Cleanup(server.Close)
+    return server
+}
+
+func TestExpectedFinalURL(t *testing.T) {
+    server := newRedirectServer(t)
+    tests := []struct {
+        name     string
+        path     string
+        expected string
+        match    string
+        want     bool
+    }{
+        {"redirected to the right destination", "/hops/2", "/ok", "", true},
+        {"redirected to the wrong destination", "/moved", "/dashboard", "", false},
+        {"exact match compares the query", "/moved", "/login", "exact", false},
+        {"prefix match", "/moved", "/login", "prefix", true},
+        {"prefix mismatch", "/moved", "/dashboard", "prefix", false},
+        {"no redirect", "/ok", "/ok", "", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", server.URL+tt.path)
+            service.ExpectedFinalURL = server.URL + tt.expected
+            service.FinalURLMatch = tt.match
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            if outcome := m.performCheck(service, m.serviceClient(service), nil); outcome.up != tt.want {
+                t.Errorf("check up = %v (%v), want %v", outcome.up, outcome.err, tt.want)
+            }
+        })
+    }
+}
+
+func TestRedirectLimits(t *testing.T) {
+    server := newRedirectServer(t)
+    intPtr := func(n int) *int { return &n }
+    tests := []struct {
+        name     string
+        path     string // /hops/N takes N+1 redirects
+        max      int
+        expected *int
+        wantErr  string // empty when the check passes
+    }{
+        {"chain within the limit", "/hops/2", 3, nil, ""},
+        {"chain past the limit", "/hops/2", 2, nil, "stopped after 2 redirects"},
+        {"loop stopped at the default limit", "/loop", 0, nil, "stopped after 10 redirects"},
+        {"expected hop count", "/hops/2", 0, intPtr(3), ""},
+        {"extra hop", "/hops/2", 0, intPtr(2), "followed 3 redirects, expected 2"},
+        {"no redirect expected", "/ok", 0, intPtr(0), ""},
+        {"unexpected redirect", "/moved", 0, intPtr(0), "followed 1 redirects, expected 0"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", server.URL+tt.path)
+            service.MaxRedirects, service.ExpectedRedirects = tt.max, tt.expected
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            outcome := m.performCheck(service, m.serviceClient(service), nil)
+            if tt.wantErr == "" && !outcome.up {
+                t.Errorf("check failed with %v, want it up", outcome.err)
+            }
+            if tt.wantErr != "" && (outcome.up || !strings.Contains(outcome.err.Error(), tt.wantErr)) {
+                t.Errorf("check up %v with %v, want an error containing %q", outcome.up, outcome.err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestValidateRedirects(t *testing.T) {
+    intPtr := func(n int) *int { return &n }
+    tests := []struct {
+        name     string
+        max      int
+        expected *int
+        wantErr  bool
+    }{
+        {"defaults", 0, nil, false},
+        {"expected within the default limit", 0, intPtr(10), false},
+        {"expected past the default limit", 0, intPtr(11), true},
+        {"expected past max_redirects", 2, intPtr(3), true},
+        {"negative max_redirects", -1, nil, true},
+        {"negative expected_redirects", 0, intPtr(-1), true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := ServiceConfig{Name: "api", MaxRedirects: tt.max, ExpectedRedirects: tt.expected}
+            if err := validateRedirects(service); (err != nil) != tt.wantErr {
+                t.Errorf("validateRedirects() = %v, want error %v", err, tt.wantErr)
+            }
+        })
+    }
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "reflect"
+    "syscall"
+    "time"
+)
+
+// A config swapped in while it is being read (e.g. a symlink flip) can fail
+// to read or parse; Reload retries before giving up
+const (
+    reloadAttempts = 3
+    reloadDelay    = 500 * time.Millisecond
+)
+
+// Reload re-reads the config file and applies service changes: new services
+// start, changed ones restart with their status kept, removed ones stop.
+// Other settings take effect on restart. If the config cannot be read or is
+// invalid, running monitors are left untouched.
+func (m *Monitor) Reload() error {
+    if m.configPath == "" {
+        return fmt.Errorf("monitor was not created from a config file")
+    }
+
+    var config MonitorConfig
+    var err error
+    for attempt := 0; attempt < reloadAttempts; attempt++ {
+        if attempt > 0 {
+            time.Sleep(reloadDelay)
+        }
+        if config, err = readConfig(m.configPath); err == nil {
+            break
+        }
+        log.Printf("Reload attempt %d failed: %v", attempt+1, err)
+    }
+    if err != nil {
+        return err
+    }
+
+    if err := loadSecrets(&config); err != nil {
+        return fmt.Errorf("error reading secrets: %v", err)
+    }
+    if err := validateConfig(config); err != nil {
+        return fmt.Errorf("invalid config: %v", err)
+    }
+
+    m.statusMutex.RLock()
+    current := append([]ServiceConfig(nil), m.config.Services...)
+    m.statusMutex.RUnlock()
+
+    wanted := make(map[string]bool)
+    for _, service := range config.Services {
+        wanted[service.Name] = true
+    }
+    for _, service := range current {
+        if !wanted[service.Name] {
+            m.removeService(service.Name)
+            log.Printf("Reload: removed %s", service.Name)
+        }
+    }
+
+    existing := make(map[string]ServiceConfig)
+    for _, service := range current {
+        existing[service.Name] = service
+    }
+    for _, service := range config.Services {
+        old, ok := existing[service.Name]
+        switch {
+        case !ok:
+            m.addService(service)
+            log.Printf("Reload: added %s", service.Name)
+        case !reflect.DeepEqual(old, service):
+            m.updateService(service)
+            log.Printf("Reload: updated %s", service.Name)
+        }
+    }
+    return nil
+}
+
+// reloadOnSignal reloads the config on each SIGHUP
+func (m *Monitor) reloadOnSignal() {
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGHUP)
+    go func() {
+        for range signals {
+            if err := m.Reload(); err != nil {
+                log.Printf("Config reload failed, keeping current config: %v", err)
+                continue
+            }
+            log.Printf("Config reloaded")
+        }
+    }()
+}
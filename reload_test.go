@@ -0,0 +1,73 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// writeConfig writes a config file monitoring services
+func writeConfig(t *testing.T, path string, services ...ServiceConfig) {
+    t.Helper()
+    data, err := json.Marshal(MonitorConfig{Services: services})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, data, 0600); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestReloadTransientReadFailure(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    api, web := testService("api", server.URL), testService("web", server.URL)
+
+    tests := []struct {
+        name     string
+        breakIt  func(path string) // how the rollout leaves the file when the reload starts
+        restored bool              // the new config lands while the reload retries
+        wantErr  bool
+        want     []string // monitors running afterwards
+    }{
+        {"file briefly missing", func(path string) { os.Remove(path) }, true, false, []string{"api", "web"}},
+        {"file briefly truncated", func(path string) { os.WriteFile(path, []byte(`{"services": [`), 0600) }, true, false, []string{"api", "web"}},
+        {"file gone for good", func(path string) { os.Remove(path) }, false, true, []string{"api"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "monitor_config.json")
+            writeConfig(t, path, api)
+            m, err := NewMonitor(path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            t.Cleanup(m.cancel)
+            m.startServiceMonitor(api)
+            defer stopMonitors(m)
+
+            rollout, _ := json.Marshal(MonitorConfig{Services: []ServiceConfig{api, web}})
+            tt.breakIt(path)
+            restored := make(chan struct{})
+            go func() {
+                defer close(restored)
+                if tt.restored {
+                    time.Sleep(reloadDelay / 2)
+                    os.WriteFile(path, rollout, 0600)
+                }
+            }()
+            err = m.Reload()
+            <-restored
+
+            if (err != nil) != tt.wantErr {
+                t.Errorf("Reload() = %v, want error %v", err, tt.wantErr)
+            }
+            if got := runningMonitors(m); !equalStrings(got, tt.want) {
+                t.Errorf("running %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
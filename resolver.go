@@ -0,0 +1,141 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "sync"
+    "time"
+)
+
+type ResolverConfig struct {
+    Server string `json:"server"`  // host:port of a DNS server, e.g. "10.0.0.2:53"
+    DoHURL string `json:"doh_url"` // DNS-over-HTTPS endpoint, takes precedence over Server
+}
+
+// newResolver builds a resolver that sends every query to the configured
+// server instead of the system resolver. It returns nil when unconfigured.
+func newResolver(config *ResolverConfig, timeout time.Duration) *net.Resolver {
+    if config == nil || (config.Server == "" && config.DoHURL == "") {
+        return nil
+    }
+
+    if config.DoHURL != "" {
+        client := &http.Client{Timeout: timeout}
+        return &net.Resolver{
+            PreferGo: true,
+            Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+                return &dohConn{ctx: ctx, url: config.DoHURL, client: client}, nil
+            },
+        }
+    }
+
+    dialer := &net.Dialer{Timeout: timeout}
+    return &net.Resolver{
+        PreferGo: true,
+        Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+            return dialer.DialContext(ctx, network, config.Server)
+        },
+    }
+}
+
+// dohConn carries the Go resolver's TCP-framed DNS messages over
+// DNS-over-HTTPS (RFC 8484). Each length-prefixed query written is POSTed to
+// the DoH endpoint and its answer is framed the same way for reading.
+type dohConn struct {
+    ctx    context.Context
+    url    string
+    client *http.Client
+
+    mutex    sync.Mutex
+    pending  bytes.Buffer
+    response bytes.Buffer
+    deadline time.Time
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    c.pending.Write(b)
+    for c.pending.Len() >= 2 {
+        size := int(binary.BigEndian.Uint16(c.pending.Bytes()[:2]))
+        if c.pending.Len() < 2+size {
+            break
+        }
+        c.pending.Next(2)
+        query := append([]byte(nil), c.pending.Next(size)...)
+
+        answer, err := c.exchange(query)
+        if err != nil {
+            return 0, err
+        }
+        var length [2]byte
+        binary.BigEndian.PutUint16(length[:], uint16(len(answer)))
+        c.response.Write(length[:])
+        c.response.Write(answer)
+    }
+    return len(b), nil
+}
+
+func (c *dohConn) exchange(query []byte) ([]byte, error) {
+    // Only the lookup's cancellation carries over: its values include the
+    // check's client trace, which must not see the DoH server's connections
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    defer context.AfterFunc(c.ctx, cancel)()
+    if !c.deadline.IsZero() {
+        ctx, cancel = context.WithDeadline(ctx, c.deadline)
+        defer cancel()
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(query))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/dns-message")
+    req.Header.Set("Accept", "application/dns-message")
+
+    resp, err := c.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("DoH server returned status %d", resp.StatusCode)
+    }
+    return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    if c.response.Len() == 0 {
+        return 0, io.EOF
+    }
+    return c.response.Read(b)
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr{} }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    c.deadline = t
+    return nil
+}
+
+type dohAddr struct{}
+
+func (dohAddr) Network() string { return "doh" }
+func (dohAddr) String() string  { return "doh" }
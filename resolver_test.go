@@ -0,0 +1,155 @@
+package main
+
+import (
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+
+    "golang.org/x/net/dns/dnsmessage"
+)
+
+// stubDNS is a UDP DNS server answering A and AAAA queries from a table it
+// can be changed through
+type stubDNS struct {
+    addr    string
+    queries atomic.Int32
+    mutex   sync.Mutex
+    records map[string][]string // lower-case name without the trailing dot -> IPv4 and IPv6 addresses
+}
+
+func newStubDNS(t *testing.T) *stubDNS {
+    t.Helper()
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listening for DNS: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    s := &stubDNS{addr: conn.LocalAddr().String(), records: make(map[string][]string)}
+    go func() {
+        buf := make([]byte, 512)
+        for {
+            n, from, err := conn.ReadFrom(buf)
+            if err != nil {
+                return
+            }
+            if answer, err := s.answer(buf[:n]); err == nil {
+                conn.WriteTo(answer, from)
+            }
+        }
+    }()
+    return s
+}
+
+// set replaces the addresses a name resolves to
+func (s *stubDNS) set(name string, ips ...string) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.records[name] = ips
+}
+
+func (s *stubDNS) answer(query []byte) ([]byte, error) {
+    var parser dnsmessage.Parser
+    header, err := parser.Start(query)
+    if err != nil {
+        return nil, err
+    }
+    question, err := parser.Question()
+    if err != nil {
+        return nil, err
+    }
+    s.queries.Add(1)
+
+    name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+    s.mutex.Lock()
+    ips, known := s.records[name]
+    s.mutex.Unlock()
+
+    response := dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeSuccess}
+    if !known {
+        response.RCode = dnsmessage.RCodeNameError
+    }
+    builder := dnsmessage.NewBuilder(nil, response)
+    builder.EnableCompression()
+    builder.StartQuestions()
+    builder.Question(question)
+    builder.StartAnswers()
+    resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 1}
+    for _, ip := range ips {
+        parsed := net.ParseIP(ip)
+        switch {
+        case question.Type == dnsmessage.TypeA && parsed.To4() != nil:
+            var a [4]byte
+            copy(a[:], parsed.To4())
+            builder.AResource(resource, dnsmessage.AResource{A: a})
+        case question.Type == dnsmessage.TypeAAAA && parsed.To4() == nil:
+            var aaaa [16]byte
+            copy(aaaa[:], parsed)
+            builder.AAAAResource(resource, dnsmessage.AAAAResource{AAAA: aaaa})
+        }
+    }
+    return builder.Finish()
+}
+
+// newStubDoH serves the stub's answers over DNS-over-HTTPS
+func newStubDoH(t *testing.T, dns *stubDNS) *httptest.Server {
+    t.Helper()
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        query, _ := io.ReadAll(r.Body)
+        if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        answer, err := dns.answer(query)
+        if err != nil {
+            w.WriteHeader(http.StatusBadRequest)
+            return
+        }
+        w.Header().Set("Content-Type", "application/dns-message")
+        w.Write(answer)
+    }))
+    t.Cleanup(server.Close)
+    return server
+}
+
+func TestCustomResolver(t *testing.T) {
+    backend := newStatusServer(t, http.StatusOK)
+    _, port, _ := net.SplitHostPort(strings.TrimPrefix(backend.URL, "http://"))
+
+    tests := []struct {
+        name string
+        doh  bool
+        host string
+        want bool
+    }{
+        {"dns server", false, "api.monitor.test", true},
+        {"dns server without the name", false, "missing.monitor.test", false},
+        {"dns over https", true, "api.monitor.test", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dns := newStubDNS(t)
+            dns.set("api.monitor.test", "127.0.0.1")
+            resolver := &ResolverConfig{Server: dns.addr}
+            if tt.doh {
+                resolver = &ResolverConfig{DoHURL: newStubDoH(t, dns).URL}
+            }
+            service := testService("api", "http://"+net.JoinHostPort(tt.host, port))
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, Resolver: resolver})
+
+            outcome := m.performCheck(service, m.serviceClient(service), nil)
+            if outcome.up != tt.want {
+                t.Errorf("check up = %v (%v), want %v", outcome.up, outcome.err, tt.want)
+            }
+            if dns.queries.Load() == 0 {
+                t.Error("the configured resolver was not queried")
+            }
+        })
+    }
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+    "encoding/json"
+    "sync"
+    "time"
+)
+
+// resultCache holds recent check outcomes keyed by the check's request and
+// assertions so identical checks in quick succession, as in --once runs or
+// repeated forced checks, do not hit the target again
+type resultCache struct {
+    mutex   sync.Mutex
+    entries map[string]cachedResult
+}
+
+type cachedResult struct {
+    outcome checkOutcome
+    expires time.Time
+}
+
+// resultCacheKey identifies a check by everything that shapes its request
+// or judges its response, so services share a cached outcome only when they
+// would have reached the same verdict. Fields that only affect scheduling
+// and alerting are left out.
+func resultCacheKey(service ServiceConfig) string {
+    if service.Type != "" && service.Type != CheckTypeHTTP {
+        // Other check types may not be identified by their URL alone
+        return service.Type + " " + service.Name
+    }
+    if service.Method == "" {
+        service.Method = "GET"
+    }
+
+    check := service
+    check.Name = ""
+    check.UpstreamHealthURL = ""
+    check.CheckInterval = 0
+    check.CriticalService = false
+    check.Severity = ""
+    check.FlapThreshold, check.FlapWindow = 0, 0
+    check.FailMode = ""
+    check.SLOResponseTimeMs, check.SLOPercentile = 0, 0
+    check.BaselineLatencyMs, check.DriftPercent = 0, 0
+    check.PagerDutySeverity, check.PagerDutyDetails, check.PagerDutyRoutingKey = "", nil, ""
+    check.Labels = nil
+    check.WarmupSeconds = 0
+    check.FailureCountAlerts = nil
+    check.Weight, check.Public = 0, false
+    check.CircuitBreaker = nil
+    check.BusinessHours = nil
+    check.RunbookURL, check.DashboardURL = "", ""
+    check.Priority, check.PriorityEscalation = "", nil
+    check.GroupKey = ""
+    check.DNSCacheTTL, check.AlertOnDNSChange = 0, false
+
+    key, err := json.Marshal(check)
+    if err != nil {
+        // Unreachable for a config that was itself decoded from JSON
+        return service.Type + " " + service.Name
+    }
+    return string(key)
+}
+
+// cachedCheck is performCheck behind the result cache. A check_cache_ttl of
+// 0 disables caching.
+func (m *Monitor) cachedCheck(service ServiceConfig) checkOutcome {
+    ttl := time.Duration(m.config.CheckCacheTTL) * time.Second
+    if ttl <= 0 {
+        return m.performCheck(service, m.serviceClient(service), nil)
+    }
+
+    key := resultCacheKey(service)
+    m.results.mutex.Lock()
+    cached, ok := m.results.entries[key]
+    m.results.mutex.Unlock()
+    if ok && time.Now().Before(cached.expires) {
+        return cached.outcome
+    }
+
+    outcome := m.performCheck(service, m.serviceClient(service), nil)
+
+    m.results.mutex.Lock()
+    defer m.results.mutex.Unlock()
+    for k, entry := range m.results.entries {
+        if time.Now().After(entry.expires) {
+            delete(m.results.entries, k)
+        }
+    }
+    m.results.entries[key] = cachedResult{outcome: outcome, expires: time.Now().Add(ttl)}
+    return outcome
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestCheckResultCache(t *testing.T) {
+    tests := []struct {
+        name    string
+        ttl     int
+        expire  bool // the cached result has expired before the second check
+        other   func(ServiceConfig) ServiceConfig
+        wantHit int32
+    }{
+        {"disabled by default", 0, false, nil, 2},
+        {"second check within the ttl", 60, false, nil, 1},
+        {"identical check of another service", 60, false, func(s ServiceConfig) ServiceConfig { s.Name = "api-copy"; return s }, 1},
+        {"check after the ttl", 60, true, nil, 2},
+        {"different method", 60, false, func(s ServiceConfig) ServiceConfig { s.Method = http.MethodHead; return s }, 2},
+        {"different body assertion", 60, false, func(s ServiceConfig) ServiceConfig { s.ExpectedBodySubstring = "ok"; return s }, 2},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var hits atomic.Int32
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                hits.Add(1)
+                w.Write([]byte("ok"))
+            }))
+            defer server.Close()
+
+            service := testService("api", server.URL)
+            second := service
+            if tt.other != nil {
+                second = tt.other(service)
+            }
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, CheckCacheTTL: tt.ttl})
+
+            if outcome := m.cachedCheck(service); !outcome.up {
+                t.Fatalf("first check failed: %v", outcome.err)
+            }
+            if tt.expire {
+                m.results.mutex.Lock()
+                for key, entry := range m.results.entries {
+                    entry.expires = time.Now().Add(-time.Second)
+                    m.results.entries[key] = entry
+                }
+                m.results.mutex.Unlock()
+            }
+            if outcome := m.cachedCheck(second); !outcome.up {
+                t.Fatalf("second check failed: %v", outcome.err)
+            }
+
+            if got := hits.Load(); got != tt.wantHit {
+                t.Errorf("server hit %d times, want %d", got, tt.wantHit)
+            }
+        })
+    }
+}
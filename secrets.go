@@ -0,0 +1,69 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strings"
+)
+
+// secretFile is a sensitive config value that may instead be read from a file
+type secretFile struct {
+    name  string
+    path  string
+    value *string
+}
+
+// loadSecrets fills sensitive values from their *_file counterparts, as
+// mounted by Docker and Kubernetes secrets, so they need not appear inline
+// in the config. A file that is configured but unreadable is an error.
+func loadSecrets(config *MonitorConfig) error {
+    secrets := []secretFile{
+        {"alerts.slack.webhook_url_file", config.Alerts.Slack.WebhookURLFile, &config.Alerts.Slack.WebhookURL},
+        {"alerts.slack.signing_secret_file", config.Alerts.Slack.SigningSecretFile, &config.Alerts.Slack.SigningSecret},
+        {"alerts.google_chat.webhook_url_file", config.Alerts.GoogleChat.WebhookURLFile, &config.Alerts.GoogleChat.WebhookURL},
+        {"alerts.email.password_file", config.Alerts.Email.PasswordFile, &config.Alerts.Email.Password},
+        {"alerts.pagerduty.service_key_file", config.Alerts.PagerDuty.ServiceKeyFile, &config.Alerts.PagerDuty.ServiceKey},
+        {"alerts.pagerduty.api_key_file", config.Alerts.PagerDuty.APIKeyFile, &config.Alerts.PagerDuty.APIKey},
+        {"api_token_file", config.APITokenFile, &config.APIToken},
+        {"result_webhook_secret_file", config.ResultWebhookSecretFile, &config.ResultWebhookSecret},
+    }
+
+    // Services are copied so a caller's config is never modified
+    config.Services = append([]ServiceConfig(nil), config.Services...)
+    for i := range config.Services {
+        secrets = append(secrets, serviceSecrets(&config.Services[i])...)
+    }
+    return readSecrets(secrets)
+}
+
+// loadServiceSecrets fills a single service's secrets from their files, for
+// services added or updated through the API
+func loadServiceSecrets(service *ServiceConfig) error {
+    return readSecrets(serviceSecrets(service))
+}
+
+// serviceSecrets lists a service's secret files. Nested configs they fill
+// are copied first, so the service no longer shares them with its source.
+func serviceSecrets(service *ServiceConfig) []secretFile {
+    if service.OAuth2 == nil || service.OAuth2.ClientSecretFile == "" {
+        return nil
+    }
+    oauth := *service.OAuth2
+    service.OAuth2 = &oauth
+    return []secretFile{{"service " + service.Name + ": oauth2.client_secret_file", oauth.ClientSecretFile, &oauth.ClientSecret}}
+}
+
+func readSecrets(secrets []secretFile) error {
+    for _, secret := range secrets {
+        if secret.path == "" {
+            continue
+        }
+        data, err := os.ReadFile(secret.path)
+        if err != nil {
+            return fmt.Errorf("%s: %v", secret.name, err)
+        }
+        // Secret files are commonly written with a trailing newline
+        *secret.value = strings.TrimRight(string(data), "\r\n")
+    }
+    return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestLoadSecrets(t *testing.T) {
+    dir := t.TempDir()
+    write := func(name, content string) string {
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+            t.Fatal(err)
+        }
+        return path
+    }
+    webhook := write("slack", "https://hooks.slack.com/services/T0/B0/xyz\n")
+    password := write("smtp", "hunter2")
+    apiKey := write("pagerduty", "pd-key\r\n")
+    clientSecret := write("oauth", "client-secret\n")
+
+    var config MonitorConfig
+    config.Alerts.Slack.WebhookURLFile = webhook
+    config.Alerts.Email.Password = "inline"
+    config.Alerts.Email.PasswordFile = password
+    config.Alerts.PagerDuty.APIKeyFile = apiKey
+    service := testService("api", "https://api.example.com/")
+    service.OAuth2 = &OAuth2Config{ClientSecretFile: clientSecret}
+    config.Services = []ServiceConfig{service}
+
+    if err := loadSecrets(&config); err != nil {
+        t.Fatalf("loadSecrets: %v", err)
+    }
+    for _, secret := range []struct{ got, want string }{
+        {config.Alerts.Slack.WebhookURL, "https://hooks.slack.com/services/T0/B0/xyz"},
+        {config.Alerts.Email.Password, "hunter2"},
+        {config.Alerts.PagerDuty.APIKey, "pd-key"},
+        {config.Services[0].OAuth2.ClientSecret, "client-secret"},
+    } {
+        if secret.got != secret.want {
+            t.Errorf("loaded %q, want %q", secret.got, secret.want)
+        }
+    }
+    if service.OAuth2.ClientSecret != "" {
+        t.Error("loadSecrets modified the caller's service config")
+    }
+}
+
+func TestLoadSecretsMissingFile(t *testing.T) {
+    tests := []struct {
+        name   string
+        config func(*MonitorConfig, string)
+        want   string
+    }{
+        {"slack webhook", func(c *MonitorConfig, path string) { c.Alerts.Slack.WebhookURLFile = path }, "alerts.slack.webhook_url_file"},
+        {"api token", func(c *MonitorConfig, path string) { c.APITokenFile = path }, "api_token_file"},
+        {"oauth2 client secret", func(c *MonitorConfig, path string) {
+            service := testService("api", "https://api.example.com/")
+            service.OAuth2 = &OAuth2Config{ClientSecretFile: path}
+            c.Services = []ServiceConfig{service}
+        }, "service api: oauth2.client_secret_file"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var config MonitorConfig
+            tt.config(&config, filepath.Join(t.TempDir(), "missing"))
+            err := loadSecrets(&config)
+            if err == nil || !strings.HasPrefix(err.Error(), tt.want+":") {
+                t.Errorf("loadSecrets() = %v, want an error naming %s", err, tt.want)
+            }
+        })
+    }
+}
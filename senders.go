@@ -0,0 +1,197 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+)
+
+// Alert event kinds
+const (
+    EventAlert    = "alert"
+    EventRecovery = "recovery"
+    EventFlapping = "flapping"
+)
+
+// AlertEvent is a notification handed to each routed AlertSender
+type AlertEvent struct {
+    Kind     string         // EventAlert, EventRecovery or EventFlapping
+    Service  ServiceConfig
+    Severity string
+    Message  string         // the check error for alerts, the rendered notice otherwise
+    Time     time.Time
+    Downtime time.Duration  // set for recoveries
+    History  HistorySummary // recent checks, for rendering trend context such as "failed 3 of last 10"
+    Route    *TimeRoute     // the time route active for the event's severity, nil outside any window
+}
+
+// errNotApplicable is returned by a built-in sender for an event its channel
+// does not carry, such as flapping to PagerDuty. It is not retried, logged or
+// recorded as a delivery.
+var errNotApplicable = errors.New("event not applicable to the channel")
+
+// AlertSender delivers alert events for one channel. Send is retried on
+// error, so implementations should be safe to call again.
+type AlertSender interface {
+    Send(ctx context.Context, event AlertEvent) error
+}
+
+// RegisterSender routes a channel name to sender, replacing any built-in
+// sender for it. Services reach the channel through alerts.routing.
+func (m *Monitor) RegisterSender(channel string, sender AlertSender) {
+    m.senderMutex.Lock()
+    defer m.senderMutex.Unlock()
+    m.senders[channel] = sender
+}
+
+func (m *Monitor) sender(channel string) (AlertSender, bool) {
+    m.senderMutex.RLock()
+    defer m.senderMutex.RUnlock()
+    sender, ok := m.senders[channel]
+    return sender, ok
+}
+
+// registerBuiltinSenders registers the channels that are configured
+func (m *Monitor) registerBuiltinSenders() {
+    alerts := m.config.Alerts
+    if alerts.Slack.WebhookURL != "" || hasSlackRoute(alerts.TimeRouting) {
+        m.senders[ChannelSlack] = slackSender{m}
+    }
+    if alerts.GoogleChat.WebhookURL != "" {
+        m.senders[ChannelGoogleChat] = googleChatSender{m}
+    }
+    if alerts.Email.SMTPServer != "" {
+        m.senders[ChannelEmail] = emailSender{m}
+    }
+    // Services may carry their own routing key, so PagerDuty is always
+    // registered and skips services without any key
+    m.senders[ChannelPagerDuty] = pagerDutySender{m}
+    if m.sns != nil {
+        m.senders[ChannelSNS] = snsSender{m}
+    }
+}
+
+func (m *Monitor) newAlertEvent(kind string, service ServiceConfig, message string) AlertEvent {
+    event := AlertEvent{
+        Kind:     kind,
+        Service:  service,
+        Severity: serviceSeverity(service),
+        Message:  message,
+        Time:     time.Now(),
+        History:  m.historySummary(service.Name),
+    }
+    event.Route, _ = m.timeRoute(event.Severity, event.Time)
+    return event
+}
+
+// deliver queues an event for each enabled channel that has a sender.
+// Known channels without configuration are skipped quietly.
+func (m *Monitor) deliver(event AlertEvent, channels []string) {
+    name := event.Service.Name
+    for _, channel := range channels {
+        if !m.channelEnabled(channel) {
+            log.Printf("Skipping disabled %s channel for %s", channel, name)
+            continue
+        }
+
+        sender, ok := m.sender(channel)
+        if !ok {
+            if !knownChannels[channel] {
+                m.logger.Printf("channel:"+channel, "Unknown alert channel %q for service %s", channel, name)
+            }
+            continue
+        }
+
+        channelEvent := event
+        channelEvent.Message = truncateMessage(event.Message, m.messageLimit(channel))
+        m.enqueueDelivery(channel, channelJob{event: channelEvent, sender: sender})
+    }
+}
+
+// deliverTo sends an event to one channel under its retry policy
+func (m *Monitor) deliverTo(channel string, job channelJob) {
+    event := job.event
+    err := m.retryDelivery(channel, func() error {
+        ctx, cancel := context.WithTimeout(m.ctx, m.alertTimeout)
+        defer cancel()
+        return job.sender.Send(ctx, event)
+    })
+    if errors.Is(err, errNotApplicable) {
+        return
+    }
+    m.recordDelivery(event, channel, err)
+    if err != nil {
+        name := event.Service.Name
+        m.logger.Printf(channel+"-"+event.Kind+":"+name, "Error sending %s %s for %s: %v", channel, event.Kind, name, err)
+    }
+}
+
+type slackSender struct{ m *Monitor }
+
+func (s slackSender) Send(ctx context.Context, event AlertEvent) error {
+    webhookURL := s.m.slackWebhookURL(event)
+    if webhookURL == "" {
+        return errNotApplicable
+    }
+    if event.Kind == EventAlert {
+        return s.m.sendSlackAlert(ctx, webhookURL, event.Service, event.Message, event.History)
+    }
+    return s.m.postSlackMessage(ctx, webhookURL, event.Message)
+}
+
+type emailSender struct{ m *Monitor }
+
+func (s emailSender) Send(ctx context.Context, event AlertEvent) error {
+    name := event.Service.Name
+    switch event.Kind {
+    case EventAlert:
+        body := fmt.Sprintf("Service %s is DOWN!\nError: %s\nTime: %s",
+            name, event.Message, s.m.formatTime(event.Time))
+        if event.History.Checks > 0 {
+            body += "\n" + event.History.String()
+        }
+        for _, link := range serviceLinks(event.Service) {
+            body += fmt.Sprintf("\n%s: %s", link.label, link.url)
+        }
+        return s.m.sendEmailAlert(fmt.Sprintf("[ALERT] %s is DOWN", name), body)
+    case EventRecovery:
+        return s.m.sendEmailAlert(fmt.Sprintf("[RECOVERED] %s", name), event.Message)
+    default:
+        return s.m.sendEmailAlert(fmt.Sprintf("[FLAPPING] %s", name), event.Message)
+    }
+}
+
+// pagerDutySender pages on alerts and resolves on recovery; flapping is
+// left to the chat channels
+type pagerDutySender struct{ m *Monitor }
+
+func (s pagerDutySender) Send(ctx context.Context, event AlertEvent) error {
+    service := s.m.pagerDutyService(event)
+    if s.m.pagerDutyRoutingKey(service) == "" {
+        return errNotApplicable
+    }
+    var err error
+    switch event.Kind {
+    case EventAlert:
+        err = s.m.sendPagerDutyAlert(ctx, service, event.Message)
+    case EventRecovery:
+        err = s.m.resolvePagerDutyIncident(ctx, service)
+    default:
+        return errNotApplicable
+    }
+    if err == nil {
+        s.m.pagerDutySent(event.Kind, service)
+    }
+    return err
+}
+
+type snsSender struct{ m *Monitor }
+
+func (s snsSender) Send(ctx context.Context, event AlertEvent) error {
+    if event.Kind == EventFlapping {
+        return errNotApplicable
+    }
+    return s.m.sendSNSAlert(ctx, event.Kind, event.Service, event.Message)
+}
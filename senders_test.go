@@ -0,0 +1,118 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+// newHangingServer accepts requests and never answers them
+func newHangingServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    release := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-r.Context().Done():
+        case <-release:
+        }
+    }))
+    // Cleanups run last first, so handlers are released before Close waits
+    t.Cleanup(server.Close)
+    t.Cleanup(func() { close(release) })
+    return server
+}
+
+func TestAlertTimeout(t *testing.T) {
+    webhook := newHangingServer(t)
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", backend.URL)
+    m, err := NewMonitorFromConfig(MonitorConfig{
+        Services:     []ServiceConfig{service},
+        AlertTimeout: 1,
+        Alerts: AlertConfig{
+            Slack:         SlackConfig{WebhookURL: webhook.URL},
+            Routing:       map[string][]string{SeverityWarning: {ChannelSlack}},
+            DeliveryRetry: map[string]RetryPolicy{ChannelSlack: {Attempts: 1}},
+        },
+    })
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    defer m.cancel()
+
+    start := time.Now()
+    m.checkService(service)
+    if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+        t.Errorf("check waited %s on the hung webhook", elapsed)
+    }
+    // Status stays readable while the delivery hangs
+    m.statusMutex.RLock()
+    state := m.serviceStatus["api"].State
+    m.statusMutex.RUnlock()
+    if state != StateDown {
+        t.Errorf("state = %s, want %s", state, StateDown)
+    }
+
+    m.alertWG.Wait()
+    if elapsed := time.Since(start); elapsed > 3*time.Second {
+        t.Errorf("delivery took %s, want it cut off after the 1s alert_timeout", elapsed)
+    }
+    m.deliveries.mutex.Lock()
+    defer m.deliveries.mutex.Unlock()
+    if len(m.deliveries.records) != 1 || m.deliveries.records[0].Success {
+        t.Errorf("deliveries %+v, want one failed slack delivery", m.deliveries.records)
+    }
+}
+
+func TestRegisteredSender(t *testing.T) {
+    server := newStatusServer(t, http.StatusServiceUnavailable)
+    service := testService("api", server.URL)
+    service.Severity = SeverityCritical
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    before := time.Now()
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+
+    sender.mutex.Lock()
+    defer sender.mutex.Unlock()
+    if len(sender.events) != 2 {
+        t.Fatalf("received %d events, want an alert and a recovery", len(sender.events))
+    }
+
+    alert := sender.events[0]
+    if alert.Kind != EventAlert || alert.Service.Name != "api" || alert.Severity != SeverityCritical {
+        t.Errorf("alert %s for %s at %s", alert.Kind, alert.Service.Name, alert.Severity)
+    }
+    if alert.Message != "unexpected status code: 503" {
+        t.Errorf("alert message %q, want the check error", alert.Message)
+    }
+    if alert.Time.Before(before) || alert.History.Checks != 1 || alert.History.Failures != 1 {
+        t.Errorf("alert at %s with history %+v", alert.Time, alert.History)
+    }
+
+    recovery := sender.events[1]
+    if recovery.Kind != EventRecovery || recovery.Service.Name != "api" || recovery.Downtime <= 0 {
+        t.Errorf("recovery %s for %s after %s", recovery.Kind, recovery.Service.Name, recovery.Downtime)
+    }
+}
+
+func TestSenderRouting(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    service.Severity = SeverityCritical
+    m, test := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    paging, chat := &recordingSender{}, &recordingSender{}
+    m.RegisterSender("paging", paging)
+    m.RegisterSender("chat", chat)
+    m.config.Alerts.Routing[SeverityCritical] = []string{"paging"}
+    m.config.Alerts.Routing[SeverityWarning] = []string{"chat"}
+
+    checkAndFlush(m, service)
+
+    if len(paging.kinds()) != 1 || len(chat.kinds()) != 0 || len(test.kinds()) != 0 {
+        t.Errorf("paging got %v, chat %v, test %v; want the alert routed to paging only", paging.kinds(), chat.kinds(), test.kinds())
+    }
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// requireAuth guards mutating API endpoints with the configured bearer
+// token. The endpoints are disabled entirely when no token is configured.
+func (m *Monitor) requireAuth(handler http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if m.config.APIToken == "" {
+            http.Error(w, "API is disabled: no api_token configured", http.StatusForbidden)
+            return
+        }
+
+        token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+        if subtle.ConstantTimeCompare([]byte(token), []byte(m.config.APIToken)) != 1 {
+            http.Error(w, "unauthorized", http.StatusUnauthorized)
+            return
+        }
+
+        handler(w, r)
+    }
+}
+
+func decodeServiceConfig(r *http.Request) (ServiceConfig, error) {
+    var service ServiceConfig
+    if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
+        return service, fmt.Errorf("invalid service config: %v", err)
+    }
+    return service, nil
+}
+
+func (m *Monitor) handleAddService(w http.ResponseWriter, r *http.Request) {
+    service, err := decodeServiceConfig(r)
+    // The response echoes the service as posted, without secrets read from files
+    posted := service
+    if err == nil {
+        err = loadServiceSecrets(&service)
+    }
+    if err == nil {
+        err = validateServiceConfig(service)
+    }
+    if err == nil {
+        err = m.groupKeyConflict(service)
+    }
+    if err == nil {
+        err = validateFleetServiceName(m.config.FleetHealthAlert, service)
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    if !m.addService(service) {
+        http.Error(w, fmt.Sprintf("service %q already exists", service.Name), http.StatusConflict)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(posted)
+}
+
+func (m *Monitor) handleUpdateService(w http.ResponseWriter, r *http.Request) {
+    name := r.PathValue("name")
+    service, err := decodeServiceConfig(r)
+    if err == nil && service.Name == "" {
+        service.Name = name
+    }
+    if err == nil && service.Name != name {
+        err = fmt.Errorf("service name %q does not match path %q", service.Name, name)
+    }
+    posted := service
+    if err == nil {
+        err = loadServiceSecrets(&service)
+    }
+    if err == nil {
+        err = validateServiceConfig(service)
+    }
+    if err == nil {
+        err = m.groupKeyConflict(service)
+    }
+    if err == nil {
+        err = validateFleetServiceName(m.config.FleetHealthAlert, service)
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    if !m.updateService(service) {
+        http.Error(w, fmt.Sprintf("service %q not found", name), http.StatusNotFound)
+        return
+    }
+
+    json.NewEncoder(w).Encode(posted)
+}
+
+func (m *Monitor) handleDeleteService(w http.ResponseWriter, r *http.Request) {
+    name := r.PathValue("name")
+    if !m.removeService(name) {
+        http.Error(w, fmt.Sprintf("service %q not found", name), http.StatusNotFound)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// addService starts monitoring a new service. It reports false if a service
+// of that name already exists.
+func (m *Monitor) addService(service ServiceConfig) bool {
+    m.statusMutex.Lock()
+    if _, exists := m.serviceStatus[service.Name]; exists {
+        m.statusMutex.Unlock()
+        return false
+    }
+    m.config.Services = append(m.config.Services, service)
+    m.serviceStatus[service.Name] = m.newServiceStatus(service)
+    m.statusMutex.Unlock()
+
+    m.startServiceMonitor(service)
+    return true
+}
+
+// updateService replaces a service's config and restarts its monitor. It
+// reports false if the service does not exist.
+func (m *Monitor) updateService(service ServiceConfig) bool {
+    m.statusMutex.Lock()
+    index := m.serviceIndex(service.Name)
+    if index < 0 {
+        m.statusMutex.Unlock()
+        return false
+    }
+    // Status history is kept so an update doesn't re-alert on an ongoing outage
+    m.config.Services[index] = service
+    m.statusMutex.Unlock()
+
+    m.stopServiceMonitor(service.Name)
+    m.resetServiceClient(service.Name)
+    m.startServiceMonitor(service)
+    return true
+}
+
+// removeService stops monitoring a service and drops its status. It reports
+// false if the service does not exist.
+func (m *Monitor) removeService(name string) bool {
+    m.statusMutex.Lock()
+    index := m.serviceIndex(name)
+    if index < 0 {
+        m.statusMutex.Unlock()
+        return false
+    }
+    if service := m.config.Services[index]; service.GroupKey != "" {
+        m.groupRecovered(service)
+    }
+    m.config.Services = append(m.config.Services[:index:index], m.config.Services[index+1:]...)
+    delete(m.serviceStatus, name)
+    m.statusMutex.Unlock()
+
+    m.stopServiceMonitor(name)
+    m.resetServiceClient(name)
+    return true
+}
+
+// serviceIndex must be called with statusMutex held
+func (m *Monitor) serviceIndex(name string) int {
+    for i, service := range m.config.Services {
+        if service.Name == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// resetServiceClient drops the client, compiled patterns and token source
+// cached for a service, so they are rebuilt from its current config
+func (m *Monitor) resetServiceClient(name string) {
+    m.clientMutex.Lock()
+    if client, ok := m.clients[name]; ok {
+        client.CloseIdleConnections()
+        delete(m.clients, name)
+    }
+    delete(m.patterns, name)
+    m.clientMutex.Unlock()
+
+    m.tokenMutex.Lock()
+    delete(m.tokenSources, name)
+    m.tokenMutex.Unlock()
+}
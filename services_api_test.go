@@ -0,0 +1,154 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+)
+
+// runningMonitors lists the services with a monitoring goroutine
+func runningMonitors(m *Monitor) []string {
+    m.monitorMutex.Lock()
+    defer m.monitorMutex.Unlock()
+    names := make([]string, 0, len(m.monitors))
+    for name := range m.monitors {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+func stopMonitors(m *Monitor) {
+    for _, name := range runningMonitors(m) {
+        m.stopServiceMonitor(name)
+    }
+}
+
+func TestServicesAPI(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    encode := func(service ServiceConfig) string {
+        body, _ := json.Marshal(service)
+        return string(body)
+    }
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{testService("api", server.URL)}, APIToken: testAPIToken})
+    m.startServiceMonitor(testService("api", server.URL))
+    t.Cleanup(func() { stopMonitors(m) })
+
+    updated := testService("web", server.URL)
+    updated.CheckInterval = 30
+    steps := []struct {
+        name     string
+        method   string
+        path     string
+        body     string
+        want     int
+        monitors []string // running afterwards
+    }{
+        {"add", http.MethodPost, "/services", encode(testService("web", server.URL)), http.StatusCreated, []string{"api", "web"}},
+        {"add duplicate", http.MethodPost, "/services", encode(testService("web", server.URL)), http.StatusConflict, []string{"api", "web"}},
+        {"add invalid", http.MethodPost, "/services", `{"name": "bad", "url": "not a url"}`, http.StatusBadRequest, []string{"api", "web"}},
+        {"update", http.MethodPut, "/services/web", encode(updated), http.StatusOK, []string{"api", "web"}},
+        {"update with mismatched name", http.MethodPut, "/services/web", encode(testService("api", server.URL)), http.StatusBadRequest, []string{"api", "web"}},
+        {"update unknown", http.MethodPut, "/services/db", encode(testService("db", server.URL)), http.StatusNotFound, []string{"api", "web"}},
+        {"delete", http.MethodDelete, "/services/api", "", http.StatusNoContent, []string{"web"}},
+        {"delete unknown", http.MethodDelete, "/services/api", "", http.StatusNotFound, []string{"web"}},
+    }
+
+    for _, step := range steps {
+        resp := apiRequest(m, step.method, step.path, step.body, true)
+        if resp.Code != step.want {
+            t.Fatalf("%s: %s %s = %d, want %d: %s", step.name, step.method, step.path, resp.Code, step.want, resp.Body)
+        }
+        if got := runningMonitors(m); !equalStrings(got, step.monitors) {
+            t.Fatalf("%s: running monitors %v, want %v", step.name, got, step.monitors)
+        }
+    }
+
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+    if _, ok := m.serviceStatus["api"]; ok {
+        t.Error("deleted service still has a status")
+    }
+    if got := m.findService("web").CheckInterval; got != 30 {
+        t.Errorf("updated check_interval = %d, want 30", got)
+    }
+}
+
+func TestServicesAPIRequiresToken(t *testing.T) {
+    tests := []struct {
+        name   string
+        config MonitorConfig
+        token  bool
+        want   int
+    }{
+        {"no api_token configured", MonitorConfig{}, true, http.StatusForbidden},
+        {"missing token", MonitorConfig{APIToken: testAPIToken}, false, http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, tt.config)
+            if resp := apiRequest(m, http.MethodDelete, "/services/api", "", tt.token); resp.Code != tt.want {
+                t.Errorf("DELETE /services/api = %d, want %d", resp.Code, tt.want)
+            }
+        })
+    }
+}
+
+func TestServicesAPISecretFiles(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    secretPath := filepath.Join(t.TempDir(), "client-secret")
+    if err := os.WriteFile(secretPath, []byte("s3cret\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    encode := func(name, secretFile string) string {
+        service := testService(name, server.URL)
+        service.OAuth2 = &OAuth2Config{TokenURL: server.URL + "/token", ClientID: "monitor", ClientSecretFile: secretFile}
+        body, _ := json.Marshal(service)
+        return string(body)
+    }
+    m, _ := newTestMonitor(t, MonitorConfig{APIToken: testAPIToken})
+    t.Cleanup(func() { stopMonitors(m) })
+
+    tests := []struct {
+        name       string
+        method     string
+        path       string
+        body       string
+        want       int
+        wantSecret string // of the stored service, when the request succeeds
+    }{
+        {"add reads the file", http.MethodPost, "/services", encode("api", secretPath), http.StatusCreated, "s3cret"},
+        {"add with a missing file", http.MethodPost, "/services", encode("web", secretPath+".missing"), http.StatusBadRequest, ""},
+        {"update with a missing file", http.MethodPut, "/services/api", encode("api", secretPath+".missing"), http.StatusBadRequest, "s3cret"},
+        {"update reads the file", http.MethodPut, "/services/api", encode("api", secretPath), http.StatusOK, "s3cret"},
+    }
+    for _, tt := range tests {
+        resp := apiRequest(m, tt.method, tt.path, tt.body, true)
+        if resp.Code != tt.want {
+            t.Fatalf("%s: %s %s = %d, want %d: %s", tt.name, tt.method, tt.path, resp.Code, tt.want, resp.Body)
+        }
+        if tt.want == http.StatusBadRequest && !strings.Contains(resp.Body.String(), "oauth2.client_secret_file") {
+            t.Errorf("%s: error %q does not name the secret file", tt.name, resp.Body)
+        }
+        if strings.Contains(resp.Body.String(), "s3cret") {
+            t.Errorf("%s: response %s echoes the secret", tt.name, resp.Body)
+        }
+
+        m.statusMutex.RLock()
+        stored := m.findService("api")
+        m.statusMutex.RUnlock()
+        if tt.wantSecret != "" && (stored.OAuth2 == nil || stored.OAuth2.ClientSecret != tt.wantSecret) {
+            t.Errorf("%s: stored oauth2 %+v, want client secret %q", tt.name, stored.OAuth2, tt.wantSecret)
+        }
+    }
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+    if _, ok := m.serviceStatus["web"]; ok {
+        t.Error("service with an unreadable secret file was added")
+    }
+}
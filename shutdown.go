@@ -0,0 +1,71 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+)
+
+// defaultShutdownDrain bounds the wait for in-flight work on shutdown when
+// shutdown_drain is unset
+const defaultShutdownDrain = 30 * time.Second
+
+// Shutdown stops scheduling checks and waits up to drain for in-flight
+// checks and queued alert deliveries to finish. Whatever is still running
+// when drain expires is cancelled, and an error reports the timeout. The
+// supervisor loops are stopped last.
+func (m *Monitor) Shutdown(drain time.Duration) error {
+    m.inflightMutex.Lock()
+    m.draining = true
+    m.inflightMutex.Unlock()
+
+    m.monitorMutex.Lock()
+    for name, stop := range m.monitors {
+        close(stop)
+        delete(m.monitors, name)
+    }
+    m.monitorMutex.Unlock()
+
+    // Checks finishing now may still queue alerts, so they are waited for
+    // before the alert queue
+    drained := make(chan struct{})
+    go func() {
+        m.checkWG.Wait()
+        m.alertWG.Wait()
+        close(drained)
+    }()
+
+    var err error
+    select {
+    case <-drained:
+    case <-time.After(drain):
+        err = fmt.Errorf("in-flight checks and alerts did not finish within %s and were cancelled", drain)
+    }
+    m.cancel()
+    m.supervisorWG.Wait()
+    return err
+}
+
+// shutdownOnSignal drains the monitor and exits on SIGTERM or SIGINT
+func (m *Monitor) shutdownOnSignal() {
+    drain := time.Duration(m.config.ShutdownDrain) * time.Second
+    if drain <= 0 {
+        drain = defaultShutdownDrain
+    }
+
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+    go func() {
+        sig := <-signals
+        log.Printf("Received %s, draining for up to %s", sig, drain)
+        if err := m.Shutdown(drain); err != nil {
+            log.Printf("Shutdown: %v", err)
+            os.Exit(1)
+        }
+        log.Printf("Shutdown complete")
+        os.Exit(0)
+    }()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestShutdownDrain(t *testing.T) {
+    tests := []struct {
+        name      string
+        delay     time.Duration // how long the check's response takes; 0 hangs until cancelled
+        drain     time.Duration
+        wantErr   bool
+        wantState ServiceState // recorded by the in-flight check
+    }{
+        {"check finishes within the drain", 200 * time.Millisecond, 5 * time.Second, false, StateUp},
+        {"check outlasting the drain is cancelled", 0, 200 * time.Millisecond, true, StateUnknown},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var requests atomic.Int32
+            started := make(chan struct{}, 1)
+            release := make(chan struct{})
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                requests.Add(1)
+                started <- struct{}{}
+                if tt.delay == 0 {
+                    select {
+                    case <-r.Context().Done():
+                    case <-release:
+                    }
+                    return
+                }
+                time.Sleep(tt.delay)
+            }))
+            defer server.Close()
+            defer close(release)
+
+            service := testService("api", server.URL)
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            checked := make(chan struct{})
+            go func() {
+                m.runCheck(service)
+                close(checked)
+            }()
+            <-started
+
+            start := time.Now()
+            err := m.Shutdown(tt.drain)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("Shutdown: %v, want error %v", err, tt.wantErr)
+            }
+            if elapsed := time.Since(start); elapsed > tt.drain+time.Second {
+                t.Errorf("Shutdown took %s, drain is %s", elapsed, tt.drain)
+            }
+            select {
+            case <-checked:
+            case <-time.After(2 * time.Second):
+                t.Fatal("in-flight check still running after Shutdown")
+            }
+
+            m.statusMutex.RLock()
+            state := m.serviceStatus["api"].State
+            m.statusMutex.RUnlock()
+            if state != tt.wantState {
+                t.Errorf("state %s, want %s", state, tt.wantState)
+            }
+            if kinds := sender.kinds(); len(kinds) != 0 {
+                t.Errorf("cancelled shutdown delivered %v", kinds)
+            }
+
+            // Nothing is scheduled once Shutdown has begun
+            m.runCheck(service)
+            if got := requests.Load(); got != 1 {
+                t.Errorf("%d requests, want only the in-flight one", got)
+            }
+        })
+    }
+}
+
+func TestShutdownWaitsForAlerts(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", backend.URL)
+    webhook := newHangingServer(t)
+    m, err := NewMonitorFromConfig(MonitorConfig{
+        Services:     []ServiceConfig{service},
+        AlertTimeout: 30,
+        Alerts: AlertConfig{
+            Slack:         SlackConfig{WebhookURL: webhook.URL},
+            Routing:       map[string][]string{SeverityWarning: {ChannelSlack}},
+            DeliveryRetry: map[string]RetryPolicy{ChannelSlack: {Attempts: 1}},
+        },
+    })
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    defer m.cancel()
+
+    m.checkService(service)
+    err = m.Shutdown(200 * time.Millisecond)
+    if err == nil || !strings.Contains(err.Error(), "did not finish within 200ms") {
+        t.Fatalf("Shutdown with a hanging delivery: %v, want a drain timeout", err)
+    }
+    done := make(chan struct{})
+    go func() {
+        m.alertWG.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(2 * time.Second):
+        t.Fatal("alert delivery not cancelled after the drain expired")
+    }
+}
+
+func TestShutdownStopsSupervisors(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{
+        Services:         []ServiceConfig{testService("api", "https://api.example.com/")},
+        FleetHealthAlert: &FleetHealthAlert{Threshold: 50, Channels: []string{ChannelSlack}},
+        StatusExport:     &StatusExportConfig{Path: filepath.Join(t.TempDir(), "status.json"), Interval: 3600},
+    })
+    m.superviseMonitoring()
+    m.startFleetHealth()
+    m.startStatusExport()
+
+    done := make(chan error, 1)
+    go func() { done <- m.Shutdown(time.Second) }()
+    select {
+    case err := <-done:
+        if err != nil {
+            t.Errorf("Shutdown: %v", err)
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("Shutdown did not return once the supervisor loops were cancelled")
+    }
+}
+
+func TestShutdownDuringRetryDelay(t *testing.T) {
+    var requests atomic.Int32
+    started := make(chan struct{}, 1)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        w.WriteHeader(http.StatusInternalServerError)
+        started <- struct{}{}
+    }))
+    defer server.Close()
+
+    service := testService("api", server.URL)
+    service.RetryAttempts, service.RetryDelay = 3, 60
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    checked := make(chan struct{})
+    go func() {
+        m.runCheck(service)
+        close(checked)
+    }()
+    <-started
+
+    start := time.Now()
+    if err := m.Shutdown(200 * time.Millisecond); err == nil {
+        t.Error("Shutdown with a check waiting to retry reported no drain timeout")
+    }
+    select {
+    case <-checked:
+    case <-time.After(2 * time.Second):
+        t.Fatal("check still waiting out its retry delay after Shutdown")
+    }
+    if elapsed := time.Since(start); elapsed > 2*time.Second {
+        t.Errorf("Shutdown and the check took %s, want about the drain", elapsed)
+    }
+    if got := requests.Load(); got != 1 {
+        t.Errorf("%d requests, want no retry after Shutdown", got)
+    }
+    if kinds := sender.kinds(); len(kinds) != 0 {
+        t.Errorf("cancelled check delivered %v", kinds)
+    }
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// alertSilence suppresses outbound notifications until it expires. An empty
+// Services list silences every service.
+type alertSilence struct {
+    Until    time.Time `json:"until"`
+    Services []string  `json:"services,omitempty"`
+}
+
+type silenceState struct {
+    mutex   sync.RWMutex
+    current *alertSilence
+}
+
+func (s *alertSilence) covers(service string, now time.Time) bool {
+    if s == nil || !now.Before(s.Until) {
+        return false
+    }
+    if len(s.Services) == 0 {
+        return true
+    }
+    for _, name := range s.Services {
+        if name == service {
+            return true
+        }
+    }
+    return false
+}
+
+func (m *Monitor) isSilenced(service string) bool {
+    m.silence.mutex.RLock()
+    defer m.silence.mutex.RUnlock()
+    return m.silence.current.covers(service, time.Now())
+}
+
+func (m *Monitor) handleSilence(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case http.MethodPost:
+        var request struct {
+            Duration string   `json:"duration"`
+            Services []string `json:"services"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+            http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+            return
+        }
+
+        duration, err := time.ParseDuration(request.Duration)
+        if err != nil || duration <= 0 {
+            http.Error(w, fmt.Sprintf("invalid duration %q", request.Duration), http.StatusBadRequest)
+            return
+        }
+
+        silence := &alertSilence{
+            Until:    time.Now().Add(duration),
+            Services: request.Services,
+        }
+        m.silence.mutex.Lock()
+        m.silence.current = silence
+        m.silence.mutex.Unlock()
+
+        json.NewEncoder(w).Encode(silence)
+    case http.MethodDelete:
+        m.silence.mutex.Lock()
+        m.silence.current = nil
+        m.silence.mutex.Unlock()
+
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    }
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestSilence(t *testing.T) {
+    tests := []struct {
+        name    string
+        request string
+        wait    time.Duration  // before the services fail
+        want    map[string]int // service -> alerts delivered
+    }{
+        {"silence every service", `{"duration": "1h"}`, 0, map[string]int{"api": 0, "web": 0}},
+        {"filtered silence", `{"duration": "1h", "services": ["web"]}`, 0, map[string]int{"api": 1, "web": 0}},
+        {"expired silence", `{"duration": "20ms"}`, 40 * time.Millisecond, map[string]int{"api": 1, "web": 1}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusInternalServerError)
+            services := []ServiceConfig{testService("api", server.URL), testService("web", server.URL)}
+            m, sender := newTestMonitor(t, MonitorConfig{Services: services, APIToken: testAPIToken})
+
+            if resp := apiRequest(m, http.MethodPost, "/silence", tt.request, true); resp.Code != http.StatusOK {
+                t.Fatalf("POST /silence = %d: %s", resp.Code, resp.Body)
+            }
+            time.Sleep(tt.wait)
+            for _, service := range services {
+                checkAndFlush(m, service)
+            }
+
+            got := make(map[string]int)
+            sender.mutex.Lock()
+            for _, event := range sender.events {
+                got[event.Service.Name]++
+            }
+            sender.mutex.Unlock()
+            for name, want := range tt.want {
+                if got[name] != want {
+                    t.Errorf("%s: delivered %d alerts, want %d", name, got[name], want)
+                }
+            }
+        })
+    }
+}
+
+func TestSilenceLifted(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, APIToken: testAPIToken})
+
+    apiRequest(m, http.MethodPost, "/silence", `{"duration": "1h"}`, true)
+    checkAndFlush(m, service)
+    if resp := apiRequest(m, http.MethodDelete, "/silence", "", true); resp.Code != http.StatusNoContent {
+        t.Fatalf("DELETE /silence = %d", resp.Code)
+    }
+    checkAndFlush(m, service)
+
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Errorf("delivered %v, want the alert deferred past the silence", got)
+    }
+}
+
+func TestSilenceRequests(t *testing.T) {
+    tests := []struct {
+        name  string
+        body  string
+        token bool
+        want  int
+    }{
+        {"valid", `{"duration": "30m"}`, true, http.StatusOK},
+        {"no token", `{"duration": "30m"}`, false, http.StatusUnauthorized},
+        {"bad duration", `{"duration": "soon"}`, true, http.StatusBadRequest},
+        {"negative duration", `{"duration": "-5m"}`, true, http.StatusBadRequest},
+        {"bad json", `{"duration":`, true, http.StatusBadRequest},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{APIToken: testAPIToken})
+            if resp := apiRequest(m, http.MethodPost, "/silence", tt.body, tt.token); resp.Code != tt.want {
+                t.Errorf("POST /silence = %d, want %d", resp.Code, tt.want)
+            }
+        })
+    }
+}
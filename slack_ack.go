@@ -0,0 +1,140 @@
+package main
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+)
+
+// slackAcknowledgeAction is the action_id of the Acknowledge button
+const slackAcknowledgeAction = "acknowledge"
+
+// slackSignatureMaxAge rejects replayed interaction callbacks
+const slackSignatureMaxAge = 5 * time.Minute
+
+// slackAlertBlocks renders an alert with link buttons for the service's
+// runbook and dashboard and, when interactive, an Acknowledge button whose
+// value is the service name
+func slackAlertBlocks(service ServiceConfig, text string, interactive bool) []interface{} {
+    var elements []interface{}
+    for _, link := range serviceLinks(service) {
+        elements = append(elements, map[string]interface{}{
+            "type": "button",
+            "text": map[string]interface{}{"type": "plain_text", "text": link.label},
+            "url":  link.url,
+        })
+    }
+    if interactive {
+        elements = append(elements, map[string]interface{}{
+            "type":      "button",
+            "action_id": slackAcknowledgeAction,
+            "text":      map[string]interface{}{"type": "plain_text", "text": "Acknowledge"},
+            "style":     "primary",
+            "value":     service.Name,
+        })
+    }
+
+    return []interface{}{
+        map[string]interface{}{
+            "type": "section",
+            "text": map[string]interface{}{"type": "mrkdwn", "text": text},
+        },
+        map[string]interface{}{
+            "type":     "actions",
+            "elements": elements,
+        },
+    }
+}
+
+// verifySlackSignature checks the v0 request signature Slack computes with
+// the app's signing secret
+func verifySlackSignature(secret string, header http.Header, body []byte) error {
+    timestamp := header.Get("X-Slack-Request-Timestamp")
+    seconds, err := strconv.ParseInt(timestamp, 10, 64)
+    if err != nil {
+        return fmt.Errorf("invalid request timestamp")
+    }
+    if age := time.Since(time.Unix(seconds, 0)); age > slackSignatureMaxAge || age < -slackSignatureMaxAge {
+        return fmt.Errorf("request timestamp too old")
+    }
+
+    mac := hmac.New(sha256.New, []byte(secret))
+    fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+    expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+    if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+        return fmt.Errorf("signature mismatch")
+    }
+    return nil
+}
+
+// handleSlackInteraction receives button clicks from Slack and acknowledges
+// the service named by an Acknowledge action
+func (m *Monitor) handleSlackInteraction(w http.ResponseWriter, r *http.Request) {
+    secret := m.config.Alerts.Slack.SigningSecret
+    if secret == "" {
+        http.Error(w, "slack interactions are not configured", http.StatusNotFound)
+        return
+    }
+
+    body, err := io.ReadAll(io.LimitReader(r.Body, defaultMaxBodyBytes))
+    if err != nil {
+        http.Error(w, "error reading request", http.StatusBadRequest)
+        return
+    }
+    if err := verifySlackSignature(secret, r.Header, body); err != nil {
+        http.Error(w, err.Error(), http.StatusUnauthorized)
+        return
+    }
+
+    form, err := url.ParseQuery(string(body))
+    if err != nil {
+        http.Error(w, "invalid form body", http.StatusBadRequest)
+        return
+    }
+    var payload struct {
+        User struct {
+            Username string `json:"username"`
+        } `json:"user"`
+        Actions []struct {
+            ActionID string `json:"action_id"`
+            Value    string `json:"value"`
+        } `json:"actions"`
+    }
+    if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+        http.Error(w, fmt.Sprintf("invalid payload: %v", err), http.StatusBadRequest)
+        return
+    }
+
+    for _, action := range payload.Actions {
+        if action.ActionID != slackAcknowledgeAction {
+            continue
+        }
+        if m.acknowledge(action.Value, payload.User.Username) {
+            log.Printf("Service %s acknowledged by %s via Slack", action.Value, payload.User.Username)
+        }
+    }
+    w.WriteHeader(http.StatusOK)
+}
+
+// acknowledge marks an ongoing outage as being handled. The flag clears
+// when the service recovers.
+func (m *Monitor) acknowledge(service, by string) bool {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+
+    status, ok := m.serviceStatus[service]
+    if !ok || status.DownSince == nil {
+        return false
+    }
+    status.Acknowledged = true
+    status.AcknowledgedBy = by
+    return true
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+const testSigningSecret = "slack-signing-secret"
+
+func TestSlackAcknowledgeButton(t *testing.T) {
+    tests := []struct {
+        name          string
+        signingSecret string
+        wantButton    bool
+    }{
+        {"interactive app", testSigningSecret, true},
+        {"plain webhook", "", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            payloads := make(chan []byte, 1)
+            webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                body, _ := io.ReadAll(r.Body)
+                payloads <- body
+            }))
+            defer webhook.Close()
+
+            config := MonitorConfig{}
+            config.Alerts.Slack.WebhookURL = webhook.URL
+            config.Alerts.Slack.SigningSecret = tt.signingSecret
+            m, _ := newTestMonitor(t, config)
+
+            if err := m.sendSlackAlert(context.Background(), m.config.Alerts.Slack.WebhookURL, testService("api", "https://api.example.com/"), "connection refused", HistorySummary{}); err != nil {
+                t.Fatalf("sendSlackAlert: %v", err)
+            }
+
+            var payload struct {
+                Blocks []struct {
+                    Type     string `json:"type"`
+                    Elements []struct {
+                        ActionID string `json:"action_id"`
+                        Value    string `json:"value"`
+                    } `json:"elements"`
+                } `json:"blocks"`
+            }
+            if err := json.Unmarshal(<-payloads, &payload); err != nil {
+                t.Fatal(err)
+            }
+            found := false
+            for _, block := range payload.Blocks {
+                for _, element := range block.Elements {
+                    if block.Type == "actions" && element.ActionID == slackAcknowledgeAction && element.Value == "api" {
+                        found = true
+                    }
+                }
+            }
+            if found != tt.wantButton {
+                t.Errorf("acknowledge button present %v, want %v: %+v", found, tt.wantButton, payload)
+            }
+        })
+    }
+}
+
+func TestSlackInteraction(t *testing.T) {
+    tests := []struct {
+        name      string
+        secret    string        // signs the callback
+        age       time.Duration // of the request timestamp
+        service   string
+        wantCode  int
+        wantAcked bool
+    }{
+        {"signed callback acks", testSigningSecret, 0, "api", http.StatusOK, true},
+        {"wrong secret", "guessed", 0, "api", http.StatusUnauthorized, false},
+        {"replayed callback", testSigningSecret, 10 * time.Minute, "api", http.StatusUnauthorized, false},
+        {"unknown service", testSigningSecret, 0, "billing", http.StatusOK, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", server.URL)
+            config := MonitorConfig{Services: []ServiceConfig{service}}
+            config.Alerts.Slack.SigningSecret = testSigningSecret
+            m, _ := newTestMonitor(t, config)
+            checkAndFlush(m, service)
+
+            interaction := fmt.Sprintf(`{"user":{"username":"oncall"},"actions":[{"action_id":%q,"value":%q}]}`, slackAcknowledgeAction, tt.service)
+            body := "payload=" + url.QueryEscape(interaction)
+            timestamp := strconv.FormatInt(time.Now().Add(-tt.age).Unix(), 10)
+            mac := hmac.New(sha256.New, []byte(tt.secret))
+            fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+            req := httptest.NewRequest(http.MethodPost, "/slack/interactions", strings.NewReader(body))
+            req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+            req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+            req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+            recorder := httptest.NewRecorder()
+            m.listenerMux(ListenerConfig{}).ServeHTTP(recorder, req)
+
+            if recorder.Code != tt.wantCode {
+                t.Errorf("callback returned %d, want %d", recorder.Code, tt.wantCode)
+            }
+            m.statusMutex.RLock()
+            status := m.serviceStatus["api"]
+            acked, by := status.Acknowledged, status.AcknowledgedBy
+            m.statusMutex.RUnlock()
+            if acked != tt.wantAcked || (acked && by != "oncall") {
+                t.Errorf("acknowledged %v by %q, want %v", acked, by, tt.wantAcked)
+            }
+        })
+    }
+}
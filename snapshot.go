@@ -0,0 +1,57 @@
+package main
+
+import (
+    "sort"
+    "time"
+)
+
+// Snapshot returns copies of all service statuses, sorted by name. The
+// copies share no memory with the monitor, so callers may keep and read
+// them without holding any lock.
+func (m *Monitor) Snapshot() []ServiceStatus {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    snapshot := make([]ServiceStatus, 0, len(m.serviceStatus))
+    for _, status := range m.serviceStatus {
+        snapshot = append(snapshot, status.clone())
+    }
+    sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Name < snapshot[j].Name })
+    return snapshot
+}
+
+// clone must be called with statusMutex held
+func (s *ServiceStatus) clone() ServiceStatus {
+    c := *s
+    if s.RecoveryTime != nil {
+        recoveryTime := *s.RecoveryTime
+        c.RecoveryTime = &recoveryTime
+    }
+    if s.DownSince != nil {
+        downSince := *s.DownSince
+        c.DownSince = &downSince
+    }
+    c.Transitions = append([]time.Time(nil), s.Transitions...)
+    c.Errors.entries = append([]errorCount(nil), s.Errors.entries...)
+    c.Endpoints = append([]EndpointResult(nil), s.Endpoints...)
+    c.ResolvedIPs = append([]string(nil), s.ResolvedIPs...)
+    if s.Latency != nil {
+        latency := *s.Latency
+        latency.bounds = append([]float64(nil), s.Latency.bounds...)
+        latency.counts = append([]uint64(nil), s.Latency.counts...)
+        c.Latency = &latency
+    }
+    if s.History != nil {
+        history := *s.History
+        history.records = append([]CheckRecord(nil), s.History.records...)
+        c.History = &history
+    }
+    if s.Trend != nil {
+        trend := *s.Trend
+        trend.raw = append([]trendPoint(nil), s.Trend.raw...)
+        trend.minute = append([]trendAggregate(nil), s.Trend.minute...)
+        trend.fiveMinute = append([]trendAggregate(nil), s.Trend.fiveMinute...)
+        c.Trend = &trend
+    }
+    return c
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+    "net/http"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestSnapshotIndependent(t *testing.T) {
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service, testService("web", server.URL)}})
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusInternalServerError)
+    checkAndFlush(m, service)
+
+    snapshot := m.Snapshot()
+    if len(snapshot) != 2 || snapshot[0].Name != "api" || snapshot[1].Name != "web" {
+        t.Fatalf("snapshot %+v, want api and web in order", snapshot)
+    }
+    got := snapshot[0]
+    if got.RecoveryTime == nil || got.DownSince == nil {
+        t.Fatalf("snapshot recovery time %v, down since %v, want both set", got.RecoveryTime, got.DownSince)
+    }
+    recoveryTime, downSince, lastError := *got.RecoveryTime, *got.DownSince, got.LastError
+    records := len(got.History.records)
+
+    // Mutate everything the snapshot took a copy of
+    m.statusMutex.Lock()
+    status := m.serviceStatus["api"]
+    *status.RecoveryTime = status.RecoveryTime.Add(time.Hour)
+    *status.DownSince = status.DownSince.Add(time.Hour)
+    status.LastError = "changed"
+    status.History.add(CheckRecord{Time: time.Now()})
+    status.Trend.add(CheckRecord{Time: time.Now()})
+    status.Latency.counts[0] += 100
+    m.statusMutex.Unlock()
+
+    if !got.RecoveryTime.Equal(recoveryTime) || !got.DownSince.Equal(downSince) {
+        t.Errorf("snapshot times moved to %v and %v", got.RecoveryTime, got.DownSince)
+    }
+    if got.LastError != lastError || len(got.History.records) != records {
+        t.Errorf("snapshot error %q with %d records, want %q with %d", got.LastError, len(got.History.records), lastError, records)
+    }
+    if len(got.Trend.raw) != 3 || got.Latency.counts[0] >= 100 {
+        t.Errorf("snapshot trend %d points, latency counts %v", len(got.Trend.raw), got.Latency.counts)
+    }
+}
+
+func TestSnapshotDuringChecks(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    service := testService("api", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    var wg sync.WaitGroup
+    wg.Add(2)
+    go func() {
+        defer wg.Done()
+        for i := 0; i < 20; i++ {
+            checkAndFlush(m, service)
+        }
+    }()
+    go func() {
+        defer wg.Done()
+        for i := 0; i < 20; i++ {
+            for _, status := range m.Snapshot() {
+                _ = status.History.records
+            }
+        }
+    }()
+    wg.Wait()
+}
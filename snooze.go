@@ -0,0 +1,89 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// snoozedUntil returns when the service's snooze expires, zero if it is
+// not snoozed
+func (m *Monitor) snoozedUntil(service string) time.Time {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+    if serviceStatus, ok := m.serviceStatus[service]; ok && time.Now().Before(serviceStatus.SnoozedUntil) {
+        return serviceStatus.SnoozedUntil
+    }
+    return time.Time{}
+}
+
+// alertsSuppressed reports whether notifications for the service are held
+// back, and by what: a silence covering it, its own snooze or a
+// maintenance calendar event
+func (m *Monitor) alertsSuppressed(service string) (string, bool) {
+    if m.isSilenced(service) {
+        return "silence", true
+    }
+    if !m.snoozedUntil(service).IsZero() {
+        return "snooze", true
+    }
+    if summary, ok := m.inMaintenance(service); ok {
+        return fmt.Sprintf("maintenance event %q", summary), true
+    }
+    return "", false
+}
+
+// snoozeEntry adds the snooze state to a status entry; statusMutex must be held
+func snoozeEntry(entry map[string]interface{}, s *ServiceStatus) {
+    remaining := time.Until(s.SnoozedUntil)
+    entry["snoozed"] = remaining > 0
+    if remaining > 0 {
+        entry["snoozed_until"] = s.SnoozedUntil
+        entry["snooze_remaining"] = remaining.Round(time.Second).String()
+    }
+}
+
+// handleSnoozeService suppresses one service's alerts for the duration
+// given as ?duration=30m, while its checks keep running
+func (m *Monitor) handleSnoozeService(w http.ResponseWriter, r *http.Request) {
+    name := r.PathValue("name")
+    duration, err := time.ParseDuration(r.URL.Query().Get("duration"))
+    if err != nil || duration <= 0 {
+        http.Error(w, fmt.Sprintf("invalid duration %q", r.URL.Query().Get("duration")), http.StatusBadRequest)
+        return
+    }
+
+    until := time.Now().Add(duration)
+    if !m.setSnooze(name, until) {
+        http.Error(w, fmt.Sprintf("service %q not found", name), http.StatusNotFound)
+        return
+    }
+
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "service": name,
+        "until":   until,
+    })
+}
+
+func (m *Monitor) handleUnsnoozeService(w http.ResponseWriter, r *http.Request) {
+    name := r.PathValue("name")
+    if !m.setSnooze(name, time.Time{}) {
+        http.Error(w, fmt.Sprintf("service %q not found", name), http.StatusNotFound)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Monitor) setSnooze(name string, until time.Time) bool {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+
+    serviceStatus, ok := m.serviceStatus[name]
+    if !ok {
+        return false
+    }
+    serviceStatus.SnoozedUntil = until
+    return true
+}
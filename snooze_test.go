@@ -0,0 +1,142 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestSnoozeDefersAlert(t *testing.T) {
+    tests := []struct {
+        name    string
+        expires int      // check before which the snooze expires, -1 for never
+        codes   []int    // service response per check
+        want    []string // event kinds delivered
+    }{
+        {
+            name:    "outage outlasting the snooze pages after expiry",
+            expires: 2,
+            codes:   []int{500, 500, 500, 200},
+            want:    []string{EventAlert, EventRecovery},
+        },
+        {
+            name:    "outage inside the snooze stays quiet",
+            expires: -1,
+            codes:   []int{500, 500, 200},
+            want:    []string{},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusOK)
+            service := testService("api", backend.URL)
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            m.setSnooze("api", time.Now().Add(time.Hour))
+
+            for i, code := range tt.codes {
+                if i == tt.expires {
+                    m.setSnooze("api", time.Time{})
+                }
+                backend.code.Store(int32(code))
+                checkAndFlush(m, service)
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestSnoozeAPI(t *testing.T) {
+    tests := []struct {
+        name          string
+        method        string
+        path          string
+        token         bool
+        wantCode      int
+        wantSnoozed   bool
+        wantRemaining string
+    }{
+        {"snooze for 30 minutes", http.MethodPost, "/services/api/snooze?duration=30m", true, http.StatusOK, true, "30m0s"},
+        {"unsnooze", http.MethodDelete, "/services/api/snooze", true, http.StatusNoContent, false, ""},
+        {"missing duration", http.MethodPost, "/services/api/snooze", true, http.StatusBadRequest, false, ""},
+        {"negative duration", http.MethodPost, "/services/api/snooze?duration=-5m", true, http.StatusBadRequest, false, ""},
+        {"unknown service", http.MethodPost, "/services/web/snooze?duration=30m", true, http.StatusNotFound, false, ""},
+        {"unauthenticated", http.MethodPost, "/services/api/snooze?duration=30m", false, http.StatusUnauthorized, false, ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusOK)
+            service := testService("api", backend.URL)
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, APIToken: testAPIToken})
+            if tt.method == http.MethodDelete {
+                m.setSnooze("api", time.Now().Add(time.Hour))
+            }
+
+            if code := apiRequest(m, tt.method, tt.path, "", tt.token).Code; code != tt.wantCode {
+                t.Fatalf("%s %s = %d, want %d", tt.method, tt.path, code, tt.wantCode)
+            }
+
+            var health map[string]struct {
+                Snoozed   bool   `json:"snoozed"`
+                Remaining string `json:"snooze_remaining"`
+            }
+            json.Unmarshal(apiRequest(m, http.MethodGet, "/health", "", true).Body.Bytes(), &health)
+            got := health["api"]
+            if got.Snoozed != tt.wantSnoozed || got.Remaining != tt.wantRemaining {
+                t.Errorf("snoozed %v with %q remaining, want %v with %q", got.Snoozed, got.Remaining, tt.wantSnoozed, tt.wantRemaining)
+            }
+        })
+    }
+}
+
+func TestSnoozeExpires(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", backend.URL)
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, APIToken: testAPIToken})
+
+    if code := apiRequest(m, http.MethodPost, "/services/api/snooze?duration=100ms", "", true).Code; code != http.StatusOK {
+        t.Fatalf("snooze = %d", code)
+    }
+    checkAndFlush(m, service)
+    if got := sender.kinds(); len(got) != 0 {
+        t.Fatalf("delivered %v while snoozed, want nothing", got)
+    }
+
+    time.Sleep(150 * time.Millisecond)
+    checkAndFlush(m, service)
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Errorf("delivered %v after the snooze expired, want an alert", got)
+    }
+}
+
+func TestRecoveryWhileSnoozed(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", backend.URL)
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    m.setSnooze("api", time.Now().Add(time.Hour))
+
+    checkAndFlush(m, service)
+    backend.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+
+    m.statusMutex.RLock()
+    status := m.serviceStatus["api"]
+    state, downSince, suppressed := status.State, status.DownSince, status.AlertSuppressed
+    m.statusMutex.RUnlock()
+    if state != StateUp || downSince != nil || suppressed {
+        t.Errorf("state %s, down since %v, alert suppressed %v; want the incident cleared", state, downSince, suppressed)
+    }
+
+    // A later outage after the snooze pages as a fresh incident
+    m.setSnooze("api", time.Time{})
+    backend.code.Store(http.StatusInternalServerError)
+    checkAndFlush(m, service)
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Errorf("delivered %v, want only the new outage's alert", got)
+    }
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+)
+
+const ChannelSNS = "sns"
+
+// SNSConfig publishes alerts to an AWS SNS topic. Credentials come from the
+// default AWS chain (environment, shared config, instance role).
+type SNSConfig struct {
+    TopicARN string `json:"topic_arn"`
+    Region   string `json:"region"` // defaults to the region in the AWS chain
+}
+
+// snsPublisher publishes one message to the configured topic
+type snsPublisher interface {
+    Publish(ctx context.Context, subject, message string) error
+}
+
+// newSNSPublisher is set by alert_sns.go when built with the sns tag, so
+// the AWS SDK is only pulled in by builds that need it
+var newSNSPublisher func(config SNSConfig) (snsPublisher, error)
+
+func validateSNSConfig(config *SNSConfig) error {
+    if config == nil {
+        return nil
+    }
+    if config.TopicARN == "" {
+        return fmt.Errorf("alerts.sns: topic_arn is required")
+    }
+    if newSNSPublisher == nil {
+        return fmt.Errorf("alerts.sns: SNS is not available in this build (build with -tags sns)")
+    }
+    return nil
+}
+
+// sendSNSAlert publishes the alert record as JSON so subscribers such as
+// Lambda can parse it; SMS and email subscribers see the subject and body
+func (m *Monitor) sendSNSAlert(ctx context.Context, event string, service ServiceConfig, message string) error {
+    body, err := json.Marshal(alertRecord{
+        Time:     m.formatTime(time.Now()),
+        Event:    event,
+        Service:  service.Name,
+        Severity: serviceSeverity(service),
+        Message:  message,
+    })
+    if err != nil {
+        return err
+    }
+
+    subject := fmt.Sprintf("[ALERT] %s is DOWN", service.Name)
+    if event == EventRecovery {
+        subject = fmt.Sprintf("[RECOVERED] %s", service.Name)
+    }
+
+    return m.sns.Publish(ctx, subject, string(body))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// fakeSNS records what would have been published to the topic
+type fakeSNS struct {
+    mutex    sync.Mutex
+    topicARN string
+    subjects []string
+    records  []alertRecord
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, subject, message string) error {
+    f.mutex.Lock()
+    defer f.mutex.Unlock()
+    var record alertRecord
+    if err := json.Unmarshal([]byte(message), &record); err != nil {
+        return err
+    }
+    f.subjects = append(f.subjects, subject)
+    f.records = append(f.records, record)
+    return nil
+}
+
+func TestSNSAlert(t *testing.T) {
+    fake := &fakeSNS{}
+    previous := newSNSPublisher
+    newSNSPublisher = func(config SNSConfig) (snsPublisher, error) {
+        fake.topicARN = config.TopicARN
+        return fake, nil
+    }
+    t.Cleanup(func() { newSNSPublisher = previous })
+
+    server := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("api", server.URL)
+    service.Severity = SeverityCritical
+    config := MonitorConfig{Services: []ServiceConfig{service}}
+    config.Alerts.SNS = &SNSConfig{TopicARN: "arn:aws:sns:eu-west-1:123456789012:alerts", Region: "eu-west-1"}
+    config.Alerts.Routing = map[string][]string{SeverityCritical: {ChannelSNS}}
+    m, err := NewMonitorFromConfig(config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(m.cancel)
+
+    checkAndFlush(m, service)
+    server.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+
+    fake.mutex.Lock()
+    defer fake.mutex.Unlock()
+    if fake.topicARN != config.Alerts.SNS.TopicARN {
+        t.Errorf("published to %q, want %q", fake.topicARN, config.Alerts.SNS.TopicARN)
+    }
+    if want := []string{"[ALERT] api is DOWN", "[RECOVERED] api"}; !equalStrings(fake.subjects, want) {
+        t.Fatalf("published %v, want %v", fake.subjects, want)
+    }
+    if got := fake.records[0]; got.Event != EventAlert || got.Service != "api" || got.Severity != SeverityCritical || !strings.Contains(got.Message, "500") {
+        t.Errorf("alert message %+v", got)
+    }
+    if got := fake.records[1]; got.Event != EventRecovery || got.Service != "api" {
+        t.Errorf("recovery message %+v", got)
+    }
+}
+
+func TestSNSConfigValidation(t *testing.T) {
+    if err := validateSNSConfig(&SNSConfig{Region: "eu-west-1"}); err == nil {
+        t.Error("validateSNSConfig accepted a config without topic_arn")
+    }
+}
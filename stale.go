@@ -0,0 +1,122 @@
+package main
+
+import (
+    "fmt"
+    "time"
+)
+
+const (
+    // defaultStaleFactor is how many check intervals may pass without a
+    // result before a service's monitoring counts as stalled
+    defaultStaleFactor = 3
+    // staleSweepInterval is how often the supervisor looks for stalls
+    staleSweepInterval = 15 * time.Second
+)
+
+// checkBudget is the longest a single check of the service may take,
+// with every attempt timing out and waiting out its retry delay
+func checkBudget(service ServiceConfig) time.Duration {
+    return time.Duration(service.RetryAttempts*(service.Timeout+service.RetryDelay)) * time.Second
+}
+
+// superviseMonitoring periodically alerts on services whose checks have
+// stopped producing results
+func (m *Monitor) superviseMonitoring() {
+    m.supervise(func() {
+        ticker := time.NewTicker(staleSweepInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-m.ctx.Done():
+                return
+            case now := <-ticker.C:
+                m.checkStaleMonitoring(now)
+            }
+        }
+    })
+}
+
+// checkStaleMonitoring alerts once for each service without a check result
+// for stale_factor intervals plus the time a check may take, and resolves
+// the alert when results resume. Paused services are expected to be quiet,
+// so a service leaving its pause is measured from then.
+func (m *Monitor) checkStaleMonitoring(now time.Time) {
+    factor := m.config.StaleFactor
+    if factor <= 0 {
+        factor = defaultStaleFactor
+    }
+
+    m.statusMutex.RLock()
+    services := append([]ServiceConfig(nil), m.config.Services...)
+    m.statusMutex.RUnlock()
+
+    for _, service := range services {
+        // The breaker may legitimately stretch the interval
+        interval := m.effectiveInterval(service)
+        allowed := time.Duration(factor*float64(interval)) + checkBudget(service)
+
+        m.statusMutex.Lock()
+        status, ok := m.serviceStatus[service.Name]
+        if !ok {
+            m.statusMutex.Unlock()
+            continue
+        }
+        last := status.LastCheck
+        if last.IsZero() {
+            last = status.AddedAt
+        }
+        if status.ResumedAt.After(last) {
+            last = status.ResumedAt
+        }
+        stalled := !status.Paused && now.Sub(last) > allowed
+        if stalled == status.MonitoringStalled {
+            m.statusMutex.Unlock()
+            continue
+        }
+        status.MonitoringStalled = stalled
+        since := status.StalledSince
+        status.StalledSince = time.Time{}
+        if stalled {
+            status.StalledSince = last
+        }
+        m.statusMutex.Unlock()
+
+        name := staleAlertName(service)
+        if !stalled {
+            downtime := last.Sub(since)
+            msg := fmt.Sprintf("✅ Monitoring of %s has RESUMED\nNo results for: %s\nTime: %s",
+                service.Name, downtime.Round(time.Second), m.formatTime(now))
+            m.dispatch(name, func() { m.sendStaleAlert(EventRecovery, name, msg, downtime) })
+            continue
+        }
+        msg := fmt.Sprintf("monitoring stalled for %s: no check result for %s, expected every %s",
+            service.Name, now.Sub(last).Round(time.Second), interval)
+        m.dispatch(name, func() { m.sendStaleAlert(EventAlert, name, msg, 0) })
+    }
+}
+
+// staleAlertName identifies a service's monitoring-stalled alerts, apart
+// from its outage alerts so resolving one leaves the other open
+func staleAlertName(service ServiceConfig) string {
+    return "monitoring/" + service.Name
+}
+
+// sendStaleAlert delivers a monitoring-stalled alert or its resolution to
+// the ops channels. The service's snooze, silences and maintenance do not
+// apply, as they quiet its outages rather than the monitor's own health.
+func (m *Monitor) sendStaleAlert(kind, name, message string, downtime time.Duration) {
+    service := ServiceConfig{Name: name, Severity: SeverityWarning}
+    m.recordAlert(kind, service, message)
+    event := m.newAlertEvent(kind, service, message)
+    event.Downtime = downtime
+    m.deliver(event, m.staleAlertChannels())
+}
+
+// staleAlertChannels are stale_alert_channels, or the warning routing when
+// unset, whatever the stalled service's own routing
+func (m *Monitor) staleAlertChannels() []string {
+    if len(m.config.StaleAlertChannels) > 0 {
+        return m.config.StaleAlertChannels
+    }
+    return m.alertChannels(ServiceConfig{Severity: SeverityWarning})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestStaleMonitoringAlert(t *testing.T) {
+    backend := newStatusServer(t, http.StatusOK)
+    service := testService("api", backend.URL)
+    m, serviceSender := newTestMonitor(t, MonitorConfig{
+        Services:           []ServiceConfig{service},
+        StaleAlertChannels: []string{ChannelSlack},
+    })
+    ops := &recordingSender{}
+    m.RegisterSender(ChannelSlack, ops)
+    checkAndFlush(m, service)
+
+    // The service's own snooze quiets its outages, not the monitor's health
+    m.setSnooze("api", time.Now().Add(time.Hour))
+    m.statusMutex.Lock()
+    m.serviceStatus["api"].LastCheck = time.Now().Add(-time.Hour)
+    m.statusMutex.Unlock()
+
+    m.checkStaleMonitoring(time.Now())
+    m.checkStaleMonitoring(time.Now())
+    m.alertWG.Wait()
+    if got := ops.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Fatalf("ops channel got %v while stalled, want one alert", got)
+    }
+
+    checkAndFlush(m, service)
+    m.checkStaleMonitoring(time.Now())
+    m.alertWG.Wait()
+    if got := ops.kinds(); !equalStrings(got, []string{EventAlert, EventRecovery}) {
+        t.Fatalf("ops channel got %v after checks resumed, want the alert resolved", got)
+    }
+    if got := serviceSender.kinds(); len(got) != 0 {
+        t.Errorf("service's routed channel got %v, want nothing", got)
+    }
+
+    ops.mutex.Lock()
+    defer ops.mutex.Unlock()
+    alert, recovery := ops.events[0], ops.events[1]
+    if alert.Service.Name != "monitoring/api" || recovery.Service.Name != alert.Service.Name {
+        t.Errorf("alerted as %q and resolved as %q, want monitoring/api for both", alert.Service.Name, recovery.Service.Name)
+    }
+    if !strings.HasPrefix(alert.Message, "monitoring stalled for api: no check result for 1h0m0s") {
+        t.Errorf("alert %q", alert.Message)
+    }
+    if !strings.HasPrefix(recovery.Message, "✅ Monitoring of api has RESUMED") || recovery.Downtime < time.Hour {
+        t.Errorf("recovery %q after %s, want the hour without results", recovery.Message, recovery.Downtime)
+    }
+}
+
+func TestStaleMonitoring(t *testing.T) {
+    tests := []struct {
+        name     string
+        age      time.Duration // since the last check result
+        paused   bool
+        factor   float64
+        wantSent bool
+    }{
+        {"overdue past the default factor", 10 * time.Minute, false, 0, true},
+        {"within the default factor", 2 * time.Minute, false, 0, false},
+        {"within a raised factor", 10 * time.Minute, false, 20, false},
+        {"paused outside business hours", time.Hour, true, 0, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", "https://api.example.com/")
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}, StaleFactor: tt.factor})
+            m.statusMutex.Lock()
+            status := m.serviceStatus["api"]
+            status.LastCheck, status.Paused = time.Now().Add(-tt.age), tt.paused
+            m.statusMutex.Unlock()
+
+            m.checkStaleMonitoring(time.Now())
+            m.alertWG.Wait()
+            // Without stale_alert_channels the warning routing is used
+            if sent := len(sender.kinds()) > 0; sent != tt.wantSent {
+                t.Errorf("stall alert sent %v, want %v", sent, tt.wantSent)
+            }
+        })
+    }
+}
+
+func TestStaleMonitoringAfterResume(t *testing.T) {
+    // The scheduler unpauses a service at the start of business hours just
+    // before its first check, with the last result from the previous day
+    service := testService("api", "https://api.example.com/")
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    m.statusMutex.Lock()
+    status := m.serviceStatus["api"]
+    status.LastCheck, status.Paused = time.Now().Add(-16*time.Hour), true
+    m.statusMutex.Unlock()
+    m.setPaused("api", false)
+
+    m.checkStaleMonitoring(time.Now())
+    m.alertWG.Wait()
+    if got := sender.kinds(); len(got) != 0 {
+        t.Fatalf("sent %v right after the pause ended, want nothing", got)
+    }
+
+    // Without a result since, the service does stall
+    m.checkStaleMonitoring(time.Now().Add(10 * time.Minute))
+    m.alertWG.Wait()
+    if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+        t.Errorf("sent %v with no result since the pause ended, want a stall alert", got)
+    }
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "time"
+)
+
+// defaultAlertDedupWindow is how long a persisted active incident stays
+// valid across restarts when alert_dedup_window is unset
+const defaultAlertDedupWindow = time.Hour
+
+// activeIncident is an ongoing outage persisted so that a restarted monitor
+// does not alert (or open a PagerDuty incident) for it a second time
+type activeIncident struct {
+    State      ServiceState `json:"state"`
+    DownSince  time.Time    `json:"down_since"`
+    AlertSent  bool         `json:"alert_sent"`
+    AlertState ServiceState `json:"alert_state"`
+    Error      string       `json:"error"`
+    LastSeen   time.Time    `json:"last_seen"`
+}
+
+type stateFile struct {
+    Incidents []Incident                `json:"incidents"`
+    Active    map[string]activeIncident `json:"active"`
+}
+
+// persistState writes the state file if one is configured. It must be
+// called with statusMutex held.
+func (m *Monitor) persistState() {
+    if m.config.StatePath == "" {
+        return
+    }
+    if err := m.saveState(); err != nil {
+        m.logger.Printf("state", "Error saving state: %v", err)
+    }
+}
+
+func (m *Monitor) saveState() error {
+    state := stateFile{
+        Incidents: m.incidents,
+        Active:    make(map[string]activeIncident),
+    }
+    for name, status := range m.serviceStatus {
+        if status.DownSince == nil {
+            continue
+        }
+        state.Active[name] = activeIncident{
+            State:      status.State,
+            DownSince:  *status.DownSince,
+            AlertSent:  status.AlertSent,
+            AlertState: status.AlertState,
+            Error:      status.IncidentError,
+            LastSeen:   status.LastCheck,
+        }
+    }
+
+    data, err := json.Marshal(state)
+    if err != nil {
+        return err
+    }
+
+    tmp := m.config.StatePath + ".tmp"
+    if err := os.WriteFile(tmp, data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, m.config.StatePath)
+}
+
+// loadState restores incident history and any active incidents still inside
+// the dedup window, so an outage spanning a restart keeps its original
+// start time and is not alerted on again
+func (m *Monitor) loadState() error {
+    data, err := os.ReadFile(m.config.StatePath)
+    if os.IsNotExist(err) {
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("error reading state: %v", err)
+    }
+
+    var state stateFile
+    if err := json.Unmarshal(data, &state); err != nil {
+        return fmt.Errorf("error parsing state: %v", err)
+    }
+    m.incidents = state.Incidents
+
+    window := time.Duration(m.config.AlertDedupWindow) * time.Second
+    if window <= 0 {
+        window = defaultAlertDedupWindow
+    }
+
+    for name, incident := range state.Active {
+        status, ok := m.serviceStatus[name]
+        if !ok || time.Since(incident.LastSeen) > window {
+            continue
+        }
+
+        downSince := incident.DownSince
+        status.State = StateDown
+        if incident.State != "" {
+            status.State = incident.State
+        }
+        status.DownSince = &downSince
+        status.AlertSent = incident.AlertSent
+        status.AlertState = incident.AlertState
+        status.IncidentError = incident.Error
+        status.LastError = incident.Error
+        log.Printf("Restored active incident for %s (down since %s)", name, m.formatTime(downSince))
+    }
+    return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func TestAlertDedupAcrossRestart(t *testing.T) {
+    tests := []struct {
+        name  string
+        age   time.Duration // how long before the restart the incident was last seen
+        codes []int         // checks after the restart
+        want  []string      // delivered after the restart
+    }{
+        {"restart inside the window", 0, []int{500}, []string{}},
+        {"restart inside the window then recovery", 0, []int{500, 200}, []string{EventRecovery}},
+        {"incident outside the window", 2 * time.Minute, []int{500}, []string{EventAlert}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, http.StatusInternalServerError)
+            service := testService("api", server.URL)
+            config := MonitorConfig{
+                Services:         []ServiceConfig{service},
+                StatePath:        filepath.Join(t.TempDir(), "state.json"),
+                AlertDedupWindow: 60,
+            }
+
+            before, sender := newTestMonitor(t, config)
+            checkAndFlush(before, service)
+            if got := sender.kinds(); !equalStrings(got, []string{EventAlert}) {
+                t.Fatalf("delivered %v before the restart, want one alert", got)
+            }
+            downSince := *before.serviceStatus["api"].DownSince
+            before.cancel()
+            ageState(t, config.StatePath, tt.age)
+
+            after, sender := newTestMonitor(t, config)
+            for _, code := range tt.codes {
+                server.code.Store(int32(code))
+                checkAndFlush(after, service)
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v after the restart, want %v", got, tt.want)
+            }
+            if tt.age == 0 && tt.codes[len(tt.codes)-1] != http.StatusOK {
+                if got := after.serviceStatus["api"].DownSince; got == nil || !got.Equal(downSince) {
+                    t.Errorf("down since %v after the restart, want %v", got, downSince)
+                }
+            }
+        })
+    }
+}
+
+// ageState moves the last sighting of every persisted active incident back
+func ageState(t *testing.T, path string, age time.Duration) {
+    t.Helper()
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var state stateFile
+    if err := json.Unmarshal(data, &state); err != nil {
+        t.Fatal(err)
+    }
+    for name, incident := range state.Active {
+        incident.LastSeen = incident.LastSeen.Add(-age)
+        state.Active[name] = incident
+    }
+    if data, err = json.Marshal(state); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, data, 0600); err != nil {
+        t.Fatal(err)
+    }
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+    "encoding/json"
+    "os"
+    "sort"
+    "time"
+)
+
+// defaultStatusExportInterval is how often the status file is rewritten
+// when interval is unset
+const defaultStatusExportInterval = 60 * time.Second
+
+// StatusExportConfig periodically writes the aggregate status of the
+// services marked public to a JSON file, e.g. for a static status page
+// generator. Only names, states, uptime and incident times are written, as
+// errors can reveal internal hosts and addresses.
+type StatusExportConfig struct {
+    Path     string `json:"path"`
+    Interval int    `json:"interval"` // in seconds
+}
+
+type exportedService struct {
+    Name          string            `json:"name"`
+    State         string            `json:"state"`
+    UptimePercent *float64          `json:"uptime_percent"` // over the recent check history, null before the first check
+    DownSince     *time.Time        `json:"down_since,omitempty"`
+    LastIncident  *exportedIncident `json:"last_incident"`
+}
+
+// exportedIncident is an incident's times, without its error
+type exportedIncident struct {
+    Start time.Time `json:"start"`
+    End   time.Time `json:"end"`
+}
+
+type statusExport struct {
+    Generated         time.Time         `json:"generated"`
+    AvailabilityScore *float64          `json:"availability_score"`
+    Services          []exportedService `json:"services"`
+}
+
+// uptimePercent is the share of successful checks in the recent history
+func uptimePercent(history *checkHistory) (float64, bool) {
+    records := history.recent()
+    if len(records) == 0 {
+        return 0, false
+    }
+    var up int
+    for _, record := range records {
+        if record.Up {
+            up++
+        }
+    }
+    return 100 * float64(up) / float64(len(records)), true
+}
+
+// statusExport builds the exported status of the public services. It must
+// be called with statusMutex held.
+func (m *Monitor) statusExport(now time.Time) statusExport {
+    export := statusExport{Generated: now, Services: []exportedService{}}
+
+    var public []ServiceConfig
+    for _, service := range m.config.Services {
+        if service.Public {
+            public = append(public, service)
+        }
+    }
+    if score, ok := m.serviceAvailability(public); ok {
+        export.AvailabilityScore = &score
+    }
+
+    lastIncidents := make(map[string]Incident)
+    for _, incident := range m.incidents {
+        lastIncidents[incident.Service] = incident
+    }
+
+    for _, config := range public {
+        status, ok := m.serviceStatus[config.Name]
+        if !ok {
+            continue
+        }
+        service := exportedService{Name: config.Name, State: string(status.State), DownSince: status.DownSince}
+        if uptime, ok := uptimePercent(status.History); ok {
+            service.UptimePercent = &uptime
+        }
+        if incident, ok := lastIncidents[config.Name]; ok {
+            service.LastIncident = &exportedIncident{Start: incident.Start, End: incident.End}
+        }
+        export.Services = append(export.Services, service)
+    }
+    sort.Slice(export.Services, func(i, j int) bool { return export.Services[i].Name < export.Services[j].Name })
+    return export
+}
+
+// writeStatusExport writes the status file atomically, so a reader never
+// sees a partial file
+func (m *Monitor) writeStatusExport(path string) error {
+    m.statusMutex.RLock()
+    export := m.statusExport(time.Now())
+    m.statusMutex.RUnlock()
+
+    data, err := json.MarshalIndent(export, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, data, 0644); err != nil {
+        return err
+    }
+    return os.Rename(tmp, path)
+}
+
+// startStatusExport rewrites the status file on its interval
+func (m *Monitor) startStatusExport() {
+    config := m.config.StatusExport
+    if config == nil {
+        return
+    }
+    interval := time.Duration(config.Interval) * time.Second
+    if interval <= 0 {
+        interval = defaultStatusExportInterval
+    }
+
+    m.supervise(func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            if err := m.writeStatusExport(config.Path); err != nil {
+                m.logger.Printf("status-export", "Error writing status export: %v", err)
+            }
+            select {
+            case <-m.ctx.Done():
+                return
+            case <-ticker.C:
+            }
+        }
+    })
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestStatusExport(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    api, internal := testService("api", backend.URL), testService("internal-db", backend.URL)
+    api.Public = true
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{api, internal}})
+
+    checkAndFlush(m, api)
+    checkAndFlush(m, internal)
+    backend.code.Store(http.StatusOK)
+    checkAndFlush(m, api)
+    start := time.Now().Add(-time.Hour)
+    m.statusMutex.Lock()
+    m.recordIncident(Incident{Service: "api", Start: start, End: start.Add(time.Minute), Duration: time.Minute,
+        Error: "dial tcp 10.0.0.12:5432: connect: connection refused"})
+    m.statusMutex.Unlock()
+
+    path := filepath.Join(t.TempDir(), "status.json")
+    if err := m.writeStatusExport(path); err != nil {
+        t.Fatalf("writeStatusExport() = %v", err)
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, leak := range []string{"internal-db", "10.0.0.12", "connection refused", "unexpected status code", `"error"`} {
+        if strings.Contains(string(data), leak) {
+            t.Errorf("export contains %q: %s", leak, data)
+        }
+    }
+
+    var export struct {
+        AvailabilityScore *float64                     `json:"availability_score"`
+        Services          []map[string]json.RawMessage `json:"services"`
+    }
+    if err := json.Unmarshal(data, &export); err != nil {
+        t.Fatal(err)
+    }
+    // Only the public service counts, and it is up
+    if export.AvailabilityScore == nil || *export.AvailabilityScore != 1 {
+        t.Errorf("availability_score %v, want 1 from the public service alone", export.AvailabilityScore)
+    }
+    if len(export.Services) != 1 {
+        t.Fatalf("%d services exported, want only the public one", len(export.Services))
+    }
+    var fields []string
+    for field := range export.Services[0] {
+        fields = append(fields, field)
+    }
+    sort.Strings(fields)
+    if want := []string{"last_incident", "name", "state", "uptime_percent"}; !equalStrings(fields, want) {
+        t.Errorf("exported fields %v, want %v", fields, want)
+    }
+
+    var typed statusExport
+    if err := json.Unmarshal(data, &typed); err != nil {
+        t.Fatal(err)
+    }
+    service := typed.Services[0]
+    if service.Name != "api" || service.State != string(StateUp) || service.UptimePercent == nil || *service.UptimePercent != 50 {
+        t.Errorf("exported %+v, want api up at 50%% uptime", service)
+    }
+    if service.LastIncident == nil || !service.LastIncident.Start.Equal(start) || !service.LastIncident.End.Equal(start.Add(time.Minute)) {
+        t.Errorf("last incident %+v, want its start and end", service.LastIncident)
+    }
+}
+
+func TestStatusExportWithoutPublicServices(t *testing.T) {
+    backend := newStatusServer(t, http.StatusOK)
+    service := testService("api", backend.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+    checkAndFlush(m, service)
+
+    m.statusMutex.RLock()
+    export := m.statusExport(time.Now())
+    m.statusMutex.RUnlock()
+    if len(export.Services) != 0 || export.AvailabilityScore != nil {
+        t.Errorf("exported %+v with score %v, want nothing", export.Services, export.AvailabilityScore)
+    }
+}
@@ -0,0 +1,95 @@
+package main
+
+import "fmt"
+
+// Interpretations a status code can be mapped to in status_code_states
+const (
+    CodeHealthy  = "healthy"
+    CodeDegraded = "degraded"
+    CodeDown     = "down"
+)
+
+// StateDegraded marks a service answering with a status code mapped to
+// "degraded": not down, but alerted on at warning severity
+const StateDegraded ServiceState = "degraded"
+
+// statusCodeState interprets a response code for a service. Codes listed in
+// status_code_states take precedence; otherwise only expected_status is
+// healthy.
+func statusCodeState(service ServiceConfig, code int) string {
+    if state, ok := service.StatusCodeStates[code]; ok {
+        return state
+    }
+    if code == service.ExpectedStatus {
+        return CodeHealthy
+    }
+    return CodeDown
+}
+
+func validateStatusCodeStates(service ServiceConfig) error {
+    for code, state := range service.StatusCodeStates {
+        if code < 100 || code > 599 {
+            return fmt.Errorf("service %s: invalid status code %d in status_code_states", service.Name, code)
+        }
+        switch state {
+        case CodeHealthy, CodeDegraded, CodeDown:
+        default:
+            return fmt.Errorf("service %s: unknown state %q for status code %d", service.Name, state, code)
+        }
+    }
+    return nil
+}
+
+// BodyAssertion is the body a response with a particular status code must
+// carry, e.g. a maintenance message on a 503
+type BodyAssertion struct {
+    ExpectedBodySubstring string `json:"expected_body_substring"`
+    ExpectedBodyRegex     string `json:"expected_body_regex"`
+}
+
+// bodyAssertionsFor returns the service with the body assertions for code:
+// an entry in status_body_checks replaces the service-wide ones
+func bodyAssertionsFor(service ServiceConfig, code int) ServiceConfig {
+    if assertion, ok := service.StatusBodyChecks[code]; ok {
+        service.ExpectedBodySubstring = assertion.ExpectedBodySubstring
+        service.ExpectedBodyRegex = assertion.ExpectedBodyRegex
+    }
+    return service
+}
+
+// validateStatusBodyChecks rejects body checks that could never pass: a
+// code treated as down fails whatever its body says
+func validateStatusBodyChecks(service ServiceConfig) error {
+    for code := range service.StatusBodyChecks {
+        if code < 100 || code > 599 {
+            return fmt.Errorf("service %s: invalid status code %d in status_body_checks", service.Name, code)
+        }
+        if statusCodeState(service, code) == CodeDown {
+            return fmt.Errorf("service %s: status_body_checks for %d, which is down whatever its body; make it expected_status or map it to healthy or degraded in status_code_states",
+                service.Name, code)
+        }
+    }
+    return nil
+}
+
+// degradedAlertConfig is the service config alerts for a degraded service are
+// routed with: warning severity regardless of how the service is configured
+func degradedAlertConfig(service ServiceConfig) ServiceConfig {
+    service.Severity = SeverityWarning
+    service.PagerDutySeverity = ""
+    return service
+}
+
+// retryableStatus reports whether a failed response is worth another
+// attempt. Without retryable_statuses every failure is retried.
+func retryableStatus(service ServiceConfig, code int) bool {
+    if len(service.RetryableStatuses) == 0 {
+        return true
+    }
+    for _, retryable := range service.RetryableStatuses {
+        if code == retryable {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+)
+
+func TestStatusCodeStates(t *testing.T) {
+    tests := []struct {
+        name         string
+        code         int
+        wantState    ServiceState
+        wantSeverity string // of the alert sent, "" for none
+    }{
+        {"expected status", http.StatusOK, StateUp, ""},
+        {"mapped healthy", http.StatusNoContent, StateUp, ""},
+        {"429 degraded", http.StatusTooManyRequests, StateDegraded, SeverityWarning},
+        {"503 down", http.StatusServiceUnavailable, StateDown, SeverityCritical},
+        {"unmapped code", http.StatusNotFound, StateDown, SeverityCritical},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, tt.code)
+            service := testService("api", server.URL)
+            service.Severity = SeverityCritical
+            service.StatusCodeStates = map[int]string{
+                http.StatusNoContent:          CodeHealthy,
+                http.StatusTooManyRequests:    CodeDegraded,
+                http.StatusServiceUnavailable: CodeDown,
+            }
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+
+            if got := m.serviceStatus["api"].State; got != tt.wantState {
+                t.Errorf("state %s, want %s", got, tt.wantState)
+            }
+            sender.mutex.Lock()
+            defer sender.mutex.Unlock()
+            if tt.wantSeverity == "" {
+                if len(sender.events) > 0 {
+                    t.Errorf("alerted %v", sender.events)
+                }
+                return
+            }
+            if len(sender.events) != 1 || sender.events[0].Severity != tt.wantSeverity {
+                t.Errorf("delivered %v, want one %s alert", sender.events, tt.wantSeverity)
+            }
+        })
+    }
+}
+
+func TestDegradedEscalation(t *testing.T) {
+    server := newStatusServer(t, http.StatusTooManyRequests)
+    service := testService("api", server.URL)
+    service.StatusCodeStates = map[int]string{http.StatusTooManyRequests: CodeDegraded}
+    m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    for _, code := range []int{429, 429, 500, 200} {
+        server.code.Store(int32(code))
+        checkAndFlush(m, service)
+    }
+
+    if got, want := sender.kinds(), []string{EventAlert, EventAlert, EventRecovery}; !equalStrings(got, want) {
+        t.Errorf("delivered %v, want %v", got, want)
+    }
+}
+
+func TestInvalidStatusCodeStates(t *testing.T) {
+    tests := []struct {
+        name   string
+        states map[int]string
+    }{
+        {"code out of range", map[int]string{42: CodeDown}},
+        {"unknown state", map[int]string{http.StatusTooManyRequests: "sluggish"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", "https://api.example.com/")
+            service.StatusCodeStates = tt.states
+            if err := validateServiceConfig(service); err == nil {
+                t.Error("validateServiceConfig accepted the mapping")
+            }
+        })
+    }
+}
+
+func TestRetryableStatuses(t *testing.T) {
+    tests := []struct {
+        name             string
+        code             int // 0 drops the connection instead
+        retryable        []int
+        noTransportRetry bool
+        wantRequests     int32
+    }{
+        {"401 fails fast", http.StatusUnauthorized, []int{502, 503}, false, 1},
+        {"503 retried", http.StatusServiceUnavailable, []int{502, 503}, false, 3},
+        {"every failure retried without a list", http.StatusUnauthorized, nil, false, 3},
+        {"transport errors retried", 0, []int{503}, false, 3},
+        {"transport retries disabled", 0, []int{503}, true, 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var requests atomic.Int32
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                requests.Add(1)
+                if tt.code == 0 {
+                    conn, _, _ := w.(http.Hijacker).Hijack()
+                    conn.Close()
+                    return
+                }
+                w.WriteHeader(tt.code)
+            }))
+            defer server.Close()
+            service := testService("api", server.URL)
+            service.RetryAttempts = 3
+            service.RetryableStatuses = tt.retryable
+            service.DisableTransportRetry = tt.noTransportRetry
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+            if got := requests.Load(); got != tt.wantRequests {
+                t.Errorf("%d requests, want %d", got, tt.wantRequests)
+            }
+        })
+    }
+}
+
+func TestStatusBodyChecks(t *testing.T) {
+    tests := []struct {
+        name      string
+        code      int
+        body      string
+        wantState ServiceState
+        wantError string // substring of the recorded error, "" when up
+    }{
+        {"200 with the expected body", http.StatusOK, `{"status":"healthy"}`, StateUp, ""},
+        {"200 without it", http.StatusOK, `{"status":"starting"}`, StateDown, `does not contain "healthy"`},
+        {"503 with the maintenance body", http.StatusServiceUnavailable, "Scheduled maintenance until 04:00", StateDegraded, "degraded status code: 503"},
+        {"503 without it", http.StatusServiceUnavailable, "upstream connect error", StateDown, `does not contain "maintenance"`},
+        {"503 carrying only the 200 body", http.StatusServiceUnavailable, `{"status":"healthy"}`, StateDown, `does not contain "maintenance"`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                w.WriteHeader(tt.code)
+                io.WriteString(w, tt.body)
+            }))
+            defer server.Close()
+            service := testService("api", server.URL)
+            service.ExpectedBodySubstring = "healthy"
+            service.StatusCodeStates = map[int]string{http.StatusServiceUnavailable: CodeDegraded}
+            service.StatusBodyChecks = map[int]BodyAssertion{
+                http.StatusServiceUnavailable: {ExpectedBodySubstring: "maintenance"},
+            }
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            checkAndFlush(m, service)
+
+            status := m.serviceStatus["api"]
+            if status.State != tt.wantState {
+                t.Errorf("state %s, want %s", status.State, tt.wantState)
+            }
+            if !strings.Contains(status.LastError, tt.wantError) || (tt.wantError == "") != (status.LastError == "") {
+                t.Errorf("error %q, want %q", status.LastError, tt.wantError)
+            }
+        })
+    }
+}
+
+func TestInvalidStatusBodyChecks(t *testing.T) {
+    tests := []struct {
+        name    string
+        code    int
+        states  map[int]string
+        wantErr string // substring of the validation error, "" when valid
+    }{
+        {"expected status", http.StatusOK, nil, ""},
+        {"mapped to degraded", http.StatusServiceUnavailable, map[int]string{http.StatusServiceUnavailable: CodeDegraded}, ""},
+        {"mapped to healthy", http.StatusAccepted, map[int]string{http.StatusAccepted: CodeHealthy}, ""},
+        {"invalid code", 1000, nil, "invalid status code 1000"},
+        {"unmapped code", http.StatusServiceUnavailable, nil, "which is down whatever its body"},
+        {"mapped to down", http.StatusServiceUnavailable, map[int]string{http.StatusServiceUnavailable: CodeDown}, "which is down whatever its body"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", "https://api.example.com/")
+            service.StatusCodeStates = tt.states
+            service.StatusBodyChecks = map[int]BodyAssertion{tt.code: {ExpectedBodySubstring: "maintenance"}}
+            err := validateServiceConfig(service)
+            if tt.wantErr == "" && err != nil {
+                t.Errorf("validateServiceConfig: %v, want valid", err)
+            }
+            if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Errorf("validateServiceConfig: %v, want %q", err, tt.wantErr)
+            }
+        })
+    }
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+)
+
+// serviceWeight is a service's share of the availability score, 1 unless
+// configured
+func serviceWeight(service ServiceConfig) float64 {
+    if service.Weight > 0 {
+        return service.Weight
+    }
+    return 1
+}
+
+// availabilityScore is the weighted fraction of checked services that are
+// up. Services not yet checked are left out; ok is false if none have been
+// checked. It must be called with statusMutex held.
+func (m *Monitor) availabilityScore() (score float64, ok bool) {
+    return m.serviceAvailability(m.config.Services)
+}
+
+// serviceAvailability is availabilityScore over the given services. It must
+// be called with statusMutex held.
+func (m *Monitor) serviceAvailability(services []ServiceConfig) (score float64, ok bool) {
+    var up, total float64
+    for _, service := range services {
+        status, exists := m.serviceStatus[service.Name]
+        if !exists || status.State == StateUnknown {
+            continue
+        }
+        weight := serviceWeight(service)
+        total += weight
+        if status.State == StateUp {
+            up += weight
+        }
+    }
+    if total == 0 {
+        return 0, false
+    }
+    return up / total, true
+}
+
+func (m *Monitor) handleSummary(w http.ResponseWriter, r *http.Request) {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    counts := make(map[ServiceState]int)
+    for _, status := range m.serviceStatus {
+        counts[status.State]++
+    }
+
+    summary := map[string]interface{}{
+        "total":    len(m.serviceStatus),
+        "up":       counts[StateUp],
+        "degraded": counts[StateDegraded],
+        "down":     counts[StateDown],
+        "unknown":  counts[StateUnknown],
+    }
+    if score, ok := m.availabilityScore(); ok {
+        summary["availability_score"] = score
+    }
+
+    json.NewEncoder(w).Encode(summary)
+}
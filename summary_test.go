@@ -0,0 +1,82 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestAvailabilityScore(t *testing.T) {
+    up := newStatusServer(t, http.StatusOK)
+    down := newStatusServer(t, http.StatusInternalServerError)
+
+    type weighted struct {
+        weight float64
+        up     bool
+    }
+    tests := []struct {
+        name     string
+        services []weighted
+        want     float64
+    }{
+        {"unweighted", []weighted{{0, true}, {0, true}, {0, false}, {0, false}}, 0.5},
+        {"critical service up dominates", []weighted{{10, true}, {1, false}, {1, false}}, 10.0 / 12},
+        {"critical service down dominates", []weighted{{10, false}, {1, true}, {1, true}}, 2.0 / 12},
+        {"all down", []weighted{{3, false}, {1, false}}, 0},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var services []ServiceConfig
+            for i, s := range tt.services {
+                server := down
+                if s.up {
+                    server = up
+                }
+                service := testService(fmt.Sprintf("service-%d", i), server.URL)
+                service.Weight = s.weight
+                services = append(services, service)
+            }
+            // Not yet checked, so left out of the score
+            services = append(services, testService("unchecked", down.URL))
+            m, _ := newTestMonitor(t, MonitorConfig{Services: services})
+            for _, service := range services[:len(tt.services)] {
+                checkAndFlush(m, service)
+            }
+
+            var summary struct {
+                Score *float64 `json:"availability_score"`
+            }
+            recorder := httptest.NewRecorder()
+            m.handleSummary(recorder, httptest.NewRequest("GET", "/summary", nil))
+            if err := json.Unmarshal(recorder.Body.Bytes(), &summary); err != nil {
+                t.Fatal(err)
+            }
+            if summary.Score == nil {
+                t.Fatalf("summary %s has no score", recorder.Body)
+            }
+            if math.Abs(*summary.Score-tt.want) > 1e-9 {
+                t.Errorf("summary score %g, want %g", *summary.Score, tt.want)
+            }
+
+            recorder = httptest.NewRecorder()
+            m.handleMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+            if want := fmt.Sprintf("monitor_availability_score %g\n", *summary.Score); !strings.Contains(recorder.Body.String(), want) {
+                t.Errorf("metrics missing %q", want)
+            }
+        })
+    }
+}
+
+func TestAvailabilityScoreUnchecked(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{testService("api", "https://api.example.com/")}})
+    recorder := httptest.NewRecorder()
+    m.handleSummary(recorder, httptest.NewRequest("GET", "/summary", nil))
+    if strings.Contains(recorder.Body.String(), "availability_score") {
+        t.Errorf("summary %s has a score before any check", recorder.Body)
+    }
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+
+package main
+
+import "fmt"
+
+type syslogWriter struct{}
+
+func newSyslogWriter(config *SyslogConfig) (*syslogWriter, error) {
+    return nil, fmt.Errorf("syslog is not supported on this platform")
+}
+
+func (s *syslogWriter) write(record alertRecord) error {
+    return nil
+}
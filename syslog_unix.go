@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+    "encoding/json"
+    "log/syslog"
+)
+
+type syslogWriter struct {
+    writer *syslog.Writer
+}
+
+func newSyslogWriter(config *SyslogConfig) (*syslogWriter, error) {
+    tag := config.Tag
+    if tag == "" {
+        tag = "monitor-alert"
+    }
+
+    writer, err := syslog.Dial(config.Network, config.Address, syslog.LOG_WARNING|syslog.LOG_DAEMON, tag)
+    if err != nil {
+        return nil, err
+    }
+    return &syslogWriter{writer: writer}, nil
+}
+
+func (s *syslogWriter) write(record alertRecord) error {
+    line, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+
+    if record.Event == "recovery" {
+        return s.writer.Notice(string(line))
+    }
+    if record.Severity == SeverityCritical {
+        return s.writer.Crit(string(line))
+    }
+    return s.writer.Warning(string(line))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+    "log"
+    "net"
+    "os"
+    "strconv"
+    "time"
+)
+
+// watchdogGrace is how far past its scheduled time, beyond its own timeout
+// and retry budget, a check may run before the monitor counts as hung
+const watchdogGrace = time.Minute
+
+// sdNotify sends a state such as "READY=1" to systemd. It does nothing
+// when not started by systemd with a notify socket.
+func sdNotify(state string) error {
+    socket := os.Getenv("NOTIFY_SOCKET")
+    if socket == "" {
+        return nil
+    }
+
+    // A leading "@" names an abstract socket, which net maps for us
+    conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    _, err = conn.Write([]byte(state))
+    return err
+}
+
+// watchdogInterval returns how often systemd expects a watchdog ping, half
+// the configured WatchdogSec, or 0 if the watchdog is not enabled for us
+func watchdogInterval() time.Duration {
+    usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+    if err != nil || usec <= 0 {
+        return 0
+    }
+    if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+        return 0
+    }
+    return time.Duration(usec) * time.Microsecond / 2
+}
+
+// notifySystemd reports readiness and, under WatchdogSec, pings the
+// watchdog for as long as the monitoring loops keep up
+func (m *Monitor) notifySystemd() {
+    if err := sdNotify("READY=1"); err != nil {
+        log.Printf("Error notifying systemd: %v", err)
+        return
+    }
+
+    interval := watchdogInterval()
+    if interval == 0 {
+        return
+    }
+    m.supervise(func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-m.ctx.Done():
+                return
+            case <-ticker.C:
+            }
+            if name, stalled := m.stalledService(time.Now()); stalled {
+                m.logger.Printf("watchdog", "Withholding watchdog ping: check for %s is overdue", name)
+                continue
+            }
+            if err := sdNotify("WATCHDOG=1"); err != nil {
+                m.logger.Printf("watchdog", "Error pinging systemd watchdog: %v", err)
+            }
+        }
+    })
+}
+
+// stalledService returns a service whose scheduled check is overdue by more
+// than the check itself may take. Taking statusMutex also means a deadlock
+// stops the pings.
+func (m *Monitor) stalledService(now time.Time) (string, bool) {
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    for _, service := range m.config.Services {
+        status, ok := m.serviceStatus[service.Name]
+        if !ok || status.NextCheck.IsZero() {
+            continue
+        }
+        if now.Sub(status.NextCheck) > checkBudget(service)+watchdogGrace {
+            return service.Name, true
+        }
+    }
+    return "", false
+}
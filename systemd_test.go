@@ -0,0 +1,138 @@
+package main
+
+import (
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "testing"
+    "time"
+)
+
+// newNotifySocket listens on a datagram socket set as NOTIFY_SOCKET and
+// returns the states sent to it
+func newNotifySocket(t *testing.T) <-chan string {
+    t.Helper()
+    // Kept short, as socket paths are limited to about 100 bytes
+    dir, err := os.MkdirTemp("", "sd")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.RemoveAll(dir) })
+    path := filepath.Join(dir, "notify")
+    conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    t.Setenv("NOTIFY_SOCKET", path)
+
+    states := make(chan string, 100)
+    go func() {
+        buf := make([]byte, 256)
+        for {
+            n, err := conn.Read(buf)
+            if err != nil {
+                close(states)
+                return
+            }
+            states <- string(buf[:n])
+        }
+    }()
+    return states
+}
+
+// receivedStates collects the states sent within d
+func receivedStates(states <-chan string, d time.Duration) []string {
+    var got []string
+    timeout := time.After(d)
+    for {
+        select {
+        case state := <-states:
+            got = append(got, state)
+        case <-timeout:
+            return got
+        }
+    }
+}
+
+func TestNotifySystemd(t *testing.T) {
+    tests := []struct {
+        name      string
+        watchdog  string // WATCHDOG_USEC
+        stalled   bool   // a scheduled check is long overdue
+        wantPings bool
+    }{
+        {"ready and watchdog pings", "100000", false, true},
+        {"ready without a watchdog", "", false, false},
+        {"pings withheld while a check is overdue", "100000", true, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            states := newNotifySocket(t)
+            t.Setenv("WATCHDOG_USEC", tt.watchdog)
+            server := newStatusServer(t, http.StatusOK)
+            service := testService("api", server.URL)
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            if tt.stalled {
+                m.statusMutex.Lock()
+                m.serviceStatus["api"].NextCheck = time.Now().Add(-time.Hour)
+                m.statusMutex.Unlock()
+            }
+
+            m.notifySystemd()
+            got := receivedStates(states, 300*time.Millisecond)
+            if len(got) == 0 || got[0] != "READY=1" {
+                t.Fatalf("received %v, want READY=1 first", got)
+            }
+            pings := 0
+            for _, state := range got[1:] {
+                if state != "WATCHDOG=1" {
+                    t.Errorf("received %q, want only watchdog pings after READY", state)
+                }
+                pings++
+            }
+            // Every 50ms for 300ms
+            if tt.wantPings && pings < 3 {
+                t.Errorf("%d watchdog pings, want periodic pings", pings)
+            }
+            if !tt.wantPings && pings != 0 {
+                t.Errorf("%d watchdog pings, want none", pings)
+            }
+        })
+    }
+}
+
+func TestWatchdogInterval(t *testing.T) {
+    tests := []struct {
+        name string
+        usec string
+        pid  string
+        want time.Duration
+    }{
+        {"half of WatchdogSec", "10000000", "", 5 * time.Second},
+        {"for this process", "10000000", strconv.Itoa(os.Getpid()), 5 * time.Second},
+        {"for another process", "10000000", "1", 0},
+        {"not enabled", "", "", 0},
+        {"invalid", "soon", "", 0},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Setenv("WATCHDOG_USEC", tt.usec)
+            t.Setenv("WATCHDOG_PID", tt.pid)
+            if got := watchdogInterval(); got != tt.want {
+                t.Errorf("watchdogInterval() = %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestSdNotifyWithoutSocket(t *testing.T) {
+    t.Setenv("NOTIFY_SOCKET", "")
+    if err := sdNotify("READY=1"); err != nil {
+        t.Errorf("sdNotify() = %v, want nil outside systemd", err)
+    }
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+    "fmt"
+    "net/url"
+    "sync"
+    "time"
+)
+
+// TimeRoute overrides severity routing during a daily UTC window, e.g. to
+// page the region that is currently on call. Its Slack webhook and PagerDuty
+// routing key replace the global ones for events it routes.
+type TimeRoute struct {
+    Start               string              `json:"start"`                 // "HH:MM" UTC
+    End                 string              `json:"end"`                   // "HH:MM" UTC, may be before start to wrap midnight
+    Routing             map[string][]string `json:"routing"`               // severity -> channels while the window is active
+    SlackWebhookURL     string              `json:"slack_webhook_url"`     // posts to this region's Slack channel instead of alerts.slack.webhook_url
+    PagerDutyRoutingKey string              `json:"pagerduty_routing_key"` // pages this region's PagerDuty service, unless the service sets its own key
+}
+
+func (r TimeRoute) active(now time.Time) bool {
+    start, _ := parseClock(r.Start)
+    end, _ := parseClock(r.End)
+    now = now.UTC()
+    minute := now.Hour()*60 + now.Minute()
+    if start <= end {
+        return minute >= start && minute < end
+    }
+    return minute >= start || minute < end
+}
+
+func validateTimeRouting(routes []TimeRoute) error {
+    for i, route := range routes {
+        if _, err := parseClock(route.Start); err != nil {
+            return fmt.Errorf("alerts.time_routing[%d]: %v", i, err)
+        }
+        if _, err := parseClock(route.End); err != nil {
+            return fmt.Errorf("alerts.time_routing[%d]: %v", i, err)
+        }
+        for severity, channels := range route.Routing {
+            for _, channel := range channels {
+                if !knownChannels[channel] {
+                    return fmt.Errorf("alerts.time_routing[%d]: unknown channel %q for %s", i, channel, severity)
+                }
+            }
+        }
+        if route.SlackWebhookURL != "" {
+            if parsed, err := url.Parse(route.SlackWebhookURL); err != nil || parsed.Host == "" {
+                return fmt.Errorf("alerts.time_routing[%d]: invalid slack_webhook_url", i)
+            }
+        }
+    }
+    return nil
+}
+
+// timeRoute returns the first time route active at now that routes severity
+func (m *Monitor) timeRoute(severity string, now time.Time) (*TimeRoute, bool) {
+    for i, route := range m.config.Alerts.TimeRouting {
+        if !route.active(now) {
+            continue
+        }
+        if _, ok := route.Routing[severity]; ok {
+            return &m.config.Alerts.TimeRouting[i], true
+        }
+    }
+    return nil, false
+}
+
+// timeRoutedChannels returns the channels for severity from the first time
+// route active at now, if any covers it
+func (m *Monitor) timeRoutedChannels(severity string, now time.Time) ([]string, bool) {
+    if route, ok := m.timeRoute(severity, now); ok {
+        return route.Routing[severity], true
+    }
+    return nil, false
+}
+
+// hasSlackRoute reports whether any time route posts to its own Slack webhook
+func hasSlackRoute(routes []TimeRoute) bool {
+    for _, route := range routes {
+        if route.SlackWebhookURL != "" {
+            return true
+        }
+    }
+    return false
+}
+
+// slackWebhookURL is the webhook an event is posted to: its time route's,
+// or the global one
+func (m *Monitor) slackWebhookURL(event AlertEvent) string {
+    if event.Route != nil && event.Route.SlackWebhookURL != "" {
+        return event.Route.SlackWebhookURL
+    }
+    return m.config.Alerts.Slack.WebhookURL
+}
+
+// pagerDutyIncidents remembers the routing key each open incident was
+// triggered with, so it is re-paged and resolved on the same PagerDuty
+// service after the on-call window has moved on
+type pagerDutyIncidents struct {
+    mutex sync.Mutex
+    keys  map[string]string // dedup key -> routing key
+}
+
+// pagerDutyService returns the service with the routing key its event is
+// sent with: the open incident's, then the service's own, then the time
+// route's, falling back to the global key
+func (m *Monitor) pagerDutyService(event AlertEvent) ServiceConfig {
+    service := event.Service
+    m.pagerDuty.mutex.Lock()
+    key, open := m.pagerDuty.keys[pagerDutyDedupKey(service)]
+    m.pagerDuty.mutex.Unlock()
+    if open {
+        service.PagerDutyRoutingKey = key
+    } else if service.PagerDutyRoutingKey == "" && event.Route != nil {
+        service.PagerDutyRoutingKey = event.Route.PagerDutyRoutingKey
+    }
+    return service
+}
+
+// pagerDutySent records a delivered trigger or resolve
+func (m *Monitor) pagerDutySent(kind string, service ServiceConfig) {
+    m.pagerDuty.mutex.Lock()
+    defer m.pagerDuty.mutex.Unlock()
+    dedupKey := pagerDutyDedupKey(service)
+    if kind == EventRecovery {
+        delete(m.pagerDuty.keys, dedupKey)
+        return
+    }
+    if m.pagerDuty.keys == nil {
+        m.pagerDuty.keys = make(map[string]string)
+    }
+    m.pagerDuty.keys[dedupKey] = m.pagerDutyRoutingKey(service)
+}
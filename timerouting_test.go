@@ -0,0 +1,152 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestTimeRouting(t *testing.T) {
+    config := MonitorConfig{}
+    config.Alerts.TimeRouting = []TimeRoute{
+        {Start: "22:00", End: "06:00", Routing: map[string][]string{SeverityCritical: {ChannelPagerDuty}}},
+        {Start: "06:00", End: "14:00", Routing: map[string][]string{SeverityCritical: {ChannelSlack}}},
+        {Start: "14:00", End: "22:00", Routing: map[string][]string{SeverityCritical: {ChannelPagerDuty, ChannelSlack}, SeverityWarning: {ChannelEmail}}},
+    }
+    m, _ := newTestMonitor(t, config)
+
+    tests := []struct {
+        name     string
+        now      time.Time
+        severity string
+        want     []string // nil for no time route
+    }{
+        {"02:00 UTC", time.Date(2024, 3, 6, 2, 0, 0, 0, time.UTC), SeverityCritical, []string{ChannelPagerDuty}},
+        {"14:00 UTC", time.Date(2024, 3, 6, 14, 0, 0, 0, time.UTC), SeverityCritical, []string{ChannelPagerDuty, ChannelSlack}},
+        {"window end is exclusive", time.Date(2024, 3, 6, 13, 59, 0, 0, time.UTC), SeverityCritical, []string{ChannelSlack}},
+        {"wraps midnight", time.Date(2024, 3, 6, 23, 30, 0, 0, time.UTC), SeverityCritical, []string{ChannelPagerDuty}},
+        {"other zones converted to UTC", time.Date(2024, 3, 6, 9, 0, 0, 0, time.FixedZone("EST", -5*3600)), SeverityCritical, []string{ChannelPagerDuty, ChannelSlack}},
+        {"severity without a time route", time.Date(2024, 3, 6, 2, 0, 0, 0, time.UTC), SeverityWarning, nil},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, ok := m.timeRoutedChannels(tt.severity, tt.now)
+            if ok != (tt.want != nil) || !equalStrings(got, tt.want) {
+                t.Errorf("timeRoutedChannels(%s, %s) = %v, %v, want %v", tt.severity, tt.now, got, ok, tt.want)
+            }
+        })
+    }
+}
+
+func TestTimeRouteOverrides(t *testing.T) {
+    // One stub serves PagerDuty's enqueue path and every Slack webhook path
+    var mutex sync.Mutex
+    var slackPaths []string
+    var pages []pagerDutyEvent
+    stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mutex.Lock()
+        defer mutex.Unlock()
+        if r.URL.Path == "/v2/enqueue" {
+            var event pagerDutyEvent
+            json.NewDecoder(r.Body).Decode(&event)
+            pages = append(pages, event)
+            w.WriteHeader(http.StatusAccepted)
+            return
+        }
+        slackPaths = append(slackPaths, r.URL.Path)
+    }))
+    defer stub.Close()
+    target, _ := url.Parse(stub.URL)
+
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    service := testService("payments", backend.URL)
+    service.Severity = SeverityCritical
+    now := time.Now().UTC()
+    m, err := NewMonitorFromConfig(MonitorConfig{
+        Services: []ServiceConfig{service},
+        Alerts: AlertConfig{
+            Slack:     SlackConfig{WebhookURL: "https://hooks.example.com/global"},
+            PagerDuty: PagerDutyConfig{ServiceKey: "global-key"},
+            Routing:   map[string][]string{SeverityCritical: {ChannelSlack, ChannelPagerDuty}},
+            TimeRouting: []TimeRoute{{
+                Start:               now.Add(-time.Minute).Format("15:04"),
+                End:                 now.Add(2 * time.Minute).Format("15:04"),
+                Routing:             map[string][]string{SeverityCritical: {ChannelSlack, ChannelPagerDuty}},
+                SlackWebhookURL:     "https://hooks.example.com/apac",
+                PagerDutyRoutingKey: "apac-key",
+            }},
+        },
+    })
+    if err != nil {
+        t.Fatalf("NewMonitorFromConfig: %v", err)
+    }
+    defer m.cancel()
+    m.httpClient.Transport = redirectTransport{target}
+
+    checkAndFlush(m, service)
+
+    // The incident is resolved where it was opened after the window closes,
+    // and the next one pages the global service
+    m.config.Alerts.TimeRouting = nil
+    backend.code.Store(http.StatusOK)
+    checkAndFlush(m, service)
+    backend.code.Store(http.StatusInternalServerError)
+    checkAndFlush(m, service)
+
+    mutex.Lock()
+    defer mutex.Unlock()
+    if want := []string{"/apac", "/global", "/global"}; !equalStrings(slackPaths, want) {
+        t.Errorf("Slack posts to %v, want %v", slackPaths, want)
+    }
+    want := []struct{ action, key string }{{"trigger", "apac-key"}, {"resolve", "apac-key"}, {"trigger", "global-key"}}
+    if len(pages) != len(want) {
+        t.Fatalf("PagerDuty events %+v, want %v", pages, want)
+    }
+    for i, page := range pages {
+        if page.EventAction != want[i].action || page.RoutingKey != want[i].key {
+            t.Errorf("event %d: %s with %q, want %s with %q", i, page.EventAction, page.RoutingKey, want[i].action, want[i].key)
+        }
+    }
+}
+
+func TestTimeRoutingFallback(t *testing.T) {
+    // A window covering all but the current minute leaves default routing in force
+    now := time.Now().UTC()
+    config := MonitorConfig{}
+    config.Alerts.TimeRouting = []TimeRoute{{
+        Start:   now.Add(2 * time.Minute).Format("15:04"),
+        End:     now.Add(-time.Minute).Format("15:04"),
+        Routing: map[string][]string{SeverityWarning: {ChannelEmail}},
+    }}
+    m, _ := newTestMonitor(t, config)
+
+    if got := m.alertChannels(testService("api", "https://api.example.com/")); !equalStrings(got, []string{"test"}) {
+        t.Errorf("routed to %v outside the window, want the default routing", got)
+    }
+}
+
+func TestInvalidTimeRouting(t *testing.T) {
+    tests := []struct {
+        name  string
+        route TimeRoute
+    }{
+        {"bad start", TimeRoute{Start: "2am", End: "06:00"}},
+        {"unknown channel", TimeRoute{Start: "22:00", End: "06:00", Routing: map[string][]string{SeverityCritical: {"apac-pager"}}}},
+        {"bad slack webhook", TimeRoute{Start: "22:00", End: "06:00", SlackWebhookURL: "hooks.example.com/apac"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            config := MonitorConfig{}
+            config.Alerts.TimeRouting = []TimeRoute{tt.route}
+            if _, err := NewMonitorFromConfig(config); err == nil {
+                t.Errorf("NewMonitorFromConfig accepted %+v", tt.route)
+            }
+        })
+    }
+}
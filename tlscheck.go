@@ -0,0 +1,80 @@
+package main
+
+import (
+    "crypto/tls"
+    "fmt"
+    "net/http"
+)
+
+var tlsVersions = map[string]uint16{
+    "1.0": tls.VersionTLS10,
+    "1.1": tls.VersionTLS11,
+    "1.2": tls.VersionTLS12,
+    "1.3": tls.VersionTLS13,
+}
+
+func tlsVersionName(version uint16) string {
+    for name, v := range tlsVersions {
+        if v == version {
+            return name
+        }
+    }
+    return fmt.Sprintf("0x%04x", version)
+}
+
+// checkMinTLSVersion fails when the negotiated version is below the minimum
+func checkMinTLSVersion(resp *http.Response, minVersion string) error {
+    if minVersion == "" {
+        return nil
+    }
+
+    required, ok := tlsVersions[minVersion]
+    if !ok {
+        return fmt.Errorf("unknown minimum TLS version %q", minVersion)
+    }
+    if resp.TLS == nil {
+        return fmt.Errorf("TLS version check failed: connection is not using TLS")
+    }
+    if resp.TLS.Version < required {
+        return fmt.Errorf("TLS version check failed: negotiated TLS %s, below required minimum %s",
+            tlsVersionName(resp.TLS.Version), minVersion)
+    }
+
+    return nil
+}
+
+// protocols are the values accepted for expected_protocol, by resp.Proto
+var protocols = map[string]bool{
+    "HTTP/1.0": true,
+    "HTTP/1.1": true,
+    "HTTP/2.0": true,
+}
+
+// checkProtocol fails when the response was not served over the expected
+// protocol, such as a load balancer silently downgrading HTTP/2
+func checkProtocol(resp *http.Response, expected string) error {
+    if expected == "" || resp.Proto == expected {
+        return nil
+    }
+    return fmt.Errorf("protocol check failed: served over %s, expected %s", resp.Proto, expected)
+}
+
+// recordConnectionState notes the TLS parameters and address family of the
+// last response
+func (m *Monitor) recordConnectionState(serviceName string, state *tls.ConnectionState, family string) {
+    m.statusMutex.Lock()
+    defer m.statusMutex.Unlock()
+
+    serviceStatus, ok := m.serviceStatus[serviceName]
+    if !ok {
+        return
+    }
+    serviceStatus.AddressFamily = family
+    if state == nil {
+        serviceStatus.TLSVersion = ""
+        serviceStatus.TLSCipher = ""
+        return
+    }
+    serviceStatus.TLSVersion = tlsVersionName(state.Version)
+    serviceStatus.TLSCipher = tls.CipherSuiteName(state.CipherSuite)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+    "crypto/tls"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// newTLSServer starts an httptest TLS server limited to the given versions
+func newTLSServer(t *testing.T, minVersion, maxVersion uint16) *httptest.Server {
+    t.Helper()
+    server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    server.TLS = &tls.Config{MinVersion: minVersion, MaxVersion: maxVersion}
+    server.StartTLS()
+    t.Cleanup(server.Close)
+    return server
+}
+
+// trustingClient is the service's client, trusting the test server's
+// certificate in addition to the settings the service configures
+func trustingClient(m *Monitor, service ServiceConfig, server *httptest.Server) *http.Client {
+    client := m.serviceClient(service)
+    transport := client.Transport.(*http.Transport)
+    config := &tls.Config{}
+    if transport.TLSClientConfig != nil {
+        config = transport.TLSClientConfig.Clone()
+    }
+    config.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+    transport.TLSClientConfig = config
+    return client
+}
+
+func TestMinTLSVersion(t *testing.T) {
+    tests := []struct {
+        name       string
+        serverMax  uint16
+        minVersion string
+        want       bool
+    }{
+        {"server pinned to TLS 1.1 fails a 1.2 minimum", tls.VersionTLS11, "1.2", false},
+        {"server pinned to TLS 1.2 passes a 1.2 minimum", tls.VersionTLS12, "1.2", true},
+        {"TLS 1.3 passes a 1.2 minimum", tls.VersionTLS13, "1.2", true},
+        {"server pinned to TLS 1.2 fails a 1.3 minimum", tls.VersionTLS12, "1.3", false},
+        {"no minimum", tls.VersionTLS13, "", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newTLSServer(t, tls.VersionTLS10, tt.serverMax)
+            service := testService("api", server.URL)
+            service.MinTLSVersion = tt.minVersion
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            outcome := m.performCheck(service, trustingClient(m, service, server), nil)
+            if outcome.up != tt.want {
+                t.Errorf("check up = %v (%v), want %v", outcome.up, outcome.err, tt.want)
+            }
+            if !tt.want && outcome.err != nil && !strings.Contains(outcome.err.Error(), "below required minimum") {
+                t.Errorf("check failed with %v, want the version reported", outcome.err)
+            }
+        })
+    }
+}
+
+func TestMinTLSVersionPlainHTTP(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    service := testService("api", server.URL)
+    service.MinTLSVersion = "1.2"
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    if outcome := m.performCheck(service, m.serviceClient(service), nil); outcome.up {
+        t.Error("a plain HTTP response passed the TLS version check")
+    }
+}
+
+func TestExpectedProtocol(t *testing.T) {
+    tests := []struct {
+        name       string
+        server     string // "h2" or "http/1.1" over TLS, or "plain"
+        expected   string
+        forceHTTP1 bool
+        wantUp     bool
+    }{
+        {"HTTP/2 server expected HTTP/2", "h2", "HTTP/2.0", false, true},
+        {"HTTP/1.1 server expected HTTP/2", "http/1.1", "HTTP/2.0", false, false},
+        {"HTTP/1.1 server expected HTTP/1.1", "http/1.1", "HTTP/1.1", false, true},
+        {"HTTP/2 server expected HTTP/1.1", "h2", "HTTP/1.1", false, false},
+        {"HTTP/2 server with HTTP/2 disabled", "h2", "HTTP/1.1", true, true},
+        {"plain HTTP expected HTTP/2", "plain", "HTTP/2.0", false, false},
+        {"no expectation", "h2", "", false, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+            server.EnableHTTP2 = tt.server == "h2"
+            if tt.server == "plain" {
+                server.Start()
+            } else {
+                server.StartTLS()
+            }
+            defer server.Close()
+
+            service := testService("api", server.URL)
+            service.ExpectedProtocol, service.ForceHTTP1 = tt.expected, tt.forceHTTP1
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            client := m.serviceClient(service)
+            if tt.server != "plain" {
+                client = trustingClient(m, service, server)
+            }
+
+            outcome := m.performCheck(service, client, nil)
+            if outcome.up != tt.wantUp {
+                t.Errorf("up = %v (%v), want %v", outcome.up, outcome.err, tt.wantUp)
+            }
+            if !tt.wantUp && (outcome.err == nil || !strings.Contains(outcome.err.Error(), "expected "+tt.expected)) {
+                t.Errorf("check failed with %v, want the protocol mismatch reported", outcome.err)
+            }
+        })
+    }
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+    "context"
+    "crypto/tls"
+    "fmt"
+    "net/http/httptrace"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+    "go.opentelemetry.io/otel/trace/noop"
+)
+
+type OTelConfig struct {
+    Endpoint    string `json:"endpoint"`     // OTLP/HTTP endpoint URL, e.g. "http://collector:4318"
+    ServiceName string `json:"service_name"` // defaults to "monitor-alert"
+}
+
+// newTracer exports check spans over OTLP when an endpoint is configured and
+// returns a no-op tracer otherwise
+func newTracer(config *OTelConfig) (trace.Tracer, error) {
+    if config == nil || config.Endpoint == "" {
+        return noop.NewTracerProvider().Tracer("monitor-alert"), nil
+    }
+
+    exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(config.Endpoint))
+    if err != nil {
+        return nil, fmt.Errorf("error creating OTLP exporter: %v", err)
+    }
+
+    serviceName := config.ServiceName
+    if serviceName == "" {
+        serviceName = "monitor-alert"
+    }
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+    )
+    return provider.Tracer("monitor-alert"), nil
+}
+
+// checkTrace records connection phases of a check as span events
+func checkTrace(span trace.Span) *httptrace.ClientTrace {
+    return &httptrace.ClientTrace{
+        DNSStart: func(info httptrace.DNSStartInfo) {
+            span.AddEvent("dns.start", trace.WithAttributes(attribute.String("dns.host", info.Host)))
+        },
+        DNSDone: func(info httptrace.DNSDoneInfo) {
+            span.AddEvent("dns.done", trace.WithAttributes(attribute.Int("dns.addresses", len(info.Addrs))))
+        },
+        ConnectStart: func(network, addr string) {
+            span.AddEvent("connect.start", trace.WithAttributes(attribute.String("net.peer", addr)))
+        },
+        ConnectDone: func(network, addr string, err error) {
+            span.AddEvent("connect.done", trace.WithAttributes(attribute.String("net.peer", addr)))
+        },
+        TLSHandshakeStart: func() {
+            span.AddEvent("tls.start")
+        },
+        TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+            span.AddEvent("tls.done")
+        },
+        GotFirstResponseByte: func() {
+            span.AddEvent("http.first_byte")
+        },
+    }
+}
+
+func endCheckSpan(span trace.Span, outcome checkOutcome) {
+    span.SetAttributes(
+        attribute.Bool("check.up", outcome.up),
+        attribute.Int("http.status_code", outcome.statusCode),
+        attribute.Int64("check.duration_ms", outcome.duration.Milliseconds()),
+    )
+    if outcome.err != nil {
+        span.SetStatus(codes.Error, outcome.err.Error())
+    }
+    span.End()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+    "net/http"
+    "testing"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCheckSpans(t *testing.T) {
+    tests := []struct {
+        name   string
+        code   int
+        up     bool
+        status codes.Code
+    }{
+        {"passing check", http.StatusOK, true, codes.Unset},
+        {"failing check", http.StatusInternalServerError, false, codes.Error},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := newStatusServer(t, tt.code)
+            service := testService("api", server.URL)
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            recorder := tracetest.NewSpanRecorder()
+            m.tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+            for i := 0; i < 2; i++ {
+                m.performCheck(service, m.serviceClient(service), nil)
+            }
+
+            spans := recorder.Ended()
+            if len(spans) != 2 {
+                t.Fatalf("recorded %d spans over 2 checks, want 2", len(spans))
+            }
+            span := spans[0]
+            if span.Name() != "check api" {
+                t.Errorf("span named %q", span.Name())
+            }
+            attributes := make(map[attribute.Key]attribute.Value)
+            for _, kv := range span.Attributes() {
+                attributes[kv.Key] = kv.Value
+            }
+            if got := attributes["service.name"].AsString(); got != "api" {
+                t.Errorf("service.name %q, want api", got)
+            }
+            if got := attributes["check.up"].AsBool(); got != tt.up {
+                t.Errorf("check.up %v, want %v", got, tt.up)
+            }
+            if got := attributes["http.status_code"].AsInt64(); got != int64(tt.code) {
+                t.Errorf("http.status_code %d, want %d", got, tt.code)
+            }
+            if span.Status().Code != tt.status {
+                t.Errorf("span status %v, want %v", span.Status().Code, tt.status)
+            }
+
+            events := make(map[string]bool)
+            for _, event := range span.Events() {
+                events[event.Name] = true
+            }
+            if !events["connect.done"] || !events["http.first_byte"] {
+                t.Errorf("span events %v, want connect and first byte", events)
+            }
+        })
+    }
+}
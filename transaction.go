@@ -0,0 +1,210 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/cookiejar"
+    "net/url"
+    "regexp"
+    "strings"
+)
+
+const CheckTypeTransaction = "transaction"
+
+// TransactionStep is one request of a transaction check. URL, header values
+// and body may reference variables captured by earlier steps as {{name}}.
+type TransactionStep struct {
+    Name           string            `json:"name"`
+    Method         string            `json:"method"`          // defaults to GET
+    URL            string            `json:"url"`
+    Headers        map[string]string `json:"headers"`
+    Body           string            `json:"body"`
+    ExpectedStatus int               `json:"expected_status"` // defaults to 200
+    Capture        map[string]string `json:"capture"`         // variable -> JSON path into the body, or "header:Name"
+}
+
+var transactionVariable = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// checkTransaction runs the service's steps in order, sharing cookies and
+// captured variables between them. It fails on the first step that fails.
+func (m *Monitor) checkTransaction(ctx context.Context, service ServiceConfig) error {
+    jar, err := cookiejar.New(nil)
+    if err != nil {
+        return err
+    }
+    client := *m.serviceClient(service)
+    client.Jar = jar
+
+    vars := map[string]string{}
+    for i, step := range service.Steps {
+        if err := m.runTransactionStep(ctx, &client, service, step, vars); err != nil {
+            name := step.Name
+            if name == "" {
+                name = step.URL
+            }
+            return fmt.Errorf("step %d (%s) failed: %v", i+1, name, err)
+        }
+    }
+    return nil
+}
+
+func (m *Monitor) runTransactionStep(ctx context.Context, client *http.Client, service ServiceConfig, step TransactionStep, vars map[string]string) error {
+    url, err := expandVariables(step.URL, vars)
+    if err != nil {
+        return err
+    }
+    body, err := expandVariables(step.Body, vars)
+    if err != nil {
+        return err
+    }
+    method := step.Method
+    if method == "" {
+        method = http.MethodGet
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+    if err != nil {
+        return err
+    }
+    for key, value := range step.Headers {
+        if value, err = expandVariables(value, vars); err != nil {
+            return err
+        }
+        req.Header.Set(key, value)
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return describeTransportError(err)
+    }
+    defer resp.Body.Close()
+
+    expected := step.ExpectedStatus
+    if expected == 0 {
+        expected = http.StatusOK
+    }
+    if resp.StatusCode != expected {
+        return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+    }
+
+    if len(step.Capture) == 0 {
+        return nil
+    }
+    respBody, _, err := readBody(resp, m.maxBodyBytes(service))
+    if err != nil {
+        return err
+    }
+    var doc interface{}
+    for variable, source := range step.Capture {
+        if header, ok := strings.CutPrefix(source, "header:"); ok {
+            value := resp.Header.Get(header)
+            if value == "" {
+                return fmt.Errorf("capture %s: header %s not present", variable, header)
+            }
+            vars[variable] = value
+            continue
+        }
+
+        if doc == nil {
+            if err := json.Unmarshal(respBody, &doc); err != nil {
+                return fmt.Errorf("capture %s: error parsing JSON response: %v", variable, err)
+            }
+        }
+        value, err := lookupJSONPath(doc, source)
+        if err != nil {
+            return fmt.Errorf("capture %s: %v", variable, err)
+        }
+        vars[variable] = jsonValueString(value)
+    }
+
+    return nil
+}
+
+// expandVariables replaces {{name}} references with captured values
+func expandVariables(s string, vars map[string]string) (string, error) {
+    var missing string
+    expanded := transactionVariable.ReplaceAllStringFunc(s, func(ref string) string {
+        name := transactionVariable.FindStringSubmatch(ref)[1]
+        value, ok := vars[name]
+        if !ok && missing == "" {
+            missing = name
+        }
+        return value
+    })
+    if missing != "" {
+        return "", fmt.Errorf("undefined variable %q", missing)
+    }
+    return expanded, nil
+}
+
+// transactionMethods are the request methods a step may use
+var transactionMethods = map[string]bool{
+    http.MethodGet:     true,
+    http.MethodHead:    true,
+    http.MethodPost:    true,
+    http.MethodPut:     true,
+    http.MethodPatch:   true,
+    http.MethodDelete:  true,
+    http.MethodOptions: true,
+}
+
+var captureVariable = regexp.MustCompile(`^\w+$`)
+
+// validateTransaction checks the steps at load: their URLs (with variables
+// filled in), methods, expected statuses and captures, and that every
+// variable is captured by an earlier step than the one using it
+func validateTransaction(service ServiceConfig) error {
+    if len(service.Steps) == 0 {
+        return fmt.Errorf("service %s: transaction checks need at least one step", service.Name)
+    }
+
+    captured := map[string]bool{}
+    for i, step := range service.Steps {
+        prefix := fmt.Sprintf("service %s: steps[%d]", service.Name, i)
+        if step.URL == "" {
+            return fmt.Errorf("%s: url is required", prefix)
+        }
+
+        templates := []string{step.URL, step.Body}
+        for _, value := range step.Headers {
+            templates = append(templates, value)
+        }
+        for _, template := range templates {
+            for _, ref := range transactionVariable.FindAllStringSubmatch(template, -1) {
+                if !captured[ref[1]] {
+                    return fmt.Errorf("%s: variable %q is not captured by an earlier step", prefix, ref[1])
+                }
+            }
+        }
+
+        filled := transactionVariable.ReplaceAllString(step.URL, "x")
+        if parsed, err := url.Parse(filled); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+            return fmt.Errorf("%s: invalid url %q", prefix, step.URL)
+        }
+        if step.Method != "" && !transactionMethods[step.Method] {
+            return fmt.Errorf("%s: unknown method %q", prefix, step.Method)
+        }
+        if step.ExpectedStatus != 0 && (step.ExpectedStatus < 100 || step.ExpectedStatus > 599) {
+            return fmt.Errorf("%s: invalid expected_status %d", prefix, step.ExpectedStatus)
+        }
+
+        for variable, source := range step.Capture {
+            if !captureVariable.MatchString(variable) {
+                return fmt.Errorf("%s: invalid capture variable %q", prefix, variable)
+            }
+            if header, ok := strings.CutPrefix(source, "header:"); ok {
+                if header == "" {
+                    return fmt.Errorf("%s: capture %s names no header", prefix, variable)
+                }
+            } else if err := validateJSONPath(source); err != nil {
+                return fmt.Errorf("%s: capture %s: %v", prefix, variable, err)
+            }
+        }
+        for variable := range step.Capture {
+            captured[variable] = true
+        }
+    }
+    return nil
+}
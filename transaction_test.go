@@ -0,0 +1,140 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+)
+
+func transactionService(steps ...TransactionStep) ServiceConfig {
+    return ServiceConfig{Name: "login", Type: CheckTypeTransaction, CheckInterval: 60, RetryAttempts: 1, Steps: steps}
+}
+
+func TestValidateTransaction(t *testing.T) {
+    login := TransactionStep{URL: "https://api.example.com/login", Method: http.MethodPost, Capture: map[string]string{"token": "$.token"}}
+    tests := []struct {
+        name    string
+        service ServiceConfig
+        wantErr bool
+    }{
+        {"steps without a top-level url", transactionService(login, TransactionStep{URL: "https://api.example.com/me?t={{token}}"}), false},
+        {"variable in the host", transactionService(login, TransactionStep{URL: "https://{{token}}.example.com/"}), false},
+        {"no steps", transactionService(), true},
+        {"relative step url", transactionService(TransactionStep{URL: "/login"}), true},
+        {"unknown method", transactionService(TransactionStep{URL: "https://api.example.com/", Method: "FETCH"}), true},
+        {"variable not captured yet", transactionService(TransactionStep{URL: "https://api.example.com/me", Headers: map[string]string{"Authorization": "Bearer {{token}}"}}, login), true},
+        {"empty capture header", transactionService(TransactionStep{URL: "https://api.example.com/", Capture: map[string]string{"id": "header:"}}), true},
+        {"bad capture path", transactionService(TransactionStep{URL: "https://api.example.com/", Capture: map[string]string{"id": "$.items[0"}}), true},
+        {"bad expected status", transactionService(TransactionStep{URL: "https://api.example.com/", ExpectedStatus: 42}), true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := validateServiceConfig(tt.service); (err != nil) != tt.wantErr {
+                t.Errorf("validateServiceConfig() = %v, want error %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestCheckTransaction(t *testing.T) {
+    login := TransactionStep{Name: "login", Method: http.MethodPost, URL: "/login", Capture: map[string]string{"token": "$.token"}}
+    me := TransactionStep{Name: "me", URL: "/me", Headers: map[string]string{"Authorization": "Bearer {{token}}"}}
+    logout := TransactionStep{Name: "logout", Method: http.MethodPost, URL: "/logout"}
+
+    tests := []struct {
+        name     string
+        steps    []TransactionStep
+        wantErr  string   // empty if the flow passes
+        wantHits []string // paths requested, in order
+    }{
+        {
+            name:     "login, fetch and logout",
+            steps:    []TransactionStep{login, me, logout},
+            wantHits: []string{"/login", "/me", "/logout"},
+        },
+        {
+            name:     "value captured from a header",
+            steps:    []TransactionStep{{Name: "login", URL: "/login", Capture: map[string]string{"token": "header:X-Token"}}, me},
+            wantHits: []string{"/login", "/me"},
+        },
+        {
+            name:     "cookies shared between steps",
+            steps:    []TransactionStep{login, {Name: "session", URL: "/session"}},
+            wantHits: []string{"/login", "/session"},
+        },
+        {
+            name:     "mid-flow failure stops the flow",
+            steps:    []TransactionStep{login, {Name: "me", URL: "/me", Headers: map[string]string{"Authorization": "Bearer stale"}}, logout},
+            wantErr:  "step 2 (me) failed: unexpected status code: 401",
+            wantHits: []string{"/login", "/me"},
+        },
+        {
+            name:     "capture missing from the body",
+            steps:    []TransactionStep{{Name: "login", URL: "/login", Capture: map[string]string{"token": "$.access_token"}}, me},
+            wantErr:  "step 1 (login) failed: capture token",
+            wantHits: []string{"/login"},
+        },
+        {
+            name:     "expected status per step",
+            steps:    []TransactionStep{login, {Name: "logout", Method: http.MethodPost, URL: "/logout", ExpectedStatus: http.StatusNoContent}},
+            wantHits: []string{"/login", "/logout"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var mutex sync.Mutex
+            var hits []string
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                mutex.Lock()
+                hits = append(hits, r.URL.Path)
+                mutex.Unlock()
+                switch r.URL.Path {
+                case "/login":
+                    http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1"})
+                    w.Header().Set("X-Token", "abc")
+                    w.Write([]byte(`{"token": "abc"}`))
+                case "/me":
+                    if r.Header.Get("Authorization") != "Bearer abc" {
+                        w.WriteHeader(http.StatusUnauthorized)
+                    }
+                case "/session":
+                    if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "s1" {
+                        w.WriteHeader(http.StatusForbidden)
+                    }
+                case "/logout":
+                    if tt.steps[len(tt.steps)-1].ExpectedStatus == http.StatusNoContent {
+                        w.WriteHeader(http.StatusNoContent)
+                    }
+                }
+            }))
+            defer server.Close()
+
+            // Step URLs are given as paths on the test server
+            steps := make([]TransactionStep, len(tt.steps))
+            for i, step := range tt.steps {
+                steps[i] = step
+                steps[i].URL = server.URL + step.URL
+            }
+            service := transactionService(steps...)
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            err := m.checkTransaction(context.Background(), service)
+            if tt.wantErr == "" && err != nil {
+                t.Errorf("checkTransaction() = %v, want nil", err)
+            }
+            if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Errorf("checkTransaction() = %v, want an error containing %q", err, tt.wantErr)
+            }
+            mutex.Lock()
+            defer mutex.Unlock()
+            if !equalStrings(hits, tt.wantHits) {
+                t.Errorf("requested %v, want %v", hits, tt.wantHits)
+            }
+        })
+    }
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+    "context"
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "golang.org/x/net/proxy"
+)
+
+// Socks5Config routes a service's checks through a SOCKS5 proxy, such as a
+// bastion reachable with ssh -D
+type Socks5Config struct {
+    Address  string `json:"address"` // host:port of the proxy
+    Username string `json:"username"`
+    Password string `json:"password"`
+}
+
+func socks5DialContext(config *Socks5Config, forward *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    var auth *proxy.Auth
+    if config.Username != "" {
+        auth = &proxy.Auth{User: config.Username, Password: config.Password}
+    }
+
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        dialer, err := proxy.SOCKS5("tcp", config.Address, auth, forward)
+        if err != nil {
+            return nil, err
+        }
+        return dialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+    }
+}
+
+const (
+    AddressFamilyAuto       = "auto"
+    AddressFamilyIPv4       = "ipv4"
+    AddressFamilyIPv6       = "ipv6"
+    AddressFamilyPreferIPv6 = "prefer-ipv6" // IPv6, falling back to IPv4 when the IPv6 dial fails
+)
+
+// dialNetwork maps an AddressFamily onto the network passed to the dialer
+func dialNetwork(family, network string) (string, error) {
+    switch family {
+    case "", AddressFamilyAuto, AddressFamilyPreferIPv6:
+        return network, nil
+    case AddressFamilyIPv4:
+        return "tcp4", nil
+    case AddressFamilyIPv6:
+        return "tcp6", nil
+    default:
+        return "", fmt.Errorf("unknown address family %q", family)
+    }
+}
+
+// dialPreferIPv6 dials over IPv6 and falls back to IPv4 if that fails. The
+// IPv6 attempt gets half the time available, so a blackholed AAAA path
+// still leaves the fallback time to connect.
+func dialPreferIPv6(ctx context.Context, dialer *net.Dialer, addr string) (net.Conn, error) {
+    ipv6 := *dialer
+    ipv6.Timeout = dialer.Timeout / 2
+    if deadline, ok := ctx.Deadline(); ok {
+        if remaining := time.Until(deadline) / 2; remaining < ipv6.Timeout {
+            ipv6.Timeout = remaining
+        }
+    }
+
+    conn, err := ipv6.DialContext(ctx, "tcp6", addr)
+    if err == nil || ctx.Err() != nil {
+        return conn, err
+    }
+    conn, ipv4Err := dialer.DialContext(ctx, "tcp4", addr)
+    if ipv4Err != nil {
+        return nil, fmt.Errorf("ipv6: %v; ipv4 fallback: %v", err, ipv4Err)
+    }
+    return conn, nil
+}
+
+// addressFamily names the family of a connection's remote address
+func addressFamily(addr net.Addr) string {
+    tcp, ok := addr.(*net.TCPAddr)
+    if !ok {
+        return ""
+    }
+    if tcp.IP.To4() != nil {
+        return AddressFamilyIPv4
+    }
+    return AddressFamilyIPv6
+}
+
+// dialTarget is the host:port a URL's connections are dialed at
+func dialTarget(rawURL string) string {
+    parsed, err := url.Parse(rawURL)
+    if err != nil {
+        return ""
+    }
+    port := parsed.Port()
+    if port == "" {
+        port = "80"
+        if parsed.Scheme == "https" {
+            port = "443"
+        }
+    }
+    return net.JoinHostPort(parsed.Hostname(), port)
+}
+
+// serverName is the TLS SNI for a host override, which may carry a port
+func serverName(host string) string {
+    if name, _, err := net.SplitHostPort(host); err == nil {
+        return name
+    }
+    return host
+}
+
+func newServiceTransport(service ServiceConfig, resolver *net.Resolver) *http.Transport {
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    dialer := &net.Dialer{
+        Timeout:   30 * time.Second,
+        KeepAlive: 30 * time.Second,
+        Resolver:  resolver, // nil uses the system resolver
+    }
+    if service.DialTimeout > 0 {
+        dialer.Timeout = time.Duration(service.DialTimeout) * time.Second
+    }
+    transport.ResponseHeaderTimeout = time.Duration(service.ResponseHeaderTimeout) * time.Second
+
+    target := dialTarget(service.URL)
+    transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+        if service.ConnectAddr != "" && addr == target {
+            // Reach a specific backend behind a VIP; redirects elsewhere
+            // are dialed normally
+            addr = service.ConnectAddr
+        }
+        if service.AddressFamily == AddressFamilyPreferIPv6 {
+            return dialPreferIPv6(ctx, dialer, addr)
+        }
+        network, err := dialNetwork(service.AddressFamily, network)
+        if err != nil {
+            return nil, err
+        }
+        return dialer.DialContext(ctx, network, addr)
+    }
+
+    if service.Socks5Proxy != nil {
+        // Target names are resolved by the proxy, so the address family and
+        // resolver settings only apply to reaching the proxy itself
+        transport.Proxy = nil
+        transport.DialContext = socks5DialContext(service.Socks5Proxy, dialer)
+    }
+
+    if service.ForceHTTP1 {
+        // A non-nil, empty TLSNextProto disables HTTP/2 over TLS
+        transport.ForceAttemptHTTP2 = false
+        transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+        // The TLS config cloned from DefaultTransport still offers h2 over
+        // ALPN, which an HTTP/2 server would accept
+        if transport.TLSClientConfig != nil {
+            transport.TLSClientConfig.NextProtos = nil
+        }
+    }
+    transport.DisableKeepAlives = service.DisableKeepAlive
+
+    if service.MinTLSVersion != "" || service.Host != "" {
+        transport.TLSClientConfig = &tls.Config{ServerName: serverName(service.Host)}
+    }
+    if service.MinTLSVersion != "" {
+        // Allow older versions to negotiate so the check can report them
+        // explicitly instead of failing with a generic handshake error
+        transport.TLSClientConfig.MinVersion = tls.VersionTLS10
+    }
+
+    return transport
+}
+
+// describeTransportError names the phase that exceeded its bound so "can't
+// connect" can be told apart from "slow to respond"
+func describeTransportError(err error) error {
+    var opErr *net.OpError
+    if errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout() {
+        return fmt.Errorf("connect timeout: %v", err)
+    }
+    if strings.Contains(err.Error(), "timeout awaiting response headers") {
+        return fmt.Errorf("response header timeout: %v", err)
+    }
+    var netErr net.Error
+    if errors.As(err, &netErr) && netErr.Timeout() {
+        return fmt.Errorf("overall timeout: %v", err)
+    }
+    return err
+}
+
+// serviceClient returns the HTTP client for a service, building its
+// transport on first use so connections are reused across checks
+func (m *Monitor) serviceClient(service ServiceConfig) *http.Client {
+    m.clientMutex.Lock()
+    defer m.clientMutex.Unlock()
+
+    if client, ok := m.clients[service.Name]; ok {
+        return client
+    }
+
+    transport := newServiceTransport(service, m.resolver)
+    if service.DNSCacheTTL > 0 && service.ConnectAddr == "" && service.Socks5Proxy == nil {
+        transport.DialContext = m.cachedDNSDial(service, transport.DialContext)
+    }
+    client := &http.Client{
+        Timeout:       time.Duration(service.Timeout) * time.Second,
+        Transport:     transport,
+        CheckRedirect: redirectPolicy(service),
+    }
+    m.clients[service.Name] = client
+    return client
+}
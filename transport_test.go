@@ -0,0 +1,560 @@
+package main
+
+import (
+    "context"
+    "encoding/binary"
+    "errors"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// listen opens a TCP listener accepting and dropping connections, skipping
+// the test where the loopback address is unavailable
+func listen(t *testing.T, addr string) string {
+    t.Helper()
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        t.Skipf("cannot listen on %s: %v", addr, err)
+    }
+    t.Cleanup(func() { listener.Close() })
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            conn.Close()
+        }
+    }()
+    return listener.Addr().String()
+}
+
+func TestAddressFamilyDial(t *testing.T) {
+    tests := []struct {
+        name     string
+        family   string
+        listenOn string // "" dials a loopback address nothing listens on
+        dial     string
+        want     string // family of the connection, "" for a failed dial
+    }{
+        {"ipv4 forced dials tcp4", AddressFamilyIPv4, "127.0.0.1:0", "", AddressFamilyIPv4},
+        {"ipv6 forced refuses an ipv4 address", AddressFamilyIPv6, "127.0.0.1:0", "", ""},
+        {"ipv6 forced dials tcp6", AddressFamilyIPv6, "[::1]:0", "", AddressFamilyIPv6},
+        {"ipv4 forced refuses an ipv6 address", AddressFamilyIPv4, "", "[::1]:9", ""},
+        {"auto dials either", AddressFamilyAuto, "127.0.0.1:0", "", AddressFamilyIPv4},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            addr := tt.dial
+            if tt.listenOn != "" {
+                addr = listen(t, tt.listenOn)
+            }
+            service := testService("api", "http://"+addr)
+            service.AddressFamily = tt.family
+            transport := newServiceTransport(service, nil)
+
+            conn, err := transport.DialContext(context.Background(), "tcp", addr)
+            if tt.want == "" {
+                if err == nil {
+                    conn.Close()
+                    t.Fatalf("dial of %s succeeded", addr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("dial of %s: %v", addr, err)
+            }
+            defer conn.Close()
+            if got := addressFamily(conn.RemoteAddr()); got != tt.want {
+                t.Errorf("connected over %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestAddressFamilyRecorded(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    service := testService("api", server.URL)
+    service.AddressFamily = AddressFamilyIPv4
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    outcome := m.performCheck(service, m.serviceClient(service), nil)
+    if !outcome.up || outcome.addressFamily != AddressFamilyIPv4 {
+        t.Errorf("up %v (%v) over %q, want an ipv4 pass", outcome.up, outcome.err, outcome.addressFamily)
+    }
+}
+
+func TestPreferIPv6Fallback(t *testing.T) {
+    tests := []struct {
+        name       string
+        ipv6, ipv4 bool   // which loopback addresses the port is listened on
+        want       string // family of the connection, "" for a failed dial
+    }{
+        {"ipv6 when it connects", true, true, AddressFamilyIPv6},
+        {"ipv4 when the ipv6 dial is refused", false, true, AddressFamilyIPv4},
+        {"both refused", false, false, ""},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            // The IPv6 listener picks the port when there is one, so the IPv4
+            // listener shares it
+            port := "9"
+            if tt.ipv6 {
+                _, port, _ = net.SplitHostPort(listen(t, "[::1]:0"))
+            } else if tt.ipv4 {
+                _, port, _ = net.SplitHostPort(listen(t, "127.0.0.1:0"))
+            }
+            if tt.ipv6 && tt.ipv4 {
+                listen(t, "127.0.0.1:"+port)
+            }
+            dns := newStubDNS(t)
+            dns.set("dual.monitor.test", "::1", "127.0.0.1")
+            addr := net.JoinHostPort("dual.monitor.test", port)
+            service := testService("api", "http://"+addr)
+            service.AddressFamily = AddressFamilyPreferIPv6
+            transport := newServiceTransport(service, newResolver(&ResolverConfig{Server: dns.addr}, time.Second))
+
+            conn, err := transport.DialContext(context.Background(), "tcp", addr)
+            if tt.want == "" {
+                if err == nil {
+                    conn.Close()
+                    t.Fatalf("dial of %s succeeded", addr)
+                }
+                if !strings.Contains(err.Error(), "ipv4 fallback") {
+                    t.Errorf("error %q does not report the ipv4 fallback", err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("dial of %s: %v", addr, err)
+            }
+            defer conn.Close()
+            if got := addressFamily(conn.RemoteAddr()); got != tt.want {
+                t.Errorf("connected over %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestConnectAddrAndHost(t *testing.T) {
+    tests := []struct {
+        name     string
+        tls      bool
+        url      string // never resolved, the check dials the test server
+        host     string
+        wantHost string // Host header the backend received
+        wantSNI  string
+    }{
+        {"host header", false, "http://vip.monitor.test/health", "api.internal", "api.internal", ""},
+        {"URL host without an override", false, "http://vip.monitor.test/health", "", "vip.monitor.test", ""},
+        {"host header and SNI", true, "https://vip.monitor.test/health", "example.com", "example.com", "example.com"},
+        {"SNI drops the host's port", true, "https://vip.monitor.test/health", "example.com:8443", "example.com:8443", "example.com"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var gotHost, gotSNI, gotPath atomic.Value
+            server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                gotHost.Store(r.Host)
+                gotPath.Store(r.URL.Path)
+                if r.TLS != nil {
+                    gotSNI.Store(r.TLS.ServerName)
+                }
+            }))
+            if tt.tls {
+                // The httptest certificate is issued for example.com
+                server.StartTLS()
+            } else {
+                server.Start()
+            }
+            defer server.Close()
+
+            service := testService("backend-1", tt.url)
+            service.ConnectAddr = server.Listener.Addr().String()
+            service.Host = tt.host
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+            client := m.serviceClient(service)
+            if tt.tls {
+                client = trustingClient(m, service, server)
+            }
+
+            outcome := m.performCheck(service, client, nil)
+            if !outcome.up {
+                t.Fatalf("check through %s failed: %v", service.ConnectAddr, outcome.err)
+            }
+            if got, _ := gotPath.Load().(string); got != "/health" {
+                t.Errorf("requested path %q, want /health", got)
+            }
+            if got, _ := gotHost.Load().(string); got != tt.wantHost {
+                t.Errorf("Host %q, want %q", got, tt.wantHost)
+            }
+            if got, _ := gotSNI.Load().(string); got != tt.wantSNI {
+                t.Errorf("SNI %q, want %q", got, tt.wantSNI)
+            }
+        })
+    }
+}
+
+func TestConnectAddrOnlyForTarget(t *testing.T) {
+    backend := listen(t, "127.0.0.1:0")
+    service := testService("backend-1", "http://vip.monitor.test/health")
+    service.ConnectAddr = backend
+    transport := newServiceTransport(service, nil)
+
+    conn, err := transport.DialContext(context.Background(), "tcp", "vip.monitor.test:80")
+    if err != nil {
+        t.Fatalf("dial of the URL's host: %v", err)
+    }
+    if got := conn.RemoteAddr().String(); got != backend {
+        t.Errorf("dialed %s, want connect_addr %s", got, backend)
+    }
+    conn.Close()
+
+    // A redirect to another host is dialed as usual
+    if conn, err := transport.DialContext(context.Background(), "tcp", "other.monitor.test:80"); err == nil {
+        conn.Close()
+        t.Errorf("dial of another host reached %s", conn.RemoteAddr())
+    }
+}
+
+func TestUnknownAddressFamily(t *testing.T) {
+    service := testService("api", "https://api.example.com/")
+    service.AddressFamily = "ipv5"
+    if err := validateServiceConfig(service); err == nil {
+        t.Error("validateServiceConfig accepted address_family ipv5")
+    }
+}
+
+func TestForceHTTP1(t *testing.T) {
+    tests := []struct {
+        name       string
+        forceHTTP1 bool
+        want       string
+    }{
+        {"negotiates HTTP/2 by default", false, "HTTP/2.0"},
+        {"force_http1", true, "HTTP/1.1"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            protocol := make(chan string, 1)
+            server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                select {
+                case protocol <- r.Proto:
+                default:
+                }
+            }))
+            server.EnableHTTP2 = true
+            server.StartTLS()
+            defer server.Close()
+
+            service := testService("api", server.URL)
+            service.ForceHTTP1 = tt.forceHTTP1
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            if outcome := m.performCheck(service, trustingClient(m, service, server), nil); !outcome.up {
+                t.Fatalf("check failed: %v", outcome.err)
+            }
+            if got := <-protocol; got != tt.want {
+                t.Errorf("served over %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestDisableKeepAlive(t *testing.T) {
+    tests := []struct {
+        name    string
+        disable bool
+        want    int32 // connections opened over three checks
+    }{
+        {"connections reused", false, 1},
+        {"new connection per check", true, 3},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var connections atomic.Int32
+            server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+            server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+                if state == http.StateNew {
+                    connections.Add(1)
+                }
+            }
+            server.Start()
+            defer server.Close()
+
+            service := testService("api", server.URL)
+            service.DisableKeepAlive = tt.disable
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            for i := 0; i < 3; i++ {
+                if outcome := m.performCheck(service, m.serviceClient(service), nil); !outcome.up {
+                    t.Fatalf("check %d failed: %v", i, outcome.err)
+                }
+            }
+            if got := connections.Load(); got != tt.want {
+                t.Errorf("opened %d connections, want %d", got, tt.want)
+            }
+        })
+    }
+}
+
+// newHangingListener accepts connections and reads requests without ever
+// writing a response
+func newHangingListener(t *testing.T) string {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { listener.Close() })
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go func() {
+                defer conn.Close()
+                io.Copy(io.Discard, conn)
+            }()
+        }
+    }()
+    return listener.Addr().String()
+}
+
+func TestCheckTimeoutPhase(t *testing.T) {
+    tests := []struct {
+        name                  string
+        timeout               int
+        responseHeaderTimeout int
+        want                  string
+    }{
+        {"response header timeout", 5, 1, "response header timeout"},
+        {"overall timeout", 1, 0, "overall timeout"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("api", "http://"+newHangingListener(t))
+            service.Timeout = tt.timeout
+            service.ResponseHeaderTimeout = tt.responseHeaderTimeout
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            outcome := m.performCheck(service, m.serviceClient(service), nil)
+            if outcome.up || outcome.err == nil {
+                t.Fatal("check of a hanging server passed")
+            }
+            if !strings.HasPrefix(outcome.err.Error(), tt.want+":") {
+                t.Errorf("error %q, want it to name the %s", outcome.err, tt.want)
+            }
+        })
+    }
+}
+
+func TestDescribeTransportError(t *testing.T) {
+    tests := []struct {
+        name string
+        err  error
+        want string // prefix added, "" for an error passed through
+    }{
+        {"connect timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, "connect timeout: "},
+        {"refused connection", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ""},
+        {"read timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, "overall timeout: "},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := describeTransportError(tt.err).Error()
+            if got != tt.want+tt.err.Error() {
+                t.Errorf("described as %q, want prefix %q", got, tt.want)
+            }
+        })
+    }
+}
+
+// stubSOCKS5 is a minimal SOCKS5 proxy handling CONNECT. It records the
+// address each client asked for and connects every request to backend.
+type stubSOCKS5 struct {
+    addr     string
+    backend  string
+    username string // "" accepts clients without authentication
+    password string
+    targets  chan string
+}
+
+func newStubSOCKS5(t *testing.T, backend, username, password string) *stubSOCKS5 {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { listener.Close() })
+
+    s := &stubSOCKS5{addr: listener.Addr().String(), backend: backend, username: username, password: password, targets: make(chan string, 16)}
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go s.serve(conn)
+        }
+    }()
+    return s
+}
+
+func (s *stubSOCKS5) serve(conn net.Conn) {
+    defer conn.Close()
+
+    // Greeting: version, then the offered methods
+    header := make([]byte, 2)
+    if _, err := io.ReadFull(conn, header); err != nil {
+        return
+    }
+    methods := make([]byte, header[1])
+    if _, err := io.ReadFull(conn, methods); err != nil {
+        return
+    }
+    if s.username == "" {
+        conn.Write([]byte{5, 0})
+    } else {
+        conn.Write([]byte{5, 2})
+        if !s.authenticate(conn) {
+            conn.Write([]byte{1, 1})
+            return
+        }
+        conn.Write([]byte{1, 0})
+    }
+
+    // Request: version, command, reserved, address type, address, port
+    request := make([]byte, 4)
+    if _, err := io.ReadFull(conn, request); err != nil {
+        return
+    }
+    var host string
+    switch request[3] {
+    case 1:
+        ip := make([]byte, 4)
+        io.ReadFull(conn, ip)
+        host = net.IP(ip).String()
+    case 3:
+        length := make([]byte, 1)
+        io.ReadFull(conn, length)
+        name := make([]byte, length[0])
+        io.ReadFull(conn, name)
+        host = string(name)
+    default:
+        return
+    }
+    port := make([]byte, 2)
+    if _, err := io.ReadFull(conn, port); err != nil {
+        return
+    }
+    s.targets <- net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+    backend, err := net.Dial("tcp", s.backend)
+    if err != nil {
+        conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+        return
+    }
+    defer backend.Close()
+    conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+    go io.Copy(backend, conn)
+    io.Copy(conn, backend)
+}
+
+// authenticate reads a username/password subnegotiation
+func (s *stubSOCKS5) authenticate(conn net.Conn) bool {
+    field := func() string {
+        length := make([]byte, 1)
+        io.ReadFull(conn, length)
+        value := make([]byte, length[0])
+        io.ReadFull(conn, value)
+        return string(value)
+    }
+    version := make([]byte, 1)
+    if _, err := io.ReadFull(conn, version); err != nil {
+        return false
+    }
+    username, password := field(), field()
+    return username == s.username && password == s.password
+}
+
+func TestSocks5Proxy(t *testing.T) {
+    tests := []struct {
+        name     string
+        username string // configured on the service
+        password string
+        wantUp   bool
+    }{
+        {"no authentication", "", "", true},
+        {"username and password", "monitor", "secret", true},
+        {"wrong password", "monitor", "guess", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusOK)
+            proxyUser, proxyPassword := "", ""
+            if tt.username != "" {
+                proxyUser, proxyPassword = "monitor", "secret"
+            }
+            proxy := newStubSOCKS5(t, backend.Listener.Addr().String(), proxyUser, proxyPassword)
+
+            // Only the proxy knows where the internal name points
+            service := testService("internal", "http://app.internal:8080/health")
+            service.Socks5Proxy = &Socks5Config{Address: proxy.addr, Username: tt.username, Password: tt.password}
+            m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            outcome := m.performCheck(service, m.serviceClient(service), nil)
+            if outcome.up != tt.wantUp {
+                t.Fatalf("up %v (%v), want %v", outcome.up, outcome.err, tt.wantUp)
+            }
+            if !tt.wantUp {
+                return
+            }
+            select {
+            case target := <-proxy.targets:
+                if target != "app.internal:8080" {
+                    t.Errorf("proxy asked to connect to %s, want app.internal:8080", target)
+                }
+            default:
+                t.Error("check did not go through the proxy")
+            }
+        })
+    }
+}
+
+func TestInvalidSocks5Proxy(t *testing.T) {
+    tests := []struct {
+        name        string
+        address     string
+        connectAddr string
+    }{
+        {"address without a port", "bastion.internal", ""},
+        {"combined with connect_addr", "bastion.internal:1080", "10.0.0.5:443"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            service := testService("internal", "http://app.internal/health")
+            service.Socks5Proxy = &Socks5Config{Address: tt.address}
+            service.ConnectAddr = tt.connectAddr
+            if err := validateServiceConfig(service); err == nil {
+                t.Error("validateServiceConfig accepted the proxy")
+            }
+        })
+    }
+}
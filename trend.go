@@ -0,0 +1,167 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// Tiered retention for the latency trend: raw points for the last hour,
+// 1-minute aggregates up to a day, 5-minute aggregates beyond that
+const (
+    trendRawRetention    = time.Hour
+    trendMinuteRetention = 24 * time.Hour
+)
+
+// defaultTrendMaxPoints caps the points kept across all tiers per service
+const defaultTrendMaxPoints = 5000
+
+// trendPoint is a single raw check in the latency trend
+type trendPoint struct {
+    Time      time.Time `json:"time"`
+    LatencyMs float64   `json:"latency_ms"`
+    Up        bool      `json:"up"`
+}
+
+// trendAggregate summarises the checks that started within one bucket
+type trendAggregate struct {
+    Start    time.Time `json:"start"`
+    Count    int       `json:"count"`
+    Failures int       `json:"failures"`
+    MinMs    float64   `json:"min_ms"`
+    MaxMs    float64   `json:"max_ms"`
+    AvgMs    float64   `json:"avg_ms"`
+}
+
+func (a *trendAggregate) merge(other trendAggregate) {
+    if a.Count == 0 {
+        *a = other
+        return
+    }
+    if other.MinMs < a.MinMs {
+        a.MinMs = other.MinMs
+    }
+    if other.MaxMs > a.MaxMs {
+        a.MaxMs = other.MaxMs
+    }
+    total := a.Count + other.Count
+    a.AvgMs = (a.AvgMs*float64(a.Count) + other.AvgMs*float64(other.Count)) / float64(total)
+    a.Count = total
+    a.Failures += other.Failures
+}
+
+func (p trendPoint) aggregate(start time.Time) trendAggregate {
+    aggregate := trendAggregate{Start: start, Count: 1, MinMs: p.LatencyMs, MaxMs: p.LatencyMs, AvgMs: p.LatencyMs}
+    if !p.Up {
+        aggregate.Failures = 1
+    }
+    return aggregate
+}
+
+// latencyTrend is the long-retention latency series for a service. Ages are
+// measured from the newest point rather than the wall clock, so compaction
+// depends only on the data. It is guarded by the monitor's status mutex.
+type latencyTrend struct {
+    raw        []trendPoint
+    minute     []trendAggregate
+    fiveMinute []trendAggregate
+    maxPoints  int
+}
+
+func newLatencyTrend(maxPoints int) *latencyTrend {
+    if maxPoints <= 0 {
+        maxPoints = defaultTrendMaxPoints
+    }
+    return &latencyTrend{maxPoints: maxPoints}
+}
+
+func (t *latencyTrend) add(record CheckRecord) {
+    t.raw = append(t.raw, trendPoint{
+        Time:      record.Time,
+        LatencyMs: float64(record.Latency) / float64(time.Millisecond),
+        Up:        record.Up,
+    })
+    t.compact(record.Time)
+}
+
+// compact moves points that aged out of a tier into the next one and then
+// drops the oldest points until the total is within maxPoints
+func (t *latencyTrend) compact(now time.Time) {
+    cutoff := now.Add(-trendRawRetention)
+    aged := 0
+    for aged < len(t.raw) && t.raw[aged].Time.Before(cutoff) {
+        start := t.raw[aged].Time.Truncate(time.Minute)
+        t.minute = appendAggregate(t.minute, t.raw[aged].aggregate(start))
+        aged++
+    }
+    t.raw = t.raw[aged:]
+
+    cutoff = now.Add(-trendMinuteRetention)
+    aged = 0
+    for aged < len(t.minute) && t.minute[aged].Start.Before(cutoff) {
+        bucket := t.minute[aged]
+        bucket.Start = bucket.Start.Truncate(5 * time.Minute)
+        t.fiveMinute = appendAggregate(t.fiveMinute, bucket)
+        aged++
+    }
+    t.minute = t.minute[aged:]
+
+    for excess := len(t.raw) + len(t.minute) + len(t.fiveMinute) - t.maxPoints; excess > 0; excess-- {
+        switch {
+        case len(t.fiveMinute) > 0:
+            t.fiveMinute = t.fiveMinute[1:]
+        case len(t.minute) > 0:
+            t.minute = t.minute[1:]
+        default:
+            t.raw = t.raw[1:]
+        }
+    }
+}
+
+// appendAggregate merges into the last bucket when it has the same start,
+// which holds because points arrive in time order
+func appendAggregate(buckets []trendAggregate, bucket trendAggregate) []trendAggregate {
+    if n := len(buckets); n > 0 && buckets[n-1].Start.Equal(bucket.Start) {
+        buckets[n-1].merge(bucket)
+        return buckets
+    }
+    return append(buckets, bucket)
+}
+
+type trendSeries struct {
+    Raw        []trendPoint     `json:"raw"`
+    Minute     []trendAggregate `json:"minute"`
+    FiveMinute []trendAggregate `json:"five_minute"`
+}
+
+func (t *latencyTrend) series() trendSeries {
+    return trendSeries{
+        Raw:        append([]trendPoint{}, t.raw...),
+        Minute:     append([]trendAggregate{}, t.minute...),
+        FiveMinute: append([]trendAggregate{}, t.fiveMinute...),
+    }
+}
+
+// handleHistory returns the tiered latency series, optionally for a single
+// service via ?service=
+func (m *Monitor) handleHistory(w http.ResponseWriter, r *http.Request) {
+    service := r.URL.Query().Get("service")
+
+    m.statusMutex.RLock()
+    defer m.statusMutex.RUnlock()
+
+    result := make(map[string]trendSeries)
+    for name, status := range m.serviceStatus {
+        if service != "" && name != service {
+            continue
+        }
+        result[name] = status.Trend.series()
+    }
+    if service != "" && len(result) == 0 {
+        http.Error(w, fmt.Sprintf("service %q not found", service), http.StatusNotFound)
+        return
+    }
+
+    json.NewEncoder(w).Encode(result)
+}
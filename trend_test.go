@@ -0,0 +1,86 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestLatencyTrendTiers(t *testing.T) {
+    base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+    point := func(offset time.Duration, ms int, up bool) CheckRecord {
+        return CheckRecord{Time: base.Add(offset), Up: up, Latency: time.Duration(ms) * time.Millisecond}
+    }
+
+    trend := newLatencyTrend(0)
+    // Two checks in one minute and one in the next, a day back by the end
+    trend.add(point(0, 100, true))
+    trend.add(point(20*time.Second, 300, false))
+    trend.add(point(70*time.Second, 50, true))
+    // Checks in two minutes two hours before the newest
+    trend.add(point(25*time.Hour, 10, true))
+    trend.add(point(25*time.Hour+30*time.Second, 20, false))
+    trend.add(point(25*time.Hour+3*time.Minute, 30, true))
+    // The newest check, which ages the first three past a day
+    trend.add(point(27*time.Hour, 80, true))
+
+    series := trend.series()
+    if len(series.Raw) != 1 || series.Raw[0].LatencyMs != 80 {
+        t.Errorf("raw %+v, want only the newest point", series.Raw)
+    }
+    if len(series.Minute) != 2 {
+        t.Fatalf("minute buckets %+v, want 2", series.Minute)
+    }
+    for i, want := range []trendAggregate{
+        {Start: base.Add(25 * time.Hour), Count: 2, Failures: 1, MinMs: 10, MaxMs: 20, AvgMs: 15},
+        {Start: base.Add(25*time.Hour + 3*time.Minute), Count: 1, MinMs: 30, MaxMs: 30, AvgMs: 30},
+    } {
+        if got := series.Minute[i]; got != want {
+            t.Errorf("minute bucket %d = %+v, want %+v", i, got, want)
+        }
+    }
+    if len(series.FiveMinute) != 1 {
+        t.Fatalf("five-minute buckets %+v, want 1", series.FiveMinute)
+    }
+    want := trendAggregate{Start: base, Count: 3, Failures: 1, MinMs: 50, MaxMs: 300, AvgMs: 150}
+    if got := series.FiveMinute[0]; got != want {
+        t.Errorf("five-minute bucket %+v, want %+v", got, want)
+    }
+}
+
+func TestLatencyTrendMaxPoints(t *testing.T) {
+    base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+    trend := newLatencyTrend(3)
+    for i := 0; i < 10; i++ {
+        trend.add(CheckRecord{Time: base.Add(time.Duration(i) * time.Minute), Up: true, Latency: time.Duration(i) * time.Millisecond})
+    }
+
+    series := trend.series()
+    if total := len(series.Raw) + len(series.Minute) + len(series.FiveMinute); total != 3 {
+        t.Fatalf("kept %d points, want 3", total)
+    }
+    if series.Raw[0].LatencyMs != 7 {
+        t.Errorf("oldest point kept %+v, want the seventh", series.Raw[0])
+    }
+}
+
+func TestHistoryEndpoint(t *testing.T) {
+    server := newStatusServer(t, http.StatusOK)
+    service := testService("api", server.URL)
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service, testService("web", server.URL)}})
+    checkAndFlush(m, service)
+
+    recorder := apiRequest(m, http.MethodGet, "/history?service=api", "", false)
+    var result map[string]trendSeries
+    if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+        t.Fatalf("decoding %q: %v", recorder.Body, err)
+    }
+    if len(result) != 1 || len(result["api"].Raw) != 1 || !result["api"].Raw[0].Up {
+        t.Errorf("history %+v, want one passing check of api", result)
+    }
+
+    if recorder := apiRequest(m, http.MethodGet, "/history?service=missing", "", false); recorder.Code != http.StatusNotFound {
+        t.Errorf("history of an unknown service returned %d, want 404", recorder.Code)
+    }
+}
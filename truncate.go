@@ -0,0 +1,47 @@
+package main
+
+import "unicode/utf8"
+
+// ellipsis marks a message that was cut to fit its channel
+const ellipsis = "…"
+
+// defaultMessageLimits are the maximum message sizes in bytes for channels
+// with payload limits. "sms" covers a custom sender registered under that
+// name. Email, file and syslog messages are never truncated by default.
+var defaultMessageLimits = map[string]int{
+    ChannelSlack:      40000,
+    ChannelGoogleChat: 4096,
+    ChannelPagerDuty:  1024,
+    ChannelSNS:        262144,
+    "sms":             160,
+}
+
+// pagerDutySummaryLimit is the Events API limit on the summary, which
+// prefixes the message with the service name
+const pagerDutySummaryLimit = 1024
+
+// messageLimit returns the channel's message limit in bytes, 0 if none
+func (m *Monitor) messageLimit(channel string) int {
+    if limit := m.config.Alerts.MessageLimits[channel]; limit > 0 {
+        return limit
+    }
+    return defaultMessageLimits[channel]
+}
+
+// truncateMessage cuts message to at most limit bytes, ending it with an
+// ellipsis and never splitting a UTF-8 sequence. A limit <= 0 means none.
+func truncateMessage(message string, limit int) string {
+    if limit <= 0 || len(message) <= limit {
+        return message
+    }
+
+    suffix := ellipsis
+    if limit < len(suffix) {
+        suffix = ""
+    }
+    cut := limit - len(suffix)
+    for cut > 0 && !utf8.RuneStart(message[cut]) {
+        cut--
+    }
+    return message[:cut] + suffix
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+func TestTruncateMessage(t *testing.T) {
+    tests := []struct {
+        name    string
+        message string
+        limit   int
+        want    string
+    }{
+        {"under the limit", "connection refused", 160, "connection refused"},
+        {"at the limit", "abcdef", 6, "abcdef"},
+        {"over the limit", "abcdefghij", 8, "abcde" + ellipsis},
+        {"no limit", strings.Repeat("x", 1000), 0, strings.Repeat("x", 1000)},
+        {"limit shorter than the ellipsis", "abcdef", 2, "ab"},
+        {"multibyte rune not split", "ab€€€", 7, "ab" + ellipsis},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := truncateMessage(tt.message, tt.limit)
+            if got != tt.want {
+                t.Errorf("truncateMessage() = %q, want %q", got, tt.want)
+            }
+            if !utf8.ValidString(got) {
+                t.Errorf("truncateMessage() = %q, not valid UTF-8", got)
+            }
+        })
+    }
+}
+
+func TestChannelMessageLimits(t *testing.T) {
+    message := strings.Repeat("upstream returned an error page ", 5000)
+
+    tests := []struct {
+        name    string
+        limits  map[string]int // alerts.message_limits
+        channel string
+        want    int // bytes delivered
+    }{
+        {"sms default", nil, "sms", 160},
+        {"slack default", nil, ChannelSlack, 40000},
+        {"pagerduty default", nil, ChannelPagerDuty, 1024},
+        {"google chat default", nil, ChannelGoogleChat, 4096},
+        {"configured limit", map[string]int{ChannelSlack: 500}, ChannelSlack, 500},
+        {"configured limit for a custom channel", map[string]int{"pager": 64}, "pager", 64},
+        {"email not truncated", nil, ChannelEmail, len(message)},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{Alerts: AlertConfig{MessageLimits: tt.limits}})
+            sender := &recordingSender{}
+            m.RegisterSender(tt.channel, sender)
+
+            m.deliver(m.newAlertEvent(EventAlert, ServiceConfig{Name: "api"}, message), []string{tt.channel})
+            m.alertWG.Wait()
+
+            sender.mutex.Lock()
+            defer sender.mutex.Unlock()
+            if len(sender.events) != 1 {
+                t.Fatalf("%d events delivered, want 1", len(sender.events))
+            }
+            got := sender.events[0].Message
+            if len(got) != tt.want {
+                t.Errorf("delivered %d bytes, want %d", len(got), tt.want)
+            }
+            if tt.want < len(message) && !strings.HasSuffix(got, ellipsis) {
+                t.Errorf("truncated message %q does not end with an ellipsis", got[len(got)-10:])
+            }
+        })
+    }
+}
+
+func TestPagerDutySummaryTruncated(t *testing.T) {
+    m, _ := newTestMonitor(t, MonitorConfig{Alerts: AlertConfig{PagerDuty: PagerDutyConfig{ServiceKey: "routing-key"}}})
+    message := strings.Repeat("x", 2000)
+    event := m.pagerDutyTriggerEvent(ServiceConfig{Name: "api"}, message)
+
+    payload := event["payload"].(map[string]interface{})
+    summary := payload["summary"].(string)
+    if len(summary) != pagerDutySummaryLimit || !strings.HasPrefix(summary, "Service api is DOWN") {
+        t.Errorf("summary is %d bytes starting %q, want %d starting with the service", len(summary), summary[:20], pagerDutySummaryLimit)
+    }
+}
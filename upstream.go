@@ -0,0 +1,38 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// upstreamProbeTimeout bounds the upstream probe made before alerting
+const upstreamProbeTimeout = 5 * time.Second
+
+// upstreamDown probes the service's upstream_health_url and reports an
+// error describing why it is down, or nil when it is healthy or unset. An
+// upstream that cannot be reached counts as down.
+func (m *Monitor) upstreamDown(service ServiceConfig) error {
+    if service.UpstreamHealthURL == "" {
+        return nil
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), upstreamProbeTimeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, service.UpstreamHealthURL, nil)
+    if err != nil {
+        return err
+    }
+
+    resp, err := m.httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+
+    if resp.StatusCode >= 400 {
+        return fmt.Errorf("status %d", resp.StatusCode)
+    }
+    return nil
+}
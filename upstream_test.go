@@ -0,0 +1,118 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+)
+
+func TestUpstreamSuppression(t *testing.T) {
+    tests := []struct {
+        name         string
+        upstreamUpAt int      // check from which the upstream is healthy, -1 for never
+        codes        []int    // service response per check
+        want         []string // event kinds delivered
+    }{
+        {
+            name:         "alert deferred until the upstream recovers",
+            upstreamUpAt: 1,
+            codes:        []int{500, 500, 200},
+            want:         []string{EventAlert, EventRecovery},
+        },
+        {
+            name:         "no recovery for a suppressed alert",
+            upstreamUpAt: -1,
+            codes:        []int{500, 500, 200},
+            want:         []string{},
+        },
+        {
+            name:         "healthy upstream alerts at once",
+            upstreamUpAt: 0,
+            codes:        []int{500, 200},
+            want:         []string{EventAlert, EventRecovery},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            backend := newStatusServer(t, http.StatusOK)
+            upstream := newStatusServer(t, http.StatusServiceUnavailable)
+            service := testService("api", backend.URL)
+            service.UpstreamHealthURL = upstream.URL
+            m, sender := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+            for i, code := range tt.codes {
+                if i == tt.upstreamUpAt {
+                    upstream.code.Store(http.StatusOK)
+                }
+                backend.code.Store(int32(code))
+                checkAndFlush(m, service)
+            }
+
+            if got := sender.kinds(); !equalStrings(got, tt.want) {
+                t.Errorf("delivered %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestUpstreamSuppressionFlag(t *testing.T) {
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    upstream := newStatusServer(t, http.StatusServiceUnavailable)
+    service := testService("api", backend.URL)
+    service.UpstreamHealthURL = upstream.URL
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    checkAndFlush(m, service)
+    m.statusMutex.RLock()
+    status := *m.serviceStatus["api"]
+    m.statusMutex.RUnlock()
+    if status.AlertSent || !status.AlertSuppressed {
+        t.Errorf("AlertSent = %v, AlertSuppressed = %v after a suppressed alert", status.AlertSent, status.AlertSuppressed)
+    }
+}
+
+func TestUpstreamDown(t *testing.T) {
+    unreachable := newStatusServer(t, http.StatusOK)
+    unreachable.Close()
+
+    tests := []struct {
+        name    string
+        url     string
+        wantErr string // empty when the upstream counts as healthy
+    }{
+        {"no upstream", "", ""},
+        {"healthy upstream", newStatusServer(t, http.StatusOK).URL, ""},
+        {"upstream not modified", newStatusServer(t, http.StatusNotModified).URL, ""},
+        {"upstream erroring", newStatusServer(t, http.StatusServiceUnavailable).URL, "status 503"},
+        {"upstream not found", newStatusServer(t, http.StatusNotFound).URL, "status 404"},
+        {"upstream unreachable", unreachable.URL, "connection refused"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m, _ := newTestMonitor(t, MonitorConfig{})
+            err := m.upstreamDown(ServiceConfig{Name: "api", UpstreamHealthURL: tt.url})
+            if tt.wantErr == "" && err != nil {
+                t.Errorf("upstreamDown() = %v, want nil", err)
+            }
+            if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Errorf("upstreamDown() = %v, want an error containing %q", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestUpstreamSuppressionLogged(t *testing.T) {
+    logs := captureLog(t)
+    backend := newStatusServer(t, http.StatusInternalServerError)
+    upstream := newStatusServer(t, http.StatusBadGateway)
+    service := testService("api", backend.URL)
+    service.UpstreamHealthURL = upstream.URL
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{service}})
+
+    checkAndFlush(m, service)
+    if want := "Alert for api suppressed, upstream " + upstream.URL + " is down: status 502"; !strings.Contains(logs.String(), want) {
+        t.Errorf("log %q, want %q", logs.String(), want)
+    }
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+    "fmt"
+    "net"
+    "net/url"
+)
+
+func validateConfig(config MonitorConfig) error {
+    seen := make(map[string]bool)
+    for _, service := range config.Services {
+        if err := validateServiceConfig(service); err != nil {
+            return err
+        }
+        if err := validateFleetServiceName(config.FleetHealthAlert, service); err != nil {
+            return err
+        }
+        if seen[service.Name] {
+            return fmt.Errorf("duplicate service name %q", service.Name)
+        }
+        seen[service.Name] = true
+    }
+    if err := validateGroupKeys(config.Services); err != nil {
+        return err
+    }
+    for channel, policy := range config.Alerts.DeliveryRetry {
+        if policy.Jitter < 0 || policy.Jitter > 1 {
+            return fmt.Errorf("alerts.delivery_retry.%s: jitter must be between 0 and 1", channel)
+        }
+    }
+    for i, listener := range config.Listeners {
+        if listener.Address == "" {
+            return fmt.Errorf("listeners[%d]: address is required", i)
+        }
+    }
+    for _, channel := range config.StaleAlertChannels {
+        if !knownChannels[channel] {
+            return fmt.Errorf("stale_alert_channels: unknown channel %q", channel)
+        }
+    }
+    if config.StatusExport != nil && config.StatusExport.Path == "" {
+        return fmt.Errorf("status_export: path is required")
+    }
+    if err := validateMaintenanceCalendar(config.MaintenanceCalendar); err != nil {
+        return err
+    }
+    if err := validateFleetHealthAlert(config.FleetHealthAlert); err != nil {
+        return err
+    }
+    if err := validateTimeRouting(config.Alerts.TimeRouting); err != nil {
+        return err
+    }
+    if err := validateSNSConfig(config.Alerts.SNS); err != nil {
+        return err
+    }
+    return validateNATSConfig(config.Alerts.NATS)
+}
+
+func validateServiceConfig(service ServiceConfig) error {
+    if service.Name == "" {
+        return fmt.Errorf("service name is required")
+    }
+
+    if !urlOptional[service.Type] || service.URL != "" {
+        parsed, err := url.Parse(service.URL)
+        if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+            return fmt.Errorf("service %s: invalid url %q", service.Name, service.URL)
+        }
+    }
+
+    if err := validateCheckType(service); err != nil {
+        return err
+    }
+
+    if service.CheckInterval <= 0 {
+        return fmt.Errorf("service %s: check_interval must be positive", service.Name)
+    }
+    if service.RetryAttempts <= 0 {
+        return fmt.Errorf("service %s: retry_attempts must be positive", service.Name)
+    }
+    if service.Timeout < 0 || service.RetryDelay < 0 {
+        return fmt.Errorf("service %s: timeout and retry_delay must not be negative", service.Name)
+    }
+
+    switch service.Severity {
+    case "", SeverityInfo, SeverityWarning, SeverityCritical:
+    default:
+        return fmt.Errorf("service %s: unknown severity %q", service.Name, service.Severity)
+    }
+
+    switch service.FailMode {
+    case "", FailModeClosed, FailModeOpen:
+    default:
+        return fmt.Errorf("service %s: unknown fail_mode %q", service.Name, service.FailMode)
+    }
+
+    switch service.PagerDutySeverity {
+    case "", "critical", "error", "warning", "info":
+    default:
+        return fmt.Errorf("service %s: unknown pagerduty_severity %q", service.Name, service.PagerDutySeverity)
+    }
+
+    switch service.ContentTypeMatch {
+    case "", "exact", "prefix":
+    default:
+        return fmt.Errorf("service %s: unknown content_type_match %q", service.Name, service.ContentTypeMatch)
+    }
+
+    switch service.FinalURLMatch {
+    case "", "exact", "prefix":
+    default:
+        return fmt.Errorf("service %s: unknown final_url_match %q", service.Name, service.FinalURLMatch)
+    }
+
+    if service.Socks5Proxy != nil {
+        if _, _, err := net.SplitHostPort(service.Socks5Proxy.Address); err != nil {
+            return fmt.Errorf("service %s: invalid socks5_proxy address %q", service.Name, service.Socks5Proxy.Address)
+        }
+    }
+
+    if service.ConnectAddr != "" {
+        if _, _, err := net.SplitHostPort(service.ConnectAddr); err != nil {
+            return fmt.Errorf("service %s: invalid connect_addr %q, expected ip:port", service.Name, service.ConnectAddr)
+        }
+        if service.Socks5Proxy != nil {
+            return fmt.Errorf("service %s: connect_addr cannot be combined with socks5_proxy", service.Name)
+        }
+    }
+
+    for _, threshold := range service.FailureCountAlerts {
+        if threshold.Count <= 0 {
+            return fmt.Errorf("service %s: failure_count_alerts count must be positive", service.Name)
+        }
+        for _, channel := range threshold.Channels {
+            if !knownChannels[channel] {
+                return fmt.Errorf("service %s: unknown channel %q in failure_count_alerts", service.Name, channel)
+            }
+        }
+    }
+
+    for _, code := range service.RetryableStatuses {
+        if code < 100 || code > 599 {
+            return fmt.Errorf("service %s: invalid status code %d in retryable_statuses", service.Name, code)
+        }
+    }
+
+    if service.MaxBodyBytes < 0 {
+        return fmt.Errorf("service %s: max_body_bytes must not be negative", service.Name)
+    }
+
+    if service.BaselineLatencyMs < 0 || service.DriftPercent < 0 {
+        return fmt.Errorf("service %s: baseline_latency_ms and drift_percent must not be negative", service.Name)
+    }
+
+    if err := validateQuorum(service); err != nil {
+        return err
+    }
+
+    if err := validateRedirects(service); err != nil {
+        return err
+    }
+
+    if err := validatePriority(service); err != nil {
+        return err
+    }
+
+    if service.DNSCacheTTL < 0 {
+        return fmt.Errorf("service %s: dns_cache_ttl must not be negative", service.Name)
+    }
+
+    if service.Weight < 0 {
+        return fmt.Errorf("service %s: weight must not be negative", service.Name)
+    }
+
+    if breaker := service.CircuitBreaker; breaker != nil && (breaker.FailureThreshold <= 0 || breaker.MaxInterval < 0) {
+        return fmt.Errorf("service %s: circuit_breaker needs a positive failure_threshold and a non-negative max_interval", service.Name)
+    }
+
+    if err := validateBusinessHours(service.BusinessHours); err != nil {
+        return fmt.Errorf("service %s: %v", service.Name, err)
+    }
+
+    if err := validateStatusCodeStates(service); err != nil {
+        return err
+    }
+
+    if err := validateStatusBodyChecks(service); err != nil {
+        return err
+    }
+
+    if err := validatePatterns(service); err != nil {
+        return err
+    }
+
+    if _, err := dialNetwork(service.AddressFamily, "tcp"); err != nil {
+        return fmt.Errorf("service %s: %v", service.Name, err)
+    }
+
+    if service.MinTLSVersion != "" {
+        if _, ok := tlsVersions[service.MinTLSVersion]; !ok {
+            return fmt.Errorf("service %s: unknown min_tls_version %q", service.Name, service.MinTLSVersion)
+        }
+    }
+
+    if service.ExpectedProtocol != "" {
+        if !protocols[service.ExpectedProtocol] {
+            return fmt.Errorf("service %s: unknown expected_protocol %q", service.Name, service.ExpectedProtocol)
+        }
+        if service.ExpectedProtocol == "HTTP/2.0" && service.ForceHTTP1 {
+            return fmt.Errorf("service %s: expected_protocol HTTP/2.0 conflicts with force_http1", service.Name)
+        }
+    }
+
+    switch service.CertValidation {
+    case "", CertValidationWarn, CertValidationStrict:
+    default:
+        return fmt.Errorf("service %s: unknown cert_validation %q", service.Name, service.CertValidation)
+    }
+
+    return nil
+}
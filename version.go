@@ -0,0 +1,44 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "time"
+)
+
+// Set at build time, e.g.
+//   go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+var (
+    version   = "dev"
+    commit    = "unknown"
+    buildDate = "unknown"
+)
+
+type versionInfo struct {
+    Version       string `json:"version"`
+    Commit        string `json:"commit"`
+    BuildDate     string `json:"build_date"`
+    ServiceCount  int    `json:"service_count"`
+    UptimeSeconds int64  `json:"uptime_seconds"`
+    Uptime        string `json:"uptime"`
+}
+
+func (m *Monitor) versionInfo() versionInfo {
+    m.statusMutex.RLock()
+    serviceCount := len(m.config.Services)
+    m.statusMutex.RUnlock()
+
+    uptime := time.Since(m.startTime)
+    return versionInfo{
+        Version:       version,
+        Commit:        commit,
+        BuildDate:     buildDate,
+        ServiceCount:  serviceCount,
+        UptimeSeconds: int64(uptime.Seconds()),
+        Uptime:        uptime.Round(time.Second).String(),
+    }
+}
+
+func (m *Monitor) handleVersion(w http.ResponseWriter, r *http.Request) {
+    json.NewEncoder(w).Encode(m.versionInfo())
+}
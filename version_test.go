@@ -0,0 +1,28 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+    defer func(v, c string) { version, commit = v, c }(version, commit)
+    version, commit = "1.2.3", "abc1234"
+
+    m, _ := newTestMonitor(t, MonitorConfig{Services: []ServiceConfig{testService("api", "https://api.example.com/")}})
+    m.startTime = time.Now().Add(-90 * time.Second)
+
+    resp := apiRequest(m, http.MethodGet, "/version", "", false)
+    var info versionInfo
+    if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+        t.Fatalf("decoding /version: %v", err)
+    }
+    if info.Version != "1.2.3" || info.Commit != "abc1234" || info.ServiceCount != 1 {
+        t.Errorf("version info %+v", info)
+    }
+    if info.UptimeSeconds < 90 || info.UptimeSeconds > 95 || info.Uptime == "" {
+        t.Errorf("uptime %ds (%q), want about 90s", info.UptimeSeconds, info.Uptime)
+    }
+}